@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// DefaultAPIKeyLifetime is the lifetime of API keys created without an
+// expiry.
+const DefaultAPIKeyLifetime = 90 * 24 * time.Hour
+
+// apiKeySecretSize is the size in bytes of the random secret of an API key.
+const apiKeySecretSize = 32
+
+// APIKey is a long-lived key that authenticates automation clients as a user,
+// usually a service account, with some of the user's roles. The key is the ID
+// and a random secret, of which only the hash is stored.
+type APIKey struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+
+	// Name describes what the key is used for. It is unique for the user.
+	Name string `json:"name"`
+
+	// SecretHash is the hex-encoded SHA-256 hash of the secret. Unlike
+	// passwords, secrets are random, so a fast hash cannot be brute-forced.
+	SecretHash string `json:"secret_hash"`
+
+	// Roles are the roles the key grants, which must also be roles of the
+	// user.
+	Roles []string `json:"roles"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// LastUsedAt is the time the key last authenticated a request, to the
+	// minute, or zero if it never has.
+	LastUsedAt time.Time `json:"last_used_at"`
+
+	ResourceVersion
+}
+
+// NewAPIKey returns a new API key of a user and the key to give to the
+// client, which is not stored.
+func NewAPIKey(userID, name string, roles []string, expiresAt time.Time) (*APIKey, string, error) {
+	secret := make([]byte, apiKeySecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	k := &APIKey{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Name:       name,
+		SecretHash: hashAPIKeySecret(encodedSecret),
+		Roles:      roles,
+		CreatedAt:  time.Now().UTC(),
+		ExpiresAt:  expiresAt.UTC(),
+	}
+	if expiresAt.IsZero() {
+		k.ExpiresAt = k.CreatedAt.Add(DefaultAPIKeyLifetime)
+	}
+
+	return k, k.ID + "." + encodedSecret, nil
+}
+
+func hashAPIKeySecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// GetTableName returns the name of the persistence table.
+func (*APIKey) GetTableName() string {
+	return "api_keys"
+}
+
+// GetID gets the ID.
+func (k *APIKey) GetID() string {
+	return k.ID
+}
+
+// SetID sets the ID.
+func (k *APIKey) SetID(id string) {
+	k.ID = id
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*APIKey) FilterFields() []string {
+	return []string{
+		"user_id",
+		"name",
+	}
+}
+
+// SensitiveFields returns the fields that are redacted in the audit log.
+func (*APIKey) SensitiveFields() []string {
+	return []string{
+		"secret_hash",
+	}
+}
+
+// CheckSecret returns whether the secret of a key matches the key. The hashes
+// are compared in constant time.
+func (k *APIKey) CheckSecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(k.SecretHash)) == 1
+}
+
+// IsExpired returns whether the key is expired at a time.
+func (k *APIKey) IsExpired(t time.Time) bool {
+	return !t.Before(k.ExpiresAt)
+}
+
+// Scope returns the roles the key grants to its user, which are the roles of
+// the key the user still has.
+func (k *APIKey) Scope(u *User) []string {
+	var roles []string
+	for _, role := range k.Roles {
+		for _, userRole := range u.Roles {
+			if role == userRole {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	return roles
+}
+
+// Validate validates the model.
+func (k *APIKey) Validate() error {
+	if !uuid.IsValid(k.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(k.UserID) {
+		return errors.New("user_id not a valid uuid")
+	}
+	if k.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if k.SecretHash == "" {
+		return errors.New("secret_hash cannot be empty")
+	}
+	if len(k.Roles) == 0 {
+		return errors.New("roles cannot be empty")
+	}
+	for _, role := range k.Roles {
+		if !IsRole(role) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+	}
+	if !k.ExpiresAt.After(k.CreatedAt) {
+		return errors.New("expires_at must be after created_at")
+	}
+
+	return nil
+}
+
+func (k *APIKey) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+APIKey[
+    ID: %s
+    UserID: %s
+    Name: %s
+    Roles: %v
+    CreatedAt: %s
+    ExpiresAt: %s
+    LastUsedAt: %s
+]`),
+		k.ID,
+		k.UserID,
+		k.Name,
+		k.Roles,
+		k.CreatedAt,
+		k.ExpiresAt,
+		k.LastUsedAt)
+}
+
+// AuthenticateAPIKey returns the API key that a key is, and its user, or nil
+// if the key is malformed, unknown, expired or of a deleted user.
+func AuthenticateAPIKey(ctx context.Context, ps PersistenceService, key string) (*APIKey, *User, error) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 || !uuid.IsValid(parts[0]) {
+		return nil, nil, nil
+	}
+
+	e, err := ps.Read(ctx, parts[0], &APIKey{})
+	if err != nil || e == nil {
+		return nil, nil, err
+	}
+	k := e.(*APIKey)
+	if !k.CheckSecret(parts[1]) || k.IsExpired(time.Now()) {
+		return nil, nil, nil
+	}
+
+	e, err = ps.Read(ctx, k.UserID, &User{})
+	if err != nil || e == nil {
+		return nil, nil, err
+	}
+
+	return k, e.(*User), nil
+}
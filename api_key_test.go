@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	"go.etcd.io/bbolt"
+)
+
+var _ = Describe("Entities: APIKey", func() {
+	var (
+		key *cce.APIKey
+	)
+
+	BeforeEach(func() {
+		key = &cce.APIKey{
+			ID:         "0b4b0cd5-f3c2-4a1b-a2c8-7d4b4c0aa6a1",
+			UserID:     "ca0fa495-1020-405b-a78c-9a1884349078",
+			Name:       "ci",
+			SecretHash: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			Roles:      []string{cce.RoleOperator},
+			CreatedAt:  time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC),
+			ExpiresAt:  time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "api_keys"`, func() {
+			Expect(key.GetTableName()).To(Equal("api_keys"))
+		})
+	})
+
+	Describe("GetID", func() {
+		It("Should return the ID", func() {
+			Expect(key.GetID()).To(Equal("0b4b0cd5-f3c2-4a1b-a2c8-7d4b4c0aa6a1"))
+		})
+	})
+
+	Describe("SetID", func() {
+		It("Should set and return the updated ID", func() {
+			By("Setting the ID")
+			key.SetID("456")
+
+			By("Getting the updated ID")
+			Expect(key.ID).To(Equal("456"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(key.FilterFields()).To(Equal([]string{
+				"user_id",
+				"name",
+			}))
+		})
+	})
+
+	Describe("NewAPIKey and CheckSecret", func() {
+		It("Should only match the secret of the key", func() {
+			k, s, err := cce.NewAPIKey(key.UserID, "ci", []string{cce.RoleViewer}, time.Time{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k.Validate()).To(Succeed())
+			Expect(k.ExpiresAt).To(Equal(k.CreatedAt.Add(cce.DefaultAPIKeyLifetime)))
+
+			parts := strings.SplitN(s, ".", 2)
+			Expect(parts).To(HaveLen(2))
+			Expect(parts[0]).To(Equal(k.ID))
+			Expect(k.SecretHash).ToNot(ContainSubstring(parts[1]))
+			Expect(k.CheckSecret(parts[1])).To(BeTrue())
+			Expect(k.CheckSecret(parts[1] + "x")).To(BeFalse())
+			Expect(k.CheckSecret("")).To(BeFalse())
+		})
+	})
+
+	Describe("IsExpired", func() {
+		It("Should return whether the key is expired", func() {
+			Expect(key.IsExpired(key.ExpiresAt.Add(-time.Second))).To(BeFalse())
+			Expect(key.IsExpired(key.ExpiresAt)).To(BeTrue())
+		})
+	})
+
+	Describe("Scope", func() {
+		It("Should return the roles of the key the user has", func() {
+			key.Roles = []string{cce.RoleOperator, cce.RoleViewer}
+			Expect(key.Scope(&cce.User{Roles: []string{cce.RoleViewer}})).To(Equal([]string{cce.RoleViewer}))
+			Expect(key.Scope(&cce.User{Roles: []string{cce.RoleAdmin}})).To(BeEmpty())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error for a valid key", func() {
+			Expect(key.Validate()).To(Succeed())
+		})
+
+		It("Should return an error for an invalid user ID", func() {
+			key.UserID = "123"
+			Expect(key.Validate()).To(MatchError("user_id not a valid uuid"))
+		})
+
+		It("Should return an error if there is no name", func() {
+			key.Name = ""
+			Expect(key.Validate()).To(MatchError("name cannot be empty"))
+		})
+
+		It("Should return an error for an unknown role", func() {
+			key.Roles = []string{"root"}
+			Expect(key.Validate()).To(MatchError(`unknown role "root"`))
+		})
+
+		It("Should return an error if it expires before it was created", func() {
+			key.ExpiresAt = key.CreatedAt
+			Expect(key.Validate()).To(MatchError("expires_at must be after created_at"))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value without the secret hash", func() {
+			Expect(key.String()).To(Equal(strings.TrimSpace(`
+APIKey[
+    ID: 0b4b0cd5-f3c2-4a1b-a2c8-7d4b4c0aa6a1
+    UserID: ca0fa495-1020-405b-a78c-9a1884349078
+    Name: ci
+    Roles: [operator]
+    CreatedAt: 2020-01-01 12:00:00 +0000 UTC
+    ExpiresAt: 2020-04-01 12:00:00 +0000 UTC
+    LastUsedAt: 0001-01-01 00:00:00 +0000 UTC
+]`,
+			)))
+		})
+	})
+})
+
+var _ = Describe("AuthenticateAPIKey", func() {
+	var (
+		ctx  = context.Background()
+		dir  string
+		db   *bbolt.DB
+		ps   cce.PersistenceService
+		user *cce.User
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "cce-api-keys")
+		Expect(err).ToNot(HaveOccurred())
+		db, err = bolt.Open(filepath.Join(dir, "cce.db"))
+		Expect(err).ToNot(HaveOccurred())
+		ps = &bolt.PersistenceService{DB: db}
+
+		user = &cce.User{
+			ID:             "ca0fa495-1020-405b-a78c-9a1884349078",
+			Name:           "ci",
+			Roles:          []string{cce.RoleOperator},
+			ServiceAccount: true,
+		}
+		Expect(ps.Create(ctx, user)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("Should only authenticate the key", func() {
+		k, s, err := cce.NewAPIKey(user.ID, "deploy", user.Roles, time.Time{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ps.Create(ctx, k)).To(Succeed())
+
+		authenticated, authenticatedUser, err := cce.AuthenticateAPIKey(ctx, ps, s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authenticated.ID).To(Equal(k.ID))
+		Expect(authenticatedUser.ID).To(Equal(user.ID))
+
+		for _, bad := range []string{"", k.ID, k.ID + ".", s + "x", "123." + strings.SplitN(s, ".", 2)[1]} {
+			authenticated, authenticatedUser, err = cce.AuthenticateAPIKey(ctx, ps, bad)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authenticated).To(BeNil())
+			Expect(authenticatedUser).To(BeNil())
+		}
+	})
+
+	It("Should not authenticate an expired key", func() {
+		k, s, err := cce.NewAPIKey(user.ID, "deploy", user.Roles, time.Now().Add(time.Millisecond))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ps.Create(ctx, k)).To(Succeed())
+		time.Sleep(2 * time.Millisecond)
+
+		authenticated, _, err := cce.AuthenticateAPIKey(ctx, ps, s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authenticated).To(BeNil())
+	})
+
+	It("Should not authenticate a key of a deleted user", func() {
+		k, s, err := cce.NewAPIKey(user.ID, "deploy", user.Roles, time.Time{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ps.Create(ctx, k)).To(Succeed())
+		Expect(ps.Delete(ctx, user.ID, &cce.User{})).To(BeTrue())
+
+		authenticated, _, err := cce.AuthenticateAPIKey(ctx, ps, s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authenticated).To(BeNil())
+	})
+})
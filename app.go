@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/open-ness/edgecontroller/k8s"
 	"github.com/open-ness/edgecontroller/uuid"
 )
 
@@ -25,6 +26,8 @@ type App struct {
 	Ports       []PortProto  `json:"ports,omitempty"`
 	Source      string       `json:"source"`
 	EPAFeatures []EPAFeature `json:"epafeatures,omitempty"`
+
+	ResourceVersion
 }
 
 // PortProto is a port and protocol combination. It is typically used to represent the ports and protocols that an
@@ -77,11 +80,12 @@ func (app *App) Validate() error { // nolint: gocyclo
 	if app.Version == "" {
 		return errors.New("version cannot be empty")
 	}
-	if app.Cores < 1 || app.Cores > MaxCores {
-		return fmt.Errorf("cores must be in [1..%d]", MaxCores)
+	limits := CurrentLimits()
+	if app.Cores < 1 || app.Cores > limits.MaxCores {
+		return fmt.Errorf("cores must be in [1..%d]", limits.MaxCores)
 	}
-	if app.Memory < 1 || app.Memory > MaxMemory {
-		return fmt.Errorf("memory must be in [1..%d]", MaxMemory)
+	if app.Memory < 1 || app.Memory > limits.MaxMemory {
+		return fmt.Errorf("memory must be in [1..%d]", limits.MaxMemory)
 	}
 	for _, pp := range app.Ports {
 		switch pp.Protocol {
@@ -109,7 +113,14 @@ func (app *App) Validate() error { // nolint: gocyclo
 
 // FilterFields returns the filterable fields for this model.
 func (*App) FilterFields() []string {
-	return []string{}
+	return []string{
+		"type",
+		"name",
+		"version",
+		"vendor",
+		"cores",
+		"memory",
+	}
 }
 
 func (app *App) String() string {
@@ -138,6 +149,25 @@ App[
 		app.EPAFeatures)
 }
 
+// K8SApp returns the app as deployed by the Kubernetes client.
+func (app *App) K8SApp() k8s.App {
+	var ports []*k8s.PortProto
+	for _, port := range app.Ports {
+		ports = append(ports, &k8s.PortProto{
+			Port:     int32(port.Port),
+			Protocol: port.Protocol,
+		})
+	}
+
+	return k8s.App{
+		ID:     app.ID,
+		Image:  app.ID + ":latest",
+		Cores:  app.Cores,
+		Memory: app.Memory,
+		Ports:  ports,
+	}
+}
+
 // EPAValidate returns error if provided nodeFeatures do not fulfill app.EPAFeatures
 func (app *App) EPAValidate(nodeFeatures map[string]string) error {
 	for _, epaFeature := range app.EPAFeatures {
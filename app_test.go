@@ -106,6 +106,16 @@ var _ = Describe("Entities: App", func() {
 				"memory must be in [1..16384]"))
 		})
 
+		It("Should validate Cores and Memory against the current limits", func() {
+			defer cce.SetLimits(cce.CurrentLimits())
+			cce.SetLimits(cce.Limits{MaxCores: 2, MaxMemory: 512, MaxBodySize: 1024})
+			Expect(app.Validate()).To(MatchError("cores must be in [1..2]"))
+			app.Cores = 2
+			Expect(app.Validate()).To(MatchError("memory must be in [1..512]"))
+			app.Memory = 512
+			Expect(app.Validate()).To(Succeed())
+		})
+
 		It("Should return an error if Ports (port) is invalid", func() {
 			app.Ports[0].Port = 99999
 			Expect(app.Validate()).To(MatchError(
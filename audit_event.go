@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/pkg/errors"
+)
+
+// Audit actions.
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	AuditActionEnroll = "enroll"
+)
+
+// AuditEvent records a change made through the API or by a node. Audit events
+// are only ever created.
+type AuditEvent struct {
+	ID string `json:"id"`
+
+	// Time is the time of the event in microseconds since the Unix epoch.
+	Time int64 `json:"time"`
+
+	// Actor is the user that made the change, "cert:" followed by the common
+	// name of an operator certificate that is not a user's, or "node:"
+	// followed by the ID of the node.
+	Actor string `json:"actor"`
+
+	// Action is one of the audit actions.
+	Action string `json:"action"`
+
+	// Request is the method and path of the HTTP request or the full gRPC
+	// method that made the change.
+	Request string `json:"request"`
+
+	// EntityType is the table name of the changed entity.
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+
+	// Diff holds the changed top-level fields of the entity.
+	Diff map[string]AuditChange `json:"diff,omitempty"`
+
+	SourceIP string `json:"source_ip"`
+
+	ResourceVersion
+}
+
+// AuditChange is the change of a field. Before is empty if the entity was
+// created and After is empty if it was deleted.
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// NewAuditEvent returns an event with a new ID at the current time for an
+// action on an entity. Before is nil if the entity was created and after is
+// nil if it was deleted.
+func NewAuditEvent(action string, before, after Persistable) (*AuditEvent, error) {
+	e := &AuditEvent{
+		ID:     uuid.New(),
+		Time:   AuditTime(time.Now()),
+		Action: action,
+	}
+
+	for _, entity := range []Persistable{after, before} {
+		if entity != nil {
+			e.EntityType = entity.GetTableName()
+			e.EntityID = entity.GetID()
+			break
+		}
+	}
+
+	var err error
+	if e.Diff, err = auditDiff(before, after); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// AuditTime converts a time to the time of an audit event.
+func AuditTime(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+// GetTime gets the time of the event.
+func (e *AuditEvent) GetTime() time.Time {
+	return time.Unix(0, e.Time*int64(time.Microsecond)).UTC()
+}
+
+func auditDiff(before, after Persistable) (map[string]AuditChange, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(map[string]AuditChange)
+	for name, value := range beforeFields {
+		if !bytes.Equal(value, afterFields[name]) {
+			diff[name] = AuditChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			diff[name] = AuditChange{After: value}
+		}
+	}
+	if len(diff) == 0 {
+		return nil, nil
+	}
+
+	// Record that sensitive fields changed, but not their values
+	for _, e := range []Persistable{before, after} {
+		s, ok := e.(Sensitive)
+		if !ok {
+			continue
+		}
+		for _, name := range s.SensitiveFields() {
+			change, ok := diff[name]
+			if !ok {
+				continue
+			}
+			if change.Before != nil {
+				change.Before = auditRedacted
+			}
+			if change.After != nil {
+				change.After = auditRedacted
+			}
+			diff[name] = change
+		}
+	}
+
+	return diff, nil
+}
+
+// auditRedacted replaces the values of sensitive fields in the audit log.
+var auditRedacted = json.RawMessage(`"*****"`)
+
+// Sensitive is an entity with fields whose values are not recorded in the
+// audit log.
+type Sensitive interface {
+	SensitiveFields() []string
+}
+
+func auditFields(e Persistable) (map[string]json.RawMessage, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling")
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(bytes, &fields); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling")
+	}
+
+	return fields, nil
+}
+
+// GetTableName returns the name of the persistence table.
+func (*AuditEvent) GetTableName() string {
+	return "audit_events"
+}
+
+// GetID gets the ID.
+func (e *AuditEvent) GetID() string {
+	return e.ID
+}
+
+// SetID sets the ID.
+func (e *AuditEvent) SetID(id string) {
+	e.ID = id
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*AuditEvent) FilterFields() []string {
+	return []string{
+		"time",
+		"actor",
+		"action",
+		"entity_type",
+		"entity_id",
+	}
+}
+
+func (e *AuditEvent) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+AuditEvent[
+    ID: %s
+    Time: %s
+    Actor: %s
+    Action: %s
+    Request: %s
+    EntityType: %s
+    EntityID: %s
+    SourceIP: %s
+]`),
+		e.ID,
+		e.GetTime(),
+		e.Actor,
+		e.Action,
+		e.Request,
+		e.EntityType,
+		e.EntityID,
+		e.SourceIP)
+}
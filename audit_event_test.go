@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: AuditEvent", func() {
+	var (
+		event *cce.AuditEvent
+		node  *cce.Node
+	)
+
+	BeforeEach(func() {
+		event = &cce.AuditEvent{
+			ID:         "ca0fa495-1020-405b-a78c-9a1884349078",
+			Time:       1577880000000000,
+			Actor:      "admin",
+			Action:     cce.AuditActionUpdate,
+			Request:    "PATCH /nodes/48606c73-3905-47e0-864f-14bc7466f5bb",
+			EntityType: "nodes",
+			EntityID:   "48606c73-3905-47e0-864f-14bc7466f5bb",
+			SourceIP:   "127.0.0.1",
+		}
+
+		node = &cce.Node{
+			ID:       "48606c73-3905-47e0-864f-14bc7466f5bb",
+			Name:     "node",
+			Location: "lab",
+			Serial:   "ABC-123",
+		}
+	})
+
+	Describe("NewAuditEvent", func() {
+		It("Should record all fields of a created entity", func() {
+			e, err := cce.NewAuditEvent(cce.AuditActionCreate, nil, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.ID).ToNot(BeEmpty())
+			Expect(e.GetTime()).To(BeTemporally("~", time.Now(), time.Second))
+			Expect(e.Action).To(Equal(cce.AuditActionCreate))
+			Expect(e.EntityType).To(Equal("nodes"))
+			Expect(e.EntityID).To(Equal(node.ID))
+			Expect(e.Diff).To(HaveLen(4))
+			Expect(e.Diff["name"]).To(Equal(cce.AuditChange{After: json.RawMessage(`"node"`)}))
+		})
+
+		It("Should record the changed fields of an updated entity", func() {
+			updated := *node
+			updated.Location = "field"
+
+			e, err := cce.NewAuditEvent(cce.AuditActionUpdate, node, &updated)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.Diff).To(Equal(map[string]cce.AuditChange{
+				"location": {
+					Before: json.RawMessage(`"lab"`),
+					After:  json.RawMessage(`"field"`),
+				},
+			}))
+		})
+
+		It("Should record all fields of a deleted entity", func() {
+			e, err := cce.NewAuditEvent(cce.AuditActionDelete, node, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.EntityID).To(Equal(node.ID))
+			Expect(e.Diff).To(HaveLen(4))
+			Expect(e.Diff["serial"]).To(Equal(cce.AuditChange{Before: json.RawMessage(`"ABC-123"`)}))
+		})
+	})
+
+	Describe("GetTime", func() {
+		It("Should return the time of the event", func() {
+			Expect(event.GetTime()).To(Equal(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)))
+			Expect(cce.AuditTime(event.GetTime())).To(Equal(event.Time))
+		})
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "audit_events"`, func() {
+			Expect(event.GetTableName()).To(Equal("audit_events"))
+		})
+	})
+
+	Describe("GetID", func() {
+		It("Should return the ID", func() {
+			Expect(event.GetID()).To(Equal(
+				"ca0fa495-1020-405b-a78c-9a1884349078"))
+		})
+	})
+
+	Describe("SetID", func() {
+		It("Should set and return the updated ID", func() {
+			By("Setting the ID")
+			event.SetID("456")
+
+			By("Getting the updated ID")
+			Expect(event.ID).To(Equal("456"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(event.FilterFields()).To(Equal([]string{
+				"time",
+				"actor",
+				"action",
+				"entity_type",
+				"entity_id",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(event.String()).To(Equal(strings.TrimSpace(`
+AuditEvent[
+    ID: ca0fa495-1020-405b-a78c-9a1884349078
+    Time: 2020-01-01 12:00:00 +0000 UTC
+    Actor: admin
+    Action: update
+    Request: PATCH /nodes/48606c73-3905-47e0-864f-14bc7466f5bb
+    EntityType: nodes
+    EntityID: 48606c73-3905-47e0-864f-14bc7466f5bb
+    SourceIP: 127.0.0.1
+]`,
+			)))
+		})
+	})
+})
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package bolt_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	"github.com/open-ness/edgecontroller/internal/persistencetest"
+	"go.etcd.io/bbolt"
+)
+
+func TestBolt(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bolt Suite")
+}
+
+var (
+	dir string
+	db  *bbolt.DB
+)
+
+var _ = BeforeSuite(func() {
+	var err error
+	dir, err = ioutil.TempDir("", "cce-bolt")
+	Expect(err).ToNot(HaveOccurred())
+})
+
+var _ = AfterSuite(func() {
+	Expect(os.RemoveAll(dir)).To(Succeed())
+})
+
+var _ = AfterEach(func() {
+	if db != nil {
+		Expect(db.Close()).To(Succeed())
+		db = nil
+	}
+})
+
+var _ = persistencetest.DescribePersistenceService(func() cce.PersistenceService {
+	f, err := ioutil.TempFile(dir, "*.db")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(f.Close()).To(Succeed())
+	Expect(os.Remove(f.Name())).To(Succeed())
+
+	db, err = bolt.Open(f.Name())
+	Expect(err).ToNot(HaveOccurred())
+
+	return &bolt.PersistenceService{DB: db}
+})
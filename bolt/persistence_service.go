@@ -0,0 +1,772 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/pkg/errors"
+	"go.etcd.io/bbolt"
+)
+
+// Open opens the database file at path, creating the file and its tables if
+// they do not exist.
+func Open(path string) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening db")
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for name := range schema {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "error creating tables")
+	}
+
+	return db, nil
+}
+
+// PersistenceService implements cce.PersistenceService on an embedded bbolt
+// database. Each table is a bucket of versioned entities keyed by ID. The
+// unique and foreign keys of the MySQL migrations are checked by scanning the
+// tables, which is intended for small deployments.
+type PersistenceService struct {
+	DB *bbolt.DB
+
+	// tx is the transaction the operations run in, if any
+	tx *bbolt.Tx
+}
+
+// WithTx calls f with a PersistenceService running in a read-write
+// transaction. The transaction is committed if f returns nil and rolled back
+// otherwise. Writers are serialized, so f should not block on other writers.
+func (s *PersistenceService) WithTx(
+	ctx context.Context,
+	f func(tx cce.PersistenceService) error,
+) error {
+	if s.tx != nil {
+		return f(s)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bbolt.Tx) error {
+		return f(&PersistenceService{DB: s.DB, tx: tx})
+	})
+}
+
+// update runs f in the service's transaction or a new read-write transaction.
+func (s *PersistenceService) update(f func(tx *bbolt.Tx) error) error {
+	if s.tx != nil {
+		return f(s.tx)
+	}
+
+	return s.DB.Update(f)
+}
+
+// view runs f in the service's transaction or a new read-only transaction.
+func (s *PersistenceService) view(f func(tx *bbolt.Tx) error) error {
+	if s.tx != nil {
+		return f(s.tx)
+	}
+
+	return s.DB.View(f)
+}
+
+// Create persists a resource.
+func (s *PersistenceService) Create(
+	ctx context.Context,
+	e cce.Persistable,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling")
+	}
+
+	err = s.update(func(tx *bbolt.Tx) error {
+		b, err := bucket(tx, e.GetTableName())
+		if err != nil {
+			return err
+		}
+
+		id, row, err := decode(bytes)
+		if err != nil {
+			return err
+		}
+		if b.Get([]byte(id)) != nil {
+			return errors.Wrapf(cce.ErrDuplicateEntry, "%q for key id", id)
+		}
+		if err := checkConstraints(tx, e.GetTableName(), id, row); err != nil {
+			return err
+		}
+
+		return b.Put([]byte(id), joinValue(1, bytes))
+	})
+	if err != nil {
+		return errors.Wrap(err, "error inserting record")
+	}
+
+	if v, ok := e.(cce.Versioned); ok {
+		v.SetResourceVersion(1)
+	}
+
+	return nil
+}
+
+// Read retrieves a single resource of the given type by ID.
+func (s *PersistenceService) Read(
+	ctx context.Context,
+	id string,
+	zv cce.Persistable,
+) (e cce.Persistable, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err = s.view(func(tx *bbolt.Tx) error {
+		b, err := bucket(tx, zv.GetTableName())
+		if err != nil {
+			return err
+		}
+
+		value := b.Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+
+		e, err = s.scan(value, zv)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error running query")
+	}
+
+	return e, nil
+}
+
+// Filter retrieves a collection of resources of the given type using a set of
+// filters.
+func (s *PersistenceService) Filter(
+	ctx context.Context,
+	zv cce.Filterable,
+	fs []cce.Filter,
+) (es []cce.Persistable, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err = (&cce.ListOptions{Filters: fs}).Validate(zv); err != nil {
+		return nil, err
+	}
+
+	err = s.view(func(tx *bbolt.Tx) error {
+		b, err := bucket(tx, zv.GetTableName())
+		if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(_, value []byte) error {
+			_, bytes, err := splitValue(value)
+			if err != nil {
+				return err
+			}
+			_, row, err := decode(bytes)
+			if err != nil {
+				return err
+			}
+			if !matches(row, fs) {
+				return nil
+			}
+
+			e, err := s.scan(value, zv)
+			if err != nil {
+				return err
+			}
+			es = append(es, e)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error running query")
+	}
+
+	return es, nil
+}
+
+// List retrieves a page of the resources of the given type matching the
+// filters of the options.
+func (s *PersistenceService) List(
+	ctx context.Context,
+	zv cce.Filterable,
+	opts cce.ListOptions,
+) (*cce.Page, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := opts.Validate(zv); err != nil {
+		return nil, err
+	}
+
+	// Collect the matching resources with the values they are sorted by
+	type sortable struct {
+		value   cce.SortValue
+		id, raw string
+		e       cce.Persistable
+	}
+	var matched []sortable
+	sortField, desc := opts.SortField()
+	err := s.view(func(tx *bbolt.Tx) error {
+		b, err := bucket(tx, zv.GetTableName())
+		if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(_, value []byte) error {
+			_, bytes, err := splitValue(value)
+			if err != nil {
+				return err
+			}
+			id, row, err := decode(bytes)
+			if err != nil {
+				return err
+			}
+			if !matches(row, opts.Filters) {
+				return nil
+			}
+
+			e, err := s.scan(value, zv)
+			if err != nil {
+				return err
+			}
+			matched = append(matched, sortable{
+				value: cce.NewSortValue(row[sortField]),
+				id:    id,
+				raw:   string(row[sortField]),
+				e:     e,
+			})
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error running query")
+	}
+
+	less := func(a, b sortable) bool {
+		if a.value != b.value {
+			return a.value.Less(b.value)
+		}
+		return a.id < b.id
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if desc {
+			return less(matched[j], matched[i])
+		}
+		return less(matched[i], matched[j])
+	})
+
+	page := &cce.Page{Total: len(matched)}
+
+	// Seek past the last resource of the previous page
+	if opts.Cursor != "" {
+		value, id, err := cce.DecodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		last := sortable{value: cce.NewSortValue(json.RawMessage(value)), id: id}
+		if sortField == "id" {
+			last.value = cce.SortValue{Rank: cce.SortRankString, String: value}
+		}
+		i := sort.Search(len(matched), func(i int) bool {
+			if desc {
+				return less(matched[i], last)
+			}
+			return less(last, matched[i])
+		})
+		matched = matched[i:]
+	}
+
+	if opts.Limit > 0 && len(matched) > opts.Limit {
+		matched = matched[:opts.Limit]
+		last := matched[opts.Limit-1]
+		value := last.raw
+		if sortField == "id" {
+			value = last.id
+		}
+		page.Next = cce.EncodeCursor(value, last.id)
+	}
+
+	for _, m := range matched {
+		page.Entities = append(page.Entities, m.e)
+	}
+
+	return page, nil
+}
+
+// ReadAll retrieves all resources of the given type.
+func (s *PersistenceService) ReadAll(
+	ctx context.Context,
+	zv cce.Persistable,
+) (es []cce.Persistable, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err = s.view(func(tx *bbolt.Tx) error {
+		b, err := bucket(tx, zv.GetTableName())
+		if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(_, value []byte) error {
+			e, err := s.scan(value, zv)
+			if err != nil {
+				return err
+			}
+			es = append(es, e)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error running query")
+	}
+
+	return es, nil
+}
+
+func (s *PersistenceService) scan(
+	value []byte,
+	zv cce.Persistable,
+) (cce.Persistable, error) {
+	version, bytes, err := splitValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	e := reflect.New(reflect.ValueOf(zv).Elem().Type()).Interface().(cce.Persistable)
+	if err := json.Unmarshal(bytes, e); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling")
+	}
+
+	if v, ok := e.(cce.Versioned); ok {
+		v.SetResourceVersion(version)
+	}
+
+	return e, nil
+}
+
+// BulkUpdate updates multiple resources and increments their versions.
+// Resources that do not exist are ignored. A resource with a non-zero version
+// is only updated if its version matches, otherwise cce.ErrVersionConflict is
+// returned.
+func (s *PersistenceService) BulkUpdate(
+	ctx context.Context,
+	es []cce.Persistable,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := s.update(func(tx *bbolt.Tx) error {
+		for _, e := range es {
+			bytes, err := json.Marshal(e)
+			if err != nil {
+				return errors.Wrap(err, "error marshaling")
+			}
+
+			b, err := bucket(tx, e.GetTableName())
+			if err != nil {
+				return err
+			}
+
+			id, row, err := decode(bytes)
+			if err != nil {
+				return err
+			}
+
+			var version int64
+			v, versioned := e.(cce.Versioned)
+			if versioned {
+				version = v.GetResourceVersion()
+			}
+
+			persisted, err := persistedVersion(b, id)
+			if err != nil {
+				return err
+			}
+			if version != 0 && version != persisted {
+				return errors.Wrapf(cce.ErrVersionConflict,
+					"%s %s is not at version %d", e.GetTableName(), id, version)
+			}
+			if persisted == 0 {
+				continue
+			}
+
+			if err := checkConstraints(tx, e.GetTableName(), id, row); err != nil {
+				return err
+			}
+
+			if err := b.Put([]byte(id), joinValue(persisted+1, bytes)); err != nil {
+				return err
+			}
+			if versioned {
+				v.SetResourceVersion(persisted + 1)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error updating record")
+	}
+
+	return nil
+}
+
+// Delete deletes a resource of the given type. If zv has a non-zero version
+// the resource is only deleted if its version matches, otherwise
+// cce.ErrVersionConflict is returned.
+func (s *PersistenceService) Delete(
+	ctx context.Context,
+	id string,
+	zv cce.Persistable,
+) (ok bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return false, err
+	}
+
+	err = s.update(func(tx *bbolt.Tx) error {
+		b, err := bucket(tx, zv.GetTableName())
+		if err != nil {
+			return err
+		}
+		persisted, err := persistedVersion(b, id)
+		if err != nil {
+			return err
+		}
+		if persisted == 0 {
+			return nil
+		}
+		if v, isVersioned := zv.(cce.Versioned); isVersioned &&
+			v.GetResourceVersion() != 0 && v.GetResourceVersion() != persisted {
+			return errors.Wrapf(cce.ErrVersionConflict,
+				"%s %s is not at version %d", zv.GetTableName(), id, v.GetResourceVersion())
+		}
+
+		ok = true
+		return deleteRow(tx, zv.GetTableName(), id)
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "error deleting record")
+	}
+
+	return ok, nil
+}
+
+// deleteRow deletes a row after deleting the rows referencing it by a foreign
+// key with cascade. It fails if the row is referenced by a foreign key
+// without cascade.
+func deleteRow(tx *bbolt.Tx, tableName, id string) error {
+	cascades := make(map[string][]string)
+	for childName, child := range schema {
+		for _, fk := range child.foreignKeys {
+			if fk.table != tableName {
+				continue
+			}
+
+			err := tx.Bucket([]byte(childName)).ForEach(func(_, value []byte) error {
+				_, bytes, err := splitValue(value)
+				if err != nil {
+					return err
+				}
+				childID, row, err := decode(bytes)
+				if err != nil {
+					return err
+				}
+				if v, ok := field(row, fk.field); !ok || v != id {
+					return nil
+				}
+				if !fk.cascade {
+					return errors.Errorf(
+						"cannot delete a parent row: %s %q is referenced by %s.%s",
+						tableName, id, childName, fk.field)
+				}
+				cascades[childName] = append(cascades[childName], childID)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for childName, childIDs := range cascades {
+		for _, childID := range childIDs {
+			if err := deleteRow(tx, childName, childID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Bucket([]byte(tableName)).Delete([]byte(id))
+}
+
+// checkConstraints checks the unique and foreign keys of a row that is about
+// to be written.
+func checkConstraints(
+	tx *bbolt.Tx,
+	tableName string,
+	id string,
+	row map[string]json.RawMessage,
+) error {
+	t := schema[tableName]
+
+	for _, uk := range t.uniqueKeys {
+		values, ok := fields(row, uk)
+		if !ok {
+			continue
+		}
+
+		err := tx.Bucket([]byte(tableName)).ForEach(func(k, value []byte) error {
+			if string(k) == id {
+				return nil
+			}
+			_, bytes, err := splitValue(value)
+			if err != nil {
+				return err
+			}
+			_, other, err := decode(bytes)
+			if err != nil {
+				return err
+			}
+			if otherValues, ok := fields(other, uk); ok && reflect.DeepEqual(values, otherValues) {
+				return errors.Wrapf(cce.ErrDuplicateEntry, "%q for key (%s)",
+					strings.Join(values, "-"), strings.Join(uk, ", "))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, fk := range t.foreignKeys {
+		v, ok := field(row, fk.field)
+		if !ok {
+			continue
+		}
+		if tx.Bucket([]byte(fk.table)).Get([]byte(v)) == nil {
+			return errors.Errorf(
+				"cannot add or update a child row: %s.%s references missing %s %q",
+				tableName, fk.field, fk.table, v)
+		}
+	}
+
+	return nil
+}
+
+func bucket(tx *bbolt.Tx, tableName string) (*bbolt.Bucket, error) {
+	b := tx.Bucket([]byte(tableName))
+	if b == nil {
+		return nil, errors.Errorf("table %q doesn't exist", tableName)
+	}
+
+	return b, nil
+}
+
+// joinValue returns the stored value of an entity, which is its version
+// followed by its JSON.
+func joinValue(version int64, bytes []byte) []byte {
+	value := make([]byte, 8, 8+len(bytes))
+	binary.BigEndian.PutUint64(value, uint64(version))
+	return append(value, bytes...)
+}
+
+// splitValue returns the version and JSON of a stored entity.
+func splitValue(value []byte) (int64, []byte, error) {
+	if len(value) < 8 {
+		return 0, nil, errors.New("stored entity is too short")
+	}
+
+	return int64(binary.BigEndian.Uint64(value)), value[8:], nil
+}
+
+// persistedVersion returns the version of a stored entity, or 0 if there is
+// none.
+func persistedVersion(b *bbolt.Bucket, id string) (int64, error) {
+	value := b.Get([]byte(id))
+	if value == nil {
+		return 0, nil
+	}
+
+	version, _, err := splitValue(value)
+	return version, err
+}
+
+// decode decodes the top-level fields of an entity and returns its ID.
+func decode(bytes []byte) (string, map[string]json.RawMessage, error) {
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal(bytes, &row); err != nil {
+		return "", nil, errors.Wrap(err, "error unmarshaling")
+	}
+
+	id, ok := field(row, "id")
+	if !ok {
+		return "", nil, errors.New("entity has no id")
+	}
+
+	return id, row, nil
+}
+
+// field returns the value of a top-level field as MySQL's ->> operator would.
+// It returns false for missing and null fields.
+func field(row map[string]json.RawMessage, name string) (string, bool) {
+	raw, ok := row[name]
+	if !ok || string(raw) == "null" {
+		return "", false
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, true
+	}
+
+	return string(raw), true
+}
+
+// matches returns whether a row matches all the filters. As in MySQL, a
+// missing field never matches.
+func matches(row map[string]json.RawMessage, fs []cce.Filter) bool {
+	for _, f := range fs {
+		if !matchesFilter(row, f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchesFilter(row map[string]json.RawMessage, f cce.Filter) bool {
+	if len(f.Or) > 0 {
+		for _, group := range f.Or {
+			if matches(row, group) {
+				return !f.Not
+			}
+		}
+		return f.Not
+	}
+
+	v, ok := field(row, f.Field)
+	if f.Op == cce.FilterOpNull {
+		return !ok != f.Not
+	}
+	if !ok {
+		return false
+	}
+
+	switch f.Op {
+	case cce.FilterOpContains:
+		ok = strings.Contains(v, f.Value)
+	case cce.FilterOpPrefix:
+		ok = strings.HasPrefix(v, f.Value)
+	case cce.FilterOpLike:
+		ok = like(v, f.Value)
+	case cce.FilterOpIn:
+		ok = false
+		for _, value := range f.Values {
+			if v == value {
+				ok = true
+				break
+			}
+		}
+	case cce.FilterOpLess, cce.FilterOpLessOrEqual, cce.FilterOpGreater, cce.FilterOpGreaterOrEqual:
+		ok = compare(row[f.Field], f.Op, f.Value)
+	default:
+		ok = v == f.Value
+	}
+
+	return ok != f.Not
+}
+
+// like returns whether s matches a SQL LIKE pattern.
+func like(s, pattern string) bool {
+	var expr strings.Builder
+	expr.WriteString("(?s)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expr.WriteString(".*")
+		case r == '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String()).MatchString(s)
+}
+
+// compare returns whether a JSON number compares to a value with a range
+// operator. As in MySQL, values of other types never match.
+func compare(raw json.RawMessage, op cce.FilterOp, value string) bool {
+	var n float64
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return false
+	}
+	v, _ := strconv.ParseFloat(value, 64) // validated
+
+	switch op {
+	case cce.FilterOpLess:
+		return n < v
+	case cce.FilterOpLessOrEqual:
+		return n <= v
+	case cce.FilterOpGreater:
+		return n > v
+	default:
+		return n >= v
+	}
+}
+
+func fields(row map[string]json.RawMessage, names []string) ([]string, bool) {
+	var values []string
+	for _, name := range names {
+		v, ok := field(row, name)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+
+	return values, true
+}
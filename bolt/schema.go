@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package bolt
+
+// table describes the constraints of a table of the MySQL migrations. The id
+// of every table is implicitly unique.
+type table struct {
+	// Unique keys other than id. Each key is a set of fields that must be
+	// unique together. As in MySQL, a key with a missing field never
+	// conflicts.
+	uniqueKeys [][]string
+
+	// Foreign keys. All foreign keys reference the id of another table.
+	foreignKeys []foreignKey
+}
+
+// foreignKey is a field referencing the id of a row in another table.
+type foreignKey struct {
+	field string
+	table string
+
+	// cascade deletes the referencing row when the referenced row is deleted.
+	// Otherwise the delete of the referenced row fails.
+	cascade bool
+}
+
+// schema must be kept in sync with the MySQL migrations.
+var schema = map[string]table{
+	// Entity tables
+	"nodes": {},
+	"node_grpc_targets": {
+		uniqueKeys: [][]string{
+			{"node_id"},
+			{"grpc_target"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes", cascade: true},
+		},
+	},
+	"nodes_nfd_features": {
+		uniqueKeys: [][]string{
+			{"node_id", "nfd_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes", cascade: true},
+		},
+	},
+	"apps":             {},
+	"traffic_policies": {},
+	"dns_configs":      {},
+	"credentials":      {},
+	"audit_events":     {},
+	"users": {
+		uniqueKeys: [][]string{
+			{"name"},
+		},
+	},
+	"token_keys":     {},
+	"revoked_tokens": {},
+	"api_keys": {
+		uniqueKeys: [][]string{
+			{"user_id", "name"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "user_id", table: "users", cascade: true},
+		},
+	},
+	"webhooks": {
+		uniqueKeys: [][]string{
+			{"name"},
+		},
+	},
+	"webhook_deliveries": {
+		foreignKeys: []foreignKey{
+			{field: "webhook_id", table: "webhooks", cascade: true},
+		},
+	},
+	"operations": {
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes", cascade: true},
+		},
+	},
+	"idempotency_keys": {
+		uniqueKeys: [][]string{
+			{"actor", "key"},
+		},
+	},
+
+	// Primary join tables
+	"dns_configs_app_aliases": {
+		uniqueKeys: [][]string{
+			{"dns_config_id", "app_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "dns_config_id", table: "dns_configs"},
+			{field: "app_id", table: "apps"},
+		},
+	},
+	"nodes_apps": {
+		uniqueKeys: [][]string{
+			{"node_id", "app_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes"},
+			{field: "app_id", table: "apps"},
+		},
+	},
+	"nodes_dns_configs": {
+		uniqueKeys: [][]string{
+			{"node_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes"},
+			{field: "dns_config_id", table: "dns_configs"},
+		},
+	},
+	"nodes_network_interfaces_traffic_policies": {
+		uniqueKeys: [][]string{
+			{"node_id", "network_interface_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes"},
+			{field: "traffic_policy_id", table: "traffic_policies"},
+		},
+	},
+
+	// Secondary join tables
+	"nodes_apps_traffic_policies": {
+		uniqueKeys: [][]string{
+			{"nodes_apps_id", "traffic_policy_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "nodes_apps_id", table: "nodes_apps"},
+			{field: "traffic_policy_id", table: "traffic_policies"},
+		},
+	},
+}
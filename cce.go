@@ -7,10 +7,13 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"time"
 
 	"github.com/open-ness/common/proxy/progutil"
+	"github.com/open-ness/edgecontroller/events"
 	"github.com/open-ness/edgecontroller/jose"
 	"github.com/open-ness/edgecontroller/k8s"
+	"github.com/open-ness/edgecontroller/ratelimit"
 )
 
 // PrefaceLis Our network callback helper
@@ -42,7 +45,6 @@ type Controller struct {
 	PersistenceService PersistenceService
 	AuthorityService   AuthorityService
 	TokenService       *jose.JWSTokenIssuer
-	AdminCreds         *AuthCreds
 
 	// The edge node's port that it listens on for gRPC connections from the
 	// Controller and serves Mm5-related endpoints for application and network
@@ -61,6 +63,31 @@ type Controller struct {
 	// EdgeNodeCreds are the transport credentials for connecting to an edge
 	// node. The server name will be overridden.
 	EdgeNodeCreds *tls.Config
+
+	// DriftReporter reports how the nodes differed from persistence when they
+	// were last reconciled. It is nil if reconciliation is disabled.
+	DriftReporter DriftReporter
+
+	// RateLimiters rate limit the HTTP API requests of each route group. The
+	// requests of groups without a limiter are not limited.
+	RateLimiters map[string]*ratelimit.Limiter
+
+	// LoginLockout locks out users logging in from a client after repeated
+	// failures. If nil logins are not locked out.
+	LoginLockout *ratelimit.Lockout
+
+	// Events is the bus the handlers publish events to, which are streamed
+	// by GET /events. If nil no events are published.
+	Events *events.Bus
+
+	// Operations runs the requests that change nodes in the background. If
+	// nil they are handled synchronously.
+	Operations OperationRunner
+
+	// IdempotencyKeyRetention is the time the responses to requests with an
+	// Idempotency-Key header are stored for, after which the key can be used
+	// again. If zero DefaultIdempotencyKeyRetention is used.
+	IdempotencyKeyRetention time.Duration
 }
 
 // PersistenceService manages entity persistence. The methods with zv parameters take a zero-value Persistable for
@@ -70,8 +97,15 @@ type PersistenceService interface {
 	Read(ctx context.Context, id string, zv Persistable) (e Persistable, err error)
 	ReadAll(ctx context.Context, zv Persistable) (ps []Persistable, err error)
 	Filter(ctx context.Context, zv Filterable, fs []Filter) (ps []Persistable, err error)
+	List(ctx context.Context, zv Filterable, opts ListOptions) (page *Page, err error)
 	BulkUpdate(ctx context.Context, ps []Persistable) error
 	Delete(ctx context.Context, id string, zv Persistable) (ok bool, err error)
+
+	// WithTx calls f with a PersistenceService whose operations run in a
+	// single transaction. The transaction is committed if f returns nil and
+	// rolled back otherwise. Calling WithTx on the PersistenceService passed
+	// to f runs in the same transaction.
+	WithTx(ctx context.Context, f func(tx PersistenceService) error) error
 }
 
 // Validatable can be validated.
@@ -107,10 +141,23 @@ type NodeEntity interface {
 	GetNodeID() string
 }
 
-// Filter filters queries in PersistenceService.Filter.
+// Filter filters queries in PersistenceService.Filter and List. A filter on a
+// missing or null field never matches, even if negated, unless its operator
+// is FilterOpNull.
 type Filter struct {
 	Field string
+	Op    FilterOp
 	Value string
+
+	// Values are the values of FilterOpIn.
+	Values []string
+
+	// Not negates the filter.
+	Not bool
+
+	// Or, if not empty, makes the filter match the entities matching all the
+	// filters of any of its groups, in place of Field, Op and Value.
+	Or [][]Filter
 }
 
 func getIP(ctx context.Context, ps PersistenceService, nodeID string) (string, error) {
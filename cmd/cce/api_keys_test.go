@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var _ = Describe("API keys", func() {
+	var name string
+
+	BeforeEach(func() {
+		name = "svc-" + uuid.New()[:8]
+
+		payload, err := json.Marshal(swagger.UserCreate{
+			Name:           name,
+			Roles:          []string{"operator"},
+			ServiceAccount: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a POST /users request for a service account")
+		resp, err := apiCli.Post("http://127.0.0.1:8080/users", "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+	})
+
+	postAPIKey := func(create swagger.APIKeyCreate, expectedStatus int) *swagger.APIKeyDetail {
+		payload, err := json.Marshal(create)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a POST /users/{name}/api_keys request")
+		resp, err := apiCli.Post(
+			"http://127.0.0.1:8080/users/"+name+"/api_keys", "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying the response status")
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+		if expectedStatus != http.StatusCreated {
+			return nil
+		}
+
+		var key swagger.APIKeyDetail
+		Expect(json.NewDecoder(resp.Body).Decode(&key)).To(Succeed())
+		Expect(key.Key).To(HavePrefix(key.ID + "."))
+
+		return &key
+	}
+
+	listAPIKeys := func() []swagger.APIKeySummary {
+		By("Sending a GET /users/{name}/api_keys request")
+		resp, err := apiCli.Get("http://127.0.0.1:8080/users/" + name + "/api_keys")
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var keys swagger.APIKeyList
+		Expect(json.NewDecoder(resp.Body).Decode(&keys)).To(Succeed())
+
+		return keys.APIKeys
+	}
+
+	It("Should authenticate requests with the key", func() {
+		key := postAPIKey(swagger.APIKeyCreate{Name: "deploy"}, http.StatusCreated)
+		Expect(key.Roles).To(Equal([]string{"operator"}))
+		Expect(key.LastUsedAt).To(BeNil())
+
+		By("Sending a GET /apps request with the key")
+		resp, err := apiClient{APIKey: key.Key}.Get("http://127.0.0.1:8080/apps")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		By("Verifying that the key was used")
+		Eventually(func() *time.Time {
+			keys := listAPIKeys()
+			Expect(keys).To(HaveLen(1))
+			Expect(keys[0].ID).To(Equal(key.ID))
+			return keys[0].LastUsedAt
+		}).ShouldNot(BeNil())
+	})
+
+	It("Should only grant the roles of the key", func() {
+		key := postAPIKey(swagger.APIKeyCreate{Name: "monitor", Roles: []string{"viewer"}}, http.StatusCreated)
+		cli := apiClient{APIKey: key.Key}
+
+		By("Sending a GET /apps request with the key")
+		resp, err := cli.Get("http://127.0.0.1:8080/apps")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		By("Sending a POST /apps request with the key")
+		resp, err = cli.Post("http://127.0.0.1:8080/apps", "application/json", strings.NewReader("{}"))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("Should not authenticate requests with a revoked key", func() {
+		key := postAPIKey(swagger.APIKeyCreate{Name: "deploy"}, http.StatusCreated)
+
+		By("Sending a DELETE /users/{name}/api_keys/{api_key_id} request")
+		resp, err := apiCli.Delete("http://127.0.0.1:8080/users/" + name + "/api_keys/" + key.ID)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(listAPIKeys()).To(BeEmpty())
+
+		By("Sending a GET /apps request with the revoked key")
+		resp, err = apiClient{APIKey: key.Key}.Get("http://127.0.0.1:8080/apps")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Should not log in a service account", func() {
+		By("Sending a POST /auth request for the service account")
+		resp, err := http.Post(
+			"http://127.0.0.1:8080/auth",
+			"application/json",
+			strings.NewReader(`{"username": "`+name+`", "password": ""}`))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Should return 409 if the key exists", func() {
+		postAPIKey(swagger.APIKeyCreate{Name: "deploy"}, http.StatusCreated)
+		postAPIKey(swagger.APIKeyCreate{Name: "deploy"}, http.StatusConflict)
+	})
+
+	It("Should return 400 for roles the user does not have", func() {
+		postAPIKey(swagger.APIKeyCreate{Name: "deploy", Roles: []string{"admin"}}, http.StatusBadRequest)
+	})
+
+	It("Should return 404 for a key of another user", func() {
+		key := postAPIKey(swagger.APIKeyCreate{Name: "deploy"}, http.StatusCreated)
+
+		By("Sending a DELETE /users/{name}/api_keys/{api_key_id} request for the admin")
+		resp, err := apiCli.Delete("http://127.0.0.1:8080/users/admin/api_keys/" + key.ID)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
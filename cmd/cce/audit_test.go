@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+var _ = Describe("Audit log", func() {
+	getAudit := func(query string, expectedStatus int) *swagger.AuditEventList {
+		By("Sending a GET /audit request")
+		resp, err := apiCli.Get("http://127.0.0.1:8080/audit?" + query)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying the response status")
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+		if expectedStatus != http.StatusOK {
+			return nil
+		}
+
+		By("Reading the response body")
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var events swagger.AuditEventList
+
+		By("Unmarshaling the response")
+		Expect(json.Unmarshal(body, &events)).To(Succeed())
+
+		return &events
+	}
+
+	Describe("GET /audit", func() {
+		It("Should return the changes of an app, newest first", func() {
+			since := time.Now().Add(-time.Second).UTC().Format(time.RFC3339)
+			appID := postApps("container")
+
+			By("Sending a PATCH /apps/{app_id} request")
+			resp, err := apiCli.Patch(
+				fmt.Sprintf("http://127.0.0.1:8080/apps/%s", appID),
+				"application/json",
+				strings.NewReader(fmt.Sprintf(`
+					{
+						"id": "%s",
+						"type": "container",
+						"name": "audited app",
+						"version": "latest",
+						"vendor": "smart edge",
+						"cores": 4,
+						"memory": 1024,
+						"source": "http://www.test.com/my_container_app.tar.gz"
+					}`, appID)))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			By("Sending a DELETE /apps/{app_id} request")
+			resp, err = apiCli.Delete(fmt.Sprintf("http://127.0.0.1:8080/apps/%s", appID))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			By("Verifying the audit events")
+			events := getAudit("entity_type=apps&entity_id="+appID+"&since="+since, http.StatusOK)
+			Expect(events.Total).To(Equal(3))
+			Expect(events.Events).To(HaveLen(3))
+			for i, action := range []string{"delete", "update", "create"} {
+				Expect(events.Events[i].Action).To(Equal(action))
+				Expect(events.Events[i].Actor).To(Equal("admin"))
+				Expect(events.Events[i].EntityID).To(Equal(appID))
+				Expect(events.Events[i].SourceIP).To(Equal("127.0.0.1"))
+			}
+			Expect(events.Events[0].Request).To(Equal("DELETE /apps/" + appID))
+			Expect(events.Events[1].Diff).To(HaveKeyWithValue("name", swagger.AuditChange{
+				Before: json.RawMessage(`"container app"`),
+				After:  json.RawMessage(`"audited app"`),
+			}))
+		})
+
+		It("Should not return events outside of the time range", func() {
+			appID := postApps("container")
+
+			events := getAudit("entity_id="+appID+"&until=2000-01-01T00:00:00Z", http.StatusOK)
+			Expect(events.Total).To(Equal(0))
+			Expect(events.Events).To(BeEmpty())
+		})
+
+		It("Should not record failed requests", func() {
+			By("Sending a DELETE /apps/{app_id} request for a missing app")
+			resp, err := apiCli.Delete("http://127.0.0.1:8080/apps/ca0fa495-1020-405b-a78c-9a1884349078")
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+			events := getAudit("entity_id=ca0fa495-1020-405b-a78c-9a1884349078", http.StatusOK)
+			Expect(events.Events).To(BeEmpty())
+		})
+
+		It("Should return the enrollment of a node", func() {
+			clearGRPCTargetsTable()
+			nodeCfg := createAndRegisterNode()
+
+			events := getAudit("entity_id="+nodeCfg.nodeID+"&action=enroll", http.StatusOK)
+			Expect(events.Events).To(HaveLen(1))
+			Expect(events.Events[0].Actor).To(Equal("node:" + nodeCfg.nodeID))
+			Expect(events.Events[0].EntityType).To(Equal("nodes"))
+		})
+
+		It("Should return 400 for a bad time", func() {
+			getAudit("since=yesterday", http.StatusBadRequest)
+		})
+	})
+})
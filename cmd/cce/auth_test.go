@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Auth tokens", func() {
+	type tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+
+	post := func(path, body string, expectedStatus int) *tokens {
+		By("Sending a POST " + path + " request")
+		resp, err := http.Post("http://127.0.0.1:8080"+path, "application/json", strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying the response status")
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+		if expectedStatus != http.StatusCreated {
+			return nil
+		}
+
+		var t tokens
+		Expect(json.NewDecoder(resp.Body).Decode(&t)).To(Succeed())
+		return &t
+	}
+
+	login := func() *tokens {
+		return post("/auth", fmt.Sprintf(`{"username": "admin", "password": "%s"}`, adminPass), http.StatusCreated)
+	}
+
+	expectAccess := func(token string, expectedStatus int) {
+		By("Sending a GET /nodes request")
+		resp, err := (&apiClient{Token: token}).Get("http://127.0.0.1:8080/nodes")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+	}
+
+	logout := func(t *tokens, expectedStatus int) {
+		By("Sending a POST /auth/logout request")
+		resp, err := (&apiClient{Token: t.Token}).Post(
+			"http://127.0.0.1:8080/auth/logout",
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"refresh_token": "%s"}`, t.RefreshToken)))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+	}
+
+	Describe("POST /auth", func() {
+		It("Should return an access token and a refresh token", func() {
+			t := login()
+			Expect(t.Token).ToNot(BeEmpty())
+			Expect(t.RefreshToken).ToNot(BeEmpty())
+			Expect(t.ExpiresIn).To(Equal(60 * 60))
+
+			expectAccess(t.Token, http.StatusOK)
+			expectAccess(t.RefreshToken, http.StatusUnauthorized)
+		})
+	})
+
+	Describe("POST /auth/refresh", func() {
+		It("Should exchange a refresh token once", func() {
+			t := login()
+
+			refreshed := post("/auth/refresh", fmt.Sprintf(`{"refresh_token": "%s"}`, t.RefreshToken),
+				http.StatusCreated)
+			Expect(refreshed.RefreshToken).ToNot(Equal(t.RefreshToken))
+			expectAccess(refreshed.Token, http.StatusOK)
+
+			post("/auth/refresh", fmt.Sprintf(`{"refresh_token": "%s"}`, t.RefreshToken),
+				http.StatusUnauthorized)
+		})
+
+		It("Should exchange a refresh token once for concurrent requests", func() {
+			t := login()
+
+			By("Sending concurrent POST /auth/refresh requests")
+			statuses := make(chan int, 5)
+			for i := 0; i < cap(statuses); i++ {
+				go func() {
+					defer GinkgoRecover()
+					resp, err := http.Post("http://127.0.0.1:8080/auth/refresh", "application/json",
+						strings.NewReader(fmt.Sprintf(`{"refresh_token": "%s"}`, t.RefreshToken)))
+					Expect(err).ToNot(HaveOccurred())
+					resp.Body.Close()
+					statuses <- resp.StatusCode
+				}()
+			}
+
+			By("Verifying a single 201 Created response")
+			var created int
+			for i := 0; i < cap(statuses); i++ {
+				status := <-statuses
+				if status == http.StatusCreated {
+					created++
+					continue
+				}
+				Expect(status).To(Equal(http.StatusUnauthorized))
+			}
+			Expect(created).To(Equal(1))
+		})
+
+		It("Should not exchange an access token", func() {
+			t := login()
+
+			post("/auth/refresh", fmt.Sprintf(`{"refresh_token": "%s"}`, t.Token), http.StatusUnauthorized)
+		})
+	})
+
+	Describe("POST /auth/logout", func() {
+		It("Should revoke the access token and the refresh token", func() {
+			t := login()
+
+			logout(t, http.StatusNoContent)
+
+			expectAccess(t.Token, http.StatusUnauthorized)
+			post("/auth/refresh", fmt.Sprintf(`{"refresh_token": "%s"}`, t.RefreshToken),
+				http.StatusUnauthorized)
+
+			By("Verifying that other tokens are still valid")
+			expectAccess(apiCli.Token, http.StatusOK)
+		})
+
+		It("Should return 400 for an invalid refresh token", func() {
+			t := login()
+
+			logout(&tokens{Token: t.Token, RefreshToken: "not a token"}, http.StatusBadRequest)
+
+			expectAccess(t.Token, http.StatusOK)
+		})
+	})
+
+	Describe("Authorization header", func() {
+		It("Should only accept a token with the Bearer scheme", func() {
+			t := login()
+
+			for scheme, expectedStatus := range map[string]int{
+				"Bearer": http.StatusOK,
+				"bearer": http.StatusOK,
+				"Basic":  http.StatusUnauthorized,
+				"Token":  http.StatusUnauthorized,
+			} {
+				By("Sending a GET /nodes request with the " + scheme + " scheme")
+				req, err := http.NewRequest("GET", "http://127.0.0.1:8080/nodes", nil)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Authorization", scheme+" "+t.Token)
+				resp, err := http.DefaultClient.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(expectedStatus), scheme)
+			}
+		})
+	})
+})
@@ -15,6 +15,12 @@ import (
 type apiClient struct {
 	// Token is a JSON Web Token.
 	Token string
+
+	// APIKey is an API key, which is injected instead of the token if set.
+	APIKey string
+
+	// Header are other headers injected into each request.
+	Header http.Header
 }
 
 // Get sends a HTTP GET request with a token and returns an HTTP response.
@@ -57,7 +63,19 @@ func (cli apiClient) Delete(url string) (*http.Response, error) {
 	return new(http.Client).Do(cli.injectToken(req))
 }
 
+// Do sends a HTTP request with a token and returns an HTTP response.
+func (cli apiClient) Do(req *http.Request) (*http.Response, error) {
+	return new(http.Client).Do(cli.injectToken(req))
+}
+
 func (cli apiClient) injectToken(r *http.Request) *http.Request {
+	for k, v := range cli.Header {
+		r.Header[k] = v
+	}
+	if cli.APIKey != "" {
+		r.Header.Add("Authorization", fmt.Sprintf("ApiKey %s", cli.APIKey))
+		return r
+	}
 	r.Header.Add("Authorization", fmt.Sprintf("Bearer %s", cli.Token))
 	return r
 }
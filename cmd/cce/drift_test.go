@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("/drift", func() {
+	Describe("GET /drift", func() {
+		It("Should return the drift report", func() {
+			By("Sending a GET /drift request")
+			resp, err := apiCli.Get("http://127.0.0.1:8080/drift")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			By("Verifying a 200 OK response")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			By("Reading the response body")
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Unmarshaling the response")
+			var drifts swagger.DriftList
+			Expect(json.Unmarshal(body, &drifts)).To(Succeed())
+			Expect(drifts.Nodes).ToNot(BeNil())
+		})
+	})
+
+	Describe("GET /nodes/{node_id}/drift", func() {
+		It("Should return 404 if the node was not reconciled", func() {
+			By("Sending a GET /nodes/{node_id}/drift request")
+			resp, err := apiCli.Get(
+				fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/drift", uuid.New()))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			By("Verifying a 404 Not Found response")
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
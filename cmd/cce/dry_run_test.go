@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+var _ = Describe("Dry runs", func() {
+	expectPlan := func(resp *http.Response) *swagger.Plan {
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var p swagger.Plan
+		Expect(json.NewDecoder(resp.Body).Decode(&p)).To(Succeed())
+		return &p
+	}
+
+	It("Should plan the rows a request would write without writing them", func() {
+		By("Sending a POST /apps request with dry_run=true")
+		resp, err := apiCli.Post(
+			"http://127.0.0.1:8080/apps?dry_run=true",
+			"application/json",
+			strings.NewReader(`
+			{
+				"type": "container",
+				"name": "dry run app",
+				"version": "latest",
+				"vendor": "smart edge",
+				"cores": 4,
+				"memory": 1024,
+				"source": "http://www.test.com/my_container_app.tar.gz"
+			}`))
+		Expect(err).ToNot(HaveOccurred())
+		p := expectPlan(resp)
+
+		By("Verifying the plan")
+		Expect(p.Status).To(Equal(http.StatusCreated))
+		var rb respBody
+		Expect(json.Unmarshal(p.Response, &rb)).To(Succeed())
+		Expect(p.Writes).To(HaveLen(1))
+		Expect(p.Writes[0].Action).To(Equal("create"))
+		Expect(p.Writes[0].EntityType).To(Equal("apps"))
+		Expect(p.Writes[0].EntityID).To(Equal(rb.ID))
+		Expect(p.Calls).To(BeEmpty())
+
+		By("Verifying the app was not created")
+		getResp, err := apiCli.Get("http://127.0.0.1:8080/apps/" + rb.ID)
+		Expect(err).ToNot(HaveOccurred())
+		defer getResp.Body.Close()
+		Expect(getResp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("Should plan the calls a request would make to a node without making them", func() {
+		clearGRPCTargetsTable()
+		nodeCfg := createAndRegisterNode()
+		appID := postApps("container")
+
+		By("Sending a POST /nodes/{node_id}/apps request with dry_run=true")
+		resp, err := apiCli.Post(
+			fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/apps?dry_run=true", nodeCfg.nodeID),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"id": "%s"}`, appID)))
+		Expect(err).ToNot(HaveOccurred())
+		p := expectPlan(resp)
+
+		By("Verifying the plan")
+		Expect(p.Status).To(Equal(http.StatusOK))
+		Expect(p.Writes).ToNot(BeEmpty())
+		Expect(p.Calls).To(HaveLen(1))
+		Expect(p.Calls[0].NodeID).To(Equal(nodeCfg.nodeID))
+		Expect(p.Calls[0].Service).To(Equal("openness.eva.ApplicationDeploymentService"))
+		Expect(p.Calls[0].Method).To(Equal("DeployContainer"))
+
+		By("Verifying the app was not deployed")
+		Expect(getNodeApps(nodeCfg.nodeID).NodeApps).To(BeEmpty())
+	})
+
+	It("Should return the error a request would fail with", func() {
+		clearGRPCTargetsTable()
+		nodeCfg := createAndRegisterNode()
+		appID := postApps("container")
+		postNodeApps(nodeCfg.nodeID, appID)
+
+		By("Sending a DELETE /apps/{app_id} request with dry_run=true")
+		req, err := http.NewRequest(
+			http.MethodDelete,
+			fmt.Sprintf("http://127.0.0.1:8080/apps/%s?dry_run=true", appID),
+			nil)
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := apiCli.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 422 Unprocessable Entity response")
+		Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	It("Should not publish the events a request would cause", func() {
+		clearGRPCTargetsTable()
+		nodeCfg := createAndRegisterNode()
+		appID := postApps("container")
+		postNodeApps(nodeCfg.nodeID, appID)
+
+		By("Sending a GET /events request")
+		req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/events?type=app.lifecycle", nil)
+		Expect(err).ToNot(HaveOccurred())
+		streamResp, err := apiCli.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer streamResp.Body.Close()
+		Expect(streamResp.StatusCode).To(Equal(http.StatusOK))
+		c := make(chan *events.Event, 100)
+		go readEvents(streamResp.Body, c)
+
+		patchApp := func(query string) *http.Response {
+			resp, err := apiCli.Patch(
+				fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/apps/%s%s", nodeCfg.nodeID, appID, query),
+				"application/json",
+				strings.NewReader(`{"command": "start"}`))
+			Expect(err).ToNot(HaveOccurred())
+			return resp
+		}
+
+		By("Sending a PATCH /nodes/{node_id}/apps/{app_id} request with dry_run=true")
+		p := expectPlan(patchApp("?dry_run=true"))
+		Expect(p.Calls).To(HaveLen(1))
+
+		By("Verifying no event was published")
+		Consistently(c).ShouldNot(Receive())
+
+		By("Sending a PATCH /nodes/{node_id}/apps/{app_id} request")
+		resp := patchApp("")
+		defer resp.Body.Close()
+		expectOperation(resp, http.StatusOK)
+
+		By("Verifying the event was published")
+		var e *events.Event
+		Eventually(c).Should(Receive(&e))
+		Expect(e.Type).To(Equal(events.TypeAppLifecycle))
+		Expect(e.NodeID).To(Equal(nodeCfg.nodeID))
+	})
+
+	It("Should reject a bad dry_run parameter", func() {
+		By("Sending a POST /apps request with dry_run=maybe")
+		resp, err := apiCli.Post("http://127.0.0.1:8080/apps?dry_run=maybe", "application/json", nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 400 Bad Request response")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal(`bad dry_run "maybe"`))
+	})
+})
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ETags", func() {
+	var (
+		appID  string
+		appURL string
+	)
+
+	BeforeEach(func() {
+		appID = postApps("container")
+		appURL = fmt.Sprintf("http://127.0.0.1:8080/apps/%s", appID)
+	})
+
+	patchApp := func(ifMatch string) *http.Response {
+		req, err := http.NewRequest(http.MethodPatch, appURL, strings.NewReader(fmt.Sprintf(`
+			{
+				"id": "%s",
+				"type": "container",
+				"name": "container app2",
+				"version": "latest",
+				"vendor": "smart edge",
+				"cores": 4,
+				"memory": 1024,
+				"source": "http://www.test.com/my_container_app.tar.gz"
+			}`, appID)))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("If-Match", ifMatch)
+
+		resp, err := apiCli.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	deleteApp := func(ifMatch string) *http.Response {
+		req, err := http.NewRequest(http.MethodDelete, appURL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("If-Match", ifMatch)
+
+		resp, err := apiCli.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	Describe("GET /apps/{app_id}", func() {
+		It("Should return the resource version as the ETag", func() {
+			By("Sending a GET /apps/{app_id} request")
+			resp, err := apiCli.Get(appURL)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			By("Verifying the ETag")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("ETag")).To(Equal(`"1"`))
+		})
+	})
+
+	Describe("PATCH /apps/{app_id}", func() {
+		It("Should update the app if the If-Match header matches", func() {
+			By("Sending a PATCH /apps/{app_id} request with the current ETag")
+			resp := patchApp(`"1"`)
+			defer resp.Body.Close()
+
+			By("Verifying a 200 OK response with the new ETag")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("ETag")).To(Equal(`"2"`))
+
+			By("Verifying the app was updated")
+			Expect(getApp(appID).Name).To(Equal("container app2"))
+		})
+
+		It("Should return 412 if the If-Match header is stale", func() {
+			By("Updating the app")
+			resp := patchApp(`"1"`)
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			By("Sending a PATCH /apps/{app_id} request with the stale ETag")
+			resp = patchApp(`"1"`)
+			defer resp.Body.Close()
+
+			By("Verifying a 412 Precondition Failed response")
+			Expect(resp.StatusCode).To(Equal(http.StatusPreconditionFailed))
+		})
+	})
+
+	Describe("DELETE /apps/{app_id}", func() {
+		It("Should return 412 if the If-Match header is stale", func() {
+			By("Sending a DELETE /apps/{app_id} request with a stale ETag")
+			resp := deleteApp(`"2"`)
+			defer resp.Body.Close()
+
+			By("Verifying a 412 Precondition Failed response")
+			Expect(resp.StatusCode).To(Equal(http.StatusPreconditionFailed))
+
+			By("Verifying the app was not deleted")
+			Expect(getApp(appID)).ToNot(BeNil())
+		})
+
+		It("Should delete the app if the If-Match header matches", func() {
+			By("Sending a DELETE /apps/{app_id} request with the current ETag")
+			resp := deleteApp(`"1"`)
+			defer resp.Body.Close()
+
+			By("Verifying a 200 OK response")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+})
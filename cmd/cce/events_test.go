@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/events"
+)
+
+var _ = Describe("Events", func() {
+	// streamEvents sends a GET /events request and returns the events read
+	// from the stream and a function that closes it.
+	streamEvents := func(query, lastEventID string) (<-chan *events.Event, func()) {
+		By("Sending a GET /events request")
+		req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/events?"+query, nil)
+		Expect(err).ToNot(HaveOccurred())
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := apiCli.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+		c := make(chan *events.Event, 100)
+		go func() {
+			defer close(c)
+			readEvents(resp.Body, c)
+		}()
+		return c, func() { resp.Body.Close() }
+	}
+
+	It("Should stream entity changes", func() {
+		c, closeStream := streamEvents("entity_type=apps", "")
+		defer closeStream()
+
+		id := postApps("container")
+
+		var e *events.Event
+		Eventually(c).Should(Receive(&e))
+		Expect(e.Type).To(Equal(events.TypeEntityCreated))
+		Expect(e.EntityType).To(Equal("apps"))
+		Expect(e.EntityID).To(Equal(id))
+		Expect(e.Actor).To(Equal("admin"))
+	})
+
+	It("Should resume after the last event", func() {
+		c, closeStream := streamEvents("entity_type=apps", "")
+		postApps("container")
+		var first *events.Event
+		Eventually(c).Should(Receive(&first))
+		closeStream()
+
+		id := postApps("container")
+
+		c, closeStream = streamEvents("entity_type=apps", strconv.FormatUint(first.ID, 10))
+		defer closeStream()
+
+		var e *events.Event
+		Eventually(c).Should(Receive(&e))
+		Expect(e.ID).To(BeNumerically(">", first.ID))
+		Expect(e.EntityID).To(Equal(id))
+	})
+
+	It("Should reject a bad last event ID", func() {
+		req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/events", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Last-Event-ID", "x")
+
+		By("Sending a GET /events request")
+		resp, err := apiCli.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+// readEvents reads the events of a server-sent event stream until it ends.
+func readEvents(r io.Reader, c chan<- *events.Event) {
+	defer GinkgoRecover()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e events.Event
+		Expect(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)).To(Succeed())
+		c <- &e
+	}
+}
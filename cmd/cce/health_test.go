@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/health"
+)
+
+var _ = Describe("Health probes", func() {
+	probe := func(path string) *health.Report {
+		By("Sending a GET " + path + " request without a token")
+		resp, err := http.Get("http://127.0.0.1:8080" + path)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var report health.Report
+		Expect(json.NewDecoder(resp.Body).Decode(&report)).To(Succeed())
+		return &report
+	}
+
+	checkNames := func(report *health.Report) []string {
+		var names []string
+		for _, result := range report.Checks {
+			Expect(result.Status).To(Equal(health.StatusOK), result.Name)
+			names = append(names, result.Name)
+		}
+		return names
+	}
+
+	It("Should report the controller is live", func() {
+		report := probe("/healthz")
+		Expect(report.Status).To(Equal(health.StatusOK))
+		Expect(checkNames(report)).To(ConsistOf("grpc", "syslog", "statsd"))
+	})
+
+	It("Should report the controller is ready", func() {
+		report := probe("/readyz")
+		Expect(report.Status).To(Equal(health.StatusOK))
+		Expect(checkNames(report)).To(ConsistOf("db", "ca", "grpc", "syslog", "statsd"))
+	})
+})
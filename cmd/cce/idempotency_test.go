@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var _ = Describe("Idempotency keys", func() {
+	var (
+		key string
+		cli *apiClient
+	)
+
+	BeforeEach(func() {
+		key = uuid.New()
+		cli = &apiClient{Token: apiCli.Token, Header: http.Header{"Idempotency-Key": {key}}}
+	})
+
+	postApp := func(body string) *http.Response {
+		By("Sending a POST /apps request with an Idempotency-Key")
+		resp, err := cli.Post("http://127.0.0.1:8080/apps", "application/json", strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	readBody := func(resp *http.Response) string {
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(body)
+	}
+
+	It("Should return the first response to a retry", func() {
+		id := postAppsAs(cli, "container", "idempotent app")
+
+		By("Retrying the request")
+		retry := postApp(appJSON("container", "idempotent app"))
+		Expect(retry.StatusCode).To(Equal(http.StatusCreated))
+		Expect(retry.Header.Get("Idempotent-Replayed")).To(Equal("true"))
+		var rb respBody
+		Expect(json.Unmarshal([]byte(readBody(retry)), &rb)).To(Succeed())
+		Expect(rb.ID).To(Equal(id))
+
+		By("Verifying the app was created once")
+		Expect(getApp(id).Name).To(Equal("idempotent app"))
+	})
+
+	It("Should reject a different request with the same key", func() {
+		postAppsAs(cli, "container", "idempotent app")
+
+		By("Sending a different request with the same key")
+		other := postApp(`{"type": "container", "name": "other app"}`)
+		Expect(other.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(readBody(other)).To(Equal(
+			fmt.Sprintf("Idempotency-Key %s was used for a different request", key)))
+	})
+
+	It("Should not store the response to a failed request", func() {
+		resp := postApp(`{"type": "container"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		readBody(resp)
+
+		By("Retrying the request after fixing it")
+		retry := postApp(appJSON("container", "idempotent app"))
+		Expect(retry.StatusCode).To(Equal(http.StatusCreated))
+		Expect(retry.Header.Get("Idempotent-Replayed")).To(BeEmpty())
+		readBody(retry)
+	})
+
+	It("Should handle concurrent requests with the same key once", func() {
+		By("Sending concurrent POST /apps requests with the same Idempotency-Key")
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			statuses = make(map[int]int)
+			ids      = make(map[string]bool)
+		)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				resp, err := cli.Post(
+					"http://127.0.0.1:8080/apps",
+					"application/json",
+					strings.NewReader(appJSON("container", "idempotent app")))
+				Expect(err).ToNot(HaveOccurred())
+				body := readBody(resp)
+
+				mu.Lock()
+				defer mu.Unlock()
+				statuses[resp.StatusCode]++
+				if resp.StatusCode == http.StatusCreated {
+					var rb respBody
+					Expect(json.Unmarshal([]byte(body), &rb)).To(Succeed())
+					ids[rb.ID] = true
+				}
+			}()
+		}
+		wg.Wait()
+
+		By("Verifying one app was created and the other requests were replayed or rejected")
+		Expect(statuses[http.StatusCreated]).To(BeNumerically(">=", 1))
+		Expect(statuses[http.StatusCreated] + statuses[http.StatusConflict]).To(Equal(5))
+		Expect(ids).To(HaveLen(1))
+	})
+
+	It("Should return the same operation to a retry", func() {
+		clearGRPCTargetsTable()
+		nodeCfg := createAndRegisterNode()
+		appID := postApps("container")
+
+		deployApp := func() *swagger.Operation {
+			By("Sending a POST /nodes/{node_id}/apps request with an Idempotency-Key")
+			resp, err := cli.Post(
+				fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/apps", nodeCfg.nodeID),
+				"application/json",
+				strings.NewReader(fmt.Sprintf(`{"id": "%s"}`, appID)))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			return expectOperation(resp, http.StatusOK)
+		}
+
+		op := deployApp()
+
+		By("Retrying the request")
+		Expect(deployApp().ID).To(Equal(op.ID))
+	})
+})
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var _ = Describe("Listing", func() {
+	var (
+		// prefix scopes the listed apps to those created by the spec
+		prefix         string
+		containerAppID string
+		vmAppID        string
+	)
+
+	BeforeEach(func() {
+		prefix = uuid.New()
+		containerAppID = postApps("container", prefix+" container")
+		vmAppID = postApps("vm", prefix+" vm")
+	})
+
+	getAppList := func(uri string, expectedStatus int) *swagger.AppList {
+		By("Sending a GET " + uri + " request")
+		resp, err := apiCli.Get("http://127.0.0.1:8080" + uri)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying the response status")
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+		if expectedStatus != http.StatusOK {
+			return nil
+		}
+
+		By("Reading the response body")
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var apps swagger.AppList
+
+		By("Unmarshaling the response")
+		Expect(json.Unmarshal(body, &apps)).To(Succeed())
+
+		return &apps
+	}
+
+	Describe("GET /apps", func() {
+		It("Should page through the apps", func() {
+			By("Getting the first page")
+			apps := getAppList("/apps?sort=type&limit=1&name~="+prefix, http.StatusOK)
+			Expect(apps.Apps).To(HaveLen(1))
+			Expect(apps.Apps[0].ID).To(Equal(containerAppID))
+			Expect(apps.Total).To(Equal(2))
+			Expect(apps.Next).ToNot(BeEmpty())
+
+			By("Following the next link to the last page")
+			apps = getAppList(apps.Next, http.StatusOK)
+			Expect(apps.Apps).To(HaveLen(1))
+			Expect(apps.Apps[0].ID).To(Equal(vmAppID))
+			Expect(apps.Total).To(Equal(2))
+			Expect(apps.Next).To(BeEmpty())
+		})
+
+		It("Should sort the apps in descending order", func() {
+			apps := getAppList("/apps?sort=-type&name~="+prefix, http.StatusOK)
+			Expect(apps.Apps).To(HaveLen(2))
+			Expect(apps.Apps[0].ID).To(Equal(vmAppID))
+			Expect(apps.Apps[1].ID).To(Equal(containerAppID))
+		})
+
+		It("Should filter the apps", func() {
+			apps := getAppList("/apps?type=vm&name~="+prefix, http.StatusOK)
+			Expect(apps.Apps).To(HaveLen(1))
+			Expect(apps.Apps[0].ID).To(Equal(vmAppID))
+			Expect(apps.Total).To(Equal(1))
+
+			apps = getAppList("/apps?name="+url.QueryEscape(prefix+" container"), http.StatusOK)
+			Expect(apps.Apps).To(HaveLen(1))
+			Expect(apps.Apps[0].ID).To(Equal(containerAppID))
+		})
+
+		DescribeTable("400 Bad Request",
+			func(uri string) {
+				getAppList(uri, http.StatusBadRequest)
+			},
+			Entry("Unknown filter field", "/apps?source=x"),
+			Entry("Unknown sort field", "/apps?sort=source"),
+			Entry("Bad limit", "/apps?limit=x"),
+			Entry("Limit too large", "/apps?limit=1001"),
+			Entry("Malformed cursor", "/apps?cursor=x"),
+		)
+	})
+})
@@ -15,8 +15,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"io"
+	"io/ioutil"
+	gohttp "net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/gorilla/handlers"
 	"golang.org/x/sync/errgroup"
@@ -31,22 +34,38 @@ import (
 	logger "github.com/open-ness/common/log"
 	"github.com/open-ness/common/proxy/progutil"
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	"github.com/open-ness/edgecontroller/config"
+	"github.com/open-ness/edgecontroller/events"
 	"github.com/open-ness/edgecontroller/gorilla"
 	"github.com/open-ness/edgecontroller/grpc"
+	"github.com/open-ness/edgecontroller/health"
 	"github.com/open-ness/edgecontroller/http"
 	"github.com/open-ness/edgecontroller/jose"
 	"github.com/open-ness/edgecontroller/k8s"
 	"github.com/open-ness/edgecontroller/mysql"
+	"github.com/open-ness/edgecontroller/operation"
 	"github.com/open-ness/edgecontroller/pki"
+	"github.com/open-ness/edgecontroller/ratelimit"
+	"github.com/open-ness/edgecontroller/reconcile"
 	"github.com/open-ness/edgecontroller/telemetry"
+	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/open-ness/edgecontroller/webhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const certsDir = "./certificates"
 
+// boltScheme is the DSN scheme selecting the embedded DB
+const boltScheme = "bolt://"
+
 var log = logger.DefaultLogger.WithField("pkg", "main")
 
 // CLI flags
 var (
+	configFile string
 	dsn        string
 	adminPass  string
 	logLevel   string
@@ -60,11 +79,57 @@ var (
 	statsdOut  string
 	orchMode   string
 	k8sClient  k8s.Client
+
+	reconcileInterval       time.Duration
+	autoMigrate             bool
+	dbConnectTimeout        time.Duration
+	operationWorkers        int
+	idempotencyKeyRetention time.Duration
+	webhookAllowedNetworks  string
+	webhookDenyPrivate      bool
+
+	accessTokenLifetime  time.Duration
+	refreshTokenLifetime time.Duration
+	tokenKeyRotation     time.Duration
+	tokenEncryptionKey   string
+
+	httpTLS           bool
+	httpServerName    string
+	httpClientCA      string
+	httpCertFile      string
+	httpKeyFile       string
+	httpCertLifetime  time.Duration
+	httpTLSMinVersion string
+	httpTLSMaxVersion string
+	httpTLSCiphers    string
+	httpRedirectPort  int
+
+	rateLimits = map[string]*ratelimit.Limit{
+		gorilla.RateLimitAuth:   {Rate: 1, Burst: 10},
+		gorilla.RateLimitClient: {Rate: 100, Burst: 200},
+		gorilla.RateLimitRead:   {Rate: 50, Burst: 100},
+		gorilla.RateLimitWrite:  {Rate: 10, Burst: 20},
+	}
+	loginLockout ratelimit.Lockout
+
+	metricsPort int
+)
+
+// Settings that are only set in the config file
+var (
+	corsOrigins = []string{"*"}
+	limits      = cce.DefaultLimits
 )
 
 func init() {
-	flag.StringVar(&dsn, "dsn", "", "Data source name")
-	flag.StringVar(&adminPass, "adminPass", "", "Admin user password")
+	flag.StringVar(&configFile, "config", "",
+		"YAML or JSON config file, overridden by the other flags. Its log_level, cors and limits are reloaded on SIGHUP")
+	flag.StringVar(&dsn, "dsn", "", "Data source name, either a MySQL DSN or bolt://<path> for an embedded DB")
+	flag.BoolVar(&autoMigrate, "auto-migrate", true,
+		"Apply pending MySQL schema migrations at startup, otherwise run the migrate command")
+	flag.DurationVar(&dbConnectTimeout, "db-connect-timeout", 5*time.Minute,
+		"Time to retry connecting to MySQL at startup for, 0 retries until it is up")
+	flag.StringVar(&adminPass, "adminPass", "", "Password of the admin user, which is created on first start")
 	flag.StringVar(&logLevel, "log-level", "info", "Syslog level")
 	flag.IntVar(&httpPort, "httpPort", 8080, "Controller HTTP port")
 	flag.IntVar(&grpcPort, "grpcPort", 8081, "Controller gRPC port")
@@ -74,9 +139,65 @@ func init() {
 	flag.IntVar(&statsdPort, "statsdPort", 8125, "Telemetry ingress port for statsd")
 	flag.StringVar(&syslogOut, "syslog-path", "./syslog.log", "Syslog output file path")
 	flag.StringVar(&statsdOut, "statsd-path", "./statsd.log", "StatsD output file path")
+	flag.DurationVar(&reconcileInterval, "reconcile-interval", 5*time.Minute,
+		"Interval between reconciling nodes with the DB, 0 disables reconciliation")
+	flag.IntVar(&operationWorkers, "operation-workers", operation.DefaultWorkers,
+		"Number of operations on nodes run at once in the background")
+	flag.DurationVar(&idempotencyKeyRetention, "idempotency-key-retention", cce.DefaultIdempotencyKeyRetention,
+		"Time the responses to HTTP API requests with an Idempotency-Key header are stored for")
+	flag.StringVar(&webhookAllowedNetworks, "webhook-allowed-networks", "",
+		"Comma-separated CIDR networks webhooks are delivered to even if they are loopback, link-local or private")
+	flag.BoolVar(&webhookDenyPrivate, "webhook-deny-private", false,
+		"Refuse to deliver webhooks to private addresses, as well as loopback and link-local ones")
+	flag.DurationVar(&accessTokenLifetime, "access-token-lifetime", jose.DefaultAccessTokenLifetime,
+		"Lifetime of the access tokens of the HTTP API")
+	flag.DurationVar(&refreshTokenLifetime, "refresh-token-lifetime", jose.DefaultRefreshTokenLifetime,
+		"Lifetime of the refresh tokens of the HTTP API, which are exchanged for new access tokens")
+	flag.DurationVar(&tokenKeyRotation, "token-key-rotation", jose.DefaultRotationInterval,
+		"Interval between rotating the key that signs the tokens of the HTTP API")
+	flag.StringVar(&tokenEncryptionKey, "token-encryption-key", "",
+		"File of the base64-encoded 32-byte key that encrypts the token signing keys in the DB. "+
+			"If empty they are stored unencrypted, and anyone who can read the DB can sign tokens")
+
+	flag.BoolVar(&httpTLS, "http-tls", false,
+		"Serve the HTTP API over HTTPS with a server certificate from the Controller CA")
+	flag.StringVar(&httpServerName, "http-server-name", "localhost",
+		"Host name of the HTTPS server certificate")
+	flag.StringVar(&httpClientCA, "http-client-ca", "",
+		"PEM file of operator CA certificates, whose client certificates authenticate HTTPS API requests "+
+			"like those of the Controller CA")
+	flag.StringVar(&httpCertFile, "http-cert", "",
+		"PEM file of the HTTPS server certificate chain, which is reloaded when it changes, "+
+			"instead of one from the Controller CA")
+	flag.StringVar(&httpKeyFile, "http-key", "", "PEM file of the key of the -http-cert certificate")
+	flag.DurationVar(&httpCertLifetime, "http-cert-lifetime", pki.DefaultServerCertLifetime,
+		"Lifetime of the HTTPS server certificates from the Controller CA, which are renewed after two thirds of it")
+	flag.StringVar(&httpTLSMinVersion, "http-tls-min-version", "1.2", "Minimum TLS version of HTTPS, 1.0 to 1.3")
+	flag.StringVar(&httpTLSMaxVersion, "http-tls-max-version", "",
+		"Maximum TLS version of HTTPS, 1.0 to 1.3, or empty for the latest")
+	flag.StringVar(&httpTLSCiphers, "http-tls-ciphers", strings.Join(pki.DefaultCipherSuites, ","),
+		"Comma-separated TLS 1.0-1.2 cipher suites of HTTPS, one of "+strings.Join(pki.CipherSuiteNames(), ", "))
+	flag.IntVar(&httpRedirectPort, "http-redirect-port", 0,
+		"Port of a plain HTTP listener that redirects to HTTPS, 0 disables it")
+
+	flag.Var(rateLimits[gorilla.RateLimitAuth], "rate-limit-auth",
+		"Rate limit of HTTP API token requests per client IP as RATE:BURST in requests per second, 0 disables it")
+	flag.Var(rateLimits[gorilla.RateLimitClient], "rate-limit-client",
+		"Rate limit of other HTTP API requests per client IP as RATE:BURST in requests per second, 0 disables it")
+	flag.Var(rateLimits[gorilla.RateLimitRead], "rate-limit-read",
+		"Rate limit of HTTP API GET requests per user as RATE:BURST in requests per second, 0 disables it")
+	flag.Var(rateLimits[gorilla.RateLimitWrite], "rate-limit-write",
+		"Rate limit of other HTTP API requests per user as RATE:BURST in requests per second, 0 disables it")
+	flag.IntVar(&loginLockout.Threshold, "login-lockout-threshold", ratelimit.DefaultLockoutThreshold,
+		"Failed logins of a user from a client before it is locked out, 0 disables lockout")
+	flag.DurationVar(&loginLockout.Duration, "login-lockout-duration", ratelimit.DefaultLockoutDuration,
+		"Duration of the first lockout, which doubles with each further failed login up to "+
+			ratelimit.DefaultMaxLockoutDuration.String())
+	flag.IntVar(&metricsPort, "metrics-port", 0,
+		"Port of the Prometheus metrics listener, 0 disables it")
 
 	// application orchestration mode
-	flag.StringVar(&orchMode, "orchestration-mode", "native", "Orchestration mode."+
+	flag.StringVar(&orchMode, "orchestration-mode", config.OrchestrationNative, "Orchestration mode."+
 		"options [native, kubernetes, kubernetes-ovn] ")
 
 	// k8s
@@ -93,12 +214,12 @@ func setupOrchestrator() (cce.OrchestrationMode, error) {
 	var err error
 
 	switch orchMode {
-	case "native":
+	case config.OrchestrationNative:
 		orchestrationMode = cce.OrchestrationModeNative
-	case "kubernetes":
+	case config.OrchestrationKubernetes:
 		orchestrationMode = cce.OrchestrationModeKubernetes
 		err = k8sClient.Ping()
-	case "kubernetes-ovn":
+	case config.OrchestrationKubernetesOVN:
 		orchestrationMode = cce.OrchestrationModeKubernetesOVN
 		err = k8sClient.Ping()
 	default:
@@ -109,13 +230,27 @@ func setupOrchestrator() (cce.OrchestrationMode, error) {
 }
 
 func main() {
+	defaults := configFromFlags()
+	flag.Usage = usage
 	flag.Parse()
 
-	// Validate flags
-	if adminPass == "" {
-		log.Alert("User admin password cannot be empty")
+	// Load the config file over the defaults of the flags, then parse the
+	// flags again so that the command line overrides it
+	if configFile != "" {
+		cfg := defaults
+		if err := config.Load(configFile, &cfg); err != nil {
+			log.Alertf("Error loading config file: %v", err)
+			os.Exit(1)
+		}
+		setFlags(cfg)
+		flag.Parse()
+	}
+	running := configFromFlags()
+	if err := running.Validate(); err != nil {
+		log.Alertf("Invalid configuration: %v", err)
 		os.Exit(1)
 	}
+	cce.SetLimits(running.Limits)
 
 	// Set log level
 	lvl, err := logger.ParseLevel(logLevel)
@@ -126,6 +261,15 @@ func main() {
 	log.Infof("Setting log level to: %s", logLevel)
 	logger.SetLevel(lvl)
 
+	// Run the migrate command instead of the controller
+	if flag.Arg(0) == "migrate" {
+		if err = migrate(flag.Args()[1:]); err != nil {
+			log.Alertf("Error migrating DB schema: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log.Info("Controller CE starting")
 
 	// Setup orchestrator
@@ -136,7 +280,18 @@ func main() {
 	}
 
 	// Connect to the db and verify
-	db := connectDB(dsn)
+	checker := &health.Checker{}
+	ps, dbCheck := connectDB(dsn)
+	checker.AddReadiness("db", dbCheck)
+	if orchestrationMode != cce.OrchestrationModeNative {
+		checker.AddReadiness("kubernetes", func(context.Context) error { return k8sClient.Ping() })
+	}
+
+	// Create the admin user on first start
+	if err = bootstrapAdmin(ps); err != nil {
+		log.Alertf("Error creating the %s user: %v", cce.AdminUsername, err)
+		os.Exit(1)
+	}
 
 	// Initialize self-signed root CA
 	rootCA, err := pki.InitRootCA(filepath.Join(certsDir, "ca"))
@@ -145,30 +300,109 @@ func main() {
 		os.Exit(1)
 	}
 	log.Info("Initialized Controller CA")
+	checker.AddReadiness("ca", func(context.Context) error { return checkCA(rootCA, time.Now()) })
 
 	// TODO: Replace printing to STDERR with writing to a file or making the
 	// certificate available via an HTTP endpoint.
 	log.Infof("Root CA:\n%s", encodeCA(rootCA))
 
+	// Load the token signing keys, generating one on first start
+	tokenStore := &cce.TokenStore{PersistenceService: ps}
+	if tokenEncryptionKey != "" {
+		if tokenStore.EncryptionKey, err = cce.ReadTokenEncryptionKey(tokenEncryptionKey); err != nil {
+			log.Alertf("Error loading token encryption key: %v", err)
+			os.Exit(1)
+		}
+	} else {
+		log.Warning("Storing the token signing keys unencrypted, set -token-encryption-key to encrypt them")
+	}
+	tokenIssuer := &jose.JWSTokenIssuer{
+		Store:                tokenStore,
+		AccessTokenLifetime:  accessTokenLifetime,
+		RefreshTokenLifetime: refreshTokenLifetime,
+		RotationInterval:     tokenKeyRotation,
+	}
+	if err = tokenIssuer.Load(context.Background()); err != nil {
+		log.Alertf("Error loading token signing keys: %v", err)
+		os.Exit(1)
+	}
+
 	// Define controller service
 	controller := &cce.Controller{
-		PersistenceService: &mysql.PersistenceService{DB: db},
+		PersistenceService: ps,
 		AuthorityService:   rootCA,
-		TokenService:       getTokenSigner(),
-		AdminCreds: &cce.AuthCreds{
-			Username: "admin",
-			Password: adminPass,
-		},
-		OrchestrationMode: orchestrationMode,
-		KubernetesClient:  &k8sClient,
-		ELAPort:           strconv.Itoa(elaPort),
-		EVAPort:           strconv.Itoa(evaPort),
-		EdgeNodeCreds:     newClientTLSConf(rootCA, "controller.openness"),
+		TokenService:       tokenIssuer,
+		OrchestrationMode:  orchestrationMode,
+		KubernetesClient:   &k8sClient,
+		ELAPort:            strconv.Itoa(elaPort),
+		EVAPort:            strconv.Itoa(evaPort),
+		EdgeNodeCreds:      newClientTLSConf(rootCA, "controller.openness"),
+		RateLimiters:       make(map[string]*ratelimit.Limiter),
+		Events:             events.NewBus(events.DefaultRetained),
+
+		IdempotencyKeyRetention: idempotencyKeyRetention,
+	}
+
+	// Rate limit the HTTP API and lock out failed logins
+	for group, limit := range rateLimits {
+		if !limit.IsZero() {
+			controller.RateLimiters[group] = &ratelimit.Limiter{Limit: *limit}
+		}
 	}
+	if loginLockout.Threshold > 0 {
+		controller.LoginLockout = &loginLockout
+	}
+	registerRateLimitMetrics(controller)
+	registerEntityMetrics(controller)
+
+	// Run the requests that change nodes in the background
+	operations := &operation.Runner{
+		Controller: controller,
+		Workers:    operationWorkers,
+	}
+	controller.Operations = operations
 
 	// Create an error group to manage server goroutines
 	eg, ctx := errgroup.WithContext(context.Background())
 
+	// Periodically reconcile nodes with the state persisted for them
+	if reconcileInterval > 0 {
+		reconciler := &reconcile.Reconciler{
+			Controller: controller,
+			Interval:   reconcileInterval,
+		}
+		controller.DriftReporter = reconciler
+		eg.Go(func() error { return reconciler.Run(ctx) })
+	}
+
+	// Rotate the token signing key
+	eg.Go(func() error { return tokenIssuer.Run(ctx) })
+
+	// Deliver events to webhooks
+	allowedNetworks, err := webhook.ParseNetworks(webhookAllowedNetworks)
+	if err != nil {
+		log.Alertf("Error parsing webhook allowed networks: %v", err)
+		os.Exit(1)
+	}
+	dispatcher := &webhook.Dispatcher{
+		Controller:      controller,
+		AllowedNetworks: allowedNetworks,
+		DenyPrivate:     webhookDenyPrivate,
+	}
+	eg.Go(func() error { return dispatcher.Run(ctx) })
+
+	// Run operations on nodes
+	eg.Go(func() error { return operations.Run(ctx) })
+
+	// Apply the CORS policy to the HTTP API
+	api := &corsHandler{api: gorilla.NewGorilla(controller)}
+	api.setOrigins(running.CORS.AllowedOrigins)
+
+	// Reload the config file on SIGHUP
+	if configFile != "" {
+		eg.Go(func() error { return reloadConfig(ctx, configFile, defaults, running, api) })
+	}
+
 	// Catch SIGINT/SIGTERM and initiate shutdown
 	var errSignalShutdown = errors.New("received INT/TERM signal, shutting down")
 	eg.Go(func() error {
@@ -189,10 +423,37 @@ func main() {
 	grpcAddr := fmt.Sprintf(":%d", grpcPort)
 	syslogAddr := fmt.Sprintf(":%d", syslogPort)
 	statsdAddr := fmt.Sprintf(":%d", statsdPort)
-	eg.Go(serveHTTP(ctx, controller, httpAddr))
-	eg.Go(serveGRPC(ctx, controller, grpcAddr, getGRPCTLS(rootCA)))
-	eg.Go(serveTelemetry(ctx, syslogOut, syslogAddr, newTLSConf(rootCA, telemetry.SyslogSNI)))
-	eg.Go(serveTelemetry(ctx, statsdOut, statsdAddr, newTLSConf(rootCA, telemetry.StatsdSNI)))
+	var httpTLSConf *tls.Config
+	if httpTLS {
+		serverCert := &pki.ServerCert{
+			CA:         rootCA,
+			ServerName: httpServerName,
+			Lifetime:   httpCertLifetime,
+			CertFile:   httpCertFile,
+			KeyFile:    httpKeyFile,
+		}
+		if httpTLSConf, err = getHTTPTLS(rootCA, serverCert, httpClientCA); err != nil {
+			log.Alertf("Error configuring HTTPS: %v", err)
+			os.Exit(1)
+		}
+
+		// Renew the server certificate before it expires
+		eg.Go(func() error { return serverCert.Run(ctx) })
+
+		if httpRedirectPort != 0 {
+			eg.Go(serveHTTPRedirect(ctx, fmt.Sprintf(":%d", httpRedirectPort), httpPort))
+		}
+	} else if httpCertFile != "" || httpKeyFile != "" || httpRedirectPort != 0 {
+		log.Alert("-http-cert, -http-key and -http-redirect-port require -http-tls")
+		os.Exit(1)
+	}
+	eg.Go(serveHTTP(ctx, api, checker, httpAddr, httpTLSConf))
+	if metricsPort != 0 {
+		eg.Go(serveMetrics(ctx, fmt.Sprintf(":%d", metricsPort)))
+	}
+	eg.Go(serveGRPC(ctx, controller, checker, grpcAddr, getGRPCTLS(rootCA)))
+	eg.Go(serveTelemetry(ctx, checker, "syslog", syslogOut, syslogAddr, newTLSConf(rootCA, telemetry.SyslogSNI)))
+	eg.Go(serveTelemetry(ctx, checker, "statsd", statsdOut, statsdAddr, newTLSConf(rootCA, telemetry.StatsdSNI)))
 
 	log.Info("Controller CE ready")
 
@@ -217,21 +478,170 @@ func registerAllNodes(ctx context.Context, ps cce.PersistenceService) {
 	}
 }
 
-// Connect to a mysql DB and ping it for readiness.
-func connectDB(dsn string) *sql.DB {
+// Connect to the DB named by the DSN, and return the check of its
+// connectivity. A bolt://<path> DSN opens the embedded DB file at path, any
+// other DSN is a MySQL DSN and the DB is pinged for readiness until it is up.
+func connectDB(dsn string) (cce.PersistenceService, health.Check) {
+	if strings.HasPrefix(dsn, boltScheme) {
+		db, err := bolt.Open(strings.TrimPrefix(dsn, boltScheme))
+		if err != nil {
+			log.Alertf("Error opening db: %v", err)
+			os.Exit(1)
+		}
+		log.Info("DB opened")
+		return &bolt.PersistenceService{DB: db}, func(context.Context) error { return nil }
+	}
+
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		log.Alertf("Error opening db: %v", err)
 		os.Exit(1)
 	}
-	// TODO: retry ping with backoff rather than exiting because DB may still
-	// be initializing
-	if err = db.Ping(); err != nil {
+	if err = pingDB(db, dbConnectTimeout); err != nil {
 		log.Alertf("DB ping failed: %v", err)
 		os.Exit(1)
 	}
 	log.Info("DB connection established")
-	return db
+
+	// Bring the schema up to date, or refuse to start if it isn't
+	migrator := mysql.NewMigrator(db)
+	if autoMigrate {
+		err = migrator.Migrate(context.Background(), migrator.Latest())
+	} else {
+		err = migrator.Check(context.Background())
+	}
+	if err != nil {
+		log.Alertf("Error checking DB schema: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("DB schema is at version %d", migrator.Latest())
+
+	return &mysql.PersistenceService{DB: db}, db.PingContext
+}
+
+// pingDB pings the DB until it is up, because it may still be initializing,
+// waiting twice as long after each failed ping up to 30 seconds. It gives up
+// after the timeout, or never if it is zero.
+func pingDB(db *sql.DB, timeout time.Duration) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	backoff := time.Second
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		log.Noticef("DB ping failed, retrying in %s: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
+
+// checkCA checks that the Controller CA certificate is valid at a time, so
+// that it can issue certificates.
+func checkCA(rootCA *pki.RootCA, t time.Time) error {
+	if rootCA.Cert == nil || rootCA.Key == nil {
+		return errors.New("CA certificate and key not loaded")
+	}
+	if t.Before(rootCA.Cert.NotBefore) || t.After(rootCA.Cert.NotAfter) {
+		return fmt.Errorf("CA certificate only valid from %s to %s",
+			rootCA.Cert.NotBefore.Format(time.RFC3339), rootCA.Cert.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// bootstrapAdmin creates the admin user with the password of the -adminPass
+// flag, which must meet the password policy, if there is no admin user. Once
+// it exists, its password is changed through the API and the flag is ignored.
+func bootstrapAdmin(ps cce.PersistenceService) error {
+	ctx := context.Background()
+
+	admin, err := cce.ReadUser(ctx, ps, cce.AdminUsername)
+	if err != nil {
+		return err
+	}
+	if admin != nil {
+		if adminPass != "" && !admin.CheckPassword(adminPass) {
+			log.Noticef("Ignoring -adminPass, the %s user exists with another password", cce.AdminUsername)
+		}
+		return nil
+	}
+
+	if adminPass == "" {
+		return errors.New("the admin password cannot be empty")
+	}
+
+	admin = &cce.User{
+		ID:    uuid.New(),
+		Name:  cce.AdminUsername,
+		Roles: []string{cce.RoleAdmin},
+	}
+	if err = admin.ValidatePassword(adminPass); err != nil {
+		return fmt.Errorf("the %s password does not meet the password policy: %v", cce.AdminUsername, err)
+	}
+	if err = admin.SetPassword(adminPass); err != nil {
+		return err
+	}
+	if err = ps.Create(ctx, admin); err != nil {
+		return err
+	}
+	log.Infof("Created the %s user", cce.AdminUsername)
+
+	return nil
+}
+
+// usage prints the usage of the controller and its commands.
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\n", os.Args[0])
+	fmt.Fprintln(flag.CommandLine.Output(), "Commands:")
+	fmt.Fprintln(flag.CommandLine.Output(),
+		"  migrate [version]\tmigrate the MySQL schema to version, or to the latest version")
+	fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// migrate migrates the schema of the MySQL DB named by the DSN to the version
+// in args, or to the latest version if there is none.
+func migrate(args []string) error {
+	if strings.HasPrefix(dsn, boltScheme) {
+		return errors.New("the embedded DB has no schema migrations")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator := mysql.NewMigrator(db)
+	target := migrator.Latest()
+	if len(args) > 0 {
+		if target, err = strconv.Atoi(args[0]); err != nil {
+			return fmt.Errorf("bad schema version %q: %v", args[0], err)
+		}
+	}
+
+	if err = migrator.Migrate(context.Background(), target); err != nil {
+		return err
+	}
+	log.Infof("DB schema is at version %d", target)
+
+	return nil
 }
 
 // Encode self-signed Controller CA. This is used to manually configure the
@@ -246,44 +656,38 @@ func encodeCA(rootCA *pki.RootCA) string {
 	))
 }
 
-// Generate a key for signing authentication tokens. The key is only stored
-// in memory and will be re-generated upon Controller restart.
-//
-// TODO: Persist the key to avoid having API/UI users have to login and get a
-// new token every time the Controller is restarted.
-func getTokenSigner() *jose.JWSTokenIssuer {
-	joseKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
-	if err != nil {
-		log.Alertf("error generating token signing key: %v", err)
-		os.Exit(1)
-	}
-	return &jose.JWSTokenIssuer{
-		Key:          joseKey,
-		KeyAlgorithm: "ES384",
-	}
-}
-
-func serveHTTP(ctx context.Context, controller *cce.Controller, addr string) func() error {
+func serveHTTP(
+	ctx context.Context,
+	api gohttp.Handler,
+	checker *health.Checker,
+	addr string,
+	conf *tls.Config,
+) func() error {
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.Alertf("Could not listen on %q: %v", addr, err)
 		os.Exit(1)
 	}
 
-	// Define Cross-Origin Resource Sharing (CORS) policy to allow the UI to be
-	// served from a separate host. This policy restricts received API requests
-	// based on the request origin, headers, and method type. The CORS policy
-	// handler must be applied at the top-level router.
-	cors := handlers.CORS(
-		handlers.AllowedOrigins([]string{"*"}),
-		handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "ContentType"}),
-		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE"}),
-	)
-
-	// Configure http server
-	koko := gorilla.NewGorilla(controller)
+	// Upgrade to TLS
+	scheme := "HTTP"
+	if conf != nil {
+		lis = tls.NewListener(lis, conf)
+		scheme = "HTTPS"
+	}
 
-	httpServer := http.NewServer(cors(koko))
+	// Serve the liveness and readiness probes without authentication
+	healthz, readyz := checker.LivenessHandler(), checker.ReadinessHandler()
+	httpServer := http.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			healthz.ServeHTTP(w, r)
+		case "/readyz":
+			readyz.ServeHTTP(w, r)
+		default:
+			api.ServeHTTP(w, r)
+		}
+	}))
 
 	// Shutdown http server on exit signal
 	go func() {
@@ -301,23 +705,298 @@ func serveHTTP(ctx context.Context, controller *cce.Controller, addr string) fun
 	}()
 
 	// Start the http server
-	log.Infof("HTTP server serving on %q", addr)
+	log.Infof("%s server serving on %q", scheme, addr)
 	return func() error {
 		defer lis.Close()
 		return httpServer.Serve(lis)
 	}
 }
 
-func serveGRPC(ctx context.Context, controller *cce.Controller, addr string, conf *tls.Config) func() error {
+// corsHandler applies the Cross-Origin Resource Sharing (CORS) policy to the
+// HTTP API to allow the UI to be served from a separate host. This policy
+// restricts received API requests based on the request origin, headers, and
+// method type. The CORS policy handler must be applied at the top-level router.
+type corsHandler struct {
+	api     gohttp.Handler
+	handler atomic.Value
+}
+
+// setOrigins replaces the origins that are allowed to make requests, which
+// takes effect for the next request.
+func (c *corsHandler) setOrigins(origins []string) {
+	c.handler.Store(handlers.CORS(
+		handlers.AllowedOrigins(origins),
+		handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "ContentType"}),
+		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE"}),
+	)(c.api))
+}
+
+func (c *corsHandler) ServeHTTP(w gohttp.ResponseWriter, r *gohttp.Request) {
+	c.handler.Load().(gohttp.Handler).ServeHTTP(w, r)
+}
+
+// configFromFlags returns the configuration set by the flags.
+func configFromFlags() config.Config {
+	return config.Config{
+		DSN:               dsn,
+		LogLevel:          logLevel,
+		OrchestrationMode: orchMode,
+		Ports: config.Ports{
+			HTTP:         httpPort,
+			GRPC:         grpcPort,
+			ELA:          elaPort,
+			EVA:          evaPort,
+			Syslog:       syslogPort,
+			StatsD:       statsdPort,
+			Metrics:      metricsPort,
+			HTTPRedirect: httpRedirectPort,
+		},
+		Kubernetes: config.Kubernetes{
+			CAFile:   k8sClient.CAFile,
+			CertFile: k8sClient.CertFile,
+			KeyFile:  k8sClient.KeyFile,
+			Host:     k8sClient.Host,
+			APIPath:  k8sClient.APIPath,
+			Username: k8sClient.Username,
+		},
+		CORS:   config.CORS{AllowedOrigins: append([]string(nil), corsOrigins...)},
+		Limits: limits,
+		Telemetry: config.Telemetry{
+			SyslogPath: syslogOut,
+			StatsDPath: statsdOut,
+		},
+	}
+}
+
+// setFlags sets the flags to the configuration, before the flags on the
+// command line are parsed again.
+func setFlags(cfg config.Config) {
+	dsn = cfg.DSN
+	logLevel = cfg.LogLevel
+	orchMode = cfg.OrchestrationMode
+
+	httpPort = cfg.Ports.HTTP
+	grpcPort = cfg.Ports.GRPC
+	elaPort = cfg.Ports.ELA
+	evaPort = cfg.Ports.EVA
+	syslogPort = cfg.Ports.Syslog
+	statsdPort = cfg.Ports.StatsD
+	metricsPort = cfg.Ports.Metrics
+	httpRedirectPort = cfg.Ports.HTTPRedirect
+
+	k8sClient.CAFile = cfg.Kubernetes.CAFile
+	k8sClient.CertFile = cfg.Kubernetes.CertFile
+	k8sClient.KeyFile = cfg.Kubernetes.KeyFile
+	k8sClient.Host = cfg.Kubernetes.Host
+	k8sClient.APIPath = cfg.Kubernetes.APIPath
+	k8sClient.Username = cfg.Kubernetes.Username
+
+	corsOrigins = cfg.CORS.AllowedOrigins
+	limits = cfg.Limits
+
+	syslogOut = cfg.Telemetry.SyslogPath
+	statsdOut = cfg.Telemetry.StatsDPath
+}
+
+// reloadConfig reloads the log level, CORS policy and limits from the config
+// file on each SIGHUP until the context is canceled. The log level set on the
+// command line is kept, and the other settings take effect on restart. A
+// config file that does not load or validate is ignored.
+func reloadConfig(ctx context.Context, path string, defaults, running config.Config, api *corsHandler) error {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+
+	var logLevelFlag bool
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "log-level" {
+			logLevelFlag = true
+		}
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ch:
+		}
+
+		cfg := defaults
+		if err := config.Load(path, &cfg); err != nil {
+			log.Errf("Error reloading config file: %v", err)
+			continue
+		}
+		next := running
+		if !logLevelFlag {
+			next.LogLevel = cfg.LogLevel
+		}
+		next.CORS = cfg.CORS
+		next.Limits = cfg.Limits
+		if err := next.Validate(); err != nil {
+			log.Errf("Invalid configuration, not reloading it: %v", err)
+			continue
+		}
+
+		lvl, _ := logger.ParseLevel(next.LogLevel)
+		logger.SetLevel(lvl)
+		api.setOrigins(next.CORS.AllowedOrigins)
+		cce.SetLimits(next.Limits)
+		running = next
+		log.Infof("Reloaded %s, settings other than log_level, cors and limits take effect on restart", path)
+	}
+}
+
+// registerRateLimitMetrics registers the metrics of the state of the rate
+// limiters and login lockout of a controller.
+func registerRateLimitMetrics(controller *cce.Controller) {
+	for group, limiter := range controller.RateLimiters {
+		limiter := limiter
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "cce_http_rate_limiter_keys",
+			Help:        "Clients or users of a route group whose rate limit is partly used.",
+			ConstLabels: prometheus.Labels{"group": group},
+		}, func() float64 { return float64(limiter.Len()) })
+	}
+	if lockout := controller.LoginLockout; lockout != nil {
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "cce_login_locked_out",
+			Help: "Users locked out on a client after failed logins.",
+		}, func() float64 { return float64(lockout.Locked()) })
+	}
+}
+
+// registerEntityMetrics registers the metrics of the number of nodes, apps
+// and deployments of apps to nodes, which are counted in persistence when the
+// metrics are scraped.
+func registerEntityMetrics(controller *cce.Controller) {
+	for _, m := range []struct {
+		name, help string
+		zv         cce.Filterable
+	}{
+		{"cce_nodes", "Nodes.", &cce.Node{}},
+		{"cce_apps", "Apps.", &cce.App{}},
+		{"cce_node_apps", "Apps deployed to nodes.", &cce.NodeApp{}},
+	} {
+		prometheus.MustRegister(&entityCollector{
+			desc:       prometheus.NewDesc(m.name, m.help, nil, nil),
+			controller: controller,
+			zv:         m.zv,
+		})
+	}
+}
+
+// entityCollector collects the number of entities of a type. No value is
+// collected if they can't be counted.
+type entityCollector struct {
+	desc       *prometheus.Desc
+	controller *cce.Controller
+	zv         cce.Filterable
+}
+
+func (c *entityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
 
+func (c *entityCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), cce.MaxDBRequestTime)
+	defer cancel()
+
+	page, err := c.controller.PersistenceService.List(ctx, c.zv, cce.ListOptions{Limit: 1})
+	if err != nil {
+		log.Errf("Error counting %s: %v", c.zv.GetTableName(), err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(page.Total))
+}
+
+// serveHTTPRedirect serves a plain HTTP listener that only redirects requests
+// to the HTTPS server on httpsPort.
+func serveHTTPRedirect(ctx context.Context, addr string, httpsPort int) func() error {
 	lis, err := net.Listen("tcp", addr)
-	cce.PrefaceLis = progutil.NewPrefaceListener(lis)
+	if err != nil {
+		log.Alertf("Could not listen on %q: %v", addr, err)
+		os.Exit(1)
+	}
+
+	redirectServer := http.NewServer(http.RedirectToHTTPS(httpsPort))
+
+	// Shutdown redirect server on exit signal
+	go func() {
+		<-ctx.Done()
+
+		ctxShutdown, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
 
+		if err := redirectServer.Shutdown(ctxShutdown); err != nil {
+			log.Info("HTTP redirect graceful shutdown exceeded timeout, using force")
+			if err := redirectServer.Close(); err != nil {
+				log.Errf("error closing HTTP redirect server: %v", err)
+			}
+		}
+	}()
+
+	// Start the redirect server
+	log.Infof("HTTP redirect server serving on %q", addr)
+	return func() error {
+		defer lis.Close()
+		return redirectServer.Serve(lis)
+	}
+}
+
+// serveMetrics serves the Prometheus metrics on a listener of its own, so
+// that they are scraped without authentication.
+func serveMetrics(ctx context.Context, addr string) func() error {
+	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.Alertf("Could not listen on %q: %v", addr, err)
 		os.Exit(1)
 	}
 
+	mux := gohttp.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	metricsServer := http.NewServer(mux)
+
+	// Shutdown metrics server on exit signal
+	go func() {
+		<-ctx.Done()
+
+		ctxShutdown, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		if err := metricsServer.Shutdown(ctxShutdown); err != nil {
+			log.Info("Metrics graceful shutdown exceeded timeout, using force")
+			if err := metricsServer.Close(); err != nil {
+				log.Errf("error closing metrics server: %v", err)
+			}
+		}
+	}()
+
+	// Start the metrics server
+	log.Infof("Metrics server serving on %q", addr)
+	return func() error {
+		defer lis.Close()
+		return metricsServer.Serve(lis)
+	}
+}
+
+func serveGRPC(
+	ctx context.Context,
+	controller *cce.Controller,
+	checker *health.Checker,
+	addr string,
+	conf *tls.Config,
+) func() error {
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Alertf("Could not listen on %q: %v", addr, err)
+		os.Exit(1)
+	}
+
+	hl := health.NewListener(lis)
+	checker.AddLiveness("grpc", hl.Check)
+	cce.PrefaceLis = progutil.NewPrefaceListener(hl)
+
 	// Configure grpc server
 	grpcServer := grpc.NewServer(controller, conf)
 
@@ -351,13 +1030,22 @@ func serveGRPC(ctx context.Context, controller *cce.Controller, addr string, con
 	}
 }
 
-func serveTelemetry(ctx context.Context, outfile, addr string, conf *tls.Config) func() error {
+func serveTelemetry(
+	ctx context.Context,
+	checker *health.Checker,
+	stream, outfile, addr string,
+	conf *tls.Config,
+) func() error {
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.Alertf("Could not listen on %q: %v", addr, err)
 		os.Exit(1)
 	}
 
+	hl := health.NewListener(lis)
+	checker.AddLiveness(stream, hl.Check)
+	lis = hl
+
 	// Upgrade to TLS
 	conf.ClientAuth = tls.RequireAndVerifyClientCert
 	lis = tls.NewListener(lis, conf)
@@ -388,7 +1076,7 @@ func serveTelemetry(ctx context.Context, outfile, addr string, conf *tls.Config)
 		//
 		//     w := bufio.NewWriter(f)
 		//     defer w.Flush()
-		w := io.Writer(f)
+		w := telemetry.CountLines(f, stream)
 
 		return telemetry.WriteToByLine(w, 0, telemetry.AcceptTCP(lis))
 	}
@@ -429,36 +1117,67 @@ func getGRPCTLS(rootCA *pki.RootCA) *tls.Config {
 	}
 }
 
+// Generate a TLS config for the HTTP API server with a server certificate,
+// which is loaded, and the TLS versions and cipher suites of the flags.
+// Clients may authenticate with a certificate from the root CA or from the
+// operator CAs in the PEM file at clientCAFile, if any.
+func getHTTPTLS(rootCA *pki.RootCA, serverCert *pki.ServerCert, clientCAFile string) (*tls.Config, error) {
+	if (serverCert.CertFile == "") != (serverCert.KeyFile == "") {
+		return nil, errors.New("-http-cert and -http-key must be set together")
+	}
+	if err := serverCert.Load(); err != nil {
+		return nil, err
+	}
+
+	conf := newServerTLSConf(rootCA)
+	conf.GetCertificate = serverCert.GetCertificate
+	conf.ClientAuth = tls.VerifyClientCertIfGiven
+
+	var err error
+	if conf.MinVersion, err = pki.ParseTLSVersion(httpTLSMinVersion); err != nil {
+		return nil, err
+	}
+	if httpTLSMaxVersion != "" {
+		if conf.MaxVersion, err = pki.ParseTLSVersion(httpTLSMaxVersion); err != nil {
+			return nil, err
+		}
+	}
+	if conf.CipherSuites, err = pki.ParseCipherSuites(httpTLSCiphers); err != nil {
+		return nil, err
+	}
+
+	if clientCAFile != "" {
+		clientCAPEM, err := ioutil.ReadFile(filepath.Clean(clientCAFile))
+		if err != nil {
+			return nil, err
+		}
+		if !conf.ClientCAs.AppendCertsFromPEM(clientCAPEM) {
+			return nil, fmt.Errorf("no certificates in %q", clientCAFile)
+		}
+	}
+
+	return conf, nil
+}
+
 // Generate a new TLS key/cert pair from a root CA for use in a TLS server with
 // some server name.
 func newTLSConf(rootCA *pki.RootCA, sni string) *tls.Config {
-	tlsKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
-	if err != nil {
-		log.Alertf("error generating TLS key for server %q: %v", sni, err)
-		os.Exit(1)
-	}
-	tlsCert, err := rootCA.NewTLSServerCert(tlsKey, sni)
+	tlsCert, err := rootCA.NewTLSServerKeyPair(sni, time.Time{})
 	if err != nil {
 		log.Alertf("error generating TLS cert for server %q: %v", sni, err)
 		os.Exit(1)
 	}
-	tlsCAChain, err := rootCA.CAChain()
-	if err != nil {
-		log.Alertf("error getting TLS CA chain for server %q: %v", sni, err)
-		os.Exit(1)
-	}
-	tlsChain := [][]byte{tlsCert.Raw}
-	for _, caCert := range tlsCAChain {
-		tlsChain = append(tlsChain, caCert.Raw)
-	}
+	conf := newServerTLSConf(rootCA)
+	conf.Certificates = []tls.Certificate{*tlsCert}
+	return conf
+}
+
+// Generate a TLS config without certificates for a TLS server, whose clients
+// may authenticate with a certificate from a root CA.
+func newServerTLSConf(rootCA *pki.RootCA) *tls.Config {
 	tlsRoots := x509.NewCertPool()
-	tlsRoots.AddCert(tlsCAChain[len(tlsCAChain)-1])
+	tlsRoots.AddCert(rootCA.Cert)
 	return &tls.Config{
-		Certificates: []tls.Certificate{{
-			Certificate: tlsChain,
-			PrivateKey:  tlsKey,
-			Leaf:        tlsCert,
-		}},
 		ClientCAs:    tlsRoots,
 		MinVersion:   tls.VersionTLS12,
 		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
@@ -43,6 +43,7 @@ import (
 	authpb "github.com/open-ness/edgecontroller/pb/auth"
 	"github.com/open-ness/edgecontroller/pki"
 	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
 )
 
 var (
@@ -131,7 +132,17 @@ func startup() {
 		"-statsdPort", "8125",
 		"-syslog-path", filepath.Join(telemDir, "syslog.log"),
 		"-statsd-path", filepath.Join(telemDir, "statsd.log"),
-		"-adminPass", adminPass)
+		"-adminPass", adminPass,
+		// The suite's token must outlive the suite
+		"-access-token-lifetime", "1h",
+		// The suite sends requests faster than clients are allowed to
+		"-rate-limit-auth", "0",
+		"-rate-limit-client", "0",
+		"-rate-limit-read", "0",
+		"-rate-limit-write", "0",
+		"-metrics-port", "8084",
+		// The suite's webhook receivers listen on the loopback interface
+		"-webhook-allowed-networks", "127.0.0.0/8")
 	ctrl, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 	Expect(err).ToNot(HaveOccurred(), "Problem starting service")
 
@@ -154,7 +165,7 @@ func startup() {
 
 	By("Requesting an authentication token from the controller")
 	apiCli = &apiClient{
-		Token: authToken(),
+		Token: authToken("admin", adminPass),
 	}
 
 	By("Building the node")
@@ -241,12 +252,41 @@ func insertNFDTags(values string) {
 	Expect(err).ToNot(HaveOccurred())
 }
 
-func authToken() string {
+func insertNodeInterfacePolicy(nodeID, interfaceID, policyID string) {
+	By("Connecting to the database")
+	db, err := sql.Open(
+		"mysql",
+		fmt.Sprintf("root:%s@tcp(:8083)/controller_ce?multiStatements=true", dbPass))
+	Expect(err).ToNot(HaveOccurred())
+
+	defer func() {
+		Expect(db.Close()).To(Succeed())
+	}()
+
+	By("Pinging the database")
+	err = db.Ping()
+	Expect(err).ToNot(HaveOccurred())
+
+	timeoutCtx, cancel := context.WithTimeout(
+		context.Background(), 2*time.Second)
+	defer cancel()
+
+	By("Executing the insert query")
+	_, err = db.ExecContext(
+		timeoutCtx,
+		"INSERT INTO nodes_network_interfaces_traffic_policies (entity) VALUES (?)",
+		fmt.Sprintf(
+			`{"id": "%s", "node_id": "%s", "network_interface_id": "%s", "traffic_policy_id": "%s"}`,
+			uuid.New(), nodeID, interfaceID, policyID))
+	Expect(err).ToNot(HaveOccurred())
+}
+
+func authToken(username, password string) string {
 	payload, err := json.Marshal(
 		struct {
 			Username string `json:"username"`
 			Password string `json:"password"`
-		}{"admin", adminPass})
+		}{username, password})
 	Expect(err).ToNot(HaveOccurred())
 
 	req, err := http.NewRequest(
@@ -275,23 +315,20 @@ type respBody struct {
 	ID string
 }
 
-func postApps(appType string) (id string) {
+func postApps(appType string, appNames ...string) (id string) {
+	return postAppsAs(apiCli, appType, appNames...)
+}
+
+func postAppsAs(cli *apiClient, appType string, appNames ...string) (id string) {
+	appName := appType + " app"
+	if len(appNames) != 0 {
+		appName = appNames[0]
+	}
 	By("Sending a POST /apps request")
-	resp, err := apiCli.Post(
+	resp, err := cli.Post(
 		"http://127.0.0.1:8080/apps",
 		"application/json",
-		strings.NewReader(fmt.Sprintf(`
-			{
-				"type": "%s",
-				"name": "%s app",
-				"version": "latest",
-				"vendor": "smart edge",
-				"description": "my %s app",
-				"cores": 4,
-				"memory": 1024,
-				"ports": [{"port": 80, "protocol": "tcp"}],
-				"source": "http://www.test.com/my_%s_app.tar.gz"
-			}`, appType, appType, appType, appType)))
+		strings.NewReader(appJSON(appType, appName)))
 	Expect(err).ToNot(HaveOccurred())
 	defer resp.Body.Close()
 
@@ -310,6 +347,21 @@ func postApps(appType string) (id string) {
 	return rb.ID
 }
 
+func appJSON(appType, appName string) string {
+	return fmt.Sprintf(`
+		{
+			"type": "%s",
+			"name": "%s",
+			"version": "latest",
+			"vendor": "smart edge",
+			"description": "my %s app",
+			"cores": 4,
+			"memory": 1024,
+			"ports": [{"port": 80, "protocol": "tcp"}],
+			"source": "http://www.test.com/my_%s_app.tar.gz"
+		}`, appType, appName, appType, appType)
+}
+
 func getApp(id string) *swagger.AppDetail {
 	By("Sending a GET /apps/{app_id} request")
 	resp, err := apiCli.Get(
@@ -368,12 +420,7 @@ func patchNodeDNS(nodeID string) {
 	Expect(err).ToNot(HaveOccurred())
 	defer resp.Body.Close()
 
-	By("Verifying a 200 OK response")
-	Expect(resp.StatusCode).To(Equal(http.StatusOK))
-
-	By("Reading the response body")
-	_, err = ioutil.ReadAll(resp.Body)
-	Expect(err).ToNot(HaveOccurred())
+	expectOperation(resp, http.StatusOK)
 }
 
 func patchNodeDNSwithApp(nodeID, appID string) {
@@ -413,12 +460,7 @@ func patchNodeDNSwithApp(nodeID, appID string) {
 	Expect(err).ToNot(HaveOccurred())
 	defer resp.Body.Close()
 
-	By("Verifying a 200 OK response")
-	Expect(resp.StatusCode).To(Equal(http.StatusOK))
-
-	By("Reading the response body")
-	_, err = ioutil.ReadAll(resp.Body)
-	Expect(err).ToNot(HaveOccurred())
+	expectOperation(resp, http.StatusOK)
 }
 
 type nodeConfig struct {
@@ -781,8 +823,7 @@ func postNodeApps(nodeID, appID string) {
 	Expect(err).ToNot(HaveOccurred())
 	defer resp.Body.Close()
 
-	By("Verifying a 200 Created response")
-	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	expectOperation(resp, http.StatusOK)
 }
 
 func getNodeApps(nodeID string) *swagger.NodeAppList {
@@ -848,8 +889,83 @@ func patchNodesAppsPolicy(
 	Expect(err).ToNot(HaveOccurred())
 	defer resp.Body.Close()
 
-	By("Verifying a 200 response")
+	expectOperation(resp, http.StatusOK)
+}
+
+func patchNodeInterfacePolicy(
+	nodeID string,
+	interfaceID string,
+	policyID string,
+) {
+	By("Sending a PATCH /nodes/{node_id}/interfaces/{interface_id}/policy request")
+	resp, err := apiCli.Patch(
+		fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/interfaces/%s/policy", nodeID, interfaceID),
+		"application/json",
+		strings.NewReader(fmt.Sprintf(
+			`
+			{
+				"id": "%s"
+			}`, policyID)))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+
+	expectOperation(resp, http.StatusOK)
+}
+
+// expectOperation verifies that a request was accepted as an operation, waits
+// for the operation to be done and verifies that its request had a response
+// with a status code. An operation succeeds if the status code is below 400.
+func expectOperation(resp *http.Response, status int) *swagger.Operation {
+	By("Verifying a 202 Accepted response")
+	Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+
+	By("Reading the response body")
+	body, err := ioutil.ReadAll(resp.Body)
+	Expect(err).ToNot(HaveOccurred())
+
+	var op *swagger.Operation
+
+	By("Unmarshaling the response")
+	Expect(json.Unmarshal(body, &op)).To(Succeed())
+	Expect(resp.Header.Get("Location")).To(Equal("/operations/" + op.ID))
+
+	By("Waiting for the operation to be done")
+	Eventually(func() string {
+		op = getOperation(op.ID)
+		return op.Status
+	}, 30*time.Second, 100*time.Millisecond).Should(Or(Equal("succeeded"), Equal("failed")))
+
+	By("Verifying the response status of the operation")
+	Expect(op.ResponseStatus).To(Equal(status), op.Error)
+	if status < http.StatusBadRequest {
+		Expect(op.Status).To(Equal("succeeded"))
+	} else {
+		Expect(op.Status).To(Equal("failed"))
+	}
+
+	return op
+}
+
+func getOperation(id string) *swagger.Operation {
+	By("Sending a GET /operations/{operation_id} request")
+	resp, err := apiCli.Get(
+		fmt.Sprintf("http://127.0.0.1:8080/operations/%s", id))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+
+	By("Verifying a 200 OK response")
 	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	By("Reading the response body")
+	body, err := ioutil.ReadAll(resp.Body)
+	Expect(err).ToNot(HaveOccurred())
+
+	var op swagger.Operation
+
+	By("Unmarshaling the response")
+	Expect(json.Unmarshal(body, &op)).To(Succeed())
+
+	return &op
 }
 
 func loadTLSConfig(dir string) *tls.Config {
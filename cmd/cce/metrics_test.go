@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	getMetrics := func() string {
+		By("Sending a GET /metrics request")
+		resp, err := http.Get("http://127.0.0.1:8084/metrics")
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(body)
+	}
+
+	It("Should export the HTTP requests by route", func() {
+		appID := postApps("container")
+		getApp(appID)
+
+		body := getMetrics()
+		Expect(body).To(MatchRegexp(
+			`\ncce_http_requests_total\{code="200",method="GET",route="/apps/\{app_id\}"\} [1-9]`))
+		Expect(body).To(MatchRegexp(
+			`\ncce_http_request_duration_seconds_count\{code="201",method="POST",route="/apps"\} [1-9]`))
+		Expect(body).To(MatchRegexp(
+			`\ncce_db_query_duration_seconds_count\{operation="create",table="apps"\} [1-9]`))
+		Expect(body).To(MatchRegexp(`\ncce_apps [1-9]`))
+	})
+
+	It("Should export the enrollments and calls to nodes", func() {
+		clearGRPCTargetsTable()
+		nodeCfg := createAndRegisterNode()
+		appID := postApps("container")
+		postNodeApps(nodeCfg.nodeID, appID)
+
+		body := getMetrics()
+		Expect(body).To(MatchRegexp(`\ncce_enrollments_total\{result="success"\} [1-9]`))
+		Expect(body).To(MatchRegexp(
+			`\ncce_node_grpc_calls_total\{code="OK",method="DeployContainer",` +
+				`service="openness.eva.ApplicationDeploymentService"\} [1-9]`))
+		Expect(body).To(MatchRegexp(`\ncce_nodes [1-9]`))
+		Expect(body).To(MatchRegexp(`\ncce_node_apps [1-9]`))
+	})
+
+	It("Should not require authentication", func() {
+		Expect(getMetrics()).To(ContainSubstring("# TYPE cce_http_requests_total counter"))
+	})
+})
@@ -50,8 +50,7 @@ var _ = Describe("/nodes/{node_id}/apps", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 200 response")
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				expectOperation(resp, http.StatusOK)
 			},
 			Entry(
 				"POST /nodes/{node_id}/apps"),
@@ -201,8 +200,7 @@ var _ = Describe("/nodes/{node_id}/apps", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer respPost.Body.Close()
 
-				By("Verifying a 200 response")
-				Expect(respPost.StatusCode).To(Equal(http.StatusOK))
+				expectOperation(respPost, http.StatusOK)
 			},
 			Entry(
 				"POST /nodes/{node_id}/apps"),
@@ -374,9 +372,7 @@ var _ = Describe("/nodes/{node_id}/apps", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 200 OK response")
-				Expect(err).ToNot(HaveOccurred())
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				expectOperation(resp, http.StatusOK)
 
 				By("Getting the updated node")
 				updatedNodeAppResp := getNodeApp(nodeCfg.nodeID, appID)
@@ -447,10 +443,9 @@ var _ = Describe("/nodes/{node_id}/apps", func() {
 						"http://127.0.0.1:8080/nodes/%s/apps/%s",
 						nodeCfg.nodeID, appID))
 
-				By("Verifying a 204 No Content response")
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
-				Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+				expectOperation(resp, http.StatusNoContent)
 
 				By("Verifying the node app was deleted")
 
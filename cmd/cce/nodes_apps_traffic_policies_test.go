@@ -40,8 +40,7 @@ var _ = Describe("/nodes/{node_id}/apps/{app_id}/policy", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 200 response")
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				expectOperation(resp, http.StatusOK)
 
 				By("Creating a new policy")
 				policy2ID := postPolicies()
@@ -58,8 +57,7 @@ var _ = Describe("/nodes/{node_id}/apps/{app_id}/policy", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp2.Body.Close()
 
-				By("Verifying a 200 response")
-				Expect(resp2.StatusCode).To(Equal(http.StatusOK))
+				expectOperation(resp2, http.StatusOK)
 			},
 			Entry(
 				"PATCH /nodes/{node_id}/apps/{app_id}/policy"),
@@ -219,8 +217,7 @@ var _ = Describe("/nodes/{node_id}/apps/{app_id}/policy", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 204 No Content response")
-				Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+				expectOperation(resp, http.StatusNoContent)
 
 				By("Verifying the node app traffic policy was deleted")
 
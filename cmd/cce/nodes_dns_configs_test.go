@@ -213,8 +213,7 @@ var _ = Describe("/nodes/{node_id}/dns", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 204 No Content response")
-				Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+				expectOperation(resp, http.StatusNoContent)
 
 				By("Verifying the node <-> DNS config was deleted")
 
@@ -248,3 +247,107 @@ var _ = Describe("/nodes/{node_id}/dns", func() {
 		)
 	})
 })
+
+var _ = Describe("/dns_configs/{dns_config_id}", func() {
+	Describe("PATCH /dns_configs/{dns_config_id}", func() {
+		var (
+			nodeCfg     *nodeConfig
+			dnsConfigID string
+		)
+
+		BeforeEach(func() {
+			clearGRPCTargetsTable()
+			nodeCfg = createAndRegisterNode()
+			patchNodeDNS(nodeCfg.nodeID)
+			dnsConfigID = getNodeDNS(nodeCfg.nodeID).ID
+		})
+
+		DescribeTable("200 OK",
+			func() {
+				By("Sending a PATCH /dns_configs/{dns_config_id} request")
+				resp, err := apiCli.Patch(
+					fmt.Sprintf(
+						"http://127.0.0.1:8080/dns_configs/%s",
+						dnsConfigID),
+					"application/json",
+					strings.NewReader(`
+					{
+						"name": "Sample DNS configuration",
+						"records": {
+						  "a": [
+							{
+								"name": "sample-app1.demosite.com",
+								"description": "The domain for my sample app 1",
+								"alias": false,
+								"values": [
+									"192.168.1.6"
+							  ]
+							}
+						  ]
+						}
+					}`))
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+
+				By("Verifying a 200 OK response")
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				By("Reading the response body")
+				body, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+
+				var statuses swagger.NodeStatusList
+
+				By("Unmarshaling the response")
+				Expect(json.Unmarshal(body, &statuses)).To(Succeed())
+
+				By("Verifying the changes were pushed to the node")
+				Expect(statuses).To(Equal(swagger.NodeStatusList{
+					Nodes: []swagger.NodeStatus{
+						{
+							ID:     nodeCfg.nodeID,
+							Status: swagger.NodeStatusApplied,
+						},
+					},
+				}))
+
+				By("Verifying the DNS config was updated")
+				Expect(getNodeDNS(nodeCfg.nodeID)).To(Equal(&swagger.DNSDetail{
+					DNSSummary: swagger.DNSSummary{
+						ID:   dnsConfigID,
+						Name: "Sample DNS configuration",
+					},
+					Records: swagger.DNSRecords{
+						A: []swagger.DNSARecord{
+							{
+								Name:        "sample-app1.demosite.com",
+								Description: "The domain for my sample app 1",
+								Alias:       false,
+								Values:      []string{"192.168.1.6"},
+							},
+						},
+					},
+				}))
+			},
+			Entry("PATCH /dns_configs/{dns_config_id}"),
+		)
+
+		DescribeTable("404 Not Found",
+			func() {
+				By("Sending a PATCH /dns_configs/{dns_config_id} request")
+				resp, err := apiCli.Patch(
+					fmt.Sprintf(
+						"http://127.0.0.1:8080/dns_configs/%s",
+						uuid.New()),
+					"application/json",
+					strings.NewReader(`{"name": "Sample DNS configuration"}`))
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+
+				By("Verifying a 404 Not Found response")
+				Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+			},
+			Entry("PATCH /dns_configs/{dns_config_id} with nonexistent ID"),
+		)
+	})
+})
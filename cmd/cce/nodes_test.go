@@ -215,8 +215,7 @@ var _ = Describe("/nodes", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 200 OK response")
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				expectOperation(resp, http.StatusOK)
 
 				By("Getting the updated node")
 				updatedNodeResp := getNodeInterfacePolicy(nodeCfg.nodeID, "if0")
@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var _ = Describe("Operations", func() {
+	var (
+		nodeCfg *nodeConfig
+	)
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		nodeCfg = createAndRegisterNode()
+	})
+
+	deployApp := func(appID string) *http.Response {
+		By("Sending a POST /nodes/{node_id}/apps request")
+		resp, err := apiCli.Post(
+			fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/apps", nodeCfg.nodeID),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"id": "%s"}`, appID)))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	It("Should deploy an app in the background", func() {
+		appID := postApps("container")
+
+		resp := deployApp(appID)
+		defer resp.Body.Close()
+		op := expectOperation(resp, http.StatusOK)
+
+		By("Verifying the operation")
+		Expect(op.Type).To(Equal("deploy_app"))
+		Expect(op.NodeID).To(Equal(nodeCfg.nodeID))
+		Expect(op.Actor).To(Equal("admin"))
+		Expect(op.Request).To(Equal("POST /nodes/" + nodeCfg.nodeID + "/apps"))
+		Expect(op.StartedAt).ToNot(BeNil())
+		Expect(op.FinishedAt).ToNot(BeNil())
+		Expect(op.Error).To(BeEmpty())
+
+		By("Verifying the app was deployed")
+		Expect(getNodeApp(nodeCfg.nodeID, appID).ID).To(Equal(appID))
+
+		By("Sending a GET /operations request")
+		listResp, err := apiCli.Get("http://127.0.0.1:8080/operations?node_id=" + nodeCfg.nodeID)
+		Expect(err).ToNot(HaveOccurred())
+		defer listResp.Body.Close()
+		Expect(listResp.StatusCode).To(Equal(http.StatusOK))
+
+		var ops swagger.OperationList
+		Expect(json.NewDecoder(listResp.Body).Decode(&ops)).To(Succeed())
+
+		By("Verifying the operation is listed")
+		Expect(ops.Operations).To(HaveLen(1))
+		Expect(ops.Operations[0].ID).To(Equal(op.ID))
+	})
+
+	It("Should fail an operation whose calls to the node fail", func() {
+		appID := postApps("container")
+		clearGRPCTargetsTable()
+
+		resp := deployApp(appID)
+		defer resp.Body.Close()
+		op := expectOperation(resp, http.StatusInternalServerError)
+
+		By("Verifying the operation error")
+		Expect(op.Error).To(Equal("Internal Server Error"))
+
+		By("Sending a GET /nodes/{node_id}/apps/{app_id} request")
+		getResp, err := apiCli.Get(
+			fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/apps/%s", nodeCfg.nodeID, appID))
+		Expect(err).ToNot(HaveOccurred())
+		defer getResp.Body.Close()
+
+		By("Verifying the node app was removed")
+		Expect(getResp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("Should not accept requests that fail validation", func() {
+		resp := deployApp(uuid.New())
+		defer resp.Body.Close()
+
+		By("Verifying a 404 Not Found response")
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+		By("Verifying that no operation was run")
+		listResp, err := apiCli.Get("http://127.0.0.1:8080/operations?node_id=" + nodeCfg.nodeID)
+		Expect(err).ToNot(HaveOccurred())
+		defer listResp.Body.Close()
+		Expect(listResp.StatusCode).To(Equal(http.StatusOK))
+
+		var ops swagger.OperationList
+		Expect(json.NewDecoder(listResp.Body).Decode(&ops)).To(Succeed())
+		Expect(ops.Operations).To(BeEmpty())
+	})
+
+	It("Should not accept operations on nonexistent nodes", func() {
+		By("Sending a POST /nodes/{node_id}/apps request")
+		resp, err := apiCli.Post(
+			fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/apps", uuid.New()),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"id": "%s"}`, uuid.New())))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 404 Not Found response")
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("Should return 404 for a nonexistent operation", func() {
+		By("Sending a GET /operations/{operation_id} request")
+		resp, err := apiCli.Get("http://127.0.0.1:8080/operations/" + uuid.New())
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 404 Not Found response")
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var _ = Describe("Login lockout", func() {
+	var name string
+
+	BeforeEach(func() {
+		name = "user-" + uuid.New()[:8]
+
+		payload, err := json.Marshal(swagger.UserCreate{Name: name, Password: password, Roles: []string{"viewer"}})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a POST /users request")
+		resp, err := apiCli.Post("http://127.0.0.1:8080/users", "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+	})
+
+	login := func(password string) *http.Response {
+		By("Sending a POST /auth request")
+		resp, err := http.Post(
+			"http://127.0.0.1:8080/auth",
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"username": "%s", "password": "%s"}`, name, password)))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		return resp
+	}
+
+	It("Should lock out a user after failed logins", func() {
+		for i := 0; i < 5; i++ {
+			Expect(login("S3cret").StatusCode).To(Equal(http.StatusUnauthorized))
+		}
+
+		resp := login(password)
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(resp.Header.Get("Retry-After")).To(Equal("60"))
+
+		By("Sending a GET /metrics request")
+		resp, err := http.Get("http://127.0.0.1:8084/metrics")
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(ContainSubstring("\ncce_login_locked_out_total "))
+		Expect(string(body)).To(MatchRegexp(`\ncce_login_locked_out [1-9]`))
+	})
+
+	It("Should reset the failed logins after a login", func() {
+		for i := 0; i < 4; i++ {
+			Expect(login("S3cret").StatusCode).To(Equal(http.StatusUnauthorized))
+		}
+		Expect(login(password).StatusCode).To(Equal(http.StatusCreated))
+		Expect(login("S3cret").StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(login(password).StatusCode).To(Equal(http.StatusCreated))
+	})
+})
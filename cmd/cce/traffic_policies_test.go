@@ -1113,6 +1113,189 @@ var _ = Describe("/policies", func() {
 		)
 	})
 
+	Describe("PATCH /policies/{id} attached to nodes", func() {
+		var (
+			nodeCfg  *nodeConfig
+			policyID string
+		)
+
+		BeforeEach(func() {
+			clearGRPCTargetsTable()
+			nodeCfg = createAndRegisterNode()
+			appID := postApps("container")
+			postNodeApps(nodeCfg.nodeID, appID)
+			policyID = postPolicies()
+			patchNodesAppsPolicy(nodeCfg.nodeID, appID, policyID)
+			patchNodeInterfacePolicy(nodeCfg.nodeID, "if0", policyID)
+		})
+
+		DescribeTable("200 OK",
+			func() {
+				By("Sending a PATCH /policies/{id} request")
+				resp, err := apiCli.Patch(
+					fmt.Sprintf("http://127.0.0.1:8080/policies/%s", policyID),
+					"application/json",
+					strings.NewReader(fmt.Sprintf(`
+					{
+						"id": "%s",
+						"name": "policy-2",
+						"traffic_rules": [{
+							"description": "test-rule-2",
+							"priority": 2,
+							"source": {
+								"description": "test-source-2",
+								"ip_filter": {
+									"address": "223.1.1.0",
+									"mask": 16,
+									"begin_port": 2000,
+									"end_port": 2012,
+									"protocol": "tcp"
+								}
+							},
+							"target": {
+								"description": "test-target-2",
+								"action": "accept"
+							}
+						}]
+					}`, policyID)))
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+
+				By("Verifying a 200 OK response")
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				By("Reading the response body")
+				body, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+
+				var statuses swagger.NodeStatusList
+
+				By("Unmarshaling the response")
+				Expect(json.Unmarshal(body, &statuses)).To(Succeed())
+
+				By("Verifying the policy was pushed to the node")
+				Expect(statuses).To(Equal(swagger.NodeStatusList{
+					Nodes: []swagger.NodeStatus{
+						{
+							ID:     nodeCfg.nodeID,
+							Status: swagger.NodeStatusApplied,
+						},
+					},
+				}))
+			},
+			Entry("PATCH /policies/{id} with nodes_apps_traffic_policies and "+
+				"nodes_network_interfaces_traffic_policies records"),
+		)
+	})
+
+	Describe("DELETE /policies/{id} attached to network interfaces", func() {
+		var (
+			nodeCfg  *nodeConfig
+			policyID string
+		)
+
+		BeforeEach(func() {
+			clearGRPCTargetsTable()
+			nodeCfg = createAndRegisterNode()
+			policyID = postPolicies()
+			patchNodeInterfacePolicy(nodeCfg.nodeID, "if0", policyID)
+		})
+
+		DescribeTable("200 OK",
+			func() {
+				By("Sending a DELETE /policies/{id} request")
+				resp, err := apiCli.Delete(
+					fmt.Sprintf("http://127.0.0.1:8080/policies/%s",
+						policyID))
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+
+				By("Verifying a 200 OK response")
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				By("Reading the response body")
+				body, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+
+				var statuses swagger.NodeStatusList
+
+				By("Unmarshaling the response")
+				Expect(json.Unmarshal(body, &statuses)).To(Succeed())
+
+				By("Verifying the policy was removed from the node")
+				Expect(statuses).To(Equal(swagger.NodeStatusList{
+					Nodes: []swagger.NodeStatus{
+						{
+							ID:     nodeCfg.nodeID,
+							Status: swagger.NodeStatusApplied,
+						},
+					},
+				}))
+
+				By("Verifying the interface no longer has a policy")
+				Expect(getNodeInterfacePolicy(nodeCfg.nodeID, "if0")).To(Equal(&swagger.BaseResource{}))
+			},
+			Entry("DELETE /policies/{id} with nodes_network_interfaces_traffic_policies record"),
+		)
+	})
+
+	Describe("DELETE /policies/{id} attached to network interfaces that fail", func() {
+		var (
+			nodeCfg  *nodeConfig
+			policyID string
+		)
+
+		BeforeEach(func() {
+			clearGRPCTargetsTable()
+			nodeCfg = createAndRegisterNode()
+			policyID = postPolicies()
+			patchNodeInterfacePolicy(nodeCfg.nodeID, "if0", policyID)
+
+			// The node has no interface if9, so the policy cannot be
+			// removed from it
+			insertNodeInterfacePolicy(nodeCfg.nodeID, "if9", policyID)
+		})
+
+		DescribeTable("500 Internal Server Error",
+			func() {
+				By("Sending a DELETE /policies/{id} request")
+				resp, err := apiCli.Delete(
+					fmt.Sprintf("http://127.0.0.1:8080/policies/%s",
+						policyID))
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+
+				By("Verifying a 500 Internal Server Error response")
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+
+				By("Reading the response body")
+				body, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+
+				var statuses swagger.NodeStatusList
+
+				By("Unmarshaling the response")
+				Expect(json.Unmarshal(body, &statuses)).To(Succeed())
+
+				By("Verifying the policy could not be removed from the node")
+				Expect(statuses.Nodes).To(HaveLen(1))
+				Expect(statuses.Nodes[0].ID).To(Equal(nodeCfg.nodeID))
+				Expect(statuses.Nodes[0].Status).To(Equal(swagger.NodeStatusFailed))
+				Expect(statuses.Nodes[0].Errors).To(ConsistOf(ContainSubstring("interface if9")))
+
+				By("Verifying the interface it was removed from no longer has it")
+				Expect(getNodeInterfacePolicy(nodeCfg.nodeID, "if0")).To(Equal(&swagger.BaseResource{}))
+
+				By("Verifying the interface it could not be removed from still has it")
+				Expect(getNodeInterfacePolicy(nodeCfg.nodeID, "if9")).To(Equal(&swagger.BaseResource{ID: policyID}))
+
+				By("Verifying the policy was kept")
+				Expect(getPolicy(policyID).ID).To(Equal(policyID))
+			},
+			Entry("DELETE /policies/{id} with a nodes_network_interfaces_traffic_policies record that fails"),
+		)
+	})
+
 	Describe("DELETE /policies/{id}", func() {
 		var (
 			policyID string
@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var _ = Describe("User certificates", func() {
+	var (
+		name   string
+		csrPEM string
+	)
+
+	BeforeEach(func() {
+		name = "user-" + uuid.New()[:8]
+
+		payload, err := json.Marshal(swagger.UserCreate{Name: name, Password: password, Roles: []string{"viewer"}})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a POST /users request")
+		resp, err := apiCli.Post("http://127.0.0.1:8080/users", "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		By("Creating a CSR")
+		key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		csrDER, err := x509.CreateCertificateRequest(rand.Reader,
+			&x509.CertificateRequest{Subject: pkix.Name{CommonName: "admin"}}, key)
+		Expect(err).ToNot(HaveOccurred())
+		csrPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}))
+	})
+
+	postCertificate := func(cli *apiClient, name, csr string, expectedStatus int) *swagger.UserCertificate {
+		payload, err := json.Marshal(swagger.UserCertificateRequest{CSR: csr})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a POST /users/{name}/certificates request")
+		resp, err := cli.Post(
+			"http://127.0.0.1:8080/users/"+name+"/certificates", "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying the response status")
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+		if expectedStatus != http.StatusCreated {
+			return nil
+		}
+
+		var cert swagger.UserCertificate
+		Expect(json.NewDecoder(resp.Body).Decode(&cert)).To(Succeed())
+
+		return &cert
+	}
+
+	It("Should issue a client certificate of the user from the Controller CA", func() {
+		cert := postCertificate(apiCli, name, csrPEM, http.StatusCreated)
+		Expect(cert.CAChain).To(Equal([]string{string(controllerRootPEM) + "\n"}))
+
+		By("Verifying the certificate")
+		block, _ := pem.Decode([]byte(cert.Certificate))
+		Expect(block).ToNot(BeNil())
+		x509Cert, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(x509Cert.Subject.CommonName).To(Equal(name))
+		Expect(x509Cert.Subject.OrganizationalUnit).To(Equal([]string{"users"}))
+		Expect(x509Cert.NotAfter).To(Equal(cert.ExpiresAt))
+
+		roots := x509.NewCertPool()
+		Expect(roots.AppendCertsFromPEM(controllerRootPEM)).To(BeTrue())
+		_, err = x509Cert.Verify(x509.VerifyOptions{
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should only issue users their own certificates", func() {
+		cli := &apiClient{Token: authToken(name, password)}
+		postCertificate(cli, name, csrPEM, http.StatusCreated)
+		postCertificate(cli, "admin", csrPEM, http.StatusForbidden)
+	})
+
+	It("Should return 400 for an invalid CSR", func() {
+		postCertificate(apiCli, name, "", http.StatusBadRequest)
+		postCertificate(apiCli, name, string(controllerRootPEM), http.StatusBadRequest)
+	})
+
+	It("Should not dry run certificate requests", func() {
+		payload, err := json.Marshal(swagger.UserCertificateRequest{CSR: csrPEM})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a POST /users/{name}/certificates request with dry_run=true")
+		resp, err := apiCli.Post(
+			"http://127.0.0.1:8080/users/"+name+"/certificates?dry_run=true",
+			"application/json",
+			bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 400 Bad Request response")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("Certificate requests cannot be dry run"))
+	})
+
+	It("Should return 404 if the user does not exist", func() {
+		postCertificate(apiCli, "user-"+uuid.New()[:8], csrPEM, http.StatusNotFound)
+	})
+
+	It("Should not issue certificates to API keys with fewer roles than the user", func() {
+		svc := "svc-" + uuid.New()[:8]
+		payload, err := json.Marshal(swagger.UserCreate{Name: svc, Roles: []string{"admin"}, ServiceAccount: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a POST /users request for an admin service account")
+		resp, err := apiCli.Post("http://127.0.0.1:8080/users", "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		By("Sending a POST /users/{name}/api_keys request for a read-scoped key")
+		payload, err = json.Marshal(swagger.APIKeyCreate{Name: "monitor", Roles: []string{"viewer"}})
+		Expect(err).ToNot(HaveOccurred())
+		resp, err = apiCli.Post(
+			"http://127.0.0.1:8080/users/"+svc+"/api_keys", "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var key swagger.APIKeyDetail
+		Expect(json.NewDecoder(resp.Body).Decode(&key)).To(Succeed())
+
+		postCertificate(&apiClient{APIKey: key.Key}, svc, csrPEM, http.StatusForbidden)
+		postCertificate(apiCli, svc, csrPEM, http.StatusCreated)
+	})
+})
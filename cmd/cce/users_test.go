@@ -0,0 +1,328 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// password meets the password policy
+const password = "Correct-Horse-42"
+
+var _ = Describe("Users", func() {
+	var name string
+
+	BeforeEach(func() {
+		name = "user-" + uuid.New()[:8]
+	})
+
+	postUser := func(cli *apiClient, name, password string, roles []string, expectedStatus int) {
+		payload, err := json.Marshal(swagger.UserCreate{Name: name, Password: password, Roles: roles})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a POST /users request")
+		resp, err := cli.Post("http://127.0.0.1:8080/users", "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying the response status")
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+	}
+
+	getUser := func(name string, expectedStatus int) *swagger.UserDetail {
+		By("Sending a GET /users/{name} request")
+		resp, err := apiCli.Get("http://127.0.0.1:8080/users/" + name)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying the response status")
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+		if expectedStatus != http.StatusOK {
+			return nil
+		}
+
+		By("Reading the response body")
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).ToNot(ContainSubstring("password"))
+
+		var user swagger.UserDetail
+
+		By("Unmarshaling the response")
+		Expect(json.Unmarshal(body, &user)).To(Succeed())
+
+		return &user
+	}
+
+	Describe("POST /users", func() {
+		It("Should create a user that can log in", func() {
+			postUser(apiCli, name, password, []string{"operator"}, http.StatusCreated)
+
+			user := getUser(name, http.StatusOK)
+			Expect(user.Name).To(Equal(name))
+			Expect(user.Roles).To(Equal([]string{"operator"}))
+
+			By("Logging in as the user")
+			cli := &apiClient{Token: authToken(name, password)}
+
+			By("Sending a GET /apps request as the user")
+			resp, err := cli.Get("http://127.0.0.1:8080/apps")
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("Should not log in a user with the wrong password", func() {
+			postUser(apiCli, name, password, []string{"viewer"}, http.StatusCreated)
+
+			By("Sending a POST /auth request with the wrong password")
+			resp, err := http.Post(
+				"http://127.0.0.1:8080/auth",
+				"application/json",
+				strings.NewReader(fmt.Sprintf(`{"username": "%s", "password": "S3cret"}`, name)))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("Should return 409 if the user exists", func() {
+			postUser(apiCli, name, password, []string{"viewer"}, http.StatusCreated)
+			postUser(apiCli, name, "Other-Passw0rd", []string{"admin"}, http.StatusConflict)
+		})
+
+		It("Should return 409 for the name of the admin", func() {
+			postUser(apiCli, "admin", password, []string{"viewer"}, http.StatusConflict)
+		})
+
+		It("Should return 400 for an invalid user", func() {
+			postUser(apiCli, name, "", []string{"viewer"}, http.StatusBadRequest)
+			postUser(apiCli, name, "horse-42", []string{"viewer"}, http.StatusBadRequest)
+			postUser(apiCli, name, "correct-horse-battery", []string{"viewer"}, http.StatusBadRequest)
+			postUser(apiCli, name, password, []string{"root"}, http.StatusBadRequest)
+			postUser(apiCli, "jane doe", password, []string{"viewer"}, http.StatusBadRequest)
+		})
+	})
+
+	Describe("GET /users", func() {
+		It("Should list the users", func() {
+			postUser(apiCli, name, password, []string{"viewer"}, http.StatusCreated)
+
+			By("Sending a GET /users request")
+			resp, err := apiCli.Get("http://127.0.0.1:8080/users?name=" + name)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			var users swagger.UserList
+			Expect(json.NewDecoder(resp.Body).Decode(&users)).To(Succeed())
+			Expect(users.Users).To(Equal([]swagger.UserSummary{{Name: name, Roles: []string{"viewer"}}}))
+		})
+	})
+
+	Describe("PATCH /users/{name}", func() {
+		It("Should update the roles of the user", func() {
+			postUser(apiCli, name, password, []string{"viewer"}, http.StatusCreated)
+
+			By("Sending a PATCH /users/{name} request")
+			resp, err := apiCli.Patch(
+				"http://127.0.0.1:8080/users/"+name,
+				"application/json",
+				strings.NewReader(`{"roles": ["viewer", "operator"]}`))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(getUser(name, http.StatusOK).Roles).To(Equal([]string{"viewer", "operator"}))
+		})
+
+		It("Should return 404 if the user does not exist", func() {
+			resp, err := apiCli.Patch(
+				"http://127.0.0.1:8080/users/"+name,
+				"application/json",
+				strings.NewReader(`{"roles": ["viewer"]}`))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("PATCH /users/{name}/password", func() {
+		const newPassword = "Battery-Staple-7"
+
+		patchPassword := func(cli *apiClient, name, current, password string, expectedStatus int) {
+			payload, err := json.Marshal(swagger.UserPassword{CurrentPassword: current, Password: password})
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Sending a PATCH /users/{name}/password request")
+			resp, err := cli.Patch(
+				"http://127.0.0.1:8080/users/"+name+"/password",
+				"application/json",
+				bytes.NewReader(payload))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+
+			By("Verifying the response status")
+			Expect(resp.StatusCode).To(Equal(expectedStatus))
+		}
+
+		expectLogin := func(name, password string, expectedStatus int) {
+			By("Sending a POST /auth request")
+			resp, err := http.Post(
+				"http://127.0.0.1:8080/auth",
+				"application/json",
+				strings.NewReader(fmt.Sprintf(`{"username": "%s", "password": "%s"}`, name, password)))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(expectedStatus))
+		}
+
+		It("Should let users change their own password", func() {
+			postUser(apiCli, name, password, []string{"viewer"}, http.StatusCreated)
+			cli := &apiClient{Token: authToken(name, password)}
+
+			patchPassword(cli, name, "Wrong-Password-1", newPassword, http.StatusForbidden)
+			patchPassword(cli, name, password, "battery", http.StatusBadRequest)
+			patchPassword(cli, name, password, newPassword, http.StatusOK)
+
+			expectLogin(name, password, http.StatusUnauthorized)
+			expectLogin(name, newPassword, http.StatusCreated)
+		})
+
+		It("Should let admins change any password", func() {
+			postUser(apiCli, name, password, []string{"viewer"}, http.StatusCreated)
+
+			patchPassword(apiCli, name, "", newPassword, http.StatusOK)
+
+			expectLogin(name, newPassword, http.StatusCreated)
+		})
+
+		It("Should not let users change the password of others", func() {
+			postUser(apiCli, name, password, []string{"operator"}, http.StatusCreated)
+			postUser(apiCli, name+"-2", password, []string{"viewer"}, http.StatusCreated)
+			cli := &apiClient{Token: authToken(name, password)}
+
+			patchPassword(cli, name+"-2", password, newPassword, http.StatusForbidden)
+
+			expectLogin(name+"-2", password, http.StatusCreated)
+		})
+
+		It("Should return 404 if the user does not exist", func() {
+			patchPassword(apiCli, name, "", newPassword, http.StatusNotFound)
+		})
+
+		It("Should not record passwords in the audit log", func() {
+			postUser(apiCli, name, password, []string{"viewer"}, http.StatusCreated)
+			patchPassword(apiCli, name, "", newPassword, http.StatusOK)
+
+			By("Sending a GET /audit request")
+			resp, err := apiCli.Get("http://127.0.0.1:8080/audit?entity_type=users&actor=admin&limit=10")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`"password_hash":{"before":"*****","after":"*****"}`))
+			Expect(string(body)).ToNot(ContainSubstring("$2a$"))
+		})
+	})
+
+	Describe("DELETE /users/{name}", func() {
+		It("Should delete the user", func() {
+			postUser(apiCli, name, password, []string{"viewer"}, http.StatusCreated)
+
+			By("Sending a DELETE /users/{name} request")
+			resp, err := apiCli.Delete("http://127.0.0.1:8080/users/" + name)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			getUser(name, http.StatusNotFound)
+		})
+	})
+
+	Describe("Role-based access control", func() {
+		It("Should only allow viewers to read", func() {
+			postUser(apiCli, name, password, []string{"viewer"}, http.StatusCreated)
+			cli := &apiClient{Token: authToken(name, password)}
+
+			By("Sending a GET /nodes request as a viewer")
+			resp, err := cli.Get("http://127.0.0.1:8080/nodes")
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			By("Sending a POST /nodes request as a viewer")
+			resp, err = cli.Post(
+				"http://127.0.0.1:8080/nodes",
+				"application/json",
+				strings.NewReader(`{"name": "viewer node", "location": "lab", "serial": "abc"}`))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("Should only allow admins to manage users and read the audit log", func() {
+			postUser(apiCli, name, password, []string{"operator"}, http.StatusCreated)
+			cli := &apiClient{Token: authToken(name, password)}
+
+			postUser(cli, name+"-2", password, []string{"admin"}, http.StatusForbidden)
+
+			for _, path := range []string{"/users", "/users/" + name, "/audit"} {
+				By("Sending a GET " + path + " request as an operator")
+				resp, err := cli.Get("http://127.0.0.1:8080" + path)
+				Expect(err).ToNot(HaveOccurred())
+				resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+			}
+		})
+
+		It("Should record the user as the actor of changes", func() {
+			postUser(apiCli, name, password, []string{"operator"}, http.StatusCreated)
+			cli := &apiClient{Token: authToken(name, password)}
+
+			By("Sending a POST /apps request as an operator")
+			resp, err := cli.Post(
+				"http://127.0.0.1:8080/apps",
+				"application/json",
+				strings.NewReader(`
+					{
+						"type": "container",
+						"name": "operator app",
+						"version": "latest",
+						"vendor": "smart edge",
+						"cores": 4,
+						"memory": 1024,
+						"source": "http://www.test.com/my_container_app.tar.gz"
+					}`))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+			var rb respBody
+			Expect(json.NewDecoder(resp.Body).Decode(&rb)).To(Succeed())
+
+			By("Sending a GET /audit request")
+			auditResp, err := apiCli.Get("http://127.0.0.1:8080/audit?entity_id=" + rb.ID)
+			Expect(err).ToNot(HaveOccurred())
+			defer auditResp.Body.Close()
+			Expect(auditResp.StatusCode).To(Equal(http.StatusOK))
+
+			var events swagger.AuditEventList
+			Expect(json.NewDecoder(auditResp.Body).Decode(&events)).To(Succeed())
+			Expect(events.Events).To(HaveLen(1))
+			Expect(events.Events[0].Actor).To(Equal(name))
+		})
+	})
+})
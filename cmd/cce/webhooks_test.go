@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/open-ness/edgecontroller/webhook"
+)
+
+var _ = Describe("Webhooks", func() {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+
+	var (
+		receiver *httptest.Server
+		requests chan received
+	)
+
+	BeforeEach(func() {
+		requests = make(chan received, 100)
+		receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests <- received{header: r.Header, body: body}
+		}))
+	})
+
+	AfterEach(func() {
+		receiver.Close()
+	})
+
+	postWebhook := func(create swagger.WebhookCreate, expectedStatus int) *swagger.WebhookDetail {
+		payload, err := json.Marshal(create)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a POST /webhooks request")
+		resp, err := apiCli.Post("http://127.0.0.1:8080/webhooks", "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying the response status")
+		Expect(resp.StatusCode).To(Equal(expectedStatus))
+		if expectedStatus != http.StatusCreated {
+			return nil
+		}
+
+		var detail swagger.WebhookDetail
+		Expect(json.NewDecoder(resp.Body).Decode(&detail)).To(Succeed())
+		return &detail
+	}
+
+	deleteWebhook := func(id string) {
+		By("Sending a DELETE /webhooks/{webhook_id} request")
+		resp, err := apiCli.Delete("http://127.0.0.1:8080/webhooks/" + id)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+
+	It("Should deliver signed events and keep their history", func() {
+		hook := postWebhook(swagger.WebhookCreate{
+			Name:       "noc-" + uuid.New()[:8],
+			URL:        receiver.URL,
+			EventTypes: []string{events.TypeEntityCreated},
+		}, http.StatusCreated)
+		defer deleteWebhook(hook.ID)
+		Expect(hook.Secret).ToNot(BeEmpty())
+
+		id := postApps("container")
+
+		By("Verifying the delivery to the receiver")
+		var req received
+		Eventually(requests, "5s").Should(Receive(&req))
+		Expect(req.header.Get(webhook.HeaderEvent)).To(Equal(events.TypeEntityCreated))
+		Expect(webhook.Verify(
+			hook.Secret,
+			req.header.Get(webhook.HeaderTimestamp),
+			req.body,
+			req.header.Get(webhook.HeaderSignature))).To(BeTrue())
+
+		var e events.Event
+		Expect(json.Unmarshal(req.body, &e)).To(Succeed())
+		Expect(e.EntityType).To(Equal("apps"))
+		Expect(e.EntityID).To(Equal(id))
+
+		By("Verifying the delivery history")
+		var delivery swagger.WebhookDelivery
+		Eventually(func() string {
+			resp, err := apiCli.Get("http://127.0.0.1:8080/webhooks/" + hook.ID + "/deliveries")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			var deliveries swagger.WebhookDeliveryList
+			Expect(json.NewDecoder(resp.Body).Decode(&deliveries)).To(Succeed())
+			for _, d := range deliveries.Deliveries {
+				if d.ID == req.header.Get(webhook.HeaderDelivery) {
+					delivery = d
+				}
+			}
+			return delivery.Status
+		}, "5s").Should(Equal("succeeded"))
+		Expect(delivery.Attempts).To(Equal(1))
+		Expect(delivery.ResponseStatus).To(Equal(http.StatusOK))
+	})
+
+	It("Should not return the secret", func() {
+		hook := postWebhook(swagger.WebhookCreate{
+			Name:   "noc-" + uuid.New()[:8],
+			URL:    receiver.URL,
+			Secret: "0123456789abcdef",
+		}, http.StatusCreated)
+		defer deleteWebhook(hook.ID)
+		Expect(hook.Secret).To(BeEmpty())
+
+		By("Sending a GET /webhooks/{webhook_id} request")
+		resp, err := apiCli.Get("http://127.0.0.1:8080/webhooks/" + hook.ID)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var detail swagger.WebhookDetail
+		Expect(json.NewDecoder(resp.Body).Decode(&detail)).To(Succeed())
+		Expect(detail.Secret).To(BeEmpty())
+		Expect(detail.EventTypes).To(BeEmpty())
+	})
+
+	It("Should reject a taken name", func() {
+		name := "noc-" + uuid.New()[:8]
+		hook := postWebhook(swagger.WebhookCreate{Name: name, URL: receiver.URL}, http.StatusCreated)
+		defer deleteWebhook(hook.ID)
+
+		postWebhook(swagger.WebhookCreate{Name: name, URL: receiver.URL}, http.StatusConflict)
+	})
+
+	It("Should reject an unknown event type", func() {
+		postWebhook(swagger.WebhookCreate{
+			Name:       "noc-" + uuid.New()[:8],
+			URL:        receiver.URL,
+			EventTypes: []string{"node.exploded"},
+		}, http.StatusBadRequest)
+	})
+
+	It("Should update the event types", func() {
+		hook := postWebhook(swagger.WebhookCreate{Name: "noc-" + uuid.New()[:8], URL: receiver.URL}, http.StatusCreated)
+		defer deleteWebhook(hook.ID)
+
+		payload, err := json.Marshal(swagger.WebhookUpdate{
+			EventTypes: &[]string{events.TypeNodeEnrolled},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a PATCH /webhooks/{webhook_id} request")
+		resp, err := apiCli.Patch(
+			"http://127.0.0.1:8080/webhooks/"+hook.ID, "application/json", bytes.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		By("Sending a GET /webhooks/{webhook_id} request")
+		resp, err = apiCli.Get("http://127.0.0.1:8080/webhooks/" + hook.ID)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		var detail swagger.WebhookDetail
+		Expect(json.NewDecoder(resp.Body).Decode(&detail)).To(Succeed())
+		Expect(detail.Name).To(Equal(hook.Name))
+		Expect(detail.EventTypes).To(Equal([]string{events.TypeNodeEnrolled}))
+	})
+})
@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+// Package config loads the configuration of the controller from a YAML or
+// JSON file.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	logger "github.com/open-ness/common/log"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Orchestration modes of the controller.
+const (
+	OrchestrationNative        = "native"
+	OrchestrationKubernetes    = "kubernetes"
+	OrchestrationKubernetesOVN = "kubernetes-ovn"
+)
+
+// Config is the configuration of the controller. Only LogLevel, CORS and
+// Limits can be reloaded while the controller is running, the other settings
+// take effect when it is restarted.
+type Config struct {
+	// DSN is the data source name, either a MySQL DSN or bolt://<path> for
+	// an embedded DB.
+	DSN string `json:"dsn"`
+
+	// LogLevel is the syslog level to log at and below.
+	LogLevel string `json:"log_level"`
+
+	// OrchestrationMode is one of native, kubernetes or kubernetes-ovn.
+	OrchestrationMode string `json:"orchestration_mode"`
+
+	Ports      Ports      `json:"ports"`
+	Kubernetes Kubernetes `json:"kubernetes"`
+	CORS       CORS       `json:"cors"`
+	Limits     cce.Limits `json:"limits"`
+	Telemetry  Telemetry  `json:"telemetry"`
+}
+
+// Ports are the ports that the controller listens on and dials nodes on.
+type Ports struct {
+	HTTP   int `json:"http"`
+	GRPC   int `json:"grpc"`
+	ELA    int `json:"ela"`
+	EVA    int `json:"eva"`
+	Syslog int `json:"syslog"`
+	StatsD int `json:"statsd"`
+
+	// Metrics is the port of the Prometheus metrics listener, or 0 to
+	// disable it.
+	Metrics int `json:"metrics"`
+
+	// HTTPRedirect is the port of the listener redirecting HTTP to HTTPS, or
+	// 0 to disable it.
+	HTTPRedirect int `json:"http_redirect"`
+}
+
+// Kubernetes are the settings of the Kubernetes client.
+type Kubernetes struct {
+	CAFile   string `json:"ca_file"`
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	Host     string `json:"host"`
+	APIPath  string `json:"api_path"`
+	Username string `json:"username"`
+}
+
+// CORS is the Cross-Origin Resource Sharing policy of the HTTP API.
+type CORS struct {
+	// AllowedOrigins are the origins, such as https://ui.example.com:3000,
+	// that are allowed to make requests, or "*" for any.
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+// Telemetry are the paths of the files that telemetry is written to.
+type Telemetry struct {
+	SyslogPath string `json:"syslog_path"`
+	StatsDPath string `json:"statsd_path"`
+}
+
+// Load reads the YAML or JSON file at path into cfg. Settings missing from
+// the file keep their values in cfg, and unknown settings are an error.
+func Load(path string, cfg *Config) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if data, err = yaml.YAMLToJSON(data); err != nil {
+		return errors.Wrapf(err, "error parsing %s", path)
+	}
+
+	// Decoding into a slice reuses its backing array, which may be shared
+	// with the caller's copy of cfg
+	cfg.CORS.AllowedOrigins = append([]string(nil), cfg.CORS.AllowedOrigins...)
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(cfg); err != nil {
+		return errors.Wrapf(err, "error parsing %s", path)
+	}
+	return nil
+}
+
+// Validate validates the configuration.
+func (cfg *Config) Validate() error {
+	if cfg.DSN == "" {
+		return errors.New("dsn cannot be empty")
+	}
+	if _, err := logger.ParseLevel(cfg.LogLevel); err != nil {
+		return errors.Wrap(err, "invalid log_level")
+	}
+
+	switch cfg.OrchestrationMode {
+	case OrchestrationNative:
+	case OrchestrationKubernetes, OrchestrationKubernetesOVN:
+		if cfg.Kubernetes.Host == "" {
+			return fmt.Errorf("kubernetes.host is required in %s orchestration mode", cfg.OrchestrationMode)
+		}
+	default:
+		return fmt.Errorf("orchestration_mode must be one of %s, %s or %s, got %q",
+			OrchestrationNative, OrchestrationKubernetes, OrchestrationKubernetesOVN, cfg.OrchestrationMode)
+	}
+
+	if err := cfg.Ports.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.CORS.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Limits.Validate(); err != nil {
+		return errors.Wrap(err, "invalid limits")
+	}
+
+	if cfg.Telemetry.SyslogPath == "" {
+		return errors.New("telemetry.syslog_path cannot be empty")
+	}
+	if cfg.Telemetry.StatsDPath == "" {
+		return errors.New("telemetry.statsd_path cannot be empty")
+	}
+	return nil
+}
+
+// Validate validates that the ports are in range and that no two listeners
+// share a port.
+func (p *Ports) Validate() error {
+	listeners := []struct {
+		name     string
+		port     int
+		optional bool
+	}{
+		{"http", p.HTTP, false},
+		{"grpc", p.GRPC, false},
+		{"syslog", p.Syslog, false},
+		{"statsd", p.StatsD, false},
+		{"metrics", p.Metrics, true},
+		{"http_redirect", p.HTTPRedirect, true},
+	}
+
+	used := make(map[int]string)
+	for _, l := range listeners {
+		if l.optional && l.port == 0 {
+			continue
+		}
+		if l.port < 1 || l.port > cce.MaxPort {
+			return fmt.Errorf("ports.%s must be in [1..%d], got %d", l.name, cce.MaxPort, l.port)
+		}
+		if other, ok := used[l.port]; ok {
+			return fmt.Errorf("ports.%s and ports.%s cannot both be %d", other, l.name, l.port)
+		}
+		used[l.port] = l.name
+	}
+
+	if p.ELA < 1 || p.ELA > cce.MaxPort {
+		return fmt.Errorf("ports.ela must be in [1..%d], got %d", cce.MaxPort, p.ELA)
+	}
+	if p.EVA < 1 || p.EVA > cce.MaxPort {
+		return fmt.Errorf("ports.eva must be in [1..%d], got %d", cce.MaxPort, p.EVA)
+	}
+	return nil
+}
+
+// Validate validates that the allowed origins are "*" or the scheme, host
+// and optional port of an HTTP or HTTPS URL.
+func (c *CORS) Validate() error {
+	if len(c.AllowedOrigins) == 0 {
+		return errors.New("cors.allowed_origins cannot be empty")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil ||
+			(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" ||
+			strings.HasSuffix(origin, "?") {
+			return fmt.Errorf("cors.allowed_origins must be \"*\" or a scheme://host[:port] origin, got %q", origin)
+		}
+	}
+	return nil
+}
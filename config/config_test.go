@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/config"
+)
+
+var _ = Describe("Config", func() {
+	var (
+		tmpDir string
+		cfg    config.Config
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "config_test")
+		Expect(err).ToNot(HaveOccurred())
+
+		cfg = config.Config{
+			DSN:               "bolt:///var/lib/cce/cce.db",
+			LogLevel:          "info",
+			OrchestrationMode: config.OrchestrationNative,
+			Ports: config.Ports{
+				HTTP:   8080,
+				GRPC:   8081,
+				ELA:    42101,
+				EVA:    42102,
+				Syslog: 6514,
+				StatsD: 8125,
+			},
+			CORS:   config.CORS{AllowedOrigins: []string{"*"}},
+			Limits: cce.DefaultLimits,
+			Telemetry: config.Telemetry{
+				SyslogPath: "./syslog.log",
+				StatsDPath: "./statsd.log",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	writeFile := func(name, data string) string {
+		path := filepath.Join(tmpDir, name)
+		Expect(ioutil.WriteFile(path, []byte(data), 0600)).To(Succeed())
+		return path
+	}
+
+	Describe("Load", func() {
+		It("Should load a YAML file over the existing settings", func() {
+			path := writeFile("cce.yml", `
+log_level: debug
+ports:
+  http: 443
+  metrics: 9100
+cors:
+  allowed_origins:
+    - https://ui.example.com
+limits:
+  max_cores: 16
+`)
+			Expect(config.Load(path, &cfg)).To(Succeed())
+			Expect(cfg.LogLevel).To(Equal("debug"))
+			Expect(cfg.Ports.HTTP).To(Equal(443))
+			Expect(cfg.Ports.GRPC).To(Equal(8081))
+			Expect(cfg.Ports.Metrics).To(Equal(9100))
+			Expect(cfg.CORS.AllowedOrigins).To(Equal([]string{"https://ui.example.com"}))
+			Expect(cfg.Limits.MaxCores).To(Equal(16))
+			Expect(cfg.Limits.MaxMemory).To(Equal(cce.DefaultMaxMemory))
+			Expect(cfg.DSN).To(Equal("bolt:///var/lib/cce/cce.db"))
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("Should load a JSON file", func() {
+			path := writeFile("cce.json", `{
+				"dsn": "root:secret@tcp(mysql:3306)/controller_ce",
+				"orchestration_mode": "kubernetes",
+				"kubernetes": {"host": "https://k8s.example.com:6443", "username": "admin"},
+				"telemetry": {"syslog_path": "/var/log/cce/syslog.log"}
+			}`)
+			Expect(config.Load(path, &cfg)).To(Succeed())
+			Expect(cfg.DSN).To(Equal("root:secret@tcp(mysql:3306)/controller_ce"))
+			Expect(cfg.OrchestrationMode).To(Equal(config.OrchestrationKubernetes))
+			Expect(cfg.Kubernetes.Host).To(Equal("https://k8s.example.com:6443"))
+			Expect(cfg.Kubernetes.Username).To(Equal("admin"))
+			Expect(cfg.Telemetry.SyslogPath).To(Equal("/var/log/cce/syslog.log"))
+			Expect(cfg.Telemetry.StatsDPath).To(Equal("./statsd.log"))
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("Should not modify the origins of a copy of the config", func() {
+			orig := cfg
+			path := writeFile("cce.yml", "cors: {allowed_origins: [\"http://localhost:3000\"]}")
+			Expect(config.Load(path, &cfg)).To(Succeed())
+			Expect(orig.CORS.AllowedOrigins).To(Equal([]string{"*"}))
+		})
+
+		It("Should return an error for unknown settings", func() {
+			path := writeFile("cce.yml", "ports:\n  htpp: 443\n")
+			Expect(config.Load(path, &cfg)).To(MatchError(ContainSubstring(`unknown field "htpp"`)))
+		})
+
+		It("Should return an error for settings of the wrong type", func() {
+			path := writeFile("cce.yml", "ports:\n  http: https\n")
+			Expect(config.Load(path, &cfg)).To(MatchError(ContainSubstring("error parsing")))
+		})
+
+		It("Should return an error for a malformed file", func() {
+			path := writeFile("cce.yml", "ports: [\n")
+			Expect(config.Load(path, &cfg)).To(MatchError(ContainSubstring("error parsing")))
+		})
+
+		It("Should return an error for a missing file", func() {
+			Expect(config.Load(filepath.Join(tmpDir, "missing.yml"), &cfg)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should accept a valid config", func() {
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if DSN is empty", func() {
+			cfg.DSN = ""
+			Expect(cfg.Validate()).To(MatchError("dsn cannot be empty"))
+		})
+
+		It("Should return an error if LogLevel is invalid", func() {
+			cfg.LogLevel = "verbose"
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("invalid log_level")))
+		})
+
+		It("Should return an error if OrchestrationMode is invalid", func() {
+			cfg.OrchestrationMode = "swarm"
+			Expect(cfg.Validate()).To(MatchError(
+				`orchestration_mode must be one of native, kubernetes or kubernetes-ovn, got "swarm"`))
+		})
+
+		It("Should require the Kubernetes host in Kubernetes modes", func() {
+			cfg.OrchestrationMode = config.OrchestrationKubernetesOVN
+			Expect(cfg.Validate()).To(MatchError(
+				"kubernetes.host is required in kubernetes-ovn orchestration mode"))
+
+			cfg.Kubernetes.Host = "https://k8s.example.com:6443"
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if a port is out of range", func() {
+			cfg.Ports.GRPC = 0
+			Expect(cfg.Validate()).To(MatchError("ports.grpc must be in [1..65535], got 0"))
+
+			cfg.Ports.GRPC = 8081
+			cfg.Ports.EVA = 65536
+			Expect(cfg.Validate()).To(MatchError("ports.eva must be in [1..65535], got 65536"))
+
+			cfg.Ports.EVA = 42102
+			cfg.Ports.Metrics = -1
+			Expect(cfg.Validate()).To(MatchError("ports.metrics must be in [1..65535], got -1"))
+		})
+
+		It("Should return an error if two listeners share a port", func() {
+			cfg.Ports.Metrics = 8080
+			Expect(cfg.Validate()).To(MatchError("ports.http and ports.metrics cannot both be 8080"))
+		})
+
+		It("Should accept origins with a scheme, host and port", func() {
+			cfg.CORS.AllowedOrigins = []string{"https://ui.example.com", "http://localhost:3000"}
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if an origin is invalid", func() {
+			for _, origin := range []string{
+				"ui.example.com", "ftp://ui.example.com", "https://ui.example.com/", "https://ui.example.com/app",
+				"https://user@ui.example.com", "https://ui.example.com?a=b", "",
+			} {
+				cfg.CORS.AllowedOrigins = []string{"*", origin}
+				Expect(cfg.Validate()).To(MatchError(ContainSubstring("cors.allowed_origins must be")), origin)
+			}
+		})
+
+		It("Should return an error if there are no origins", func() {
+			cfg.CORS.AllowedOrigins = nil
+			Expect(cfg.Validate()).To(MatchError("cors.allowed_origins cannot be empty"))
+		})
+
+		It("Should return an error if the limits are invalid", func() {
+			cfg.Limits.MaxBodySize = 0
+			Expect(cfg.Validate()).To(MatchError("invalid limits: max_body_size must be at least 1, got 0"))
+		})
+
+		It("Should return an error if a telemetry path is empty", func() {
+			cfg.Telemetry.StatsDPath = ""
+			Expect(cfg.Validate()).To(MatchError("telemetry.statsd_path cannot be empty"))
+		})
+	})
+})
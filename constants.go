@@ -7,8 +7,9 @@ import (
 	"time"
 )
 
-// MaxBodySize is the maximum size (in bytes) of an acceptable request body
-const MaxBodySize = 64 * 1024
+// DefaultMaxBodySize is the default maximum size (in bytes) of an acceptable
+// request body
+const DefaultMaxBodySize = 64 * 1024
 
 // MaxHTTPRequestTime is the maximum time to request HTTP data before timing out
 const MaxHTTPRequestTime = 2 * time.Minute
@@ -16,11 +17,13 @@ const MaxHTTPRequestTime = 2 * time.Minute
 // MaxDBRequestTime is the maximum time to request database data before timing out
 const MaxDBRequestTime = 10 * time.Second
 
-// MaxCores is the maximum number of cores that an application can use.
-const MaxCores = 8
+// DefaultMaxCores is the default maximum number of cores that an application
+// can use.
+const DefaultMaxCores = 8
 
-// MaxMemory is the maximum memory (in MB) that an application can use.
-const MaxMemory = 16 * 1024
+// DefaultMaxMemory is the default maximum memory (in MB) that an application can
+// use.
+const DefaultMaxMemory = 16 * 1024
 
 // MaxPort is the maximum port allowed in the TCP/IP stack
 const MaxPort = 65535
@@ -21,6 +21,8 @@ type Credentials struct {
 	ID string `json:"id"`
 	// Certificate is a PEM-encoded X.509 certificate.
 	Certificate string `json:"certificate"`
+
+	ResourceVersion
 }
 
 // GetTableName returns the name of the table this entity is saved in.
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 
 	"github.com/open-ness/edgecontroller/uuid"
@@ -18,6 +19,8 @@ type DNSConfig struct {
 	Name       string          `json:"name"`
 	ARecords   []*DNSARecord   `json:"a_records"`
 	Forwarders []*DNSForwarder `json:"forwarders"`
+
+	ResourceVersion
 }
 
 // GetTableName returns the name of the persistence table.
@@ -101,6 +104,60 @@ DNSConfig[
 		forwarders)
 }
 
+// DiffDNSARecords compares two sets of A records and returns the records that
+// have to be set and deleted to turn old into new. A record whose description
+// or IPs changed is returned in both: the old version is removed and the new
+// one set.
+func DiffDNSARecords(old, new []*DNSARecord) (added, removed []*DNSARecord) {
+	for _, o := range old {
+		if !containsDNSARecord(new, o) {
+			removed = append(removed, o)
+		}
+	}
+	for _, n := range new {
+		if !containsDNSARecord(old, n) {
+			added = append(added, n)
+		}
+	}
+
+	return added, removed
+}
+
+func containsDNSARecord(records []*DNSARecord, r *DNSARecord) bool {
+	for _, record := range records {
+		if record.equal(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffDNSForwarders compares two sets of forwarders and returns the forwarders
+// that have to be set and deleted to turn old into new.
+func DiffDNSForwarders(old, new []*DNSForwarder) (added, removed []*DNSForwarder) {
+	for _, o := range old {
+		if !containsDNSForwarder(new, o) {
+			removed = append(removed, o)
+		}
+	}
+	for _, n := range new {
+		if !containsDNSForwarder(old, n) {
+			added = append(added, n)
+		}
+	}
+
+	return added, removed
+}
+
+func containsDNSForwarder(forwarders []*DNSForwarder, f *DNSForwarder) bool {
+	for _, forwarder := range forwarders {
+		if *forwarder == *f {
+			return true
+		}
+	}
+	return false
+}
+
 // DNSARecord is a DNS A record.
 type DNSARecord struct {
 	Name        string   `json:"name"`
@@ -134,6 +191,26 @@ func (r *DNSARecord) Validate() error {
 	return nil
 }
 
+// equal reports whether both records have the same name, description and set
+// of IPs. The order of the IPs does not matter.
+func (r *DNSARecord) equal(o *DNSARecord) bool {
+	if r.Name != o.Name || r.Description != o.Description || len(r.IPs) != len(o.IPs) {
+		return false
+	}
+
+	rIPs := append([]string(nil), r.IPs...)
+	oIPs := append([]string(nil), o.IPs...)
+	sort.Strings(rIPs)
+	sort.Strings(oIPs)
+	for i := range rIPs {
+		if rIPs[i] != oIPs[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (r *DNSARecord) String() string {
 	ips := ""
 
@@ -18,6 +18,8 @@ type DNSConfigAppAlias struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	AppID       string `json:"app_id"`
+
+	ResourceVersion
 }
 
 // GetTableName returns the name of the persistence table.
@@ -64,6 +66,15 @@ func (*DNSConfigAppAlias) FilterFields() []string {
 	}
 }
 
+// ARecord returns the A record the alias is set on a node as.
+func (cfg_alias *DNSConfigAppAlias) ARecord() *DNSARecord {
+	return &DNSARecord{
+		Name:        cfg_alias.AppID,
+		Description: cfg_alias.Description,
+		IPs:         []string{cfg_alias.AppID},
+	}
+}
+
 func (cfg_alias *DNSConfigAppAlias) String() string {
 	return fmt.Sprintf(strings.TrimSpace(`
 DNSConfigAppAlias[
@@ -189,4 +189,63 @@ DNSConfig[
 			)))
 		})
 	})
+
+	Describe("DiffDNSARecords", func() {
+		It("Should return nothing if the records are the same", func() {
+			added, removed := cce.DiffDNSARecords(cfg.ARecords, []*cce.DNSARecord{
+				{
+					Name:        "patient-checkin.choc.org",
+					Description: "Patient Check-in Dashboard",
+					IPs: []string{
+						"172.16.55.44",
+						"172.16.55.43",
+					},
+				},
+			})
+			Expect(added).To(BeEmpty())
+			Expect(removed).To(BeEmpty())
+		})
+
+		It("Should return the added and removed records", func() {
+			newRecords := []*cce.DNSARecord{
+				{
+					Name:        "patient-checkin.choc.org",
+					Description: "Patient Check-in Dashboard",
+					IPs: []string{
+						"172.16.55.45",
+					},
+				},
+				{
+					Name:        "visitor-checkin.choc.org",
+					Description: "Visitor Check-in Dashboard",
+					IPs: []string{
+						"172.16.55.46",
+					},
+				},
+			}
+			added, removed := cce.DiffDNSARecords(cfg.ARecords, newRecords)
+			Expect(added).To(Equal(newRecords))
+			Expect(removed).To(Equal(cfg.ARecords))
+		})
+	})
+
+	Describe("DiffDNSForwarders", func() {
+		It("Should return the added and removed forwarders", func() {
+			newForwarders := []*cce.DNSForwarder{
+				{
+					Name:        "Cloudflare DNS #1",
+					Description: "Cloudflare's DNS servers (backup)",
+					IP:          "1.1.1.1",
+				},
+				{
+					Name:        "Google DNS #2",
+					Description: "Google's DNS servers (secondary)",
+					IP:          "8.8.4.4",
+				},
+			}
+			added, removed := cce.DiffDNSForwarders(cfg.Forwarders, newForwarders)
+			Expect(added).To(Equal(newForwarders[1:]))
+			Expect(removed).To(Equal(cfg.Forwarders[:1]))
+		})
+	})
 })
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import "time"
+
+// NodeDrift is the difference between the state of a node and the state
+// persisted for it, as found by a single reconciliation pass.
+type NodeDrift struct {
+	NodeID    string
+	CheckedAt time.Time
+
+	// MissingApps are the IDs of apps in nodes_apps the node did not report.
+	// They are deployed again.
+	MissingApps []string
+	// MissingInterfaces are the IDs of network interfaces with a traffic
+	// policy in persistence that the node did not report. They cannot be
+	// repaired by the controller.
+	MissingInterfaces []string
+
+	// The node cannot report its traffic policies or DNS records, so they are
+	// re-applied on every pass. These count how many were re-applied.
+	ReappliedAppPolicies       int
+	ReappliedInterfacePolicies int
+	ReappliedDNSRecords        int
+
+	// Errors are the errors hit while reconciling the node.
+	Errors []string
+}
+
+// InSync returns whether the node matched persistence.
+func (d *NodeDrift) InSync() bool {
+	return len(d.MissingApps) == 0 && len(d.MissingInterfaces) == 0 && len(d.Errors) == 0
+}
+
+// DriftReporter reports the drift found the last time each node was
+// reconciled.
+type DriftReporter interface {
+	DriftReport() []*NodeDrift
+}
@@ -0,0 +1,306 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	// TypeEntityCreated, TypeEntityUpdated and TypeEntityDeleted are the
+	// changes of entities made through the API.
+	TypeEntityCreated = "entity.created"
+	TypeEntityUpdated = "entity.updated"
+	TypeEntityDeleted = "entity.deleted"
+
+	// TypeAppLifecycle is a lifecycle command run on an app deployed to a
+	// node. Its data is an AppLifecycle.
+	TypeAppLifecycle = "app.lifecycle"
+
+	// TypeAppStatus is a change of the status of an app deployed to a node,
+	// as observed by the Controller. Its data is an AppStatus.
+	TypeAppStatus = "app.status"
+
+	// TypeNodeEnrolled is the enrollment of a node.
+	TypeNodeEnrolled = "node.enrolled"
+
+	// TypePolicyApplied is the result of applying a policy or DNS config to a
+	// node. Its data is a PolicyResult.
+	TypePolicyApplied = "policy.applied"
+
+	// TypeOperationDone is the end of an operation run in the background.
+	// Its data is an OperationResult.
+	TypeOperationDone = "operation.done"
+)
+
+// Types are the event types.
+var Types = []string{
+	TypeEntityCreated,
+	TypeEntityUpdated,
+	TypeEntityDeleted,
+	TypeAppLifecycle,
+	TypeAppStatus,
+	TypeNodeEnrolled,
+	TypePolicyApplied,
+	TypeOperationDone,
+}
+
+// IsType returns whether a string is an event type.
+func IsType(t string) bool {
+	for _, typ := range Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRetained is the default number of recent events a bus retains for
+// subscribers resuming from the last event they received.
+const DefaultRetained = 1000
+
+// subscriptionBuffer is the number of events buffered for a subscriber before
+// it is dropped for being too slow.
+const subscriptionBuffer = 100
+
+// Event is something that happened in the Controller. Events are immutable
+// once published.
+type Event struct {
+	// ID is the sequence number of the event on the bus, which starts at 1
+	// when the Controller starts.
+	ID   uint64    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+
+	// NodeID is the ID of the node the event concerns, if any.
+	NodeID string `json:"node_id,omitempty"`
+
+	// EntityType is the table name of the entity the event concerns.
+	EntityType string `json:"entity_type,omitempty"`
+	EntityID   string `json:"entity_id,omitempty"`
+
+	// Actor is the user or node that caused the event, as in audit events.
+	Actor string `json:"actor,omitempty"`
+
+	Data interface{} `json:"data,omitempty"`
+}
+
+// AppLifecycle is the data of a TypeAppLifecycle event.
+type AppLifecycle struct {
+	AppID   string `json:"app_id"`
+	Command string `json:"command"`
+}
+
+// AppStatus is the data of a TypeAppStatus event. Previous is empty if the
+// status was not observed before.
+type AppStatus struct {
+	AppID    string `json:"app_id"`
+	Status   string `json:"status"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// PolicyResult is the data of a TypePolicyApplied event.
+type PolicyResult struct {
+	Status string   `json:"status"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// OperationResult is the data of a TypeOperationDone event.
+type OperationResult struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Filter selects events. Empty fields match any event.
+type Filter struct {
+	NodeID     string
+	EntityType string
+	Types      []string
+}
+
+// Match returns whether an event matches the filter.
+func (f Filter) Match(e *Event) bool {
+	if f.NodeID != "" && e.NodeID != f.NodeID {
+		return false
+	}
+	if f.EntityType != "" && e.EntityType != f.EntityType {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if e.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Bus delivers published events to subscribers. It retains the recent events
+// so subscribers can resume after the last event they received.
+type Bus struct {
+	// Now returns the current time. If nil time.Now is used.
+	Now func() time.Time
+
+	mu     sync.Mutex
+	lastID uint64
+	recent []*Event // ring of the retained events
+	subs   map[*Subscription]struct{}
+
+	// appStatuses are the last published statuses of node apps by ID
+	appStatuses map[string]string
+}
+
+// NewBus returns a bus that retains up to retained recent events.
+func NewBus(retained int) *Bus {
+	return &Bus{
+		recent: make([]*Event, 0, retained),
+		subs:   make(map[*Subscription]struct{}),
+
+		appStatuses: make(map[string]string),
+	}
+}
+
+// Publish sets the ID and time of an event and delivers it to the subscribers
+// it matches. Subscribers whose buffer is full are dropped, which closes
+// their channel.
+func (b *Bus) Publish(e *Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.publish(e)
+}
+
+// PublishAppStatus publishes a TypeAppStatus event for the status of an app
+// deployed to a node, the node app being the entity of the event, unless it
+// is the last status published for the node app.
+func (b *Bus) PublishAppStatus(e *Event, appID, status string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous, ok := b.appStatuses[e.EntityID]
+	if ok && previous == status {
+		return
+	}
+	b.appStatuses[e.EntityID] = status
+
+	e.Type = TypeAppStatus
+	e.Data = AppStatus{AppID: appID, Status: status, Previous: previous}
+	b.publish(e)
+}
+
+func (b *Bus) publish(e *Event) {
+	if e.Type == TypeEntityDeleted {
+		delete(b.appStatuses, e.EntityID)
+	}
+
+	b.lastID++
+	e.ID = b.lastID
+	if b.Now != nil {
+		e.Time = b.Now()
+	} else {
+		e.Time = time.Now()
+	}
+
+	if cap(b.recent) > 0 {
+		if len(b.recent) < cap(b.recent) {
+			b.recent = append(b.recent, e)
+		} else {
+			b.recent[(e.ID-1)%uint64(cap(b.recent))] = e
+		}
+	}
+
+	for s := range b.subs {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			b.unsubscribe(s)
+		}
+	}
+}
+
+// Subscribe subscribes to the events matching a filter that are published
+// from now on.
+func (b *Bus) Subscribe(f Filter) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.subscribe(f)
+}
+
+// SubscribeAfter subscribes to the events matching a filter that are published
+// after the event of lastID. The retained events after it are returned and
+// are followed by those of the subscription. If lastID is after the last
+// event, as when the Controller restarted, all retained events are returned.
+func (b *Bus) SubscribeAfter(f Filter, lastID uint64) (*Subscription, []*Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if lastID > b.lastID {
+		lastID = 0
+	}
+
+	var missed []*Event
+	for i := range b.recent {
+		// The oldest event is the one after the last in the ring
+		e := b.recent[(int(b.lastID)+i)%len(b.recent)]
+		if e.ID > lastID && f.Match(e) {
+			missed = append(missed, e)
+		}
+	}
+
+	return b.subscribe(f), missed
+}
+
+func (b *Bus) subscribe(f Filter) *Subscription {
+	s := &Subscription{
+		bus:    b,
+		filter: f,
+		c:      make(chan *Event, subscriptionBuffer),
+	}
+	s.C = s.c
+	b.subs[s] = struct{}{}
+	return s
+}
+
+func (b *Bus) unsubscribe(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.c)
+	}
+}
+
+// Subscribers returns the number of subscriptions.
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subs)
+}
+
+// Subscription is a subscription to the events of a bus.
+type Subscription struct {
+	// C receives the events of the subscription. It is closed when the
+	// subscription is closed or dropped for being too slow.
+	C <-chan *Event
+
+	bus    *Bus
+	filter Filter
+	c      chan *Event
+}
+
+// Close closes the subscription.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	s.bus.unsubscribe(s)
+}
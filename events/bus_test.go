@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package events_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/events"
+)
+
+var _ = Describe("Bus", func() {
+	var (
+		now time.Time
+		bus *events.Bus
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		bus = events.NewBus(3)
+		bus.Now = func() time.Time { return now }
+	})
+
+	publish := func(nodeID, entityType string) *events.Event {
+		e := &events.Event{Type: events.TypeEntityCreated, NodeID: nodeID, EntityType: entityType}
+		bus.Publish(e)
+		return e
+	}
+
+	ids := func(es []*events.Event) []uint64 {
+		var ids []uint64
+		for _, e := range es {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	It("Should number and time the events", func() {
+		Expect(publish("n1", "nodes")).To(Equal(&events.Event{
+			ID:         1,
+			Type:       events.TypeEntityCreated,
+			Time:       now,
+			NodeID:     "n1",
+			EntityType: "nodes",
+		}))
+		Expect(publish("n1", "nodes").ID).To(Equal(uint64(2)))
+	})
+
+	It("Should deliver the matching events to subscribers", func() {
+		all := bus.Subscribe(events.Filter{})
+		node := bus.Subscribe(events.Filter{NodeID: "n1"})
+		apps := bus.Subscribe(events.Filter{EntityType: "nodes_apps"})
+
+		e1 := publish("n1", "nodes")
+		e2 := publish("n2", "nodes_apps")
+
+		Expect(all.C).To(Receive(Equal(e1)))
+		Expect(all.C).To(Receive(Equal(e2)))
+		Expect(node.C).To(Receive(Equal(e1)))
+		Expect(node.C).ToNot(Receive())
+		Expect(apps.C).To(Receive(Equal(e2)))
+		Expect(apps.C).ToNot(Receive())
+	})
+
+	It("Should resume after the last event", func() {
+		publish("n1", "nodes")
+		publish("n2", "nodes")
+		publish("n1", "nodes")
+
+		sub, missed := bus.SubscribeAfter(events.Filter{NodeID: "n1"}, 1)
+		Expect(ids(missed)).To(Equal([]uint64{3}))
+
+		e4 := publish("n1", "nodes")
+		Expect(sub.C).To(Receive(Equal(e4)))
+	})
+
+	It("Should only retain the recent events", func() {
+		for i := 0; i < 5; i++ {
+			publish("n1", "nodes")
+		}
+
+		_, missed := bus.SubscribeAfter(events.Filter{}, 0)
+		Expect(ids(missed)).To(Equal([]uint64{3, 4, 5}))
+
+		_, missed = bus.SubscribeAfter(events.Filter{}, 4)
+		Expect(ids(missed)).To(Equal([]uint64{5}))
+	})
+
+	It("Should resume from the start after an unknown event", func() {
+		publish("n1", "nodes")
+		publish("n1", "nodes")
+
+		_, missed := bus.SubscribeAfter(events.Filter{}, 42)
+		Expect(ids(missed)).To(Equal([]uint64{1, 2}))
+	})
+
+	It("Should filter by type", func() {
+		sub := bus.Subscribe(events.Filter{Types: []string{events.TypeNodeEnrolled}})
+
+		publish("n1", "nodes")
+		enrolled := &events.Event{Type: events.TypeNodeEnrolled, NodeID: "n1"}
+		bus.Publish(enrolled)
+
+		Expect(sub.C).To(Receive(Equal(enrolled)))
+		Expect(sub.C).ToNot(Receive())
+	})
+
+	It("Should publish the changes of app statuses", func() {
+		sub := bus.Subscribe(events.Filter{})
+		publishStatus := func(status string) {
+			bus.PublishAppStatus(&events.Event{NodeID: "n1", EntityType: "nodes_apps", EntityID: "na1"}, "a1", status)
+		}
+
+		publishStatus("deployed")
+		publishStatus("deployed")
+		publishStatus("error")
+
+		var e *events.Event
+		Expect(sub.C).To(Receive(&e))
+		Expect(e.Type).To(Equal(events.TypeAppStatus))
+		Expect(e.Data).To(Equal(events.AppStatus{AppID: "a1", Status: "deployed"}))
+		Expect(sub.C).To(Receive(&e))
+		Expect(e.Data).To(Equal(events.AppStatus{AppID: "a1", Status: "error", Previous: "deployed"}))
+		Expect(sub.C).ToNot(Receive())
+
+		By("Forgetting the status of deleted node apps")
+		bus.Publish(&events.Event{Type: events.TypeEntityDeleted, EntityType: "nodes_apps", EntityID: "na1"})
+		Expect(sub.C).To(Receive())
+		publishStatus("error")
+		Expect(sub.C).To(Receive(&e))
+		Expect(e.Data).To(Equal(events.AppStatus{AppID: "a1", Status: "error"}))
+	})
+
+	It("Should close subscriptions", func() {
+		sub := bus.Subscribe(events.Filter{})
+		Expect(bus.Subscribers()).To(Equal(1))
+
+		sub.Close()
+		sub.Close()
+		Expect(bus.Subscribers()).To(Equal(0))
+		Expect(sub.C).To(BeClosed())
+	})
+
+	It("Should drop slow subscribers", func() {
+		sub := bus.Subscribe(events.Filter{})
+		for i := 0; i < 101; i++ {
+			publish("n1", "nodes")
+		}
+
+		Expect(bus.Subscribers()).To(Equal(0))
+		for i := 0; i < 100; i++ {
+			Expect(sub.C).To(Receive())
+		}
+		Expect(sub.C).To(BeClosed())
+	})
+})
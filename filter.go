@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// FilterOp is the operator of a Filter. The zero value is equality.
+type FilterOp string
+
+const (
+	// FilterOpEqual matches a field equal to the value.
+	FilterOpEqual FilterOp = ""
+	// FilterOpContains matches a field containing the value.
+	FilterOpContains FilterOp = "~"
+	// FilterOpPrefix matches a field starting with the value.
+	FilterOpPrefix FilterOp = "^"
+	// FilterOpLike matches a field against the value as a SQL LIKE pattern,
+	// where "%" matches any string, "_" matches any character and "\" escapes
+	// the next character.
+	FilterOpLike FilterOp = "like"
+	// FilterOpIn matches a field equal to any of the values.
+	FilterOpIn FilterOp = "in"
+	// FilterOpNull matches a field that is missing or null.
+	FilterOpNull FilterOp = "null"
+	// FilterOpLess matches a number less than the value.
+	FilterOpLess FilterOp = "<"
+	// FilterOpLessOrEqual matches a number less than or equal to the value.
+	FilterOpLessOrEqual FilterOp = "<="
+	// FilterOpGreater matches a number greater than the value.
+	FilterOpGreater FilterOp = ">"
+	// FilterOpGreaterOrEqual matches a number greater than or equal to the
+	// value.
+	FilterOpGreaterOrEqual FilterOp = ">="
+)
+
+// validateFilters validates filters on entities like zv. Only the filter
+// fields of zv and "id" may be filtered on, which keeps the fields safe to
+// inject into SQL queries.
+func validateFilters(zv Filterable, fs []Filter) error {
+	for _, f := range fs {
+		if len(f.Or) > 0 {
+			for _, group := range f.Or {
+				if err := validateFilters(zv, group); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if f.Field != "id" && !isFilterField(zv, f.Field) {
+			return errors.Errorf("disallowed filter field %q", f.Field)
+		}
+
+		switch f.Op {
+		case FilterOpEqual, FilterOpContains, FilterOpPrefix, FilterOpLike, FilterOpIn, FilterOpNull:
+		case FilterOpLess, FilterOpLessOrEqual, FilterOpGreater, FilterOpGreaterOrEqual:
+			if _, err := strconv.ParseFloat(f.Value, 64); err != nil {
+				return errors.Errorf("filter value %q of field %q is not a number", f.Value, f.Field)
+			}
+		default:
+			return errors.Errorf("unknown filter operator %q", f.Op)
+		}
+	}
+
+	return nil
+}
+
+func isFilterField(zv Filterable, field string) bool {
+	for _, allowed := range zv.FilterFields() {
+		if field == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -15,12 +15,15 @@ require (
 	github.com/open-ness/common/log v0.0.0-20191220144925-273a86a3f0d0
 	github.com/open-ness/common/proxy v0.0.0-20191220144925-273a86a3f0d0
 	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v1.5.1
 	github.com/satori/go.uuid v1.2.0
-	golang.org/x/crypto v0.0.0-20190909091759-094676da4a83 // indirect
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/crypto v0.0.0-20190909091759-094676da4a83
 	golang.org/x/net v0.0.0-20190909003024-a7b16738d86b // indirect
-	golang.org/x/sync v0.0.0-20190423024810-112230192c58
-	golang.org/x/sys v0.0.0-20190910064555-bbd175535a8b // indirect
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
+	golang.org/x/sys v0.0.0-20200202164722-d101bd2416d5 // indirect
 	golang.org/x/text v0.3.2 // indirect
+	golang.org/x/time v0.0.0-20190921001708-c4c64cad1fd0
 	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55
 	google.golang.org/grpc v1.27.1
 	gopkg.in/square/go-jose.v2 v2.3.1
@@ -29,6 +32,7 @@ require (
 	k8s.io/client-go v0.0.0-20190501104856-ef81ee0960bf
 	k8s.io/utils v0.0.0-20190520173318-324c5df7d3f0 // indirect
 	sigs.k8s.io/node-feature-discovery v0.5.0
+	sigs.k8s.io/yaml v1.1.0
 )
 
 replace golang.org/x/sys => golang.org/x/sys v0.0.0-20190226215855-775f8194d0f9
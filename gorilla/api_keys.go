@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// authenticateAPIKey authenticates a request with an API key and returns the
+// name of its user and the roles it grants, and records that the key was
+// used.
+func authenticateAPIKey(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	uses *apiKeyUses,
+	key string,
+) (string, []string, error) {
+	k, user, err := cce.AuthenticateAPIKey(ctx, ps, key)
+	if err != nil {
+		return "", nil, err
+	}
+	if k == nil {
+		return "", nil, errors.New("invalid API key")
+	}
+
+	uses.record(ps, k)
+
+	return user.Name, k.Scope(user), nil
+}
+
+// apiKeyUses records when API keys were last used, to the minute, so that a
+// busy client doesn't update its key on every request. The uses are recorded
+// in the background, so that requests don't wait for them.
+type apiKeyUses struct {
+	mu sync.Mutex
+
+	// recorded are the minutes recorded last for each key
+	recorded map[string]time.Time
+}
+
+// record records that k was used now unless a use in the same minute was
+// already recorded.
+func (u *apiKeyUses) record(ps cce.PersistenceService, k *cce.APIKey) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	if !k.LastUsedAt.Before(now) {
+		return
+	}
+
+	u.mu.Lock()
+	if !u.recorded[k.ID].Before(now) {
+		u.mu.Unlock()
+		return
+	}
+	if u.recorded == nil {
+		u.recorded = make(map[string]time.Time)
+	}
+	for id, t := range u.recorded {
+		if t.Before(now) {
+			delete(u.recorded, id)
+		}
+	}
+	u.recorded[k.ID] = now
+	u.mu.Unlock()
+
+	go func() {
+		if err := recordAPIKeyUse(context.Background(), ps, k.ID, now); err != nil {
+			log.Errf("Error recording use of API key %s: %v", k.ID, err)
+
+			// Let the next use record it
+			u.mu.Lock()
+			delete(u.recorded, k.ID)
+			u.mu.Unlock()
+		}
+	}()
+}
+
+// recordAPIKeyUse sets the time an API key was last used. The key is read
+// again and updated at the version read, so that changes made to it since it
+// authenticated the request are kept.
+func recordAPIKeyUse(ctx context.Context, ps cce.PersistenceService, id string, t time.Time) error {
+	e, err := ps.Read(ctx, id, &cce.APIKey{})
+	if err != nil || e == nil {
+		return err
+	}
+	k := e.(*cce.APIKey)
+	if !k.LastUsedAt.Before(t) {
+		return nil
+	}
+
+	k.LastUsedAt = t
+	return ps.BulkUpdate(ctx, []cce.Persistable{k})
+}
+
+func toSwaggerAPIKeySummary(k *cce.APIKey) swagger.APIKeySummary {
+	summary := swagger.APIKeySummary{
+		ID:        k.ID,
+		Name:      k.Name,
+		Roles:     k.Roles,
+		CreatedAt: k.CreatedAt,
+		ExpiresAt: k.ExpiresAt,
+	}
+	if !k.LastUsedAt.IsZero() {
+		lastUsedAt := k.LastUsedAt
+		summary.LastUsedAt = &lastUsedAt
+	}
+
+	return summary
+}
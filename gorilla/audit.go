@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/pkg/errors"
+)
+
+// auditRecorder is a PersistenceService that records an audit event for each
+// entity it creates, updates or deletes. The events are written by write, so
+// that they are only persisted for successful requests, and published to the
+// event bus by publish once the request's transaction is committed.
+type auditRecorder struct {
+	cce.PersistenceService
+
+	// events and nodeIDs are shared with the recorders of nested
+	// transactions
+	events  *[]*cce.AuditEvent
+	nodeIDs *[]string
+}
+
+func newAuditRecorder(ps cce.PersistenceService) *auditRecorder {
+	return &auditRecorder{
+		PersistenceService: ps,
+		events:             new([]*cce.AuditEvent),
+		nodeIDs:            new([]string),
+	}
+}
+
+func (a *auditRecorder) record(action string, before, after cce.Persistable) error {
+	e, err := cce.NewAuditEvent(action, before, after)
+	if err != nil {
+		return err
+	}
+	*a.events = append(*a.events, e)
+
+	var nodeID string
+	for _, entity := range []cce.Persistable{after, before} {
+		if ne, ok := entity.(cce.NodeEntity); ok {
+			nodeID = ne.GetNodeID()
+			break
+		}
+	}
+	*a.nodeIDs = append(*a.nodeIDs, nodeID)
+	return nil
+}
+
+// Create creates an entity and records its creation.
+func (a *auditRecorder) Create(ctx context.Context, e cce.Persistable) error {
+	if err := a.PersistenceService.Create(ctx, e); err != nil {
+		return err
+	}
+	return a.record(cce.AuditActionCreate, nil, e)
+}
+
+// BulkUpdate updates entities and records their changes.
+func (a *auditRecorder) BulkUpdate(ctx context.Context, es []cce.Persistable) error {
+	var befores []cce.Persistable
+	for _, e := range es {
+		before, err := a.PersistenceService.Read(ctx, e.GetID(), zeroValue(e))
+		if err != nil {
+			return err
+		}
+		befores = append(befores, before)
+	}
+
+	if err := a.PersistenceService.BulkUpdate(ctx, es); err != nil {
+		return err
+	}
+
+	for i, e := range es {
+		if err := a.record(cce.AuditActionUpdate, befores[i], e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete deletes an entity and records its deletion.
+func (a *auditRecorder) Delete(ctx context.Context, id string, zv cce.Persistable) (bool, error) {
+	before, err := a.PersistenceService.Read(ctx, id, zeroValue(zv))
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := a.PersistenceService.Delete(ctx, id, zv)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	return true, a.record(cce.AuditActionDelete, before, nil)
+}
+
+// WithTx runs f in a transaction, recording its changes unless they are
+// rolled back.
+func (a *auditRecorder) WithTx(ctx context.Context, f func(tx cce.PersistenceService) error) error {
+	n := len(*a.events)
+	err := a.PersistenceService.WithTx(ctx, func(tx cce.PersistenceService) error {
+		return f(&auditRecorder{PersistenceService: tx, events: a.events, nodeIDs: a.nodeIDs})
+	})
+	if err != nil {
+		*a.events = (*a.events)[:n]
+		*a.nodeIDs = (*a.nodeIDs)[:n]
+	}
+	return err
+}
+
+// write persists the recorded events of a request.
+func (a *auditRecorder) write(r *http.Request) error {
+	actor, _ := r.Context().Value(contextKey("actor")).(string)
+
+	for _, e := range *a.events {
+		e.Actor = actor
+		e.Request = r.Method + " " + r.URL.Path
+		e.SourceIP = clientIP(r)
+		if err := a.PersistenceService.Create(r.Context(), e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entityEventTypes are the types of the events published for audit actions.
+var entityEventTypes = map[string]string{
+	cce.AuditActionCreate: events.TypeEntityCreated,
+	cce.AuditActionUpdate: events.TypeEntityUpdated,
+	cce.AuditActionDelete: events.TypeEntityDeleted,
+}
+
+// publish publishes the recorded changes of a request, which must have been
+// written, to the event bus.
+func (a *auditRecorder) publish(ctx context.Context) {
+	for i, e := range *a.events {
+		publish(ctx, &events.Event{
+			Type:       entityEventTypes[e.Action],
+			NodeID:     (*a.nodeIDs)[i],
+			EntityType: e.EntityType,
+			EntityID:   e.EntityID,
+			Actor:      e.Actor,
+		})
+	}
+}
+
+// zeroValue returns a new zero value of the type of an entity.
+func zeroValue(e cce.Persistable) cce.Persistable {
+	return reflect.New(reflect.TypeOf(e).Elem()).Interface().(cce.Persistable)
+}
+
+// parseAuditTimeRange parses the since and until query parameters, which are
+// RFC 3339 times, to filters on the time of audit events. The parameters are
+// removed from the query.
+func parseAuditTimeRange(query url.Values) ([]cce.Filter, error) {
+	var fs []cce.Filter
+	for param, op := range map[string]cce.FilterOp{
+		"since": cce.FilterOpGreaterOrEqual,
+		"until": cce.FilterOpLess,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		query.Del(param)
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.Errorf("bad %s time %q", param, value)
+		}
+		fs = append(fs, cce.Filter{
+			Field: "time",
+			Op:    op,
+			Value: strconv.FormatInt(cce.AuditTime(t), 10),
+		})
+	}
+
+	return fs, nil
+}
+
+func toSwaggerAuditEvent(e *cce.AuditEvent) swagger.AuditEvent {
+	event := swagger.AuditEvent{
+		ID:         e.ID,
+		Time:       e.GetTime(),
+		Actor:      e.Actor,
+		Action:     e.Action,
+		Request:    e.Request,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		SourceIP:   e.SourceIP,
+	}
+	if len(e.Diff) > 0 {
+		event.Diff = make(map[string]swagger.AuditChange)
+		for field, change := range e.Diff {
+			event.Diff[field] = swagger.AuditChange{Before: change.Before, After: change.After}
+		}
+	}
+
+	return event
+}
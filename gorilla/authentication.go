@@ -4,11 +4,13 @@
 package gorilla
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
 
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/jose"
 )
 
 func authenticate(w http.ResponseWriter, r *http.Request) {
@@ -24,33 +26,179 @@ func authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject the logins of a user locked out on the client after failed
+	// logins
+	lockoutKey := u.Username + "|" + clientIP(r)
+	if ctrl.LoginLockout != nil {
+		if retryAfter := ctrl.LoginLockout.Check(lockoutKey); retryAfter > 0 {
+			log.Debugf("Login attempt for locked out user '%s' from %s", u.Username, clientIP(r))
+			loginsLockedOut.Inc()
+			writeTooManyRequests(w, retryAfter)
+			return
+		}
+	}
+
 	// Verify the user name and password
-	if u.Username != ctrl.AdminCreds.Username {
-		log.Debugf("Unsuccessful login attempt for user '%s'", u.Username)
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+	user, err := cce.AuthenticateUser(r.Context(), ctrl.PersistenceService, u.Username, u.Password)
+	if err != nil {
+		log.Errf("Error authenticating user '%s': %v", u.Username, err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	if u.Password != ctrl.AdminCreds.Password {
+	if user == nil {
 		log.Debugf("Unsuccessful login attempt for user '%s'", u.Username)
+		loginFailures.Inc()
+		if ctrl.LoginLockout != nil {
+			if d := ctrl.LoginLockout.Fail(lockoutKey); d > 0 {
+				log.Noticef("Locked out user '%s' from %s for %s after failed logins", u.Username, clientIP(r), d)
+			}
+		}
 		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
 	log.Debugf("Successfully authenticated user: %s", u.Username)
+	if ctrl.LoginLockout != nil {
+		ctrl.LoginLockout.Succeed(lockoutKey)
+	}
+
+	writeTokens(w, ctrl, user)
+}
+
+// refresh exchanges a refresh token for a new access token and refresh token.
+// The roles of the new access token are the current roles of the user. The
+// exchanged refresh token is revoked, so that each can only be used once.
+func refresh(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctrl = r.Context().Value(contextKey("controller")).(*cce.Controller)
+		body = r.Context().Value(contextKey("body")).([]byte)
+	)
+
+	// Extract the refresh token from JSON
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Validate the refresh token
+	claims, err := ctrl.TokenService.ValidateRefresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		log.Debugf("Invalid refresh token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Fetch the user, who may have been deleted since
+	user, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, claims.Subject)
+	if err != nil {
+		log.Errf("Error reading user '%s': %v", claims.Subject, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		log.Debugf("Refresh token of deleted user '%s'", claims.Subject)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Revoke the exchanged refresh token. Of concurrent requests exchanging
+	// it, only the one that revokes it is issued tokens.
+	revoked, err := ctrl.TokenService.Revoke(r.Context(), claims)
+	if err != nil {
+		log.Errf("Error revoking refresh token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !revoked {
+		log.Debugf("Refresh token of user '%s' was already exchanged", claims.Subject)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
 
-	// Create an auth token
-	token, err := ctrl.TokenService.Issue()
+	writeTokens(w, ctrl, user)
+}
+
+// logout revokes the access token of the request and the refresh token in the
+// payload, if any.
+func logout(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctrl      = r.Context().Value(contextKey("controller")).(*cce.Controller)
+		body      = r.Context().Value(contextKey("body")).([]byte)
+		claims, _ = r.Context().Value(contextKey("claims")).(*jose.Claims)
+	)
+
+	// API keys are revoked through their own endpoint
+	if claims == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Extract the refresh token from JSON
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Revoke the refresh token, which must be the user's
+	if req.RefreshToken != "" {
+		refreshClaims, err := ctrl.TokenService.ValidateRefresh(r.Context(), req.RefreshToken)
+		if err != nil || refreshClaims.Subject != claims.Subject {
+			log.Debugf("Invalid refresh token: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if _, err = ctrl.TokenService.Revoke(r.Context(), refreshClaims); err != nil {
+			log.Errf("Error revoking refresh token: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Revoke the access token
+	if _, err := ctrl.TokenService.Revoke(r.Context(), claims); err != nil {
+		log.Errf("Error revoking access token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	log.Debugf("Logged out user: %s", claims.Subject)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeTokens issues an access token and a refresh token for a user and
+// responds with them.
+func writeTokens(w http.ResponseWriter, ctrl *cce.Controller, user *cce.User) {
+	// Create the auth tokens
+	token, err := ctrl.TokenService.Issue(user.Name, user.Roles)
+	if err != nil {
+		log.Errf("Error signing authentication token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	refreshToken, err := ctrl.TokenService.IssueRefresh(user.Name)
 	if err != nil {
-		log.Debugf("Error signing authentication token: %v", err)
+		log.Errf("Error signing refresh token: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	// Wrap auth token in JSON
+	// Wrap auth tokens in JSON
 	bytes, err := json.Marshal(
 		struct {
-			Token string `json:"token"`
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
+			ExpiresIn    int    `json:"expires_in"`
 		}{
 			token,
+			refreshToken,
+			int(ctrl.TokenService.GetAccessTokenLifetime().Seconds()),
 		})
 	if err != nil {
 		log.Errf("Error marshaling authentication token: %v", err)
@@ -58,43 +206,82 @@ func authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Respond with status code 201
-	w.WriteHeader(http.StatusCreated)
-
-	// Return JSON-encoded auth token
+	// Respond with status code 201 and the JSON-encoded auth tokens
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	if _, err = w.Write(bytes); err != nil {
 		log.Errf("Error writing response: %v", err)
 	}
 }
 
 // requireAuthHandler is a handler that only allows HTTP requests with a valid
-// JSON Web Token issued by the Controller Token Authentication service.
-func requireAuthHandler(next http.Handler) http.Handler {
+// JSON Web Token issued by the Controller Token Authentication service, a
+// valid API key in an "ApiKey" Authorization header, or without an
+// Authorization header a verified client certificate of a user, whose roles
+// have the permission required by the route.
+func requireAuthHandler(next http.Handler, uses *apiKeyUses) http.Handler { //nolint:gocyclo
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
+		var (
+			subject string
+			roles   []string
+			claims  *jose.Claims
+			err     error
+		)
+
 		// Get the Authorization header
 		auth := r.Header.Get("Authorization")
-		if auth == "" {
+		switch {
+		case auth == "" && r.TLS != nil && len(r.TLS.VerifiedChains) > 0:
+			// Authenticate the client certificate
+			subject, roles, err = authenticateCertificate(r.Context(), ctrl, r.TLS)
+		case auth == "":
 			w.WriteHeader(http.StatusUnauthorized)
 			return
-		}
+		default:
+			// Extract the auth scheme and token
+			bearer := strings.Split(auth, " ")
+			if len(bearer) != 2 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
 
-		// Extract the auth token
-		bearer := strings.Split(auth, " ")
-		if len(bearer) != 2 {
+			switch {
+			case strings.EqualFold(bearer[0], "ApiKey"):
+				// Authenticate the API key
+				subject, roles, err = authenticateAPIKey(r.Context(), ctrl.PersistenceService, uses, bearer[1])
+			case strings.EqualFold(bearer[0], "Bearer"):
+				if claims, err = ctrl.TokenService.Validate(r.Context(), bearer[1]); err == nil {
+					// The auth token is valid
+					subject, roles = claims.Subject, claims.Roles
+				}
+			default:
+				log.Debugf("Unsupported auth scheme %q", bearer[0])
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		if err != nil {
+			log.Debugf("Unsuccessful authentication: %v", err)
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		// Validate the auth token
-		err := ctrl.TokenService.Validate(bearer[1])
-		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
+		// Check the permission of the user's roles
+		if !cce.HasPermission(roles, requiredPermission(r)) {
+			log.Debugf("User '%s' is not permitted to %s %s", subject, r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusForbidden)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		// Inject the user as the actor of any changes, their roles and the
+		// claims of their token, if any
+		ctx := context.WithValue(r.Context(), contextKey("actor"), subject)
+		ctx = context.WithValue(ctx, contextKey("roles"), roles)
+		if claims != nil {
+			ctx = context.WithValue(ctx, contextKey("claims"), claims)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/pkg/errors"
+)
+
+// authenticateCertificate authenticates the verified client certificate of a
+// TLS connection. It returns the subject and roles of the certificate, or an
+// error if it maps to no user or role.
+func authenticateCertificate(
+	ctx context.Context,
+	ctrl *cce.Controller,
+	state *tls.ConnectionState,
+) (string, []string, error) {
+	chain := state.VerifiedChains[0]
+	cert := chain[0]
+
+	// Tell certificates of the Controller CA apart from those of operator CAs
+	caChain, err := ctrl.AuthorityService.CAChain()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "unable to get CA chain")
+	}
+	controllerCA := bytes.Equal(chain[len(chain)-1].Raw, caChain[len(caChain)-1].Raw)
+
+	subject, roles, err := cce.AuthenticateCertificate(ctx, ctrl.PersistenceService, cert, controllerCA)
+	if err != nil {
+		return "", nil, err
+	}
+	if subject == "" {
+		return "", nil, errors.Errorf("certificate %q is not a user's", cert.Subject)
+	}
+
+	return subject, roles, nil
+}
+
+// parseCSR decodes a PEM-encoded certificate signing request and checks its
+// signature.
+func parseCSR(csrPEM string) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("csr must be a PEM-encoded certificate request")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse csr")
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return nil, errors.Wrap(err, "invalid csr signature")
+	}
+
+	return csr, nil
+}
+
+func toSwaggerUserCertificate(cert *x509.Certificate, caChain []*x509.Certificate) swagger.UserCertificate {
+	encode := func(c *x509.Certificate) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}))
+	}
+
+	userCert := swagger.UserCertificate{
+		Certificate: encode(cert),
+		CAChain:     []string{},
+		ExpiresAt:   cert.NotAfter,
+	}
+	for _, caCert := range caChain {
+		userCert.CAChain = append(userCert.CAChain, encode(caCert))
+	}
+
+	return userCert
+}
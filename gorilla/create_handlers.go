@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/operation"
 )
 
 func handleCreateNodesApps(ctx context.Context, ps cce.PersistenceService, e cce.Persistable) error {
@@ -29,16 +30,18 @@ func handleCreateNodesApps(ctx context.Context, ps cce.PersistenceService, e cce
 	}
 	defer disconnectNode(nodeCC)
 
+	operation.Progress(ctx, "Deploying app to node")
 	if err := nodeCC.AppDeploySvcCli.Deploy(ctx, app.(*cce.App)); err != nil {
 		return err
 	}
 
 	if ctrl.OrchestrationMode == cce.OrchestrationModeKubernetes ||
 		ctrl.OrchestrationMode == cce.OrchestrationModeKubernetesOVN {
+		operation.Progress(ctx, "Creating Kubernetes deployment")
 		err := ctrl.KubernetesClient.Deploy(
 			ctx,
 			e.(*cce.NodeApp).GetNodeID(),
-			toK8SApp(app.(*cce.App)))
+			app.(*cce.App).K8SApp())
 		if err != nil {
 			return err
 		}
@@ -98,14 +101,9 @@ func handleCreateNodesDNSConfigsWithAliases(
 	}
 	defer disconnectNode(nodeCC)
 
+	operation.Progress(ctx, "Setting DNS records")
 	for _, alias := range dnsAliases {
-		record := &cce.DNSARecord{
-			Name:        alias.(*cce.DNSConfigAppAlias).AppID,
-			Description: alias.(*cce.DNSConfigAppAlias).Description,
-			IPs:         []string{alias.(*cce.DNSConfigAppAlias).AppID},
-		}
-
-		if err := nodeCC.DNSSvcCli.SetA(ctx, record); err != nil {
+		if err := nodeCC.DNSSvcCli.SetA(ctx, alias.(*cce.DNSConfigAppAlias).ARecord()); err != nil {
 			return err
 		}
 	}
@@ -7,6 +7,10 @@ import (
 	"context"
 
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/open-ness/edgecontroller/operation"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/pkg/errors"
 )
 
 func handleDeleteNodesApps(ctx context.Context, ps cce.PersistenceService, e cce.Persistable) error {
@@ -32,6 +36,7 @@ func handleDeleteNodesApps(ctx context.Context, ps cce.PersistenceService, e cce
 	// if kubernetes un-deploy application
 	if ctrl.OrchestrationMode == cce.OrchestrationModeKubernetes ||
 		ctrl.OrchestrationMode == cce.OrchestrationModeKubernetesOVN {
+		operation.Progress(ctx, "Deleting Kubernetes deployment")
 		if err = ctrl.KubernetesClient.Undeploy(
 			ctx,
 			e.(*cce.NodeApp).NodeID,
@@ -41,6 +46,7 @@ func handleDeleteNodesApps(ctx context.Context, ps cce.PersistenceService, e cce
 		}
 	}
 
+	operation.Progress(ctx, "Undeploying app from node")
 	err = nodeCC.AppDeploySvcCli.Undeploy(ctx, app.GetID())
 
 	return err
@@ -94,14 +100,9 @@ func handleDeleteNodesDNSConfigsWithAliases(
 	}
 	defer disconnectNode(nodeCC)
 
+	operation.Progress(ctx, "Deleting DNS records")
 	for _, alias := range dnsAliases {
-		record := &cce.DNSARecord{
-			Name:        alias.(*cce.DNSConfigAppAlias).AppID,
-			Description: alias.(*cce.DNSConfigAppAlias).Description,
-			IPs:         []string{alias.(*cce.DNSConfigAppAlias).AppID},
-		}
-
-		if err := nodeCC.DNSSvcCli.DeleteA(ctx, record); err != nil {
+		if err := nodeCC.DNSSvcCli.DeleteA(ctx, alias.(*cce.DNSConfigAppAlias).ARecord()); err != nil {
 			return err
 		}
 	}
@@ -120,3 +121,48 @@ func handleDeleteNodesDNSConfigsWithAliases(
 
 	return nil
 }
+
+// handleDeleteTrafficPolicies detaches a traffic policy that is about to be
+// deleted from the network interfaces using it. Interfaces are reset to an
+// empty policy on the node and their nodes_network_interfaces_traffic_policies
+// records are removed only for the nodes where that succeeded. Apps cannot be
+// detached this way; checkDBDeleteTrafficPolicies rejects the delete instead.
+func handleDeleteTrafficPolicies(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	id string,
+) ([]swagger.NodeStatus, error) {
+	nodeIDs, attachments, err := getTrafficPolicyAttachments(ctx, ps, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl := getController(ctx)
+	nodePort := ctrl.ELAPort
+	if nodePort == "" {
+		nodePort = defaultELAPort
+	}
+
+	return pushToNodes(ctx, ps, nodeIDs, nodePort, func(nodeCC *node.ClientConn, nodeID string) []error {
+		var errs []error
+		for _, nitp := range attachments[nodeID].ifacePolicies {
+			// set no policy
+			if err := nodeCC.IfacePolicySvcCli.Set(ctx, nitp.NetworkInterfaceID, &cce.TrafficPolicy{}); err != nil {
+				errs = append(errs, errors.Wrapf(err, "interface %s", nitp.NetworkInterfaceID))
+				continue
+			}
+
+			ok, err := ps.Delete(ctx, nitp.ID, &cce.NodeInterfaceTrafficPolicy{})
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "interface %s", nitp.NetworkInterfaceID))
+				continue
+			}
+			if !ok {
+				errs = append(errs, errors.Errorf(
+					"interface %s: did not delete 1 record from nodes_network_interfaces_traffic_policies",
+					nitp.NetworkInterfaceID))
+			}
+		}
+		return errs
+	}), nil
+}
@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/plan"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/pkg/errors"
+)
+
+// parseDryRun parses the dry_run query parameter of a request, which is false
+// if it is missing.
+func parseDryRun(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("dry_run")
+	if value == "" {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Errorf("bad dry_run %q", value)
+	}
+	return dryRun, nil
+}
+
+// serveDryRun serves a dry run of a request, which is handled in a DB
+// transaction that is always rolled back, with its calls to nodes recorded
+// rather than made. If the request would succeed the response is the plan of
+// the rows it would write and the calls it would make, and otherwise the
+// error it would fail with.
+func serveDryRun(controller *cce.Controller, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	rec := &responseRecorder{header: make(http.Header)}
+	calls := &plan.Recorder{}
+	p := swagger.Plan{
+		Writes: []swagger.PlannedWrite{},
+		Calls:  []swagger.PlannedCall{},
+	}
+
+	err := controller.PersistenceService.WithTx(
+		r.Context(),
+		func(tx cce.PersistenceService) error {
+			audit := newAuditRecorder(tx)
+			txController := *controller
+			txController.PersistenceService = audit
+
+			// Nothing is committed, so the hooks to run after the commit
+			// are dropped
+			ctx := context.WithValue(r.Context(), contextKey("controller"), &txController)
+			ctx = context.WithValue(ctx, contextKey("committed"), new([]func()))
+			ctx = plan.WithRecorder(ctx, calls)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			for i, e := range *audit.events {
+				p.Writes = append(p.Writes, toSwaggerPlannedWrite(e, (*audit.nodeIDs)[i]))
+			}
+			return errRollback
+		})
+	if err != errRollback {
+		log.Errf("Error dry running %s %s in a transaction: %v", r.Method, r.URL.Path, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if rec.status >= http.StatusBadRequest {
+		rec.flush(w)
+		return
+	}
+
+	p.Status = rec.status
+	if p.Status == 0 {
+		p.Status = http.StatusOK
+	}
+	if body := rec.body.Bytes(); len(body) != 0 {
+		if json.Valid(body) {
+			p.Response = body
+		} else if p.Response, err = json.Marshal(string(body)); err != nil {
+			log.Errf("Error marshaling response: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	for _, c := range calls.Calls() {
+		p.Calls = append(p.Calls, swagger.PlannedCall{
+			NodeID:  c.NodeID,
+			Service: c.Service,
+			Method:  c.Method,
+			Request: c.Request,
+		})
+	}
+
+	// Marshal the response object to JSON
+	planJSON, err := json.Marshal(p)
+	if err != nil {
+		log.Errf("Error marshaling plan: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(planJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+func toSwaggerPlannedWrite(e *cce.AuditEvent, nodeID string) swagger.PlannedWrite {
+	write := swagger.PlannedWrite{
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		NodeID:     nodeID,
+	}
+	if len(e.Diff) > 0 {
+		write.Diff = make(map[string]swagger.AuditChange)
+		for field, change := range e.Diff {
+			write.Diff[field] = swagger.AuditChange{Before: change.Before, After: change.After}
+		}
+	}
+
+	return write
+}
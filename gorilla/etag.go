@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/pkg/errors"
+)
+
+// entityTag returns the entity tag of a persisted entity, which is its quoted
+// resource version, or "" if there is no entity.
+func entityTag(e cce.Persistable) string {
+	v, ok := e.(cce.Versioned)
+	if !ok || v.GetResourceVersion() == 0 {
+		return ""
+	}
+	return strconv.Quote(strconv.FormatInt(v.GetResourceVersion(), 10))
+}
+
+// replacedEntityTag returns the entity tag of a sub-resource such as a node's
+// DNS configuration or policy. These are replaced with a new entity on every
+// update rather than updated, so the tag is qualified with the entity's ID.
+func replacedEntityTag(e cce.Persistable) string {
+	v, ok := e.(cce.Versioned)
+	if !ok || v.GetResourceVersion() == 0 {
+		return ""
+	}
+	return strconv.Quote(fmt.Sprintf("%s.%d", e.GetID(), v.GetResourceVersion()))
+}
+
+// setETag sets the ETag header of the response, unless tag is "".
+func setETag(w http.ResponseWriter, tag string) {
+	if tag != "" {
+		w.Header().Set("ETag", tag)
+	}
+}
+
+// checkIfMatch checks the If-Match header of the request against the entity
+// tag of the current resource, which is "" if there is none. If the
+// precondition fails it responds with 412 Precondition Failed and returns
+// false.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, tag string) bool {
+	values, ok := r.Header["If-Match"]
+	if !ok {
+		return true
+	}
+
+	for _, value := range values {
+		for _, t := range strings.Split(value, ",") {
+			t = strings.TrimSpace(t)
+			if tag != "" && (t == "*" || t == tag) {
+				return true
+			}
+		}
+	}
+
+	log.Debugf("If-Match %v does not match %s", values, tag)
+	w.WriteHeader(http.StatusPreconditionFailed)
+	return false
+}
+
+// ifMatchVersion returns the resource version of e that an update or delete
+// must be conditional on, which is 0 (unconditional) if the request has no
+// If-Match header.
+func ifMatchVersion(r *http.Request, e cce.Persistable) int64 {
+	if _, ok := r.Header["If-Match"]; !ok {
+		return 0
+	}
+	if v, ok := e.(cce.Versioned); ok {
+		return v.GetResourceVersion()
+	}
+	return 0
+}
+
+// isVersionConflict returns whether err is caused by an update or delete
+// conditional on a resource version that is no longer current.
+func isVersionConflict(err error) bool {
+	return errors.Cause(err) == cce.ErrVersionConflict
+}
+
+// checkIfMatchFilter checks the If-Match header of the request against the
+// entity tag of a replaced sub-resource, which is fetched from persistence
+// with the filters. If the precondition fails or the sub-resource cannot be
+// fetched it responds with an error and returns false.
+func checkIfMatchFilter(
+	w http.ResponseWriter,
+	r *http.Request,
+	ps cce.PersistenceService,
+	zv cce.Filterable,
+	fs []cce.Filter,
+) bool {
+	if _, ok := r.Header["If-Match"]; !ok {
+		return true
+	}
+
+	es, err := ps.Filter(r.Context(), zv, fs)
+	if err != nil {
+		log.Errf("Error filtering %s: %v", zv.GetTableName(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	var tag string
+	if len(es) == 1 {
+		tag = replacedEntityTag(es[0])
+	}
+	return checkIfMatch(w, r, tag)
+}
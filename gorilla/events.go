@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/plan"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/pkg/errors"
+)
+
+// eventsKeepAlive is the interval of the comments sent on idle event streams
+// to keep proxies from closing them.
+const eventsKeepAlive = 15 * time.Second
+
+// adminEntityTypes are the types of entities whose events are only streamed
+// to admins, like the routes that change them.
+var adminEntityTypes = map[string]bool{
+	(&cce.User{}).GetTableName():    true,
+	(&cce.APIKey{}).GetTableName():  true,
+	(&cce.Webhook{}).GetTableName(): true,
+}
+
+// publish publishes an event to the bus of the controller, if any. The actor
+// of the request is the actor of the event, unless it is set. Nothing is
+// published in dry runs, as nothing they do takes effect.
+func publish(ctx context.Context, e *events.Event) {
+	ctrl := getController(ctx)
+	if ctrl.Events == nil || plan.FromContext(ctx) != nil {
+		return
+	}
+
+	if e.Actor == "" {
+		e.Actor, _ = ctx.Value(contextKey("actor")).(string)
+	}
+	ctrl.Events.Publish(e)
+}
+
+// publishNodeStatuses publishes the results of applying a policy or DNS
+// config to nodes.
+func publishNodeStatuses(ctx context.Context, e cce.Persistable, statuses []swagger.NodeStatus) {
+	for _, status := range statuses {
+		publish(ctx, &events.Event{
+			Type:       events.TypePolicyApplied,
+			NodeID:     status.ID,
+			EntityType: e.GetTableName(),
+			EntityID:   e.GetID(),
+			Data:       events.PolicyResult{Status: status.Status, Errors: status.Errors},
+		})
+	}
+}
+
+// publishNodeResult publishes the result of applying a policy to a node.
+func publishNodeResult(ctx context.Context, e cce.Persistable, nodeID string, err error) {
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	publishNodeStatuses(ctx, e, []swagger.NodeStatus{toNodeStatus(nodeID, errs)})
+}
+
+// parseEventFilter parses the node_id, entity_type and type query parameters
+// to an event filter. The type parameter may be repeated.
+func parseEventFilter(query url.Values) events.Filter {
+	return events.Filter{
+		NodeID:     query.Get("node_id"),
+		EntityType: query.Get("entity_type"),
+		Types:      query["type"],
+	}
+}
+
+// parseLastEventID parses the ID of the last event a client received, sent by
+// EventSource clients in the Last-Event-ID header when they reconnect or in
+// the last_event_id query parameter. It returns false if there is none.
+func parseLastEventID(r *http.Request) (uint64, bool, error) {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get("last_event_id")
+	}
+	if s == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, false, errors.Errorf("bad last event ID %q", s)
+	}
+	return id, true, nil
+}
+
+// subscribeEvents subscribes to the events matching a filter, returning the
+// retained events after lastID first if the client resumes.
+func subscribeEvents(
+	bus *events.Bus,
+	filter events.Filter,
+	lastID uint64,
+	resume bool,
+) (*events.Subscription, []*events.Event) {
+	if resume {
+		return bus.SubscribeAfter(filter, lastID)
+	}
+	return bus.Subscribe(filter), nil
+}
+
+// canStreamEvent returns whether the caller of a request may receive an
+// event.
+func canStreamEvent(r *http.Request, e *events.Event) bool {
+	if !adminEntityTypes[e.EntityType] {
+		return true
+	}
+	roles, _ := r.Context().Value(contextKey("roles")).([]string)
+	return cce.HasPermission(roles, cce.PermissionAdmin)
+}
+
+// writeEvent writes an event in the server-sent events format.
+func writeEvent(w http.ResponseWriter, e *events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
 )
 
 func handleGetNodes(
@@ -40,6 +41,12 @@ func handleGetNodes(
 
 func handleGetNodesApps(ctx context.Context, ps cce.PersistenceService, e cce.Persistable) (cce.RespEntity, error) {
 	ctrl := getController(ctx)
+	if e.(*cce.NodeApp).Deploying {
+		return &cce.NodeAppResp{
+			NodeApp: *e.(*cce.NodeApp),
+			Status:  cce.Deploying.String(),
+		}, nil
+	}
 	nodePort := ctrl.EVAPort
 	if nodePort == "" {
 		nodePort = defaultEVAPort
@@ -55,31 +62,32 @@ func handleGetNodesApps(ctx context.Context, ps cce.PersistenceService, e cce.Pe
 	if err != nil {
 		return nil, err
 	}
-
-	if ctrl.OrchestrationMode == cce.OrchestrationModeNative {
-		return &cce.NodeAppResp{
-			NodeApp: *e.(*cce.NodeApp),
-			Status:  s.String(),
-		}, nil
-	}
+	appStatus := s.String()
 
 	// Kubernetes status
-	// For Unknown, Deploying and Error return immediately
-	switch s {
-	case cce.Unknown, cce.Deploying, cce.Error:
-		return &cce.NodeAppResp{
-			NodeApp: *e.(*cce.NodeApp),
-			Status:  s.String(),
-		}, nil
+	// For Unknown, Deploying and Error use the node's status
+	if ctrl.OrchestrationMode != cce.OrchestrationModeNative {
+		switch s {
+		case cce.Unknown, cce.Deploying, cce.Error:
+		default:
+			k8sStatus, err := ctrl.KubernetesClient.Status(ctx, e.(*cce.NodeApp).NodeID, e.(*cce.NodeApp).AppID)
+			if err != nil {
+				return nil, err
+			}
+			appStatus = string(k8sStatus)
+		}
 	}
 
-	k8sStatus, err := ctrl.KubernetesClient.Status(ctx, e.(*cce.NodeApp).NodeID, e.(*cce.NodeApp).AppID)
-	if err != nil {
-		return nil, err
+	if ctrl.Events != nil {
+		ctrl.Events.PublishAppStatus(&events.Event{
+			NodeID:     e.(*cce.NodeApp).NodeID,
+			EntityType: e.GetTableName(),
+			EntityID:   e.GetID(),
+		}, e.(*cce.NodeApp).AppID, appStatus)
 	}
 
 	return &cce.NodeAppResp{
 		NodeApp: *e.(*cce.NodeApp),
-		Status:  string(k8sStatus),
+		Status:  appStatus,
 	}, nil
 }
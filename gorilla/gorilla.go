@@ -4,7 +4,9 @@
 package gorilla
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"runtime/debug"
@@ -127,7 +129,9 @@ func NewGorilla( //nolint:gocyclo
 	}
 
 	routes := map[string]http.HandlerFunc{
-		"POST     /auth": authenticate,
+		"POST     /auth":         authenticate,
+		"POST     /auth/refresh": refresh,
+		"POST     /auth/logout":  logout,
 
 		"GET      /nodes":           g.swagGETNodes,
 		"POST     /nodes":           g.swagPOSTNodes,
@@ -145,6 +149,9 @@ func NewGorilla( //nolint:gocyclo
 		"PATCH    /nodes/{node_id}/dns": g.swagPATCHNodeDNS,
 		"DELETE   /nodes/{node_id}/dns": g.swagDELETENodeDNS,
 
+		"GET      /dns_configs/{dns_config_id}": g.swagGETDNSConfigByID,
+		"PATCH    /dns_configs/{dns_config_id}": g.swagPATCHDNSConfigByID,
+
 		"GET      /nodes/{node_id}/interfaces":                g.swagGETInterfaces,
 		"PATCH    /nodes/{node_id}/interfaces":                g.swagPATCHInterfaces,
 		"GET      /nodes/{node_id}/interfaces/{interface_id}": g.swagGETInterfaceByID,
@@ -156,6 +163,38 @@ func NewGorilla( //nolint:gocyclo
 		"DELETE   /nodes/{node_id}/apps/{app_id}": g.swagDELETENodeAppByID,
 
 		"GET      /nodes/{node_id}/nfd": g.swagGETNodeNFDTags,
+
+		"GET      /drift":                 g.swagGETDrift,
+		"GET      /nodes/{node_id}/drift": g.swagGETNodeDrift,
+
+		"GET      /audit": g.swagGETAudit,
+
+		"GET      /events": g.swagGETEvents,
+
+		"GET      /users":        g.swagGETUsers,
+		"POST     /users":        g.swagPOSTUsers,
+		"GET      /users/{name}": g.swagGETUserByName,
+		"PATCH    /users/{name}": g.swagPATCHUserByName,
+		"DELETE   /users/{name}": g.swagDELETEUserByName,
+
+		"PATCH    /users/{name}/password": g.swagPATCHUserPassword,
+
+		"GET      /users/{name}/api_keys":              g.swagGETUserAPIKeys,
+		"POST     /users/{name}/api_keys":              g.swagPOSTUserAPIKeys,
+		"DELETE   /users/{name}/api_keys/{api_key_id}": g.swagDELETEUserAPIKeyByID,
+
+		"POST     /users/{name}/certificates": g.swagPOSTUserCertificates,
+
+		"GET      /webhooks":              g.swagGETWebhooks,
+		"POST     /webhooks":              g.swagPOSTWebhooks,
+		"GET      /webhooks/{webhook_id}": g.swagGETWebhookByID,
+		"PATCH    /webhooks/{webhook_id}": g.swagPATCHWebhookByID,
+		"DELETE   /webhooks/{webhook_id}": g.swagDELETEWebhookByID,
+
+		"GET      /webhooks/{webhook_id}/deliveries": g.swagGETWebhookDeliveries,
+
+		"GET      /operations":                g.swagGETOperations,
+		"GET      /operations/{operation_id}": g.swagGETOperationByID,
 	}
 
 	if controller.OrchestrationMode == cce.OrchestrationModeKubernetesOVN {
@@ -170,9 +209,15 @@ func NewGorilla( //nolint:gocyclo
 
 	for endpoint, handlerFunc := range routes {
 		split := strings.Fields(endpoint)
+		if opType, ok := operationTypes[split[0]+" "+split[1]]; ok {
+			handlerFunc = operationHandler(controller, opType, handlerFunc)
+		}
 		g.router.HandleFunc(split[1], handlerFunc).Methods(split[0])
 	}
 
+	// Count requests and observe their latency, including those that panic
+	g.router.Use(metricsHandler)
+
 	// Catch panics
 	g.router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -190,14 +235,19 @@ func NewGorilla( //nolint:gocyclo
 	// Limit size of all request payloads to prevent resource starvation
 	g.router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			r.Body = http.MaxBytesReader(w, r.Body, cce.MaxBodySize)
+			r.Body = http.MaxBytesReader(w, r.Body, cce.CurrentLimits().MaxBodySize)
 			next.ServeHTTP(w, r)
 		})
 	})
 
-	// Set a timeout on all requests to prevent resource starvation
+	// Set a timeout on all requests to prevent resource starvation, except
+	// for event streams which last until the client disconnects
 	g.router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Path == "/events" {
+				next.ServeHTTP(w, r)
+				return
+			}
 			ctx, cancel := context.WithTimeout(r.Context(), cce.MaxHTTPRequestTime)
 			defer cancel()
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -215,17 +265,25 @@ func NewGorilla( //nolint:gocyclo
 		})
 	})
 
-	// Require auth token for all endpoints except POST /auth
+	// Rate limit requests per client IP before authentication
+	g.router.Use(clientRateLimitHandler)
+
+	// Require auth token for all endpoints except POST /auth and POST
+	// /auth/refresh
+	apiKeyUses := &apiKeyUses{}
 	g.router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.RequestURI == "/auth" {
+			if r.RequestURI == "/auth" || r.RequestURI == "/auth/refresh" {
 				next.ServeHTTP(w, r)
 			} else {
-				requireAuthHandler(next).ServeHTTP(w, r)
+				requireAuthHandler(next, apiKeyUses).ServeHTTP(w, r)
 			}
 		})
 	})
 
+	// Rate limit requests per user after authentication
+	g.router.Use(userRateLimitHandler)
+
 	// Read and inject the body for POST and PATCH requests
 	g.router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -243,7 +301,8 @@ func NewGorilla( //nolint:gocyclo
 				// Scrub for the body payload for potentially sensitive authentication data
 				// (this only affects logging, not the actual request body)
 				// TODO: Log the JSON payload here but with the password field scrubbed
-				if r.URL.Path == "/auth" {
+				if strings.HasPrefix(r.URL.Path, "/auth") || strings.HasPrefix(r.URL.Path, "/users") ||
+					strings.HasPrefix(r.URL.Path, "/webhooks") {
 					body = []byte("***** REDACTED *****")
 				}
 
@@ -255,9 +314,203 @@ func NewGorilla( //nolint:gocyclo
 		})
 	})
 
+	// Run POST, PATCH and DELETE requests in a DB transaction that is rolled
+	// back if the response is an error, so each request is all-or-nothing
+	// until it calls nodes, which it does after the transaction. Token
+	// requests don't change resources, and revoke tokens outside the
+	// transaction. Retries of POST requests with an Idempotency-Key header
+	// get the response to the first request. Requests with dry_run=true are
+	// always rolled back and respond with what they would have done.
+	idempotency := &idempotencyKeys{}
+	g.router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case "POST", "PATCH", "DELETE":
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			dryRun, err := parseDryRun(r)
+			if err != nil {
+				log.Debugf("Error parsing dry run: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				if _, err = w.Write([]byte(err.Error())); err != nil {
+					log.Errf("Error writing response: %v", err)
+				}
+				return
+			}
+
+			if strings.HasPrefix(r.URL.Path, "/auth") {
+				if dryRun {
+					w.WriteHeader(http.StatusBadRequest)
+					if _, err = w.Write([]byte("Token requests cannot be dry run")); err != nil {
+						log.Errf("Error writing response: %v", err)
+					}
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Certificates are signed by the CA rather than written to the
+			// DB, so a dry run can't roll them back
+			if dryRun && strings.HasPrefix(r.URL.Path, "/users/") &&
+				strings.HasSuffix(r.URL.Path, "/certificates") {
+				w.WriteHeader(http.StatusBadRequest)
+				if _, err = w.Write([]byte("Certificate requests cannot be dry run")); err != nil {
+					log.Errf("Error writing response: %v", err)
+				}
+				return
+			}
+
+			if dryRun {
+				serveDryRun(controller, w, r, next)
+				return
+			}
+			serveInTx(controller, w, r, idempotency.handler(next))
+		})
+	})
+
 	return g
 }
 
+// errRollback rolls back the transaction of a request with an error response.
+var errRollback = errors.New("request failed, rolling back")
+
+// serveInTx serves a request in a DB transaction that is rolled back if the
+// response is an error. The changes of a successful request are written to
+// the audit log in the same transaction, and published to the event bus once
+// it is committed. The calls of the request to nodes are made once it is
+// committed, outside the transaction; see callNodes.
+func serveInTx(controller *cce.Controller, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	// The response is held back until the transaction is done
+	rec := &responseRecorder{header: make(http.Header)}
+	var (
+		audit     *auditRecorder
+		committed []func()
+		nodeCalls http.HandlerFunc
+	)
+	err := controller.PersistenceService.WithTx(
+		r.Context(),
+		func(tx cce.PersistenceService) error {
+			audit = newAuditRecorder(tx)
+			txController := *controller
+			txController.PersistenceService = audit
+
+			ctx := context.WithValue(r.Context(), contextKey("controller"), &txController)
+			ctx = context.WithValue(ctx, contextKey("committed"), &committed)
+			ctx = context.WithValue(ctx, contextKey("nodeCalls"), &nodeCalls)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			if rec.status >= http.StatusBadRequest {
+				return errRollback
+			}
+			return audit.write(r)
+		})
+	if err != nil && err != errRollback {
+		log.Errf("Error running %s %s in a transaction: %v", r.Method, r.URL.Path, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		audit.publish(r.Context())
+		for _, f := range committed {
+			f()
+		}
+		if nodeCalls != nil {
+			serveOutsideTx(controller, rec, r, nodeCalls)
+		}
+	}
+
+	rec.flush(w)
+}
+
+// serveOutsideTx serves the rest of a request outside a transaction, so each
+// change it makes is committed as it is made. The changes are written to the
+// audit log and published whatever the response, as they are not rolled back.
+func serveOutsideTx(controller *cce.Controller, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	audit := newAuditRecorder(controller.PersistenceService)
+	ctrl := *controller
+	ctrl.PersistenceService = audit
+	var committed []func()
+
+	ctx := context.WithValue(r.Context(), contextKey("controller"), &ctrl)
+	ctx = context.WithValue(ctx, contextKey("committed"), &committed)
+	ctx = context.WithValue(ctx, contextKey("nodeCalls"), (*http.HandlerFunc)(nil))
+	next.ServeHTTP(w, r.WithContext(ctx))
+
+	if err := audit.write(r); err != nil {
+		log.Errf("Error writing the audit log of %s %s: %v", r.Method, r.URL.Path, err)
+	}
+	audit.publish(r.Context())
+	for _, f := range committed {
+		f()
+	}
+}
+
+// afterCommit calls f once the transaction of a request is committed, or now
+// if the request doesn't run in a transaction.
+func afterCommit(ctx context.Context, f func()) {
+	committed, ok := ctx.Value(contextKey("committed")).(*[]func())
+	if !ok {
+		f()
+		return
+	}
+	*committed = append(*committed, f)
+}
+
+// callNodes serves the rest of a request, f, which calls nodes, once the
+// changes the request has made so far are committed, so that its transaction
+// isn't held open while the nodes are called. f writes the response, and
+// reads the controller from its request again, as that of the transaction is
+// done with. Changes that f makes are committed one by one, so that those
+// for the nodes that succeeded are kept if others fail. If the request isn't
+// served by serveInTx, as in a dry run, f serves it now.
+func callNodes(w http.ResponseWriter, r *http.Request, f http.HandlerFunc) {
+	nodeCalls, _ := r.Context().Value(contextKey("nodeCalls")).(*http.HandlerFunc)
+	if nodeCalls == nil {
+		f(w, r)
+		return
+	}
+	*nodeCalls = f
+}
+
+// responseRecorder buffers a response so it can be discarded.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(b)
+}
+
+// flush writes the buffered response to w.
+func (rec *responseRecorder) flush(w http.ResponseWriter) {
+	for k, v := range rec.header {
+		w.Header()[k] = v
+	}
+	if rec.status != 0 {
+		w.WriteHeader(rec.status)
+	}
+	if _, err := w.Write(rec.body.Bytes()); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
 type contextKey string
 
 func (c contextKey) String() string {
@@ -6,11 +6,13 @@ package gorilla
 import (
 	"context"
 	"crypto/tls"
-	"fmt"
+	"net/http"
 
 	cce "github.com/open-ness/edgecontroller"
 	"github.com/open-ness/edgecontroller/grpc/node"
-	"github.com/open-ness/edgecontroller/k8s"
+	"github.com/open-ness/edgecontroller/operation"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
 	"github.com/pkg/errors"
 )
 
@@ -26,40 +28,23 @@ func connectNode(
 	port string,
 	conf *tls.Config,
 ) (*node.ClientConn, error) {
-	targets, err := ps.Filter(
-		ctx,
-		&cce.NodeGRPCTarget{},
-		[]cce.Filter{
-			{
-				Field: "node_id",
-				Value: e.GetNodeID(),
-			},
-		})
+	target, err := node.Target(ctx, ps, e.GetNodeID())
 	if err != nil {
-		return nil, errors.Wrapf(err, "could not fetch gRPC target from DB")
-	}
-	// sanity check since we are about to access targets[0]
-	if len(targets) != 1 {
-		return nil, fmt.Errorf("filter returned %v", targets)
-	}
-
-	target := targets[0].(*cce.NodeGRPCTarget)
-	addr := target.GRPCTarget
-	if conf != nil {
-		conf = conf.Clone()
-		conf.ServerName = e.GetNodeID()
+		log.Noticef("Could not connect to node: %v", err)
+		return nil, err
 	}
 
 	log.Debugf("connectNode(%v): connecting to %v", e.GetNodeID(), target)
+	operation.Progress(ctx, "Connecting to node")
 
-	nodeCC := node.ClientConn{Addr: addr, Port: port, TLS: conf}
-	if err := nodeCC.Connect(ctx); err != nil {
+	nodeCC, err := node.DialTarget(ctx, target, port, conf)
+	if err != nil {
 		log.Noticef("Could not connect to node: %v", err)
-		return nil, errors.Wrap(err, "could not connect to node")
+		return nil, err
 	}
-	log.Debugf("Connection to node %s established: %s", e.GetNodeID(), addr)
+	log.Debugf("Connection to node %s established: %s", e.GetNodeID(), nodeCC.Addr)
 
-	return &nodeCC, nil
+	return nodeCC, nil
 }
 
 func disconnectNode(nodeCC *node.ClientConn) {
@@ -67,24 +52,91 @@ func disconnectNode(nodeCC *node.ClientConn) {
 	nodeCC.Disconnect()
 }
 
-func getController(ctx context.Context) *cce.Controller {
-	return ctx.Value(contextKey("controller")).(*cce.Controller)
+// pushToNodes connects to each node in nodeIDs on the given port and calls push
+// to apply a change on it. A failure on one node does not stop the change from
+// being pushed to the others; the result for every node is returned in the
+// order of nodeIDs.
+func pushToNodes(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	nodeIDs []string,
+	port string,
+	push func(nodeCC *node.ClientConn, nodeID string) []error,
+) []swagger.NodeStatus {
+	ctrl := getController(ctx)
+
+	statuses := []swagger.NodeStatus{}
+	for _, nodeID := range nodeIDs {
+		errs := func() []error {
+			nodeCC, err := connectNode(ctx, ps, &cce.Node{ID: nodeID}, port, ctrl.EdgeNodeCreds)
+			if err != nil {
+				return []error{err}
+			}
+			defer disconnectNode(nodeCC)
+
+			return push(nodeCC, nodeID)
+		}()
+		statuses = append(statuses, toNodeStatus(nodeID, errs))
+	}
+
+	return statuses
 }
 
-func toK8SApp(app *cce.App) k8s.App {
-	var ports []*k8s.PortProto
-	for _, port := range app.Ports {
-		ports = append(ports, &k8s.PortProto{
-			Port:     int32(port.Port),
-			Protocol: port.Protocol,
-		})
+func toNodeStatus(nodeID string, errs []error) swagger.NodeStatus {
+	if len(errs) == 0 {
+		return swagger.NodeStatus{ID: nodeID, Status: swagger.NodeStatusApplied}
 	}
 
-	return k8s.App{
-		ID:     app.ID,
-		Image:  app.ID + ":latest",
-		Cores:  app.Cores,
-		Memory: app.Memory,
-		Ports:  ports,
+	nodeStatus := swagger.NodeStatus{ID: nodeID, Status: swagger.NodeStatusFailed}
+	for _, err := range errs {
+		log.Errf("Error pushing change to node %s: %v", nodeID, err)
+		nodeStatus.Errors = append(nodeStatus.Errors, err.Error())
 	}
+
+	return nodeStatus
+}
+
+func getController(ctx context.Context) *cce.Controller {
+	return ctx.Value(contextKey("controller")).(*cce.Controller)
+}
+
+// replaceNodeAppPolicy replaces the record of the traffic policy of a node app
+// in a transaction. A replaced record must be at the version that the
+// request's precondition matched.
+func replaceNodeAppPolicy(r *http.Request, ps cce.PersistenceService, nodeAppID, policyID string) error {
+	return ps.WithTx(r.Context(), func(tx cce.PersistenceService) error {
+		// Filter nodes_apps_traffic_policies to see if a record already exists
+		nodeAppPolicies, err := tx.Filter(
+			r.Context(),
+			&cce.NodeAppTrafficPolicy{},
+			[]cce.Filter{
+				{
+					Field: "nodes_apps_id",
+					Value: nodeAppID,
+				},
+			})
+		if err != nil {
+			return errors.Wrap(err, "error reading nodes_apps_traffic_policies")
+		}
+
+		// If it exists, delete it
+		if len(nodeAppPolicies) == 1 {
+			zv := &cce.NodeAppTrafficPolicy{}
+			zv.SetResourceVersion(ifMatchVersion(r, nodeAppPolicies[0]))
+			ok, err := tx.Delete(r.Context(), nodeAppPolicies[0].GetID(), zv)
+			if err != nil {
+				return errors.Wrap(err, "error deleting from nodes_apps_traffic_policies")
+			}
+			if !ok {
+				return errors.New("did not delete 1 record from nodes_apps_traffic_policies")
+			}
+		}
+
+		// Persist the new record
+		return tx.Create(r.Context(), &cce.NodeAppTrafficPolicy{
+			ID:              uuid.New(),
+			NodeAppID:       nodeAppID,
+			TrafficPolicyID: policyID,
+		})
+	})
 }
@@ -0,0 +1,323 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/pkg/errors"
+)
+
+// idempotencyPruneInterval is the minimum interval between deleting the
+// idempotency keys past their retention.
+const idempotencyPruneInterval = time.Hour
+
+// storedResponseHeaders are the response headers that are returned again
+// with a stored response.
+var storedResponseHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// secretResponseRoutes are the routes whose responses contain secrets, which
+// are not stored. Retries of these requests are rejected by the unique names
+// of the resources they create instead.
+var secretResponseRoutes = map[string]bool{
+	"POST /users/{name}/api_keys": true,
+	"POST /webhooks":              true,
+}
+
+// idempotencyKeys stores the responses to POST requests made with an
+// Idempotency-Key header, so that retries of the requests with the same key
+// return the same response rather than being handled again.
+type idempotencyKeys struct {
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+// handler is a handler that returns the stored response to a POST request
+// with the same Idempotency-Key from the same user, or handles the request and
+// stores its response if it succeeds. A request with a key that was used for
+// a different request is rejected with 422 Unprocessable Entity. Failed
+// requests changed nothing, so they are not stored and can be retried with
+// the same key. The handler must run in the transaction of the request, so
+// that a response is stored only if the request's changes are committed, and
+// the response to a request that calls nodes is stored once they are called.
+//
+// The key is reserved in the transaction before the request is handled. A
+// concurrent request with the same key waits for the reservation to be
+// committed or rolled back by the DB, and is rejected with 409 Conflict if it
+// was committed and the response is not stored yet.
+func (k *idempotencyKeys) handler(next http.Handler) http.Handler { //nolint:gocyclo
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if r.Method != http.MethodPost || key == "" || hasSecretResponse(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+		actor, _ := r.Context().Value(contextKey("actor")).(string)
+
+		// Stored responses are not changes of resources, so they aren't
+		// audited
+		ps := ctrl.PersistenceService
+		if audit, ok := ps.(*auditRecorder); ok {
+			ps = audit.PersistenceService
+		}
+
+		if len(key) > cce.MaxIdempotencyKeyLength {
+			w.WriteHeader(http.StatusBadRequest)
+			_, err := w.Write([]byte(fmt.Sprintf(
+				"Idempotency-Key cannot be longer than %d characters", cce.MaxIdempotencyKeyLength)))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
+		}
+
+		now := time.Now()
+		retention := ctrl.IdempotencyKeyRetention
+		if retention == 0 {
+			retention = cce.DefaultIdempotencyKeyRetention
+		}
+		hash := requestHash(r)
+
+		// Fetch the stored response from persistence and check if it's there
+		stored, err := ps.Filter(
+			r.Context(),
+			&cce.IdempotencyKey{},
+			[]cce.Filter{
+				{
+					Field: "actor",
+					Value: actor,
+				},
+				{
+					Field: "key",
+					Value: key,
+				},
+			})
+		if err != nil {
+			log.Errf("Error filtering idempotency keys: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for _, e := range stored {
+			stored := e.(*cce.IdempotencyKey)
+
+			// Keys past their retention can be used again
+			if now.Sub(stored.GetTime()) > retention {
+				if _, err = ps.Delete(r.Context(), stored.ID, &cce.IdempotencyKey{}); err != nil {
+					log.Errf("Error deleting idempotency key: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				continue
+			}
+
+			if stored.RequestHash != hash {
+				log.Debugf("Idempotency-Key '%s' of '%s' was used for a different request", key, actor)
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, err = w.Write([]byte(fmt.Sprintf(
+					"Idempotency-Key %s was used for a different request", key)))
+				if err != nil {
+					log.Errf("Error writing response: %v", err)
+				}
+				return
+			}
+
+			if stored.Pending {
+				log.Debugf("Request with Idempotency-Key '%s' of '%s' is in progress", key, actor)
+				writeIdempotencyConflict(w, key)
+				return
+			}
+
+			log.Debugf("Returning the stored response to Idempotency-Key '%s' of '%s'", key, actor)
+			writeStoredResponse(w, stored)
+			return
+		}
+
+		// Reserve the key, which fails if a concurrent request reserved it
+		reserved := &cce.IdempotencyKey{
+			ID:          uuid.New(),
+			Key:         key,
+			Actor:       actor,
+			RequestHash: hash,
+			Time:        cce.AuditTime(now),
+			Pending:     true,
+		}
+		if err = ps.Create(r.Context(), reserved); err != nil {
+			if errors.Cause(err) == cce.ErrDuplicateEntry {
+				log.Debugf("Idempotency-Key '%s' of '%s' was reserved by a concurrent request", key, actor)
+				writeIdempotencyConflict(w, key)
+				return
+			}
+			log.Errf("Error creating idempotency key: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rec := &responseRecorder{header: make(http.Header)}
+		next.ServeHTTP(rec, r)
+
+		// The response to a request that calls nodes is only known once they
+		// have been called, after its transaction
+		nodeCalls, _ := r.Context().Value(contextKey("nodeCalls")).(*http.HandlerFunc)
+		if rec.status < http.StatusBadRequest && nodeCalls != nil && *nodeCalls != nil {
+			f := *nodeCalls
+			*nodeCalls = func(w http.ResponseWriter, r *http.Request) {
+				rec := &responseRecorder{header: w.Header()}
+				f(rec, r)
+				k.store(w, r, rec, reserved, now)
+			}
+			rec.flush(w)
+			return
+		}
+
+		k.store(w, r, rec, reserved, now)
+	})
+}
+
+// store persists the response to a request in its reserved idempotency key
+// and writes it to w. The key of a failed request is deleted, if it isn't
+// rolled back with the request's transaction, so that the request can be
+// retried with it.
+func (k *idempotencyKeys) store(
+	w http.ResponseWriter,
+	r *http.Request,
+	rec *responseRecorder,
+	reserved *cce.IdempotencyKey,
+	now time.Time,
+) {
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	ps := ctrl.PersistenceService
+	if audit, ok := ps.(*auditRecorder); ok {
+		ps = audit.PersistenceService
+	}
+	retention := ctrl.IdempotencyKeyRetention
+	if retention == 0 {
+		retention = cce.DefaultIdempotencyKeyRetention
+	}
+
+	if rec.status >= http.StatusBadRequest {
+		if _, err := ps.Delete(r.Context(), reserved.ID, &cce.IdempotencyKey{}); err != nil {
+			log.Errf("Error deleting idempotency key: %v", err)
+		}
+		rec.flush(w)
+		return
+	}
+
+	// Persist the response
+	reserved.Pending = false
+	reserved.ResponseStatus = rec.status
+	reserved.ResponseHeader = make(map[string]string)
+	reserved.ResponseBody = rec.body.Bytes()
+	if reserved.ResponseStatus == 0 {
+		reserved.ResponseStatus = http.StatusOK
+	}
+	for _, h := range storedResponseHeaders {
+		if v := rec.header.Get(h); v != "" {
+			reserved.ResponseHeader[h] = v
+		}
+	}
+	if err := ps.BulkUpdate(r.Context(), []cce.Persistable{reserved}); err != nil {
+		log.Errf("Error updating idempotency key: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	k.prune(r.Context(), ps, now, retention)
+
+	rec.flush(w)
+}
+
+// prune deletes the idempotency keys past their retention, at most once per
+// idempotencyPruneInterval.
+func (k *idempotencyKeys) prune(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	now time.Time,
+	retention time.Duration,
+) {
+	k.mu.Lock()
+	if now.Sub(k.pruned) < idempotencyPruneInterval {
+		k.mu.Unlock()
+		return
+	}
+	k.pruned = now
+	k.mu.Unlock()
+
+	expired, err := ps.Filter(
+		ctx,
+		&cce.IdempotencyKey{},
+		[]cce.Filter{
+			{
+				Field: "time",
+				Op:    cce.FilterOpLess,
+				Value: strconv.FormatInt(cce.AuditTime(now.Add(-retention)), 10),
+			},
+		})
+	if err != nil {
+		log.Errf("Error fetching expired idempotency keys: %v", err)
+		return
+	}
+
+	for _, e := range expired {
+		if _, err = ps.Delete(ctx, e.GetID(), &cce.IdempotencyKey{}); err != nil {
+			log.Errf("Error deleting idempotency key %s: %v", e.GetID(), err)
+		}
+	}
+}
+
+// requestHash returns the hex-encoded SHA-256 hash of the method, URI and
+// body of a request.
+func requestHash(r *http.Request) string {
+	body, _ := r.Context().Value(contextKey("body")).([]byte)
+
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeIdempotencyConflict writes a 409 Conflict for a request whose
+// Idempotency-Key is in use by a request in progress.
+func writeIdempotencyConflict(w http.ResponseWriter, key string) {
+	w.WriteHeader(http.StatusConflict)
+	_, err := w.Write([]byte(fmt.Sprintf(
+		"a request with Idempotency-Key %s is in progress, retry it later", key)))
+	if err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// writeStoredResponse writes a stored response, marked as replayed.
+func writeStoredResponse(w http.ResponseWriter, k *cce.IdempotencyKey) {
+	for h, v := range k.ResponseHeader {
+		w.Header().Set(h, v)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(k.ResponseStatus)
+	if _, err := w.Write(k.ResponseBody); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// hasSecretResponse returns whether the response to a request contains
+// secrets.
+func hasSecretResponse(r *http.Request) bool {
+	if route := mux.CurrentRoute(r); route != nil {
+		if path, err := route.GetPathTemplate(); err == nil {
+			return secretResponseRoutes[r.Method+" "+path]
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/pkg/errors"
+)
+
+// parseListOptions parses the query parameters of a request for listing
+// entities like zv. The limit, cursor and sort parameters page and sort the
+// list. Any other parameter is a filter on the filter field of the same name,
+// which matches values equal to the parameter, or containing it if the name
+// is suffixed with "~" as in ?name~=edge.
+func parseListOptions(query url.Values, zv cce.Filterable) (opts cce.ListOptions, err error) {
+	for param, values := range query {
+		value := values[len(values)-1]
+
+		switch param {
+		case "limit":
+			if opts.Limit, err = strconv.Atoi(value); err != nil {
+				return opts, errors.Errorf("bad limit %q", value)
+			}
+		case "cursor":
+			opts.Cursor = value
+		case "sort":
+			opts.Sort = value
+		default:
+			f := cce.Filter{Field: param}
+			if strings.HasSuffix(param, "~") {
+				f = cce.Filter{Field: strings.TrimSuffix(param, "~"), Op: cce.FilterOpContains}
+			}
+			for _, value := range values {
+				f.Value = value
+				opts.Filters = append(opts.Filters, f)
+			}
+		}
+	}
+
+	return opts, opts.Validate(zv)
+}
+
+// toSwaggerListPage converts a page to the swagger representation, linking to
+// the next page with the query parameters of the request.
+func toSwaggerListPage(r *http.Request, page *cce.Page) swagger.ListPage {
+	listPage := swagger.ListPage{Total: page.Total}
+	if page.Next != "" {
+		next := *r.URL
+		query := next.Query()
+		query.Set("cursor", page.Next)
+		next.RawQuery = query.Encode()
+		listPage.Next = next.RequestURI()
+	}
+
+	return listPage
+}
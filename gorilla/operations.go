@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/plan"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// operationTypes are the types of the operations that the routes changing
+// nodes run as, keyed by method and path template like the routes of
+// NewGorilla.
+var operationTypes = map[string]string{
+	"POST /nodes/{node_id}/apps":            cce.OperationDeployApp,
+	"PATCH /nodes/{node_id}/apps/{app_id}":  cce.OperationUpdateApp,
+	"DELETE /nodes/{node_id}/apps/{app_id}": cce.OperationUndeployApp,
+
+	"PATCH /nodes/{node_id}/dns":  cce.OperationUpdateDNS,
+	"DELETE /nodes/{node_id}/dns": cce.OperationDeleteDNS,
+
+	"PATCH /nodes/{node_id}/interfaces/{interface_id}/policy":  cce.OperationUpdatePolicy,
+	"DELETE /nodes/{node_id}/interfaces/{interface_id}/policy": cce.OperationDeletePolicy,
+	"PATCH /nodes/{node_id}/apps/{app_id}/policy":              cce.OperationUpdatePolicy,
+	"DELETE /nodes/{node_id}/apps/{app_id}/policy":             cce.OperationDeletePolicy,
+	"PATCH /nodes/{node_id}/apps/{app_id}/kube_ovn/policy":     cce.OperationUpdatePolicy,
+	"DELETE /nodes/{node_id}/apps/{app_id}/kube_ovn/policy":    cce.OperationDeletePolicy,
+}
+
+// operationHandler runs the calls to nodes of the requests of a route that
+// changes a node in the background as operations of a type, if the
+// controller has an operation runner. The request is validated and its
+// changes are made in the request's transaction, as if it was handled
+// synchronously, and a request that fails is responded to as such. A request
+// that goes on to call nodes is accepted with the operation, which is
+// persisted in the request's transaction and submitted once it is committed,
+// and the operation makes the calls as callNodes would. The operation runs
+// after those submitted before it on the node of the route. Dry runs are
+// handled synchronously, as they don't change the node.
+func operationHandler(controller *cce.Controller, opType string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Load the controller to access the persistence
+		ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+		if ctrl.Operations == nil || plan.FromContext(r.Context()) != nil {
+			next(w, r)
+			return
+		}
+
+		// Handle the request up to its calls to nodes
+		rec := &responseRecorder{header: make(http.Header)}
+		var nodeCalls http.HandlerFunc
+		next(rec, r.WithContext(context.WithValue(r.Context(), contextKey("nodeCalls"), &nodeCalls)))
+		if rec.status >= http.StatusBadRequest || nodeCalls == nil {
+			rec.flush(w)
+			return
+		}
+
+		// Persist the operation
+		actor, _ := r.Context().Value(contextKey("actor")).(string)
+		op := &cce.Operation{
+			ID:      uuid.New(),
+			Type:    opType,
+			NodeID:  mux.Vars(r)["node_id"],
+			Actor:   actor,
+			Request: r.Method + " " + r.URL.Path,
+			Time:    cce.AuditTime(time.Now()),
+			Status:  cce.OperationPending,
+		}
+		if err := ctrl.PersistenceService.Create(r.Context(), op); err != nil {
+			log.Errf("Error creating entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		afterCommit(r.Context(), func() {
+			ctrl.Operations.Submit(op, runOperation(controller, r, nodeCalls))
+		})
+
+		// Marshal the response object to JSON
+		opJSON, err := json.Marshal(toSwaggerOperation(op))
+		if err != nil {
+			log.Errf("Error marshaling operation: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Location", "/operations/"+op.ID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if _, err = w.Write(opJSON); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+	}
+}
+
+// runOperation returns a function that serves the calls to nodes of the
+// request of an operation, outside a transaction, and returns the response.
+// The request is detached from the client's connection, so the operation
+// completes if the client disconnects.
+func runOperation(controller *cce.Controller, r *http.Request, nodeCalls http.Handler) cce.OperationFunc {
+	return func(ctx context.Context) (int, []byte) {
+		rec := &responseRecorder{header: make(http.Header)}
+		serveOutsideTx(controller, rec, r.WithContext(detachedContext{Context: ctx, values: r.Context()}), nodeCalls)
+
+		if rec.status == 0 {
+			return http.StatusOK, rec.body.Bytes()
+		}
+		return rec.status, rec.body.Bytes()
+	}
+}
+
+// detachedContext has the deadline and cancellation of a context and the
+// values of another, such as those of a request that has ended.
+type detachedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.values.Value(key)
+}
+
+func toSwaggerOperation(o *cce.Operation) swagger.Operation {
+	op := swagger.Operation{
+		ID:             o.ID,
+		Type:           o.Type,
+		NodeID:         o.NodeID,
+		Actor:          o.Actor,
+		Request:        o.Request,
+		Time:           o.GetTime(),
+		Status:         o.Status,
+		Progress:       o.Progress,
+		ResponseStatus: o.ResponseStatus,
+		Result:         o.Result,
+		Error:          o.Error,
+	}
+	if !o.StartedAt.IsZero() {
+		startedAt := o.StartedAt
+		op.StartedAt = &startedAt
+	}
+	if !o.FinishedAt.IsZero() {
+		finishedAt := o.FinishedAt
+		op.FinishedAt = &finishedAt
+	}
+
+	return op
+}
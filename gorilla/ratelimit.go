@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Route groups of the rate limiters of cce.Controller.
+const (
+	// RateLimitAuth limits POST /auth and POST /auth/refresh per client IP.
+	RateLimitAuth = "auth"
+
+	// RateLimitClient limits other requests per client IP before they are
+	// authenticated.
+	RateLimitClient = "client"
+
+	// RateLimitRead limits GET requests per user.
+	RateLimitRead = "read"
+
+	// RateLimitWrite limits other requests per user.
+	RateLimitWrite = "write"
+)
+
+var (
+	rateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cce_http_rate_limited_requests_total",
+		Help: "HTTP API requests rejected by the rate limiter of a route group.",
+	}, []string{"group"})
+	loginFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cce_login_failures_total",
+		Help: "Logins that failed because of a wrong user name or password.",
+	})
+	loginsLockedOut = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cce_login_locked_out_total",
+		Help: "Logins rejected because the user was locked out.",
+	})
+)
+
+// clientRateLimitHandler is a handler that rate limits HTTP requests per
+// client IP before they are authenticated, token requests with the limiter of
+// RateLimitAuth and others with the limiter of RateLimitClient.
+func clientRateLimitHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := RateLimitClient
+		if r.RequestURI == "/auth" || r.RequestURI == "/auth/refresh" {
+			group = RateLimitAuth
+		}
+
+		if allowRequest(w, r, group, clientIP(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// userRateLimitHandler is a handler that rate limits authenticated HTTP
+// requests per user, GET requests with the limiter of RateLimitRead and
+// others with the limiter of RateLimitWrite. Token requests, which are not
+// authenticated, are not limited.
+func userRateLimitHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor, ok := r.Context().Value(contextKey("actor")).(string)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		group := RateLimitWrite
+		if r.Method == http.MethodGet {
+			group = RateLimitRead
+		}
+
+		if allowRequest(w, r, group, actor) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// allowRequest returns whether the limiter of a route group allows a request
+// of a client or user, and otherwise responds with status code 429.
+func allowRequest(w http.ResponseWriter, r *http.Request, group, key string) bool {
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	limiter, ok := ctrl.RateLimiters[group]
+	if !ok {
+		return true
+	}
+	if ok, retryAfter := limiter.Allow(key); !ok {
+		log.Debugf("Rate limited %s %s of '%s'", r.Method, r.URL.Path, key)
+		rateLimitedRequests.WithLabelValues(group).Inc()
+		writeTooManyRequests(w, retryAfter)
+		return false
+	}
+	return true
+}
+
+// writeTooManyRequests responds with status code 429 and the number of
+// seconds to retry after.
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
+
+// clientIP returns the IP address of the client of a request.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
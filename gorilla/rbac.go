@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+// routePermissions are the permissions required by routes, keyed by method
+// and path template like the routes of NewGorilla. Other routes require
+// cce.PermissionRead for GET and cce.PermissionWrite for other methods.
+var routePermissions = map[string]cce.Permission{
+	"GET /audit": cce.PermissionAdmin,
+
+	"GET /users":           cce.PermissionAdmin,
+	"POST /users":          cce.PermissionAdmin,
+	"GET /users/{name}":    cce.PermissionAdmin,
+	"PATCH /users/{name}":  cce.PermissionAdmin,
+	"DELETE /users/{name}": cce.PermissionAdmin,
+
+	"GET /users/{name}/api_keys":                 cce.PermissionAdmin,
+	"POST /users/{name}/api_keys":                cce.PermissionAdmin,
+	"DELETE /users/{name}/api_keys/{api_key_id}": cce.PermissionAdmin,
+
+	// Users can change their own password, which swagPATCHUserPassword checks
+	"PATCH /users/{name}/password": cce.PermissionRead,
+
+	// Users can request their own certificates, which
+	// swagPOSTUserCertificates checks
+	"POST /users/{name}/certificates": cce.PermissionRead,
+
+	"GET /webhooks":                         cce.PermissionAdmin,
+	"POST /webhooks":                        cce.PermissionAdmin,
+	"GET /webhooks/{webhook_id}":            cce.PermissionAdmin,
+	"PATCH /webhooks/{webhook_id}":          cce.PermissionAdmin,
+	"DELETE /webhooks/{webhook_id}":         cce.PermissionAdmin,
+	"GET /webhooks/{webhook_id}/deliveries": cce.PermissionAdmin,
+
+	// Any user can log out
+	"POST /auth/logout": cce.PermissionRead,
+}
+
+// requiredPermission returns the permission required by the route of a
+// request.
+func requiredPermission(r *http.Request) cce.Permission {
+	if route := mux.CurrentRoute(r); route != nil {
+		if path, err := route.GetPathTemplate(); err == nil {
+			if p, ok := routePermissions[r.Method+" "+path]; ok {
+				return p
+			}
+		}
+	}
+
+	if r.Method == http.MethodGet {
+		return cce.PermissionRead
+	}
+	return cce.PermissionWrite
+}
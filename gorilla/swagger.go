@@ -22,12 +22,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	cce "github.com/open-ness/edgecontroller"
 	"github.com/open-ness/edgecontroller/nfd-master"
 	"github.com/open-ness/edgecontroller/swagger"
 	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/pkg/errors"
 )
 
 // The following handlers are compliant to our published Swagger (OpenAPI 3.0) schema.
@@ -37,16 +39,28 @@ func (g *Gorilla) swagGETNodes(w http.ResponseWriter, r *http.Request) {
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.Node{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
 	// Fetch the nodes from persistence
-	persisted, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.Node{})
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.Node{}, opts)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	// Construct the response object
-	nodes := swagger.NodeList{Nodes: []swagger.NodeSummary{}}
-	for _, n := range persisted {
+	nodes := swagger.NodeList{Nodes: []swagger.NodeSummary{}, ListPage: toSwaggerListPage(r, page)}
+	for _, n := range page.Entities {
 		node := swagger.NodeSummary{
 			ID:       n.(*cce.Node).ID,
 			Name:     n.(*cce.Node).Name,
@@ -88,6 +102,7 @@ func (g *Gorilla) swagGETNodeByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	setETag(w, entityTag(persisted))
 
 	// Construct the response object
 	node := swagger.NodeDetail{
@@ -144,12 +159,29 @@ func (g *Gorilla) swagPATCHNodeByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fetch the current entity from persistence and check the precondition
+	current, err := ctrl.PersistenceService.Read(r.Context(), persisted.ID, &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !checkIfMatch(w, r, entityTag(current)) {
+		return
+	}
+	persisted.SetResourceVersion(ifMatchVersion(r, current))
+
 	// Persist the object
 	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&persisted}); err != nil {
 		log.Errf("Error updating entities: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, entityTag(&persisted))
 }
 
 // Used for DELETE /nodes/{node_id} endpoint
@@ -183,10 +215,20 @@ func (g *Gorilla) swagDELETENodeByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	if !checkIfMatch(w, r, entityTag(persisted)) {
+		return
+	}
 
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
+	// Delete the entity, if it's still at the version the precondition matched
+	zv := &cce.Node{}
+	zv.SetResourceVersion(ifMatchVersion(r, persisted))
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["node_id"], zv)
 	if err != nil {
 		log.Errf("Error deleting entity: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -203,16 +245,28 @@ func (g *Gorilla) swagGETApps(w http.ResponseWriter, r *http.Request) {
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.App{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
 	// Fetch the nodes from persistence
-	persisted, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.App{})
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.App{}, opts)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	// Construct the response object
-	apps := swagger.AppList{Apps: []swagger.AppSummary{}}
-	for _, a := range persisted {
+	apps := swagger.AppList{Apps: []swagger.AppSummary{}, ListPage: toSwaggerListPage(r, page)}
+	for _, a := range page.Entities {
 		app := swagger.AppSummary{
 			ID:          a.(*cce.App).ID,
 			Type:        a.(*cce.App).Type,
@@ -256,6 +310,7 @@ func (g *Gorilla) swagGETAppByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	setETag(w, entityTag(persisted))
 
 	// Construct the response object
 	app := swagger.AppDetail{
@@ -326,12 +381,29 @@ func (g *Gorilla) swagPATCHAppByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fetch the current entity from persistence and check the precondition
+	current, err := ctrl.PersistenceService.Read(r.Context(), persisted.ID, &cce.App{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !checkIfMatch(w, r, entityTag(current)) {
+		return
+	}
+	persisted.SetResourceVersion(ifMatchVersion(r, current))
+
 	// Persist the object
 	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&persisted}); err != nil {
 		log.Errf("Error updating entities: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, entityTag(&persisted))
 }
 
 // Used for DELETE /apps/{app_id} endpoint
@@ -365,10 +437,20 @@ func (g *Gorilla) swagDELETEAppByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	if !checkIfMatch(w, r, entityTag(persisted)) {
+		return
+	}
 
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["app_id"], &cce.App{})
+	// Delete the entity, if it's still at the version the precondition matched
+	zv := &cce.App{}
+	zv.SetResourceVersion(ifMatchVersion(r, persisted))
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["app_id"], zv)
 	if err != nil {
 		log.Errf("Error deleting entity: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -385,16 +467,28 @@ func (g *Gorilla) swagGETPolicies(w http.ResponseWriter, r *http.Request) {
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.TrafficPolicy{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
 	// Fetch the nodes from persistence
-	persisted, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.TrafficPolicy{})
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.TrafficPolicy{}, opts)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	// Construct the response object
-	policies := swagger.PolicyList{Policies: []swagger.PolicySummary{}}
-	for _, a := range persisted {
+	policies := swagger.PolicyList{Policies: []swagger.PolicySummary{}, ListPage: toSwaggerListPage(r, page)}
+	for _, a := range page.Entities {
 		policy := swagger.PolicySummary{
 			ID:   a.(*cce.TrafficPolicy).ID,
 			Name: a.(*cce.TrafficPolicy).Name,
@@ -434,6 +528,7 @@ func (g *Gorilla) swagGETPolicyByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	setETag(w, entityTag(persisted))
 
 	// Construct the response object
 	policy := swagger.PolicyDetail{
@@ -488,12 +583,55 @@ func (g *Gorilla) swagPATCHPolicyByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fetch the current entity from persistence and check the precondition
+	current, err := ctrl.PersistenceService.Read(r.Context(), persisted.ID, &cce.TrafficPolicy{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !checkIfMatch(w, r, entityTag(current)) {
+		return
+	}
+	persisted.SetResourceVersion(ifMatchVersion(r, current))
+
 	// Persist the object
 	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&persisted}); err != nil {
 		log.Errf("Error updating entities: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, entityTag(&persisted))
+
+	// Push the updated policy to the nodes using it, once it is committed
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		statuses, err := handleUpdateTrafficPolicies(r.Context(), ctrl.PersistenceService, &persisted)
+		if err != nil {
+			log.Errf("Error updating remote entities: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Publish the results on the nodes
+		publishNodeStatuses(r.Context(), &persisted, statuses)
+
+		// Marshal the response object to JSON
+		statusesJSON, err := json.Marshal(swagger.NodeStatusList{Nodes: statuses})
+		if err != nil {
+			log.Errf("Error marshaling response: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(statusesJSON); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+	})
 }
 
 // Used for DELETE /policies/{policy_id}
@@ -530,19 +668,70 @@ func (g *Gorilla) swagDELETEPolicyByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["policy_id"], &cce.TrafficPolicy{})
-	if err != nil {
-		log.Errf("Error deleting entity: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+	if !checkIfMatch(w, r, entityTag(persisted)) {
 		return
 	}
 
-	// we just fetched the entity, so if !ok then something went wrong
-	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	// Detach the policy from the nodes using it, and delete it if that succeeds,
+	// outside the transaction so that the nodes where that succeeded stay
+	// detached if others fail
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		statuses, err := handleDeleteTrafficPolicies(r.Context(), ctrl.PersistenceService, mux.Vars(r)["policy_id"])
+		if err != nil {
+			log.Errf("Error updating remote entities: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Publish the results on the nodes
+		publishNodeStatuses(r.Context(), persisted, statuses)
+
+		// Marshal the response object to JSON
+		statusesJSON, err := json.Marshal(swagger.NodeStatusList{Nodes: statuses})
+		if err != nil {
+			log.Errf("Error marshaling response: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// The policy is still in use on the nodes that failed, so keep it
+		for _, status := range statuses {
+			if status.Status != swagger.NodeStatusApplied {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				if _, err = w.Write(statusesJSON); err != nil {
+					log.Errf("Error writing response: %v", err)
+				}
+				return
+			}
+		}
+
+		// Delete the entity, if it's still at the version the precondition matched
+		zv := &cce.TrafficPolicy{}
+		zv.SetResourceVersion(ifMatchVersion(r, persisted))
+		ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["policy_id"], zv)
+		if err != nil {
+			log.Errf("Error deleting entity: %v", err)
+			if isVersionConflict(err) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// we just fetched the entity, so if !ok then something went wrong
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(statusesJSON); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+	})
 }
 
 // Used for GET /kube_ovn/policies endpoints
@@ -550,8 +739,20 @@ func (g *Gorilla) swagGETKubeOVNPolicies(w http.ResponseWriter, r *http.Request)
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.TrafficPolicyKubeOVN{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
 	// Fetch the nodes from persistence
-	persisted, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.TrafficPolicyKubeOVN{})
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.TrafficPolicyKubeOVN{}, opts)
 	if err != nil {
 		log.Errf("Failed to fetch the nodes from persistence: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -559,8 +760,8 @@ func (g *Gorilla) swagGETKubeOVNPolicies(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Construct the response object
-	policies := swagger.PolicyList{Policies: []swagger.PolicySummary{}}
-	for _, a := range persisted {
+	policies := swagger.PolicyList{Policies: []swagger.PolicySummary{}, ListPage: toSwaggerListPage(r, page)}
+	for _, a := range page.Entities {
 		policy := swagger.PolicySummary{
 			ID:   a.(*cce.TrafficPolicyKubeOVN).ID,
 			Name: a.(*cce.TrafficPolicyKubeOVN).Name,
@@ -601,6 +802,7 @@ func (g *Gorilla) swagGETKubeOVNPolicyByID(w http.ResponseWriter, r *http.Reques
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	setETag(w, entityTag(persisted))
 
 	// Construct the response object
 	policy := swagger.PolicyKubeOVNDetail{
@@ -657,12 +859,55 @@ func (g *Gorilla) swagPATCHKubeOVNPolicyByID(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Fetch the current entity from persistence and check the precondition
+	current, err := ctrl.PersistenceService.Read(r.Context(), persisted.ID, &cce.TrafficPolicyKubeOVN{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !checkIfMatch(w, r, entityTag(current)) {
+		return
+	}
+	persisted.SetResourceVersion(ifMatchVersion(r, current))
+
 	// Persist the object
 	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&persisted}); err != nil {
 		log.Errf("Error updating entities: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, entityTag(&persisted))
+
+	// Re-apply the updated policy for the apps using it, once it is committed
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		statuses, err := handleUpdateTrafficPoliciesKubeOVN(r.Context(), ctrl.PersistenceService, &persisted)
+		if err != nil {
+			log.Errf("Error updating remote entities: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Publish the results on the nodes
+		publishNodeStatuses(r.Context(), &persisted, statuses)
+
+		// Marshal the response object to JSON
+		statusesJSON, err := json.Marshal(swagger.NodeStatusList{Nodes: statuses})
+		if err != nil {
+			log.Errf("Error marshaling response: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(statusesJSON); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+	})
 }
 
 // Used for DELETE /kube_ovn/policies/{policy_id}
@@ -699,10 +944,20 @@ func (g *Gorilla) swagDELETEKubeOVNPolicyByID(w http.ResponseWriter, r *http.Req
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	if !checkIfMatch(w, r, entityTag(persisted)) {
+		return
+	}
 
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["policy_id"], &cce.TrafficPolicyKubeOVN{})
+	// Delete the entity, if it's still at the version the precondition matched
+	zv := &cce.TrafficPolicyKubeOVN{}
+	zv.SetResourceVersion(ifMatchVersion(r, persisted))
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["policy_id"], zv)
 	if err != nil {
 		log.Errf("Error deleting entity: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -763,44 +1018,8 @@ func (g *Gorilla) swagGETNodeDNS(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Construct the response object
-		dns = swagger.DNSDetail{
-			DNSSummary: swagger.DNSSummary{
-				ID:   persistedConfig.(*cce.DNSConfig).ID,
-				Name: persistedConfig.(*cce.DNSConfig).Name,
-			},
-		}
-
-		// Add the IP based A records to the response
-		for _, record := range persistedConfig.(*cce.DNSConfig).ARecords {
-			rec := swagger.DNSARecord{
-				Name:        record.Name,
-				Description: record.Description,
-				Alias:       false,
-				Values:      record.IPs,
-			}
-			dns.Records.A = append(dns.Records.A, rec)
-		}
-
-		// Add the alias based A records to the response
-		for _, record := range persistedAliases {
-			rec := swagger.DNSARecord{
-				Name:        record.(*cce.DNSConfigAppAlias).Name,
-				Description: record.(*cce.DNSConfigAppAlias).Description,
-				Alias:       true,
-				Values:      []string{record.(*cce.DNSConfigAppAlias).AppID},
-			}
-			dns.Records.A = append(dns.Records.A, rec)
-		}
-
-		// Add the forwarders to the response
-		for _, forwarder := range persistedConfig.(*cce.DNSConfig).Forwarders {
-			fwdr := swagger.DNSForwarder{
-				Name:        forwarder.Name,
-				Description: forwarder.Description,
-				Value:       forwarder.IP,
-			}
-			dns.Configurations.Forwarders = append(dns.Configurations.Forwarders, fwdr)
-		}
+		dns = toSwaggerDNSDetail(persistedConfig.(*cce.DNSConfig), persistedAliases)
+		setETag(w, replacedEntityTag(persistedConfig))
 	}
 
 	// Marshal the response object to JSON
@@ -830,24 +1049,40 @@ func (g *Gorilla) swagPATCHNodeDNS(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-
-	// Delete the old persisted data
-	if err := g.swagDNSDeleteHelper(w, r); err != nil {
-		_, err = w.Write([]byte(fmt.Sprintf("DNS call failed mid operation: %v", err)))
-		if err != nil {
-			log.Errf("Error writing response: %v", err)
-		}
+	if !g.checkNodeDNSIfMatch(w, r) {
 		return
 	}
 
-	// Create the new requested data
-	if err := g.swagDNSCreateHelper(w, r); err != nil {
+	// Convert the requested data to persistable objects
+	nodeDNS, newConfig, newAliases, err := g.swagDNSParseHelper(w, r)
+	if err != nil {
 		_, err = w.Write([]byte(fmt.Sprintf("DNS call failed mid operation: %v", err)))
 		if err != nil {
 			log.Errf("Error writing response: %v", err)
 		}
 		return
 	}
+
+	// Replace the data on the node and in persistence, outside the transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		// Delete the old persisted data
+		if err := g.swagDNSDeleteHelper(w, r); err != nil {
+			_, err = w.Write([]byte(fmt.Sprintf("DNS call failed mid operation: %v", err)))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
+		}
+
+		// Create the new requested data
+		if err := g.swagDNSCreateHelper(w, r, nodeDNS, newConfig, newAliases); err != nil {
+			_, err = w.Write([]byte(fmt.Sprintf("DNS call failed mid operation: %v", err)))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
+		}
+	})
 }
 
 // Used for DELETE /nodes/{node_id}/dns endpoint
@@ -865,22 +1100,33 @@ func (g *Gorilla) swagDELETENodeDNS(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-
-	// Delete the old persisted data
-	if err := g.swagDNSDeleteHelper(w, r); err != nil {
-		_, err = w.Write([]byte(fmt.Sprintf("DNS call failed mid operation: %v", err)))
-		if err != nil {
-			log.Errf("Error writing response: %v", err)
-		}
+	if !g.checkNodeDNSIfMatch(w, r) {
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	// Delete the old persisted data from the node and from persistence,
+	// outside the transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		if err := g.swagDNSDeleteHelper(w, r); err != nil {
+			_, err = w.Write([]byte(fmt.Sprintf("DNS call failed mid operation: %v", err)))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
 }
 
-func (g *Gorilla) swagDNSCreateHelper(w http.ResponseWriter, r *http.Request) error { //nolint:gocyclo
-	// Load the controller to access the persistence and the payload
-	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+// swagDNSParseHelper converts the DNS config of a request to the persistable
+// entities of the node's DNS config, its aliases and its association with the
+// node.
+func (g *Gorilla) swagDNSParseHelper(
+	w http.ResponseWriter,
+	r *http.Request,
+) (*cce.NodeDNSConfig, *cce.DNSConfig, []cce.Persistable, error) {
+	// Load the payload
 	body := r.Context().Value(contextKey("body")).([]byte)
 
 	// Unmarshal the requested DNS configurations
@@ -888,19 +1134,25 @@ func (g *Gorilla) swagDNSCreateHelper(w http.ResponseWriter, r *http.Request) er
 	if err := json.Unmarshal(body, &requested); err != nil {
 		log.Errf("Error unmarshaling json: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
-		return err
+		return nil, nil, nil, err
 	}
 
 	if len(requested.Configurations.Forwarders) != 0 {
 		log.Err("Received unimplemented field forwarders in request")
 		w.WriteHeader(http.StatusNotImplemented)
-		return fmt.Errorf("received unimplemented field forwarders in request")
+		return nil, nil, nil, fmt.Errorf("received unimplemented field forwarders in request")
 	}
 
-	// Create the new persistable entity for the DNS config
-	newConfig := &cce.DNSConfig{
-		ID:   uuid.New(),
-		Name: requested.Name,
+	// Construct the persistable entities for the DNS config and aliases
+	newConfig, aliases, err := fromSwaggerDNSDetail(&requested, uuid.New())
+	if err != nil {
+		log.Errf("Error creating DNS config: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, nil, nil, err
+	}
+	var newAliases []cce.Persistable
+	for _, alias := range aliases {
+		newAliases = append(newAliases, alias)
 	}
 
 	// Create the new persistable association
@@ -910,53 +1162,18 @@ func (g *Gorilla) swagDNSCreateHelper(w http.ResponseWriter, r *http.Request) er
 		DNSConfigID: newConfig.ID,
 	}
 
-	// Create the new persistable entity for the DNS aliases
-	var newAliases []cce.Persistable
+	return nodeDNS, newConfig, newAliases, nil
+}
 
-	// Construct the persistable entities
-	for _, req := range requested.Records.A {
-		switch {
-		case req.Alias && len(req.Values) != 0:
-			record := cce.DNSConfigAppAlias{
-				ID:          uuid.New(),
-				DNSConfigID: newConfig.ID,
-				Name:        req.Name,
-				Description: req.Description,
-				AppID:       req.Values[0],
-			}
-			if err := record.Validate(); err != nil {
-				log.Errf("Error creating DNS config aliases: %v", err)
-				w.WriteHeader(http.StatusBadRequest)
-				return err
-			}
-			newAliases = append(newAliases, &record)
-		case !req.Alias:
-			record := &cce.DNSARecord{
-				Name:        req.Name,
-				Description: req.Description,
-				IPs:         req.Values,
-			}
-			if err := record.Validate(); err != nil {
-				log.Errf("Error creating DNS config non-aliases: %v", err)
-				w.WriteHeader(http.StatusBadRequest)
-				return err
-			}
-			newConfig.ARecords = append(newConfig.ARecords, record)
-		}
-	}
-	for _, req := range requested.Configurations.Forwarders {
-		config := &cce.DNSForwarder{
-			Name:        req.Name,
-			Description: req.Description,
-			IP:          req.Value,
-		}
-		if err := config.Validate(); err != nil {
-			log.Errf("Error creating DNS config forwarders: %v", err)
-			w.WriteHeader(http.StatusBadRequest)
-			return err
-		}
-		newConfig.Forwarders = append(newConfig.Forwarders, config)
-	}
+func (g *Gorilla) swagDNSCreateHelper(
+	w http.ResponseWriter,
+	r *http.Request,
+	nodeDNS *cce.NodeDNSConfig,
+	newConfig *cce.DNSConfig,
+	newAliases []cce.Persistable,
+) error {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
 	// Create the DNS config and aliases from the node
 	if err := handleCreateNodesDNSConfigsWithAliases(
@@ -966,22 +1183,19 @@ func (g *Gorilla) swagDNSCreateHelper(w http.ResponseWriter, r *http.Request) er
 		return err
 	}
 
-	// Create the config in persistence
-	if err := ctrl.PersistenceService.Create(r.Context(), newConfig); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return err
-	}
-
-	// Create the aliases in persistence
-	for _, alias := range newAliases {
-		if err := ctrl.PersistenceService.Create(r.Context(), alias); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+	// Create the config, aliases and association in persistence
+	err := ctrl.PersistenceService.WithTx(r.Context(), func(tx cce.PersistenceService) error {
+		if err := tx.Create(r.Context(), newConfig); err != nil {
 			return err
 		}
-	}
-
-	// Create the association in persistence
-	if err := ctrl.PersistenceService.Create(r.Context(), nodeDNS); err != nil {
+		for _, alias := range newAliases {
+			if err := tx.Create(r.Context(), alias); err != nil {
+				return err
+			}
+		}
+		return tx.Create(r.Context(), nodeDNS)
+	})
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return err
 	}
@@ -1038,24 +1252,20 @@ func (g *Gorilla) swagDNSDeleteHelper(w http.ResponseWriter, r *http.Request) er
 			return err
 		}
 
-		// Delete the association from persistence
-		if _, err := ctrl.PersistenceService.Delete(
-			r.Context(), persistedNode[0].GetID(), persistedNode[0],
-		); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
-
-		// Delete the aliases from persistence
-		for _, alias := range persistedAliases {
-			if _, err := ctrl.PersistenceService.Delete(r.Context(), alias.GetID(), alias); err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
+		// Delete the association, aliases and config from persistence
+		err = ctrl.PersistenceService.WithTx(r.Context(), func(tx cce.PersistenceService) error {
+			if _, err := tx.Delete(r.Context(), persistedNode[0].GetID(), persistedNode[0]); err != nil {
 				return err
 			}
-		}
-
-		// Delete the config from persistence
-		if _, err := ctrl.PersistenceService.Delete(r.Context(), persistedConfig.GetID(), persistedConfig); err != nil {
+			for _, alias := range persistedAliases {
+				if _, err := tx.Delete(r.Context(), alias.GetID(), alias); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Delete(r.Context(), persistedConfig.GetID(), persistedConfig)
+			return err
+		})
+		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return err
 		}
@@ -1064,12 +1274,324 @@ func (g *Gorilla) swagDNSDeleteHelper(w http.ResponseWriter, r *http.Request) er
 	return nil
 }
 
-// Used for GET /nodes/{node_id}/interfaces endpoint
-func (g *Gorilla) swagGETInterfaces(w http.ResponseWriter, r *http.Request) {
+// checkNodeDNSIfMatch checks the If-Match header of a request against the
+// current DNS config of the node. If the precondition fails or the config
+// cannot be fetched it responds with an error and returns false.
+func (g *Gorilla) checkNodeDNSIfMatch(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := r.Header["If-Match"]; !ok {
+		return true
+	}
+
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
-	// Fetch the entity from persistence and check if it's there
+	// Fetch the association from persistence
+	persistedNode, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.NodeDNSConfig{},
+		[]cce.Filter{{Field: "node_id", Value: mux.Vars(r)["node_id"]}},
+	)
+	if err != nil {
+		log.Errf("Error filtering nodes_dns_configs: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if len(persistedNode) == 0 {
+		return checkIfMatch(w, r, "")
+	}
+
+	// Fetch the DNS config from persistence
+	persistedConfig, err := ctrl.PersistenceService.Read(
+		r.Context(),
+		persistedNode[0].(*cce.NodeDNSConfig).DNSConfigID,
+		&cce.DNSConfig{},
+	)
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	return checkIfMatch(w, r, replacedEntityTag(persistedConfig))
+}
+
+// Used for GET /dns_configs/{dns_config_id} endpoint
+func (g *Gorilla) swagGETDNSConfigByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the DNS config from persistence and check if it's there
+	persistedConfig, err := ctrl.PersistenceService.Read(
+		r.Context(),
+		mux.Vars(r)["dns_config_id"],
+		&cce.DNSConfig{},
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persistedConfig == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	setETag(w, entityTag(persistedConfig))
+
+	// Fetch the DNS aliases from persistence
+	persistedAliases, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.DNSConfigAppAlias{},
+		[]cce.Filter{
+			{Field: "dns_config_id", Value: persistedConfig.GetID()},
+		},
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	dns := toSwaggerDNSDetail(persistedConfig.(*cce.DNSConfig), persistedAliases)
+
+	// Marshal the response object to JSON
+	dnsJSON, err := json.Marshal(dns)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(dnsJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for PATCH /dns_configs/{dns_config_id} endpoint
+func (g *Gorilla) swagPATCHDNSConfigByID(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	requested := swagger.DNSDetail{}
+	if err := json.Unmarshal(body, &requested); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the DNS config from persistence and check if it's there
+	oldConfig, err := ctrl.PersistenceService.Read(
+		r.Context(),
+		mux.Vars(r)["dns_config_id"],
+		&cce.DNSConfig{},
+	)
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if oldConfig == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !checkIfMatch(w, r, entityTag(oldConfig)) {
+		return
+	}
+
+	// Fetch the DNS aliases from persistence
+	persistedAliases, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.DNSConfigAppAlias{},
+		[]cce.Filter{
+			{Field: "dns_config_id", Value: oldConfig.GetID()},
+		},
+	)
+	if err != nil {
+		log.Errf("Error reading dns_configs_app_aliases: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var oldAliases []*cce.DNSConfigAppAlias
+	for _, alias := range persistedAliases {
+		oldAliases = append(oldAliases, alias.(*cce.DNSConfigAppAlias))
+	}
+
+	// Convert the payload to persistable objects
+	newConfig, newAliases, err := fromSwaggerDNSDetail(&requested, oldConfig.GetID())
+	if err != nil {
+		log.Debugf("Validation failed for %#v: %v", requested, err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the config
+	newConfig.SetResourceVersion(ifMatchVersion(r, oldConfig))
+	if err = ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{newConfig}); err != nil {
+		log.Errf("Error updating entities: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	setETag(w, entityTag(newConfig))
+
+	// Replace the aliases in persistence
+	for _, alias := range oldAliases {
+		if _, err = ctrl.PersistenceService.Delete(r.Context(), alias.GetID(), alias); err != nil {
+			log.Errf("Error deleting from dns_configs_app_aliases: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	for _, alias := range newAliases {
+		if err = ctrl.PersistenceService.Create(r.Context(), alias); err != nil {
+			log.Errf("Error creating entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Push the changes to the nodes using the config, once they are committed
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		statuses, err := handleUpdateDNSConfigs(
+			r.Context(),
+			ctrl.PersistenceService,
+			oldConfig.(*cce.DNSConfig), newConfig,
+			oldAliases, newAliases,
+		)
+		if err != nil {
+			log.Errf("Error updating remote entities: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Publish the results on the nodes
+		publishNodeStatuses(r.Context(), newConfig, statuses)
+
+		// Marshal the response object to JSON
+		statusesJSON, err := json.Marshal(swagger.NodeStatusList{Nodes: statuses})
+		if err != nil {
+			log.Errf("Error marshaling response: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(statusesJSON); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+	})
+}
+
+// toSwaggerDNSDetail converts a persisted DNS config and its aliases to the
+// swagger representation.
+func toSwaggerDNSDetail(config *cce.DNSConfig, aliases []cce.Persistable) swagger.DNSDetail {
+	dns := swagger.DNSDetail{
+		DNSSummary: swagger.DNSSummary{
+			ID:   config.ID,
+			Name: config.Name,
+		},
+	}
+
+	// Add the IP based A records
+	for _, record := range config.ARecords {
+		rec := swagger.DNSARecord{
+			Name:        record.Name,
+			Description: record.Description,
+			Alias:       false,
+			Values:      record.IPs,
+		}
+		dns.Records.A = append(dns.Records.A, rec)
+	}
+
+	// Add the alias based A records
+	for _, record := range aliases {
+		rec := swagger.DNSARecord{
+			Name:        record.(*cce.DNSConfigAppAlias).Name,
+			Description: record.(*cce.DNSConfigAppAlias).Description,
+			Alias:       true,
+			Values:      []string{record.(*cce.DNSConfigAppAlias).AppID},
+		}
+		dns.Records.A = append(dns.Records.A, rec)
+	}
+
+	// Add the forwarders
+	for _, forwarder := range config.Forwarders {
+		fwdr := swagger.DNSForwarder{
+			Name:        forwarder.Name,
+			Description: forwarder.Description,
+			Value:       forwarder.IP,
+		}
+		dns.Configurations.Forwarders = append(dns.Configurations.Forwarders, fwdr)
+	}
+
+	return dns
+}
+
+// fromSwaggerDNSDetail converts the swagger representation of DNS settings to
+// a persistable DNS config with the given ID and its app aliases.
+func fromSwaggerDNSDetail(
+	requested *swagger.DNSDetail,
+	configID string,
+) (*cce.DNSConfig, []*cce.DNSConfigAppAlias, error) {
+	config := &cce.DNSConfig{
+		ID:   configID,
+		Name: requested.Name,
+	}
+	var aliases []*cce.DNSConfigAppAlias
+
+	for _, req := range requested.Records.A {
+		switch {
+		case req.Alias && len(req.Values) != 0:
+			record := &cce.DNSConfigAppAlias{
+				ID:          uuid.New(),
+				DNSConfigID: config.ID,
+				Name:        req.Name,
+				Description: req.Description,
+				AppID:       req.Values[0],
+			}
+			if err := record.Validate(); err != nil {
+				return nil, nil, err
+			}
+			aliases = append(aliases, record)
+		case !req.Alias:
+			record := &cce.DNSARecord{
+				Name:        req.Name,
+				Description: req.Description,
+				IPs:         req.Values,
+			}
+			if err := record.Validate(); err != nil {
+				return nil, nil, err
+			}
+			config.ARecords = append(config.ARecords, record)
+		}
+	}
+	for _, req := range requested.Configurations.Forwarders {
+		forwarder := &cce.DNSForwarder{
+			Name:        req.Name,
+			Description: req.Description,
+			IP:          req.Value,
+		}
+		if err := forwarder.Validate(); err != nil {
+			return nil, nil, err
+		}
+		config.Forwarders = append(config.Forwarders, forwarder)
+	}
+
+	return config, aliases, nil
+}
+
+// Used for GET /nodes/{node_id}/interfaces endpoint
+func (g *Gorilla) swagGETInterfaces(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the entity from persistence and check if it's there
 	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -1171,24 +1693,28 @@ func (g *Gorilla) swagPATCHInterfaces(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	code, err := handleUpdateNodes(r.Context(), ctrl.PersistenceService, &requested)
-	switch {
-	case code != 0:
-		log.Errf("Error updating remote entities: %v", err)
-		w.WriteHeader(code)
-		_, err = w.Write([]byte(err.Error()))
-		if err != nil {
-			log.Errf("Error writing response: %v", err)
+	// Update the interfaces on the node, outside the transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		code, err := handleUpdateNodes(r.Context(), ctrl.PersistenceService, &requested)
+		switch {
+		case code != 0:
+			log.Errf("Error updating remote entities: %v", err)
+			w.WriteHeader(code)
+			_, err = w.Write([]byte(err.Error()))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
 		}
-		return
-	}
 
-	// Persist the object
-	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&requested}); err != nil {
-		log.Errf("Error updating entities: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+		// Persist the object
+		if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&requested}); err != nil {
+			log.Errf("Error updating entities: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
 }
 
 // Used for GET /nodes/{node_id}/interfaces/{interface_id} endpoint
@@ -1282,6 +1808,7 @@ func (g *Gorilla) swagGETNodeInterfacePolicy(w http.ResponseWriter, r *http.Requ
 		baseResource = swagger.BaseResource{
 			ID: nodeIFacePolicies[0].(*cce.NodeInterfaceTrafficPolicy).TrafficPolicyID,
 		}
+		setETag(w, replacedEntityTag(nodeIFacePolicies[0]))
 	}
 
 	// Marshal the response object to JSON
@@ -1322,6 +1849,15 @@ func (g *Gorilla) swagPATCHNodeInterfacePolicy(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// Check the precondition against the current policy of the interface
+	if !checkIfMatchFilter(w, r, ctrl.PersistenceService, &cce.NodeInterfaceTrafficPolicy{},
+		[]cce.Filter{
+			{Field: "node_id", Value: mux.Vars(r)["node_id"]},
+			{Field: "network_interface_id", Value: mux.Vars(r)["interface_id"]},
+		}) {
+		return
+	}
+
 	// TODO: Verify the interface ID is valid
 
 	// Query traffic_policies to verify the baseResourceID is valid
@@ -1353,68 +1889,71 @@ func (g *Gorilla) swagPATCHNodeInterfacePolicy(w http.ResponseWriter, r *http.Re
 		},
 	}
 
-	// Update the remote node
-	code, err := handleUpdateNodes(r.Context(), ctrl.PersistenceService, &requested)
-	switch {
-	case code != 0:
-		log.Errf("Error updating remote entities: %v", err)
-		w.WriteHeader(code)
-		_, err = w.Write([]byte(err.Error()))
-		if err != nil {
-			log.Errf("Error writing response: %v", err)
+	// Update the remote node and persist the policy if that succeeds, outside
+	// the transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+
+		// Update the remote node and publish the result
+		code, err := handleUpdateNodes(r.Context(), ctrl.PersistenceService, &requested)
+		publishNodeResult(r.Context(), policy, mux.Vars(r)["node_id"], err)
+		switch {
+		case code != 0:
+			log.Errf("Error updating remote entities: %v", err)
+			w.WriteHeader(code)
+			_, err = w.Write([]byte(err.Error()))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
 		}
-		return
-	}
 
-	// Filter nodes_interfaces_traffic_policies to see if a record already exists
-	nodeIfacePolicy, err := ctrl.PersistenceService.Filter(
-		r.Context(),
-		&cce.NodeInterfaceTrafficPolicy{},
-		[]cce.Filter{
-			{
-				Field: "network_interface_id",
-				Value: mux.Vars(r)["interface_id"],
-			},
-		})
-	if err != nil {
-		log.Errf("Error reading nodes_interfaces_traffic_policies: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+		// Replace the record of the interface's policy in a transaction
+		err = ctrl.PersistenceService.WithTx(r.Context(), func(tx cce.PersistenceService) error {
+			// Filter nodes_interfaces_traffic_policies to see if a record already exists
+			nodeIfacePolicy, err := tx.Filter(
+				r.Context(),
+				&cce.NodeInterfaceTrafficPolicy{},
+				[]cce.Filter{
+					{
+						Field: "network_interface_id",
+						Value: mux.Vars(r)["interface_id"],
+					},
+				})
+			if err != nil {
+				return errors.Wrap(err, "error reading nodes_interfaces_traffic_policies")
+			}
 
-	// If it exists, delete it
-	if len(nodeIfacePolicy) == 1 {
-		ok, err := ctrl.PersistenceService.Delete(
-			r.Context(),
-			nodeIfacePolicy[0].GetID(),
-			&cce.NodeInterfaceTrafficPolicy{},
-		)
+			// If it exists, delete it
+			if len(nodeIfacePolicy) == 1 {
+				zv := &cce.NodeInterfaceTrafficPolicy{}
+				zv.SetResourceVersion(ifMatchVersion(r, nodeIfacePolicy[0]))
+				ok, err := tx.Delete(r.Context(), nodeIfacePolicy[0].GetID(), zv)
+				if err != nil {
+					return errors.Wrap(err, "error deleting from nodes_interfaces_traffic_policies")
+				}
+				if !ok {
+					return errors.New("did not delete 1 record from nodes_interfaces_traffic_policies")
+				}
+			}
+
+			// Persist the new record
+			return tx.Create(r.Context(), &cce.NodeInterfaceTrafficPolicy{
+				ID:                 uuid.New(),
+				NodeID:             mux.Vars(r)["node_id"],
+				NetworkInterfaceID: mux.Vars(r)["interface_id"],
+				TrafficPolicyID:    baseResource.ID,
+			})
+		})
 		if err != nil {
-			log.Errf("Error deleting from nodes_interfaces_traffic_policies: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		if !ok {
-			log.Err("Did not delete 1 record from nodes_interfaces_traffic_policies")
+			log.Errf("Error replacing the policy of the interface: %v", err)
+			if isVersionConflict(err) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
 			w.WriteHeader(http.StatusInternalServerError)
-			return
 		}
-	}
-
-	// Convert the base resource to a persistable object
-	persisted := cce.NodeInterfaceTrafficPolicy{
-		ID:                 uuid.New(),
-		NodeID:             mux.Vars(r)["node_id"],
-		NetworkInterfaceID: mux.Vars(r)["interface_id"],
-		TrafficPolicyID:    baseResource.ID,
-	}
-
-	// Persist the object
-	if err := ctrl.PersistenceService.Create(r.Context(), &persisted); err != nil {
-		log.Errf("Error creating entity: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	})
 }
 
 // Used for DELETE /nodes/{node_id}/interfaces/{interface_id}/policy endpoint
@@ -1432,6 +1971,15 @@ func (g *Gorilla) swagDELETENodeInterfacePolicy(w http.ResponseWriter, r *http.R
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+
+	// Check the precondition against the current policy of the interface
+	if !checkIfMatchFilter(w, r, ctrl.PersistenceService, &cce.NodeInterfaceTrafficPolicy{},
+		[]cce.Filter{
+			{Field: "node_id", Value: mux.Vars(r)["node_id"]},
+			{Field: "network_interface_id", Value: mux.Vars(r)["interface_id"]},
+		}) {
+		return
+	}
 	// TODO: Verify the interface ID is valid
 
 	// Construct the update object to dial to the node
@@ -1447,54 +1995,60 @@ func (g *Gorilla) swagDELETENodeInterfacePolicy(w http.ResponseWriter, r *http.R
 		},
 	}
 
-	// Update the remote node
-	code, err := handleUpdateNodes(r.Context(), ctrl.PersistenceService, &requested)
-	switch {
-	case code != 0:
-		log.Errf("Error updating remote entities: %v", err)
-		w.WriteHeader(code)
-		_, err = w.Write([]byte(err.Error()))
-		if err != nil {
-			log.Errf("Error writing response: %v", err)
+	// Update the remote node and delete the persisted policy if that succeeds,
+	// outside the transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		code, err := handleUpdateNodes(r.Context(), ctrl.PersistenceService, &requested)
+		switch {
+		case code != 0:
+			log.Errf("Error updating remote entities: %v", err)
+			w.WriteHeader(code)
+			_, err = w.Write([]byte(err.Error()))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
 		}
-		return
-	}
-
-	// Filter nodes_interfaces_traffic_policies to see if a record already exists
-	nodeIfacePolicy, err := ctrl.PersistenceService.Filter(
-		r.Context(),
-		&cce.NodeInterfaceTrafficPolicy{},
-		[]cce.Filter{
-			{
-				Field: "network_interface_id",
-				Value: mux.Vars(r)["interface_id"],
-			},
-		})
-	if err != nil {
-		log.Errf("Error reading nodes_interfaces_traffic_policies: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
 
-	// If it exists, delete it
-	if len(nodeIfacePolicy) == 1 {
-		ok, err := ctrl.PersistenceService.Delete(
+		// Filter nodes_interfaces_traffic_policies to see if a record already exists
+		nodeIfacePolicy, err := ctrl.PersistenceService.Filter(
 			r.Context(),
-			nodeIfacePolicy[0].GetID(),
 			&cce.NodeInterfaceTrafficPolicy{},
-		)
+			[]cce.Filter{
+				{
+					Field: "network_interface_id",
+					Value: mux.Vars(r)["interface_id"],
+				},
+			})
 		if err != nil {
-			log.Errf("Error deleting from nodes_interfaces_traffic_policies: %v", err)
+			log.Errf("Error reading nodes_interfaces_traffic_policies: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		if !ok {
-			log.Err("Did not delete 1 record from nodes_interfaces_traffic_policies")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+
+		// If it exists, delete it
+		if len(nodeIfacePolicy) == 1 {
+			zv := &cce.NodeInterfaceTrafficPolicy{}
+			zv.SetResourceVersion(ifMatchVersion(r, nodeIfacePolicy[0]))
+			ok, err := ctrl.PersistenceService.Delete(r.Context(), nodeIfacePolicy[0].GetID(), zv)
+			if err != nil {
+				log.Errf("Error deleting from nodes_interfaces_traffic_policies: %v", err)
+				if isVersionConflict(err) {
+					w.WriteHeader(http.StatusPreconditionFailed)
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				log.Err("Did not delete 1 record from nodes_interfaces_traffic_policies")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
 		}
-	}
-	w.WriteHeader(http.StatusNoContent)
+		w.WriteHeader(http.StatusNoContent)
+	})
 }
 
 // Query the DB to get the NFD features for a node. Return in a map form.
@@ -1543,25 +2097,30 @@ func (g *Gorilla) swagGETNodeApps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Construct the response object
-	nodeApps := swagger.NodeAppList{NodeApps: []swagger.NodeAppSummary{}}
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.NodeApp{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
 
 	// Filter nodes_apps to get the node_app_id
-	persisted, err := ctrl.PersistenceService.Filter(
-		r.Context(),
-		&cce.NodeApp{},
-		[]cce.Filter{
-			{
-				Field: "node_id",
-				Value: mux.Vars(r)["node_id"],
-			},
-		})
+	opts.Filters = append([]cce.Filter{{Field: "node_id", Value: mux.Vars(r)["node_id"]}}, opts.Filters...)
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.NodeApp{}, opts)
 	if err != nil {
 		log.Errf("Error filtering node_apps: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	for _, a := range persisted {
+
+	// Construct the response object
+	nodeApps := swagger.NodeAppList{NodeApps: []swagger.NodeAppSummary{}, ListPage: toSwaggerListPage(r, page)}
+	for _, a := range page.Entities {
 		nodeApps.NodeApps = append(nodeApps.NodeApps, swagger.NodeAppSummary{
 			ID: a.(*cce.NodeApp).AppID,
 		})
@@ -1688,20 +2247,60 @@ func (g *Gorilla) swagPOSTNodeApp(w http.ResponseWriter, r *http.Request) { //no
 		return
 	}
 
-	// Create the remote node app
-	err = handleCreateNodesApps(r.Context(), ctrl.PersistenceService, &nodeApp)
-	if err != nil {
-		log.Errf("Error creating node app: %v", err)
+	// Persist the object as deploying in the transaction, so that a concurrent
+	// request for the same app sees it and the app cannot be deleted under it
+	nodeApp.Deploying = true
+	if err = ctrl.PersistenceService.Create(r.Context(), &nodeApp); err != nil {
+		log.Errf("Error creating entity: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	// Persist the object
-	if err := ctrl.PersistenceService.Create(r.Context(), &nodeApp); err != nil {
-		log.Errf("Error creating entity: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	// Deploy the app to the node outside the transaction, and mark it deployed
+	// if that succeeds or delete it if it fails
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		if err := handleCreateNodesApps(r.Context(), ctrl.PersistenceService, &nodeApp); err != nil {
+			log.Errf("Error creating node app: %v", err)
+			if _, err = ctrl.PersistenceService.Delete(r.Context(), nodeApp.ID, &cce.NodeApp{}); err != nil {
+				log.Errf("Error deleting entity: %v", err)
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		persisted, err := ctrl.PersistenceService.Read(r.Context(), nodeApp.ID, &cce.NodeApp{})
+		if err != nil {
+			log.Errf("Error reading entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if persisted == nil {
+			log.Errf("Node app %s was deleted while it was being deployed", nodeApp.ID)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		persisted.(*cce.NodeApp).Deploying = false
+		if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{persisted}); err != nil {
+			log.Errf("Error updating entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// checkNodeAppDeployed writes a 409 if the node app is still being deployed and
+// reports whether the request may go on.
+func checkNodeAppDeployed(w http.ResponseWriter, nodeApp *cce.NodeApp) bool {
+	if !nodeApp.Deploying {
+		return true
+	}
+	w.WriteHeader(http.StatusConflict)
+	_, err := w.Write([]byte(fmt.Sprintf("app %s is being deployed to node %s", nodeApp.AppID, nodeApp.NodeID)))
+	if err != nil {
+		log.Errf("Error writing response: %v", err)
 	}
+	return false
 }
 
 // Used for GET /nodes/{node_id}/apps/{app_id} endpoint
@@ -1738,6 +2337,7 @@ func (g *Gorilla) swagGETNodeAppsByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, entityTag(nodeApps[0]))
 
 	// Create the remote node app
 	response, err := handleGetNodesApps(r.Context(), ctrl.PersistenceService, nodeApps[0])
@@ -1813,6 +2413,12 @@ func (g *Gorilla) swagPATCHNodeAppsByID(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	if !checkIfMatch(w, r, entityTag(nodeApps[0])) {
+		return
+	}
+	if !checkNodeAppDeployed(w, nodeApps[0].(*cce.NodeApp)) {
+		return
+	}
 
 	// Convert it to a persistable object
 	requested := cce.NodeAppReq{
@@ -1831,17 +2437,21 @@ func (g *Gorilla) swagPATCHNodeAppsByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	code, err := handleUpdateNodesApps(r.Context(), ctrl.PersistenceService, &requested)
-	switch {
-	case code != 0:
-		log.Errf("Error updating remote entities: %v", err)
-		w.WriteHeader(code)
-		_, err = w.Write([]byte("error updating remote entity"))
-		if err != nil {
-			log.Errf("Error writing response: %v", err)
+	// Run the command on the node, outside the transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		code, err := handleUpdateNodesApps(r.Context(), ctrl.PersistenceService, &requested)
+		switch {
+		case code != 0:
+			log.Errf("Error updating remote entities: %v", err)
+			w.WriteHeader(code)
+			_, err = w.Write([]byte("error updating remote entity"))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
 		}
-		return
-	}
+	})
 }
 
 // Used for DELETE /nodes/{node_id}/apps/{app_id} endpoint
@@ -1877,6 +2487,12 @@ func (g *Gorilla) swagDELETENodeAppByID(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	if !checkIfMatch(w, r, entityTag(nodeApps[0])) {
+		return
+	}
+	if !checkNodeAppDeployed(w, nodeApps[0].(*cce.NodeApp)) {
+		return
+	}
 
 	// Check that we can delete the entity
 	var statusCode int
@@ -1893,28 +2509,38 @@ func (g *Gorilla) swagDELETENodeAppByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Delete the app from the node
-	if err = handleDeleteNodesApps(
-		r.Context(), ctrl.PersistenceService, nodeApps[0],
-	); err != nil {
-		log.Errf("Error making remote call: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	// Undeploy the app from the node and delete it if that succeeds, outside the
+	// transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		if err := handleDeleteNodesApps(
+			r.Context(), ctrl.PersistenceService, nodeApps[0],
+		); err != nil {
+			log.Errf("Error making remote call: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	// Delete the resource
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), nodeApps[0].(*cce.NodeApp).ID, &cce.NodeApp{})
-	if err != nil {
-		log.Errf("Error deleting entity: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+		// Delete the resource
+		zv := &cce.NodeApp{}
+		zv.SetResourceVersion(ifMatchVersion(r, nodeApps[0]))
+		ok, err := ctrl.PersistenceService.Delete(r.Context(), nodeApps[0].(*cce.NodeApp).ID, zv)
+		if err != nil {
+			log.Errf("Error deleting entity: %v", err)
+			if isVersionConflict(err) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	w.WriteHeader(http.StatusNoContent)
+		w.WriteHeader(http.StatusNoContent)
+	})
 }
 
 // Used for GET /nodes/{node_id}/apps/{app_id}/policy endpoint
@@ -1974,6 +2600,7 @@ func (g *Gorilla) swagGETNodeAppPolicy(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, replacedEntityTag(nodeAppTrafficPolicies[0]))
 
 	// Construct the response object
 	baseResource := swagger.BaseResource{
@@ -2036,6 +2663,12 @@ func (g *Gorilla) swagPATCHNodeAppPolicy(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Check the precondition against the current policy of the node app
+	if !checkIfMatchFilter(w, r, ctrl.PersistenceService, &cce.NodeAppTrafficPolicy{},
+		[]cce.Filter{{Field: "nodes_apps_id", Value: nodeApps[0].GetID()}}) {
+		return
+	}
+
 	// Query traffic_policies to verify the baseResourceID is valid
 	policy, err := ctrl.PersistenceService.Read(r.Context(), baseResource.ID, &cce.TrafficPolicy{})
 	if err != nil {
@@ -2048,78 +2681,52 @@ func (g *Gorilla) swagPATCHNodeAppPolicy(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Connect to node
-	nodePort := ctrl.ELAPort
-	if nodePort == "" {
-		nodePort = defaultELAPort
-	}
-	nodeCC, err := connectNode(
-		r.Context(),
-		ctrl.PersistenceService,
-		nodeApps[0].(*cce.NodeApp),
-		nodePort,
-		ctrl.EdgeNodeCreds)
-	if err != nil {
-		log.Errf("Error connecting to node: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	// Make gRPC call to node to set the policy
-	if err = nodeCC.AppPolicySvcCli.Set(
-		r.Context(),
-		nodeApps[0].(*cce.NodeApp).AppID,
-		policy.(*cce.TrafficPolicy),
-	); err != nil {
-		log.Errf("Error setting policy: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	// Filter nodes_apps_traffic_policies to see if a record already exists
-	nodeAppPolicies, err := ctrl.PersistenceService.Filter(
-		r.Context(),
-		&cce.NodeAppTrafficPolicy{},
-		[]cce.Filter{
-			{
-				Field: "nodes_apps_id",
-				Value: nodeApps[0].GetID(),
-			},
-		})
-	if err != nil {
-		log.Errf("Error reading nodes_apps_traffic_policies: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	// Set the policy on the node and persist it if that succeeds, outside the
+	// transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
 
-	// If it exists, delete it
-	if len(nodeAppPolicies) == 1 {
-		ok, err := ctrl.PersistenceService.Delete(r.Context(), nodeAppPolicies[0].GetID(), &cce.NodeAppTrafficPolicy{})
+		// Connect to node
+		nodePort := ctrl.ELAPort
+		if nodePort == "" {
+			nodePort = defaultELAPort
+		}
+		nodeCC, err := connectNode(
+			r.Context(),
+			ctrl.PersistenceService,
+			nodeApps[0].(*cce.NodeApp),
+			nodePort,
+			ctrl.EdgeNodeCreds)
 		if err != nil {
-			log.Errf("Error deleting from nodes_apps_traffic_policies: %v", err)
+			log.Errf("Error connecting to node: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		if !ok {
-			log.Err("Did not delete 1 record from nodes_apps_traffic_policies")
+
+		// Make gRPC call to node to set the policy and publish the result
+		err = nodeCC.AppPolicySvcCli.Set(
+			r.Context(),
+			nodeApps[0].(*cce.NodeApp).AppID,
+			policy.(*cce.TrafficPolicy),
+		)
+		publishNodeResult(r.Context(), policy, mux.Vars(r)["node_id"], err)
+		if err != nil {
+			log.Errf("Error setting policy: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-	}
 
-	// Convert the base resource to a persistable object
-	persisted := cce.NodeAppTrafficPolicy{
-		ID:              uuid.New(),
-		NodeAppID:       nodeApps[0].GetID(),
-		TrafficPolicyID: baseResource.ID,
-	}
-
-	// Persist the object
-	if err := ctrl.PersistenceService.Create(r.Context(), &persisted); err != nil {
-		log.Errf("Error creating entity: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+		// Replace the persisted policy of the node app
+		err = replaceNodeAppPolicy(r, ctrl.PersistenceService, nodeApps[0].GetID(), baseResource.ID)
+		if err != nil {
+			log.Errf("Error replacing the policy of the node app: %v", err)
+			if isVersionConflict(err) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
 }
 
 // Used for DELETE /nodes/{node_id}/apps/{app_id}/policy endpoint
@@ -2156,6 +2763,12 @@ func (g *Gorilla) swagDELETENodeAppPolicy(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Check the precondition against the current policy of the node app
+	if !checkIfMatchFilter(w, r, ctrl.PersistenceService, &cce.NodeAppTrafficPolicy{},
+		[]cce.Filter{{Field: "nodes_apps_id", Value: nodeApps[0].GetID()}}) {
+		return
+	}
+
 	// Filter nodes_apps_traffic_policies to get the ID
 	nodeAppPolicies, err := ctrl.PersistenceService.Filter(
 		r.Context(),
@@ -2172,47 +2785,57 @@ func (g *Gorilla) swagDELETENodeAppPolicy(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Connect to node
-	nodePort := ctrl.ELAPort
-	if nodePort == "" {
-		nodePort = defaultELAPort
-	}
-	nodeCC, err := connectNode(
-		r.Context(),
-		ctrl.PersistenceService,
-		nodeApps[0].(*cce.NodeApp),
-		nodePort,
-		ctrl.EdgeNodeCreds)
-	if err != nil {
-		log.Errf("Error connecting to node: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	// Delete the policy from the node and from persistence if that succeeds,
+	// outside the transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		nodePort := ctrl.ELAPort
+		if nodePort == "" {
+			nodePort = defaultELAPort
+		}
+		nodeCC, err := connectNode(
+			r.Context(),
+			ctrl.PersistenceService,
+			nodeApps[0].(*cce.NodeApp),
+			nodePort,
+			ctrl.EdgeNodeCreds)
+		if err != nil {
+			log.Errf("Error connecting to node: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	// Make gRPC call to node to delete the policy
-	if err = nodeCC.AppPolicySvcCli.Delete(
-		r.Context(),
-		nodeApps[0].(*cce.NodeApp).AppID,
-	); err != nil {
-		log.Errf("Error deleting policy: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+		// Make gRPC call to node to delete the policy
+		if err = nodeCC.AppPolicySvcCli.Delete(
+			r.Context(),
+			nodeApps[0].(*cce.NodeApp).AppID,
+		); err != nil {
+			log.Errf("Error deleting policy: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	// Delete the resource
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), nodeAppPolicies[0].GetID(), &cce.NodeAppTrafficPolicy{})
-	if err != nil {
-		log.Errf("Error deleting from nodes_apps_traffic_policies: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if !ok {
-		log.Err("Did not delete 1 record from nodes_apps_traffic_policies")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+		// Delete the resource
+		zv := &cce.NodeAppTrafficPolicy{}
+		zv.SetResourceVersion(ifMatchVersion(r, nodeAppPolicies[0]))
+		ok, err := ctrl.PersistenceService.Delete(r.Context(), nodeAppPolicies[0].GetID(), zv)
+		if err != nil {
+			log.Errf("Error deleting from nodes_apps_traffic_policies: %v", err)
+			if isVersionConflict(err) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			log.Err("Did not delete 1 record from nodes_apps_traffic_policies")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	w.WriteHeader(http.StatusNoContent)
+		w.WriteHeader(http.StatusNoContent)
+	})
 }
 
 // Used for GET /nodes/{node_id}/apps/{app_id}/kube_ovn/policy endpoint
@@ -2272,6 +2895,7 @@ func (g *Gorilla) swagGETNodeAppKubeOVNPolicy(w http.ResponseWriter, r *http.Req
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, replacedEntityTag(nodeAppTrafficPolicies[0]))
 
 	// Construct the response object
 	baseResource := swagger.BaseResource{
@@ -2334,6 +2958,12 @@ func (g *Gorilla) swagPATCHNodeAppKubeOVNPolicy(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	// Check the precondition against the current policy of the node app
+	if !checkIfMatchFilter(w, r, ctrl.PersistenceService, &cce.NodeAppTrafficPolicy{},
+		[]cce.Filter{{Field: "nodes_apps_id", Value: nodeApps[0].GetID()}}) {
+		return
+	}
+
 	// Query traffic_policies to verify the baseResourceID is valid
 	policy, err := ctrl.PersistenceService.Read(r.Context(), baseResource.ID, &cce.TrafficPolicyKubeOVN{})
 	if err != nil {
@@ -2346,63 +2976,35 @@ func (g *Gorilla) swagPATCHNodeAppKubeOVNPolicy(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Try delete network policy for app
-	_ = ctrl.KubernetesClient.DeleteNetworkPolicy(r.Context(), nodeApps[0].(*cce.NodeApp).NodeID,
-		nodeApps[0].(*cce.NodeApp).AppID)
-
-	// Apply new network policy for app
-	if err = ctrl.KubernetesClient.ApplyNetworkPolicy(r.Context(), nodeApps[0].(*cce.NodeApp).NodeID,
-		nodeApps[0].(*cce.NodeApp).AppID, policy.(*cce.TrafficPolicyKubeOVN).ToK8s(),
-	); err != nil {
-		log.Errf("Error setting policy: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	// Apply the policy for the app and persist it if that succeeds, outside the
+	// transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
 
-	// Filter nodes_apps_traffic_policies to see if a record already exists
-	nodeAppPolicies, err := ctrl.PersistenceService.Filter(
-		r.Context(),
-		&cce.NodeAppTrafficPolicy{},
-		[]cce.Filter{
-			{
-				Field: "nodes_apps_id",
-				Value: nodeApps[0].GetID(),
-			},
-		})
-	if err != nil {
-		log.Errf("Error reading nodes_apps_traffic_policies: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+		// Try delete network policy for app
+		_ = ctrl.KubernetesClient.DeleteNetworkPolicy(r.Context(), nodeApps[0].(*cce.NodeApp).NodeID,
+			nodeApps[0].(*cce.NodeApp).AppID)
 
-	// If it exists, delete it
-	if len(nodeAppPolicies) == 1 {
-		ok, err := ctrl.PersistenceService.Delete(r.Context(), nodeAppPolicies[0].GetID(), &cce.NodeAppTrafficPolicy{})
-		if err != nil {
-			log.Errf("Error deleting from nodes_apps_traffic_policies: %v", err)
+		// Apply new network policy for app
+		if err := ctrl.KubernetesClient.ApplyNetworkPolicy(r.Context(), nodeApps[0].(*cce.NodeApp).NodeID,
+			nodeApps[0].(*cce.NodeApp).AppID, policy.(*cce.TrafficPolicyKubeOVN).ToK8s(),
+		); err != nil {
+			log.Errf("Error setting policy: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		if !ok {
-			log.Err("Did not delete 1 record from nodes_apps_traffic_policies")
+
+		// Replace the persisted policy of the node app
+		err := replaceNodeAppPolicy(r, ctrl.PersistenceService, nodeApps[0].GetID(), baseResource.ID)
+		if err != nil {
+			log.Errf("Error replacing the policy of the node app: %v", err)
+			if isVersionConflict(err) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
 			w.WriteHeader(http.StatusInternalServerError)
-			return
 		}
-	}
-
-	// Convert the base resource to a persistable object
-	persisted := cce.NodeAppTrafficPolicy{
-		ID:              uuid.New(),
-		NodeAppID:       nodeApps[0].GetID(),
-		TrafficPolicyID: baseResource.ID,
-	}
-
-	// Persist the object
-	if err := ctrl.PersistenceService.Create(r.Context(), &persisted); err != nil {
-		log.Errf("Error creating entity: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	})
 }
 
 // Used for DELETE /nodes/{node_id}/apps/{app_id}/kube_ovn/policy endpoint
@@ -2439,6 +3041,12 @@ func (g *Gorilla) swagDELETENodeAppKubeOVNPolicy(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// Check the precondition against the current policy of the node app
+	if !checkIfMatchFilter(w, r, ctrl.PersistenceService, &cce.NodeAppTrafficPolicy{},
+		[]cce.Filter{{Field: "nodes_apps_id", Value: nodeApps[0].GetID()}}) {
+		return
+	}
+
 	// Filter nodes_apps_traffic_policies to get the ID
 	nodeAppPolicies, err := ctrl.PersistenceService.Filter(
 		r.Context(),
@@ -2455,29 +3063,39 @@ func (g *Gorilla) swagDELETENodeAppKubeOVNPolicy(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// Make gRPC call to node to delete the policy
-	if err = ctrl.KubernetesClient.DeleteNetworkPolicy(
-		r.Context(), nodeApps[0].(*cce.NodeApp).NodeID, nodeApps[0].(*cce.NodeApp).AppID,
-	); err != nil {
-		log.Errf("Error deleting policy: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	// Delete the network policy of the app and its persisted policy if that
+	// succeeds, outside the transaction
+	callNodes(w, r, func(w http.ResponseWriter, r *http.Request) {
+		ctrl := getController(r.Context())
+		if err := ctrl.KubernetesClient.DeleteNetworkPolicy(
+			r.Context(), nodeApps[0].(*cce.NodeApp).NodeID, nodeApps[0].(*cce.NodeApp).AppID,
+		); err != nil {
+			log.Errf("Error deleting policy: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	// Delete the resource
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), nodeAppPolicies[0].GetID(), &cce.NodeAppTrafficPolicy{})
-	if err != nil {
-		log.Errf("Error deleting from nodes_apps_traffic_policies: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if !ok {
-		log.Err("Did not delete 1 record from nodes_apps_traffic_policies")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+		// Delete the resource
+		zv := &cce.NodeAppTrafficPolicy{}
+		zv.SetResourceVersion(ifMatchVersion(r, nodeAppPolicies[0]))
+		ok, err := ctrl.PersistenceService.Delete(r.Context(), nodeAppPolicies[0].GetID(), zv)
+		if err != nil {
+			log.Errf("Error deleting from nodes_apps_traffic_policies: %v", err)
+			if isVersionConflict(err) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			log.Err("Did not delete 1 record from nodes_apps_traffic_policies")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	w.WriteHeader(http.StatusNoContent)
+		w.WriteHeader(http.StatusNoContent)
+	})
 }
 
 // Return the NFD tags of a node in a Json form to the remote caller
@@ -2510,3 +3128,1276 @@ func (g *Gorilla) swagGETNodeNFDTags(w http.ResponseWriter, r *http.Request) {
 	}
 	fmt.Fprintf(w, "\n")
 }
+
+// Used for GET /audit endpoint
+func (g *Gorilla) swagGETAudit(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the time range and list options from the query parameters
+	query := r.URL.Query()
+	timeFilters, err := parseAuditTimeRange(query)
+	var opts cce.ListOptions
+	if err == nil {
+		opts, err = parseListOptions(query, &cce.AuditEvent{})
+	}
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+	opts.Filters = append(opts.Filters, timeFilters...)
+	if opts.Sort == "" {
+		// Newest first
+		opts.Sort = "-time"
+	}
+
+	// Fetch the audit events from persistence
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.AuditEvent{}, opts)
+	if err != nil {
+		log.Errf("Error listing audit events: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	events := swagger.AuditEventList{Events: []swagger.AuditEvent{}, ListPage: toSwaggerListPage(r, page)}
+	for _, e := range page.Entities {
+		events.Events = append(events.Events, toSwaggerAuditEvent(e.(*cce.AuditEvent)))
+	}
+
+	// Marshal the response object to JSON
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		log.Errf("Error marshaling audit events: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(eventsJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /users endpoint
+func (g *Gorilla) swagGETUsers(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.User{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Fetch the users from persistence
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.User{}, opts)
+	if err != nil {
+		log.Errf("Error listing users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	users := swagger.UserList{Users: []swagger.UserSummary{}, ListPage: toSwaggerListPage(r, page)}
+	for _, e := range page.Entities {
+		users.Users = append(users.Users, toSwaggerUserSummary(e.(*cce.User)))
+	}
+
+	// Marshal the response object to JSON
+	usersJSON, err := json.Marshal(users)
+	if err != nil {
+		log.Errf("Error marshaling users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(usersJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /users endpoint
+func (g *Gorilla) swagPOSTUsers(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	create := swagger.UserCreate{}
+	if err := json.Unmarshal(body, &create); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Convert it to a persistable object
+	persisted := cce.User{
+		ID:             uuid.New(),
+		Name:           create.Name,
+		Roles:          create.Roles,
+		ServiceAccount: create.ServiceAccount,
+	}
+
+	// Validate the password, unless it's a service account without one, and
+	// the object
+	var err error
+	if create.Password != "" || !persisted.ServiceAccount {
+		err = persisted.ValidatePassword(create.Password)
+		if err == nil {
+			if err = persisted.SetPassword(create.Password); err != nil {
+				log.Errf("Error hashing password: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	if err == nil {
+		err = persisted.Validate()
+	}
+	if err != nil {
+		log.Debugf("Validation failed for %s: %v", persisted.String(), err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Check that the name is not taken
+	existing, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, persisted.Name)
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		w.WriteHeader(http.StatusConflict)
+		_, err = w.Write([]byte(fmt.Sprintf("user %q already exists", persisted.Name)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the object
+	if err = ctrl.PersistenceService.Create(r.Context(), &persisted); err != nil {
+		log.Errf("Error creating entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Marshal the response object to JSON
+	userJSON, err := json.Marshal(swagger.UserDetail{UserSummary: toSwaggerUserSummary(&persisted)})
+	if err != nil {
+		log.Errf("Error marshaling user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	setETag(w, entityTag(&persisted))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(userJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /users/{name} endpoint
+func (g *Gorilla) swagGETUserByName(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the entity from persistence and check if it's there
+	persisted, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, mux.Vars(r)["name"])
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	setETag(w, entityTag(persisted))
+
+	// Marshal the response object to JSON
+	userJSON, err := json.Marshal(swagger.UserDetail{UserSummary: toSwaggerUserSummary(persisted)})
+	if err != nil {
+		log.Errf("Error marshaling user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(userJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for PATCH /users/{name} endpoint
+func (g *Gorilla) swagPATCHUserByName(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	update := swagger.UserUpdate{}
+	if err := json.Unmarshal(body, &update); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the current entity from persistence and check the precondition
+	persisted, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, mux.Vars(r)["name"])
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !checkIfMatch(w, r, entityTag(persisted)) {
+		return
+	}
+	persisted.SetResourceVersion(ifMatchVersion(r, persisted))
+
+	// Update and validate the object
+	persisted.Roles = update.Roles
+	if err = persisted.Validate(); err != nil {
+		log.Debugf("Validation failed for %s: %v", persisted.String(), err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the object
+	if err = ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{persisted}); err != nil {
+		log.Errf("Error updating entities: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	setETag(w, entityTag(persisted))
+}
+
+// Used for PATCH /users/{name}/password endpoint
+func (g *Gorilla) swagPATCHUserPassword(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Users can change their own password, admins can change any password
+	actor, _ := r.Context().Value(contextKey("actor")).(string)
+	roles, _ := r.Context().Value(contextKey("roles")).([]string)
+	self := actor == mux.Vars(r)["name"]
+	if !self && !cce.HasPermission(roles, cce.PermissionAdmin) {
+		log.Debugf("User '%s' is not permitted to change the password of '%s'", actor, mux.Vars(r)["name"])
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Unmarshal the payload
+	change := swagger.UserPassword{}
+	if err := json.Unmarshal(body, &change); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the current entity from persistence and check the precondition
+	persisted, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, mux.Vars(r)["name"])
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !checkIfMatch(w, r, entityTag(persisted)) {
+		return
+	}
+	persisted.SetResourceVersion(ifMatchVersion(r, persisted))
+
+	// Users must confirm their current password
+	if self && !persisted.CheckPassword(change.CurrentPassword) {
+		log.Debugf("Wrong current password for user '%s'", persisted.Name)
+		w.WriteHeader(http.StatusForbidden)
+		_, err = w.Write([]byte("current password is incorrect"))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Validate and set the password, which service accounts cannot have
+	if err = persisted.ValidatePassword(change.Password); err == nil {
+		if err = persisted.SetPassword(change.Password); err != nil {
+			log.Errf("Error hashing password: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		err = persisted.Validate()
+	}
+	if err != nil {
+		log.Debugf("Validation failed for the password of %s: %v", persisted.Name, err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the object
+	if err = ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{persisted}); err != nil {
+		log.Errf("Error updating entities: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	setETag(w, entityTag(persisted))
+}
+
+// Used for DELETE /users/{name} endpoint
+func (g *Gorilla) swagDELETEUserByName(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the entity from persistence and check if it's there
+	persisted, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, mux.Vars(r)["name"])
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !checkIfMatch(w, r, entityTag(persisted)) {
+		return
+	}
+
+	// Delete the entity, if it's still at the version the precondition matched
+	zv := &cce.User{}
+	zv.SetResourceVersion(ifMatchVersion(r, persisted))
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), persisted.ID, zv)
+	if err != nil {
+		log.Errf("Error deleting entity: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// we just fetched the entity, so if !ok then something went wrong
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// Used for GET /users/{name}/api_keys endpoint
+func (g *Gorilla) swagGETUserAPIKeys(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the user from persistence and check if it's there
+	user, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, mux.Vars(r)["name"])
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.APIKey{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+	opts.Filters = append(opts.Filters, cce.Filter{Field: "user_id", Value: user.ID})
+
+	// Fetch the API keys from persistence
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.APIKey{}, opts)
+	if err != nil {
+		log.Errf("Error listing API keys: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	keys := swagger.APIKeyList{APIKeys: []swagger.APIKeySummary{}, ListPage: toSwaggerListPage(r, page)}
+	for _, e := range page.Entities {
+		keys.APIKeys = append(keys.APIKeys, toSwaggerAPIKeySummary(e.(*cce.APIKey)))
+	}
+
+	// Marshal the response object to JSON
+	keysJSON, err := json.Marshal(keys)
+	if err != nil {
+		log.Errf("Error marshaling API keys: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(keysJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /users/{name}/api_keys endpoint
+func (g *Gorilla) swagPOSTUserAPIKeys(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	create := swagger.APIKeyCreate{}
+	if err := json.Unmarshal(body, &create); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the user from persistence and check if it's there
+	user, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, mux.Vars(r)["name"])
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Create the key with the user's roles, unless it's scoped to fewer
+	roles := create.Roles
+	if len(roles) == 0 {
+		roles = user.Roles
+	}
+	var expiresAt time.Time
+	if create.ExpiresAt != nil {
+		expiresAt = *create.ExpiresAt
+	}
+	persisted, key, err := cce.NewAPIKey(user.ID, create.Name, roles, expiresAt)
+	if err != nil {
+		log.Errf("Error generating API key: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Validate the object
+	err = persisted.Validate()
+	if err == nil && len(persisted.Scope(user)) != len(persisted.Roles) {
+		err = fmt.Errorf("roles must be roles of user %q", user.Name)
+	}
+	if err != nil {
+		log.Debugf("Validation failed for %s: %v", persisted.String(), err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Check that the name is not taken
+	existing, err := ctrl.PersistenceService.Filter(r.Context(), &cce.APIKey{}, []cce.Filter{
+		{Field: "user_id", Value: user.ID},
+		{Field: "name", Value: persisted.Name},
+	})
+	if err != nil {
+		log.Errf("Error filtering API keys: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(existing) != 0 {
+		w.WriteHeader(http.StatusConflict)
+		_, err = w.Write([]byte(fmt.Sprintf("API key %q of user %q already exists", persisted.Name, user.Name)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the object
+	if err = ctrl.PersistenceService.Create(r.Context(), persisted); err != nil {
+		log.Errf("Error creating entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Marshal the response object, which is the only time the key is shown,
+	// to JSON
+	keyJSON, err := json.Marshal(swagger.APIKeyDetail{
+		APIKeySummary: toSwaggerAPIKeySummary(persisted),
+		Key:           key,
+	})
+	if err != nil {
+		log.Errf("Error marshaling API key: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(keyJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for DELETE /users/{name}/api_keys/{api_key_id} endpoint
+func (g *Gorilla) swagDELETEUserAPIKeyByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the user and the entity from persistence and check if they're
+	// there
+	user, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, mux.Vars(r)["name"])
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["api_key_id"], &cce.APIKey{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil || persisted.(*cce.APIKey).UserID != user.ID {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Delete the entity, which revokes the key
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), persisted.GetID(), &cce.APIKey{})
+	if err != nil {
+		log.Errf("Error deleting entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// we just fetched the entity, so if !ok then something went wrong
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// Used for POST /users/{name}/certificates endpoint
+func (g *Gorilla) swagPOSTUserCertificates(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
+	// Load the controller to access the persistence, the CA and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Users can request their own certificates, admins any user's
+	actor, _ := r.Context().Value(contextKey("actor")).(string)
+	roles, _ := r.Context().Value(contextKey("roles")).([]string)
+	if actor != mux.Vars(r)["name"] && !cce.HasPermission(roles, cce.PermissionAdmin) {
+		log.Debugf("User '%s' is not permitted to request certificates of '%s'", actor, mux.Vars(r)["name"])
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Unmarshal the payload
+	req := swagger.UserCertificateRequest{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the user from persistence and check if it's there
+	user, err := cce.ReadUser(r.Context(), ctrl.PersistenceService, mux.Vars(r)["name"])
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Certificates authenticate with all of the user's roles, so callers
+	// authenticated with fewer, as with a scoped API key, cannot request them
+	if !cce.CoversRoles(roles, user.Roles) {
+		log.Debugf("User '%s' is not permitted to request certificates with the roles of '%s'", actor, user.Name)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Decode and validate the CSR, which must be signed with its key
+	csr, err := parseCSR(req.CSR)
+	if err != nil {
+		log.Debugf("Validation failed for the CSR of %s: %v", user.Name, err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Sign the CSR for the user
+	cert, err := ctrl.AuthorityService.SignCSR(
+		csr.Raw, cce.NewUserCertificateTemplate(user, cce.DefaultUserCertificateLifetime))
+	if err != nil {
+		log.Errf("Error signing CSR: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	caChain, err := ctrl.AuthorityService.CAChain()
+	if err != nil {
+		log.Errf("Error getting CA chain: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	log.Infof("Issued a certificate expiring at %s to user '%s'", cert.NotAfter, user.Name)
+
+	// Marshal the response object to JSON
+	certJSON, err := json.Marshal(toSwaggerUserCertificate(cert, caChain))
+	if err != nil {
+		log.Errf("Error marshaling certificate: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(certJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /events endpoint
+func (g *Gorilla) swagGETEvents(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
+	// Load the controller to access the event bus
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	if ctrl.Events == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Err("Error streaming events: response writer cannot flush")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Parse the filter and the last event the client received
+	filter := parseEventFilter(r.URL.Query())
+	lastID, resume, err := parseLastEventID(r)
+	if err != nil {
+		log.Debugf("Bad last event ID: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Subscribe to the events, resuming after the last one
+	sub, missed := subscribeEvents(ctrl.Events, filter, lastID, resume)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Stream the events until the client disconnects
+	for _, e := range missed {
+		if !canStreamEvent(r, e) {
+			continue
+		}
+		if err = writeEvent(w, e); err != nil {
+			log.Debugf("Error writing event: %v", err)
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err = w.Write([]byte(": keep-alive\n\n")); err != nil {
+				log.Debugf("Error writing keep-alive: %v", err)
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				// The client was too slow and resumes when it reconnects
+				log.Debugf("Dropped slow event stream of %s", r.RemoteAddr)
+				return
+			}
+			if !canStreamEvent(r, e) {
+				continue
+			}
+			if err = writeEvent(w, e); err != nil {
+				log.Debugf("Error writing event: %v", err)
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// Used for GET /webhooks endpoint
+func (g *Gorilla) swagGETWebhooks(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.Webhook{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Fetch the webhooks from persistence
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.Webhook{}, opts)
+	if err != nil {
+		log.Errf("Error listing webhooks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	webhooks := swagger.WebhookList{Webhooks: []swagger.WebhookSummary{}, ListPage: toSwaggerListPage(r, page)}
+	for _, e := range page.Entities {
+		webhooks.Webhooks = append(webhooks.Webhooks, toSwaggerWebhookSummary(e.(*cce.Webhook)))
+	}
+
+	// Marshal the response object to JSON
+	webhooksJSON, err := json.Marshal(webhooks)
+	if err != nil {
+		log.Errf("Error marshaling webhooks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(webhooksJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /webhooks endpoint
+func (g *Gorilla) swagPOSTWebhooks(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	create := swagger.WebhookCreate{}
+	if err := json.Unmarshal(body, &create); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Convert it to a persistable object, generating a secret if none is given
+	persisted := cce.Webhook{
+		ID:         uuid.New(),
+		Name:       create.Name,
+		URL:        create.URL,
+		EventTypes: create.EventTypes,
+		Secret:     create.Secret,
+	}
+	generated := persisted.Secret == ""
+	if generated {
+		var err error
+		if persisted.Secret, err = cce.NewWebhookSecret(); err != nil {
+			log.Errf("Error generating webhook secret: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Validate the object
+	if err := persisted.Validate(); err != nil {
+		log.Debugf("Validation failed for %s: %v", persisted.String(), err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Check that the name is not taken
+	ok, err := checkWebhookName(r.Context(), ctrl.PersistenceService, &persisted)
+	if err != nil {
+		log.Errf("Error filtering webhooks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+		_, err = w.Write([]byte(webhookNameConflict(persisted.Name)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the object
+	if err = ctrl.PersistenceService.Create(r.Context(), &persisted); err != nil {
+		log.Errf("Error creating entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object, which only includes the secret if it was
+	// generated as the client doesn't know it
+	detail := swagger.WebhookDetail{WebhookSummary: toSwaggerWebhookSummary(&persisted)}
+	if generated {
+		detail.Secret = persisted.Secret
+	}
+
+	// Marshal the response object to JSON
+	webhookJSON, err := json.Marshal(detail)
+	if err != nil {
+		log.Errf("Error marshaling webhook: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	setETag(w, entityTag(&persisted))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(webhookJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /webhooks/{webhook_id} endpoint
+func (g *Gorilla) swagGETWebhookByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the entity from persistence and check if it's there
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["webhook_id"], &cce.Webhook{})
+	if err != nil {
+		log.Errf("Error reading webhook: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	setETag(w, entityTag(persisted))
+
+	// Marshal the response object to JSON
+	webhookJSON, err := json.Marshal(
+		swagger.WebhookDetail{WebhookSummary: toSwaggerWebhookSummary(persisted.(*cce.Webhook))})
+	if err != nil {
+		log.Errf("Error marshaling webhook: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(webhookJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for PATCH /webhooks/{webhook_id} endpoint
+func (g *Gorilla) swagPATCHWebhookByID(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	update := swagger.WebhookUpdate{}
+	if err := json.Unmarshal(body, &update); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the current entity from persistence and check the precondition
+	e, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["webhook_id"], &cce.Webhook{})
+	if err != nil {
+		log.Errf("Error reading webhook: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	persisted := e.(*cce.Webhook)
+	if !checkIfMatch(w, r, entityTag(persisted)) {
+		return
+	}
+	persisted.SetResourceVersion(ifMatchVersion(r, persisted))
+
+	// Update and validate the object
+	if update.Name != nil {
+		persisted.Name = *update.Name
+	}
+	if update.URL != nil {
+		persisted.URL = *update.URL
+	}
+	if update.EventTypes != nil {
+		persisted.EventTypes = *update.EventTypes
+	}
+	if update.Secret != nil {
+		persisted.Secret = *update.Secret
+	}
+	if err = persisted.Validate(); err != nil {
+		log.Debugf("Validation failed for %s: %v", persisted.String(), err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Check that the name is not taken
+	ok, err := checkWebhookName(r.Context(), ctrl.PersistenceService, persisted)
+	if err != nil {
+		log.Errf("Error filtering webhooks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+		_, err = w.Write([]byte(webhookNameConflict(persisted.Name)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the object
+	if err = ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{persisted}); err != nil {
+		log.Errf("Error updating entities: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	setETag(w, entityTag(persisted))
+}
+
+// Used for DELETE /webhooks/{webhook_id} endpoint
+func (g *Gorilla) swagDELETEWebhookByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the entity from persistence and check if it's there
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["webhook_id"], &cce.Webhook{})
+	if err != nil {
+		log.Errf("Error reading webhook: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !checkIfMatch(w, r, entityTag(persisted)) {
+		return
+	}
+
+	// Delete the entity, if it's still at the version the precondition
+	// matched. Its delivery history is deleted with it.
+	zv := &cce.Webhook{}
+	zv.SetResourceVersion(ifMatchVersion(r, persisted))
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), persisted.GetID(), zv)
+	if err != nil {
+		log.Errf("Error deleting entity: %v", err)
+		if isVersionConflict(err) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// we just fetched the entity, so if !ok then something went wrong
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// Used for GET /webhooks/{webhook_id}/deliveries endpoint
+func (g *Gorilla) swagGETWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the webhook from persistence and check if it's there
+	webhook, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["webhook_id"], &cce.Webhook{})
+	if err != nil {
+		log.Errf("Error reading webhook: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if webhook == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.WebhookDelivery{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+	opts.Filters = append(opts.Filters, cce.Filter{Field: "webhook_id", Value: webhook.GetID()})
+	if opts.Sort == "" {
+		// Newest first
+		opts.Sort = "-time"
+	}
+
+	// Fetch the deliveries from persistence
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.WebhookDelivery{}, opts)
+	if err != nil {
+		log.Errf("Error listing webhook deliveries: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	deliveries := swagger.WebhookDeliveryList{
+		Deliveries: []swagger.WebhookDelivery{},
+		ListPage:   toSwaggerListPage(r, page),
+	}
+	for _, e := range page.Entities {
+		deliveries.Deliveries = append(deliveries.Deliveries, toSwaggerWebhookDelivery(e.(*cce.WebhookDelivery)))
+	}
+
+	// Marshal the response object to JSON
+	deliveriesJSON, err := json.Marshal(deliveries)
+	if err != nil {
+		log.Errf("Error marshaling webhook deliveries: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(deliveriesJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /operations endpoint
+func (g *Gorilla) swagGETOperations(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the list options from the query parameters
+	opts, err := parseListOptions(r.URL.Query(), &cce.Operation{})
+	if err != nil {
+		log.Debugf("Bad list options: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+	if opts.Sort == "" {
+		// Newest first
+		opts.Sort = "-time"
+	}
+
+	// Fetch the operations from persistence
+	page, err := ctrl.PersistenceService.List(r.Context(), &cce.Operation{}, opts)
+	if err != nil {
+		log.Errf("Error listing operations: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	ops := swagger.OperationList{Operations: []swagger.Operation{}, ListPage: toSwaggerListPage(r, page)}
+	for _, e := range page.Entities {
+		ops.Operations = append(ops.Operations, toSwaggerOperation(e.(*cce.Operation)))
+	}
+
+	// Marshal the response object to JSON
+	opsJSON, err := json.Marshal(ops)
+	if err != nil {
+		log.Errf("Error marshaling operations: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(opsJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /operations/{operation_id} endpoint
+func (g *Gorilla) swagGETOperationByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the entity from persistence and check if it's there
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["operation_id"], &cce.Operation{})
+	if err != nil {
+		log.Errf("Error reading operation: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Marshal the response object to JSON
+	opJSON, err := json.Marshal(toSwaggerOperation(persisted.(*cce.Operation)))
+	if err != nil {
+		log.Errf("Error marshaling operation: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(opJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /drift endpoint
+func (g *Gorilla) swagGETDrift(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the drift reporter
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	if ctrl.DriftReporter == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if _, err := w.Write([]byte("reconciliation is disabled")); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Construct the response object
+	drifts := swagger.DriftList{Nodes: []swagger.NodeDrift{}}
+	for _, drift := range ctrl.DriftReporter.DriftReport() {
+		drifts.Nodes = append(drifts.Nodes, toSwaggerNodeDrift(drift))
+	}
+
+	// Marshal the response object to JSON
+	driftsJSON, err := json.Marshal(drifts)
+	if err != nil {
+		log.Errf("Error marshaling drift report: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(driftsJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /nodes/{node_id}/drift endpoint
+func (g *Gorilla) swagGETNodeDrift(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the drift reporter
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	if ctrl.DriftReporter == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if _, err := w.Write([]byte("reconciliation is disabled")); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Find the node in the last report
+	var nodeDrift *cce.NodeDrift
+	for _, drift := range ctrl.DriftReporter.DriftReport() {
+		if drift.NodeID == mux.Vars(r)["node_id"] {
+			nodeDrift = drift
+		}
+	}
+	if nodeDrift == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Marshal the response object to JSON
+	driftJSON, err := json.Marshal(toSwaggerNodeDrift(nodeDrift))
+	if err != nil {
+		log.Errf("Error marshaling drift report: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(driftJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+func toSwaggerNodeDrift(drift *cce.NodeDrift) swagger.NodeDrift {
+	return swagger.NodeDrift{
+		ID:                         drift.NodeID,
+		CheckedAt:                  drift.CheckedAt,
+		InSync:                     drift.InSync(),
+		MissingApps:                drift.MissingApps,
+		MissingInterfaces:          drift.MissingInterfaces,
+		ReappliedAppPolicies:       drift.ReappliedAppPolicies,
+		ReappliedInterfacePolicies: drift.ReappliedInterfacePolicies,
+		ReappliedDNSRecords:        drift.ReappliedDNSRecords,
+		Errors:                     drift.Errors,
+	}
+}
@@ -6,8 +6,13 @@ package gorilla
 import (
 	"context"
 	"net/http"
+	"sort"
 
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/open-ness/edgecontroller/operation"
+	"github.com/open-ness/edgecontroller/swagger"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -39,16 +44,40 @@ func handleUpdateNodes(
 		}
 	}
 
+	if len(e.(*cce.NodeReq).TrafficPolicies) == 0 {
+		return 0, nil
+	}
+
+	// Fetch the traffic policies in one query
+	var tpIDs []string
 	for _, nitp := range e.(*cce.NodeReq).TrafficPolicies {
-		tp, err := ps.Read(ctx, nitp.TrafficPolicyID, &cce.TrafficPolicy{})
-		if err != nil {
-			return http.StatusInternalServerError, err
-		}
-		if tp == nil {
-			// If nil, set an empty policy
+		tpIDs = append(tpIDs, nitp.TrafficPolicyID)
+	}
+	tps, err := ps.Filter(
+		ctx,
+		&cce.TrafficPolicy{},
+		[]cce.Filter{
+			{
+				Field:  "id",
+				Op:     cce.FilterOpIn,
+				Values: tpIDs,
+			},
+		})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	tpsByID := make(map[string]*cce.TrafficPolicy)
+	for _, tp := range tps {
+		tpsByID[tp.GetID()] = tp.(*cce.TrafficPolicy)
+	}
+
+	for _, nitp := range e.(*cce.NodeReq).TrafficPolicies {
+		tp, ok := tpsByID[nitp.TrafficPolicyID]
+		if !ok {
+			// If not found, set an empty policy
 			tp = &cce.TrafficPolicy{}
 		}
-		if err := nodeCC.IfacePolicySvcCli.Set(ctx, nitp.NetworkInterfaceID, tp.(*cce.TrafficPolicy)); err != nil {
+		if err := nodeCC.IfacePolicySvcCli.Set(ctx, nitp.NetworkInterfaceID, tp); err != nil {
 			return http.StatusInternalServerError, err
 		}
 	}
@@ -71,6 +100,10 @@ func handleUpdateNodesApps( //nolint: gocyclo
 		return http.StatusInternalServerError, err
 	}
 
+	if cmd := e.(*cce.NodeAppReq).Cmd; cmd != "" {
+		operation.Progress(ctx, "Running %s command on app", cmd)
+	}
+
 	switch ctrl.OrchestrationMode {
 	case cce.OrchestrationModeNative:
 		switch e.(*cce.NodeAppReq).Cmd {
@@ -101,5 +134,243 @@ func handleUpdateNodesApps( //nolint: gocyclo
 		}
 	}
 
+	// The command was run on the node even if the request fails later
+	if cmd := e.(*cce.NodeAppReq).Cmd; cmd != "" {
+		publish(ctx, &events.Event{
+			Type:       events.TypeAppLifecycle,
+			NodeID:     e.(*cce.NodeAppReq).NodeID,
+			EntityType: e.(*cce.NodeAppReq).GetTableName(),
+			EntityID:   e.(*cce.NodeAppReq).ID,
+			Data:       events.AppLifecycle{AppID: e.(*cce.NodeAppReq).AppID, Command: cmd},
+		})
+	}
+
 	return 0, nil
 }
+
+// trafficPolicyAttachments holds the node apps and network interfaces a traffic
+// policy is attached to on a single node.
+type trafficPolicyAttachments struct {
+	nodeApps      []*cce.NodeApp
+	ifacePolicies []*cce.NodeInterfaceTrafficPolicy
+}
+
+// getTrafficPolicyAttachments returns the attachments of a traffic policy
+// grouped by node ID, along with the sorted node IDs.
+func getTrafficPolicyAttachments(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	id string,
+) (nodeIDs []string, attachments map[string]*trafficPolicyAttachments, err error) {
+	attachments = make(map[string]*trafficPolicyAttachments)
+	forNode := func(nodeID string) *trafficPolicyAttachments {
+		if _, ok := attachments[nodeID]; !ok {
+			attachments[nodeID] = &trafficPolicyAttachments{}
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+		return attachments[nodeID]
+	}
+
+	nodeAppPolicies, err := ps.Filter(
+		ctx,
+		&cce.NodeAppTrafficPolicy{},
+		[]cce.Filter{
+			{
+				Field: "traffic_policy_id",
+				Value: id,
+			},
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(nodeAppPolicies) != 0 {
+		var nodeAppIDs []string
+		seen := make(map[string]bool)
+		for _, nodeAppPolicy := range nodeAppPolicies {
+			id := nodeAppPolicy.(*cce.NodeAppTrafficPolicy).NodeAppID
+			if !seen[id] {
+				seen[id] = true
+				nodeAppIDs = append(nodeAppIDs, id)
+			}
+		}
+		nodeApps, err := ps.Filter(
+			ctx,
+			&cce.NodeApp{},
+			[]cce.Filter{
+				{
+					Field:  "id",
+					Op:     cce.FilterOpIn,
+					Values: nodeAppIDs,
+				},
+			})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(nodeApps) != len(nodeAppIDs) {
+			return nil, nil, errors.Errorf("%d of %d nodes_apps records not found",
+				len(nodeAppIDs)-len(nodeApps), len(nodeAppIDs))
+		}
+		for _, nodeApp := range nodeApps {
+			a := forNode(nodeApp.(*cce.NodeApp).NodeID)
+			a.nodeApps = append(a.nodeApps, nodeApp.(*cce.NodeApp))
+		}
+	}
+
+	nodeIfacePolicies, err := ps.Filter(
+		ctx,
+		&cce.NodeInterfaceTrafficPolicy{},
+		[]cce.Filter{
+			{
+				Field: "traffic_policy_id",
+				Value: id,
+			},
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, nodeIfacePolicy := range nodeIfacePolicies {
+		a := forNode(nodeIfacePolicy.(*cce.NodeInterfaceTrafficPolicy).NodeID)
+		a.ifacePolicies = append(a.ifacePolicies, nodeIfacePolicy.(*cce.NodeInterfaceTrafficPolicy))
+	}
+
+	sort.Strings(nodeIDs)
+
+	return nodeIDs, attachments, nil
+}
+
+// handleUpdateTrafficPolicies re-pushes an updated traffic policy to every app
+// and network interface it is attached to.
+func handleUpdateTrafficPolicies(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	tp *cce.TrafficPolicy,
+) ([]swagger.NodeStatus, error) {
+	nodeIDs, attachments, err := getTrafficPolicyAttachments(ctx, ps, tp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl := getController(ctx)
+	nodePort := ctrl.ELAPort
+	if nodePort == "" {
+		nodePort = defaultELAPort
+	}
+
+	return pushToNodes(ctx, ps, nodeIDs, nodePort, func(nodeCC *node.ClientConn, nodeID string) []error {
+		var errs []error
+		for _, nodeApp := range attachments[nodeID].nodeApps {
+			if err := nodeCC.AppPolicySvcCli.Set(ctx, nodeApp.AppID, tp); err != nil {
+				errs = append(errs, errors.Wrapf(err, "app %s", nodeApp.AppID))
+			}
+		}
+		for _, nitp := range attachments[nodeID].ifacePolicies {
+			if err := nodeCC.IfacePolicySvcCli.Set(ctx, nitp.NetworkInterfaceID, tp); err != nil {
+				errs = append(errs, errors.Wrapf(err, "interface %s", nitp.NetworkInterfaceID))
+			}
+		}
+		return errs
+	}), nil
+}
+
+// handleUpdateTrafficPoliciesKubeOVN re-applies an updated Kube-OVN traffic
+// policy as a network policy for every app it is attached to.
+func handleUpdateTrafficPoliciesKubeOVN(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	tp *cce.TrafficPolicyKubeOVN,
+) ([]swagger.NodeStatus, error) {
+	nodeIDs, attachments, err := getTrafficPolicyAttachments(ctx, ps, tp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl := getController(ctx)
+
+	statuses := []swagger.NodeStatus{}
+	for _, nodeID := range nodeIDs {
+		var errs []error
+		for _, nodeApp := range attachments[nodeID].nodeApps {
+			// Try delete network policy for app
+			_ = ctrl.KubernetesClient.DeleteNetworkPolicy(ctx, nodeID, nodeApp.AppID)
+
+			// Apply new network policy for app
+			if err := ctrl.KubernetesClient.ApplyNetworkPolicy(ctx, nodeID, nodeApp.AppID, tp.ToK8s()); err != nil {
+				errs = append(errs, errors.Wrapf(err, "app %s", nodeApp.AppID))
+			}
+		}
+		statuses = append(statuses, toNodeStatus(nodeID, errs))
+	}
+
+	return statuses, nil
+}
+
+// handleUpdateDNSConfigs pushes the changes between two versions of a DNS
+// config and its app aliases to every node using that config. Only the A
+// records and forwarders that changed are deleted from or set on the nodes.
+func handleUpdateDNSConfigs(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	oldConfig, newConfig *cce.DNSConfig,
+	oldAliases, newAliases []*cce.DNSConfigAppAlias,
+) ([]swagger.NodeStatus, error) {
+	nodeDNSConfigs, err := ps.Filter(
+		ctx,
+		&cce.NodeDNSConfig{},
+		[]cce.Filter{
+			{
+				Field: "dns_config_id",
+				Value: newConfig.ID,
+			},
+		})
+	if err != nil {
+		return nil, err
+	}
+	var nodeIDs []string
+	for _, nodeDNSConfig := range nodeDNSConfigs {
+		nodeIDs = append(nodeIDs, nodeDNSConfig.(*cce.NodeDNSConfig).NodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	// Aliases are pushed to the node as A records, so diff them together
+	oldRecords := append([]*cce.DNSARecord(nil), oldConfig.ARecords...)
+	for _, alias := range oldAliases {
+		oldRecords = append(oldRecords, alias.ARecord())
+	}
+	newRecords := append([]*cce.DNSARecord(nil), newConfig.ARecords...)
+	for _, alias := range newAliases {
+		newRecords = append(newRecords, alias.ARecord())
+	}
+	addedRecords, removedRecords := cce.DiffDNSARecords(oldRecords, newRecords)
+	addedForwarders, removedForwarders := cce.DiffDNSForwarders(oldConfig.Forwarders, newConfig.Forwarders)
+
+	ctrl := getController(ctx)
+	nodePort := ctrl.ELAPort
+	if nodePort == "" {
+		nodePort = defaultELAPort
+	}
+
+	return pushToNodes(ctx, ps, nodeIDs, nodePort, func(nodeCC *node.ClientConn, nodeID string) []error {
+		var errs []error
+		for _, record := range removedRecords {
+			if err := nodeCC.DNSSvcCli.DeleteA(ctx, record); err != nil {
+				errs = append(errs, errors.Wrapf(err, "a record %s", record.Name))
+			}
+		}
+		for _, record := range addedRecords {
+			if err := nodeCC.DNSSvcCli.SetA(ctx, record); err != nil {
+				errs = append(errs, errors.Wrapf(err, "a record %s", record.Name))
+			}
+		}
+		if len(removedForwarders) != 0 {
+			if err := nodeCC.DNSSvcCli.DeleteForwarders(ctx, removedForwarders); err != nil {
+				errs = append(errs, errors.Wrap(err, "forwarders"))
+			}
+		}
+		if len(addedForwarders) != 0 {
+			if err := nodeCC.DNSSvcCli.SetForwarders(ctx, addedForwarders); err != nil {
+				errs = append(errs, errors.Wrap(err, "forwarders"))
+			}
+		}
+		return errs
+	}), nil
+}
@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+func toSwaggerUserSummary(u *cce.User) swagger.UserSummary {
+	return swagger.UserSummary{
+		Name:           u.Name,
+		Roles:          u.Roles,
+		ServiceAccount: u.ServiceAccount,
+	}
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"fmt"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// checkWebhookName returns whether the name of a webhook is not taken by
+// another webhook.
+func checkWebhookName(ctx context.Context, ps cce.PersistenceService, w *cce.Webhook) (bool, error) {
+	existing, err := ps.Filter(ctx, &cce.Webhook{}, []cce.Filter{{Field: "name", Value: w.Name}})
+	if err != nil {
+		return false, err
+	}
+	for _, e := range existing {
+		if e.GetID() != w.ID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// webhookNameConflict is the message of the response to a request that takes
+// the name of another webhook.
+func webhookNameConflict(name string) string {
+	return fmt.Sprintf("webhook %q already exists", name)
+}
+
+func toSwaggerWebhookSummary(w *cce.Webhook) swagger.WebhookSummary {
+	summary := swagger.WebhookSummary{
+		ID:         w.ID,
+		Name:       w.Name,
+		URL:        w.URL,
+		EventTypes: w.EventTypes,
+	}
+	if summary.EventTypes == nil {
+		summary.EventTypes = []string{}
+	}
+
+	return summary
+}
+
+func toSwaggerWebhookDelivery(d *cce.WebhookDelivery) swagger.WebhookDelivery {
+	delivery := swagger.WebhookDelivery{
+		ID:             d.ID,
+		Time:           d.GetTime(),
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		Error:          d.Error,
+	}
+	if !d.LastAttemptAt.IsZero() {
+		lastAttemptAt := d.LastAttemptAt
+		delivery.LastAttemptAt = &lastAttemptAt
+	}
+	if d.Status == cce.WebhookDeliveryPending {
+		nextAttemptAt := d.NextAttemptAt
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+
+	return delivery
+}
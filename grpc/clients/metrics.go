@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package clients
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	nodeCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cce_node_grpc_calls_total",
+		Help: "gRPC calls to nodes by service, method and status code, which is not OK for errors.",
+	}, []string{"service", "method", "code"})
+	nodeCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cce_node_grpc_call_duration_seconds",
+		Help:    "Latency of gRPC calls to nodes by service and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method"})
+)
+
+// MetricsUnaryClientInterceptor counts the calls of a client connection to a
+// node and observes their latency.
+func MetricsUnaryClientInterceptor(
+	ctx context.Context,
+	fullMethod string,
+	req, reply interface{},
+	cc *ggrpc.ClientConn,
+	invoker ggrpc.UnaryInvoker,
+	opts ...ggrpc.CallOption,
+) error {
+	// Full methods are "/package.Service/Method"
+	service, method := strings.TrimPrefix(fullMethod, "/"), ""
+	if i := strings.LastIndex(service, "/"); i >= 0 {
+		service, method = service[:i], service[i+1:]
+	}
+
+	start := time.Now()
+	err := invoker(ctx, fullMethod, req, reply, cc, opts...)
+	nodeCallDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+	nodeCalls.WithLabelValues(service, method, status.Code(err).String()).Inc()
+	return err
+}
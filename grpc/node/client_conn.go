@@ -6,18 +6,22 @@ package node
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 
 	cce "github.com/open-ness/edgecontroller"
 	"github.com/open-ness/edgecontroller/grpc"
 	gclients "github.com/open-ness/edgecontroller/grpc/clients"
+	"github.com/open-ness/edgecontroller/plan"
+	"github.com/pkg/errors"
 	ggrpc "google.golang.org/grpc"
 )
 
 // ClientConn wraps a Node and provides a Connect() method to create wrapped gRPC clients.
 type ClientConn struct {
-	Addr string
-	Port string
-	TLS  *tls.Config
+	NodeID string
+	Addr   string
+	Port   string
+	TLS    *tls.Config
 
 	conn *grpc.ClientConn
 
@@ -38,7 +42,7 @@ func (cc *ClientConn) Connect(ctx context.Context) error {
 		// OP-1742: ContextDialler not supported by Gateway
 		//nolint:staticcheck
 		cc.conn, err = grpc.Dial(ctx, cc.Addr, cc.TLS,
-			ggrpc.WithDialer(cce.PrefaceLis.DialEva))
+			cc.dialOptions(ctx, ggrpc.WithDialer(cce.PrefaceLis.DialEva))...)
 
 		// EVA
 		cc.AppDeploySvcCli = gclients.NewApplicationDeploymentServiceClient(cc.conn)
@@ -47,7 +51,7 @@ func (cc *ClientConn) Connect(ctx context.Context) error {
 		// OP-1742: ContextDialler not supported by Gateway
 		//nolint:staticcheck
 		cc.conn, err = grpc.Dial(ctx, cc.Addr, cc.TLS,
-			ggrpc.WithDialer(cce.PrefaceLis.DialEla))
+			cc.dialOptions(ctx, ggrpc.WithDialer(cce.PrefaceLis.DialEla))...)
 
 		// ELA
 		cc.AppPolicySvcCli = gclients.NewApplicationPolicyServiceClient(cc.conn)
@@ -61,6 +65,88 @@ func (cc *ClientConn) Connect(ctx context.Context) error {
 	return err
 }
 
+// dialOptions returns the options to dial the node with a dialer, whose calls
+// are counted in the metrics. The calls of dry runs are recorded instead, so
+// they never connect.
+func (cc *ClientConn) dialOptions(ctx context.Context, dialer ggrpc.DialOption) []ggrpc.DialOption {
+	rec := plan.FromContext(ctx)
+	if rec == nil {
+		return []ggrpc.DialOption{dialer, ggrpc.WithUnaryInterceptor(gclients.MetricsUnaryClientInterceptor)}
+	}
+
+	return []ggrpc.DialOption{
+		ggrpc.WithContextDialer(plan.Dialer),
+		ggrpc.WithUnaryInterceptor(rec.UnaryClientInterceptor(cc.NodeID)),
+	}
+}
+
 func (cc *ClientConn) Disconnect() {
-	cc.conn.Close()
+	if cc.conn != nil {
+		cc.conn.Close()
+	}
+}
+
+// Dial looks up the gRPC target of a node in persistence and connects to it on
+// the given port, as DialTarget does.
+func Dial(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	nodeID string,
+	port string,
+	conf *tls.Config,
+) (*ClientConn, error) {
+	target, err := Target(ctx, ps, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return DialTarget(ctx, target, port, conf)
+}
+
+// Target looks up the gRPC target of a node in persistence.
+func Target(ctx context.Context, ps cce.PersistenceService, nodeID string) (*cce.NodeGRPCTarget, error) {
+	targets, err := ps.Filter(
+		ctx,
+		&cce.NodeGRPCTarget{},
+		[]cce.Filter{
+			{
+				Field: "node_id",
+				Value: nodeID,
+			},
+		})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not fetch gRPC target from DB")
+	}
+	// sanity check since we are about to access targets[0]
+	if len(targets) != 1 {
+		return nil, fmt.Errorf("filter returned %v", targets)
+	}
+
+	return targets[0].(*cce.NodeGRPCTarget), nil
+}
+
+// DialTarget connects to the gRPC target of a node on the given port. The TLS
+// config, if any, is cloned and its server name is set to the node ID.
+func DialTarget(
+	ctx context.Context,
+	target *cce.NodeGRPCTarget,
+	port string,
+	conf *tls.Config,
+) (*ClientConn, error) {
+	if conf != nil {
+		conf = conf.Clone()
+		conf.ServerName = target.NodeID
+	}
+
+	nodeCC := ClientConn{
+		NodeID: target.NodeID,
+		Addr:   target.GRPCTarget,
+		Port:   port,
+		TLS:    conf,
+	}
+	if err := nodeCC.Connect(ctx); err != nil {
+		return nil, errors.Wrap(err, "could not connect to node")
+	}
+
+	return &nodeCC, nil
 }
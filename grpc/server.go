@@ -13,6 +13,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -22,9 +23,12 @@ import (
 	"google.golang.org/grpc/status"
 
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
 	authpb "github.com/open-ness/edgecontroller/pb/auth"
 	evapb "github.com/open-ness/edgecontroller/pb/eva"
 	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 const (
@@ -44,6 +48,11 @@ const (
 	enrollmentMethod = "/openness.auth.AuthService/RequestCredentials"
 )
 
+var enrollments = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cce_enrollments_total",
+	Help: "Node enrollments by result, which is success or failure.",
+}, []string{"result"})
+
 // Server wraps grpc.Server
 type Server struct {
 	controller *cce.Controller
@@ -54,40 +63,51 @@ type Server struct {
 func NewServer(controller *cce.Controller, conf *tls.Config) *Server {
 	s := &Server{
 		controller: controller,
-		grpc: grpc.NewServer(
-			grpc.Creds(credentials.NewTLS(conf)),
-			grpc.UnaryInterceptor(
-				func(
-					ctx context.Context,
-					req interface{},
-					info *grpc.UnaryServerInfo,
-					handler grpc.UnaryHandler,
-				) (resp interface{}, err error) {
-					// apply checkAuth middleware
-					if err := checkAuth(ctx,
-						info.FullMethod); err != nil {
-						return nil, err
-					}
-					return handler(ctx, req)
-				},
-			),
-			grpc.StreamInterceptor(
-				func(
-					srv interface{},
-					ss grpc.ServerStream,
-					info *grpc.StreamServerInfo,
-					handler grpc.StreamHandler,
-				) error {
-					// apply checkAuth middleware
-					if err := checkAuth(ss.Context(),
-						info.FullMethod); err != nil {
-						return err
+	}
+	s.grpc = grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(conf)),
+		grpc.UnaryInterceptor(
+			func(
+				ctx context.Context,
+				req interface{},
+				info *grpc.UnaryServerInfo,
+				handler grpc.UnaryHandler,
+			) (resp interface{}, err error) {
+				// apply checkAuth middleware
+				if err := checkAuth(ctx,
+					info.FullMethod); err != nil {
+					return nil, err
+				}
+				if resp, err = handler(ctx, req); err != nil {
+					if info.FullMethod == enrollmentMethod {
+						enrollments.WithLabelValues("failure").Inc()
 					}
-					return handler(srv, ss)
-				},
-			),
+					return nil, err
+				}
+				// record enrollments in the audit log
+				if info.FullMethod == enrollmentMethod {
+					enrollments.WithLabelValues("success").Inc()
+					s.auditEnrollment(ctx, resp.(*authpb.Credentials))
+				}
+				return resp, nil
+			},
 		),
-	}
+		grpc.StreamInterceptor(
+			func(
+				srv interface{},
+				ss grpc.ServerStream,
+				info *grpc.StreamServerInfo,
+				handler grpc.StreamHandler,
+			) error {
+				// apply checkAuth middleware
+				if err := checkAuth(ss.Context(),
+					info.FullMethod); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			},
+		),
+	)
 
 	authpb.RegisterAuthServiceServer(s.grpc, s)
 	evapb.RegisterControllerVirtualizationAgentServer(s.grpc, s)
@@ -121,6 +141,52 @@ func checkAuth(ctx context.Context, method string) error {
 	}
 }
 
+// auditEnrollment records the enrollment of the node a certificate was issued
+// to and publishes it. The enrollment has already succeeded, so errors are
+// only logged.
+func (s *Server) auditEnrollment(ctx context.Context, creds *authpb.Credentials) {
+	certPEM, _ := pem.Decode([]byte(creds.GetCertificate()))
+	if certPEM == nil {
+		log.Errf("Failed to audit enrollment: unable to decode certificate")
+		return
+	}
+	cert, err := x509.ParseCertificate(certPEM.Bytes)
+	if err != nil {
+		log.Errf("Failed to audit enrollment: %v", err)
+		return
+	}
+	nodeID := cert.Subject.CommonName
+
+	var sourceIP string
+	if p, ok := peer.FromContext(ctx); ok {
+		sourceIP, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+
+	e := &cce.AuditEvent{
+		ID:         uuid.New(),
+		Time:       cce.AuditTime(time.Now()),
+		Actor:      "node:" + nodeID,
+		Action:     cce.AuditActionEnroll,
+		Request:    enrollmentMethod,
+		EntityType: (&cce.Node{}).GetTableName(),
+		EntityID:   nodeID,
+		SourceIP:   sourceIP,
+	}
+	if err = s.controller.PersistenceService.Create(ctx, e); err != nil {
+		log.Errf("Failed to audit enrollment of node %s: %v", nodeID, err)
+	}
+
+	if s.controller.Events != nil {
+		s.controller.Events.Publish(&events.Event{
+			Type:       events.TypeNodeEnrolled,
+			NodeID:     nodeID,
+			EntityType: e.EntityType,
+			EntityID:   nodeID,
+			Actor:      e.Actor,
+		})
+	}
+}
+
 // Serve wraps grpc.Server.Serve.
 func (s *Server) Serve(lis net.Listener) error {
 	return s.grpc.Serve(lis)
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+// Package health checks the dependencies of the controller for the liveness
+// and readiness probes of Kubernetes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	logger "github.com/open-ness/common/log"
+	"github.com/pkg/errors"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "health")
+
+// DefaultTimeout is the default time a check has to pass.
+const DefaultTimeout = 5 * time.Second
+
+// Statuses of checks and reports.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check checks a dependency and returns an error if it isn't available.
+type Check func(ctx context.Context) error
+
+// Result is the result of a check.
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the result of all the checks of a probe, whose status is only ok
+// if all of them are.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the checks of the liveness and readiness probes. Liveness
+// checks fail if the controller must be restarted, and are also readiness
+// checks. Readiness checks fail if it cannot serve requests yet.
+type Checker struct {
+	// Timeout is the time a check has to pass, or DefaultTimeout if zero.
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	live  []namedCheck
+	ready []namedCheck
+}
+
+// AddLiveness adds a check to the liveness and readiness probes.
+func (c *Checker) AddLiveness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.live = append(c.live, namedCheck{name, check})
+	c.ready = append(c.ready, namedCheck{name, check})
+}
+
+// AddReadiness adds a check to the readiness probe.
+func (c *Checker) AddReadiness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready = append(c.ready, namedCheck{name, check})
+}
+
+// Liveness runs the checks of the liveness probe.
+func (c *Checker) Liveness(ctx context.Context) *Report {
+	c.mu.Lock()
+	checks := append([]namedCheck(nil), c.live...)
+	c.mu.Unlock()
+	return c.run(ctx, checks)
+}
+
+// Readiness runs the checks of the readiness probe.
+func (c *Checker) Readiness(ctx context.Context) *Report {
+	c.mu.Lock()
+	checks := append([]namedCheck(nil), c.ready...)
+	c.mu.Unlock()
+	return c.run(ctx, checks)
+}
+
+// run runs checks concurrently, failing those that don't pass in time.
+func (c *Checker) run(ctx context.Context, checks []namedCheck) *Report {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	report := &Report{
+		Status: StatusOK,
+		Checks: make([]Result, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func(i int, nc namedCheck) {
+			defer wg.Done()
+
+			// Checks that don't take a context are abandoned when it is done
+			errC := make(chan error, 1)
+			go func() { errC <- nc.check(ctx) }()
+
+			var err error
+			select {
+			case err = <-errC:
+			case <-ctx.Done():
+				err = errors.Errorf("timed out after %s", timeout)
+			}
+
+			report.Checks[i] = Result{Name: nc.name, Status: StatusOK}
+			if err != nil {
+				report.Checks[i].Status = StatusFail
+				report.Checks[i].Error = err.Error()
+			}
+		}(i, nc)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// LivenessHandler returns a handler that serves the report of the liveness
+// probe.
+func (c *Checker) LivenessHandler() http.Handler {
+	return reportHandler(c.Liveness)
+}
+
+// ReadinessHandler returns a handler that serves the report of the readiness
+// probe.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return reportHandler(c.Readiness)
+}
+
+// reportHandler serves a report in JSON, with a 503 Service Unavailable
+// status if it failed.
+func reportHandler(probe func(ctx context.Context) *Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		report := probe(r.Context())
+
+		// Marshal the response object to JSON
+		reportJSON, err := json.Marshal(report)
+		if err != nil {
+			log.Errf("Error marshaling report: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if _, err = w.Write(reportJSON); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+	})
+}
+
+// Listener is a listener whose check fails once it stops accepting
+// connections.
+type Listener struct {
+	net.Listener
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewListener wraps a listener to check it.
+func NewListener(lis net.Listener) *Listener {
+	return &Listener{Listener: lis}
+}
+
+// Accept accepts a connection, and fails the check of the listener if it
+// cannot accept any more.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		if netErr, ok := err.(net.Error); !ok || !netErr.Temporary() {
+			l.fail(errors.Wrap(err, "not accepting connections"))
+		}
+	}
+	return conn, err
+}
+
+// Close closes the listener and fails its check.
+func (l *Listener) Close() error {
+	l.fail(errors.New("listener closed"))
+	return l.Listener.Close()
+}
+
+func (l *Listener) fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+// Check checks that the listener accepts connections.
+func (l *Listener) Check(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/health"
+)
+
+var _ = Describe("Checker", func() {
+	var checker *health.Checker
+
+	ok := func(context.Context) error { return nil }
+
+	BeforeEach(func() {
+		checker = &health.Checker{Timeout: 100 * time.Millisecond}
+	})
+
+	It("Should report the status of each check", func() {
+		checker.AddLiveness("grpc", ok)
+		checker.AddReadiness("db", func(context.Context) error { return errors.New("connection refused") })
+
+		Expect(checker.Liveness(context.Background())).To(Equal(&health.Report{
+			Status: health.StatusOK,
+			Checks: []health.Result{{Name: "grpc", Status: health.StatusOK}},
+		}))
+		Expect(checker.Readiness(context.Background())).To(Equal(&health.Report{
+			Status: health.StatusFail,
+			Checks: []health.Result{
+				{Name: "grpc", Status: health.StatusOK},
+				{Name: "db", Status: health.StatusFail, Error: "connection refused"},
+			},
+		}))
+	})
+
+	It("Should fail checks that time out", func() {
+		block := make(chan struct{})
+		defer close(block)
+		checker.AddReadiness("kubernetes", func(context.Context) error {
+			<-block
+			return nil
+		})
+
+		report := checker.Readiness(context.Background())
+		Expect(report.Status).To(Equal(health.StatusFail))
+		Expect(report.Checks[0].Error).To(Equal("timed out after 100ms"))
+	})
+
+	It("Should serve the reports", func() {
+		checker.AddLiveness("grpc", ok)
+		checker.AddReadiness("db", func(context.Context) error { return errors.New("connection refused") })
+
+		rec := httptest.NewRecorder()
+		checker.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		rec = httptest.NewRecorder()
+		checker.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+		var report health.Report
+		Expect(json.Unmarshal(rec.Body.Bytes(), &report)).To(Succeed())
+		Expect(report.Status).To(Equal(health.StatusFail))
+		Expect(report.Checks).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("Listener", func() {
+	It("Should fail its check once closed", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		hl := health.NewListener(lis)
+		Expect(hl.Check(context.Background())).To(Succeed())
+
+		Expect(hl.Close()).To(Succeed())
+		Expect(hl.Check(context.Background())).To(MatchError("listener closed"))
+	})
+})
@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	cehttp "github.com/open-ness/edgecontroller/http"
+)
+
+var _ = Describe("RedirectToHTTPS", func() {
+	DescribeTable("Should redirect to the same URL over HTTPS",
+		func(port int, method, url, location string) {
+			rec := httptest.NewRecorder()
+			cehttp.RedirectToHTTPS(port).ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+
+			Expect(rec.Code).To(Equal(http.StatusPermanentRedirect))
+			Expect(rec.Header().Get("Location")).To(Equal(location))
+		},
+		Entry("GET on another port", 8443, http.MethodGet,
+			"http://controller:8080/nodes?limit=10", "https://controller:8443/nodes?limit=10"),
+		Entry("POST on the default port", 443, http.MethodPost,
+			"http://controller/auth", "https://controller/auth"),
+		Entry("IPv6 host", 8443, http.MethodGet,
+			"http://[::1]:8080/apps", "https://[::1]:8443/apps"),
+		Entry("IPv6 host on the default port", 443, http.MethodGet,
+			"http://[::1]/apps", "https://[::1]/apps"),
+	)
+})
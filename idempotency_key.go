@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// MaxIdempotencyKeyLength is the maximum length of an Idempotency-Key header.
+const MaxIdempotencyKeyLength = 255
+
+// DefaultIdempotencyKeyRetention is the default time the responses to
+// requests with an Idempotency-Key header are stored for.
+const DefaultIdempotencyKeyRetention = 24 * time.Hour
+
+// IdempotencyKey is the response to a successful request made with an
+// Idempotency-Key header, which is returned again for retries of the request
+// with the same key.
+type IdempotencyKey struct {
+	ID string `json:"id"`
+
+	// Key is the Idempotency-Key header of the request. Keys are unique per
+	// actor.
+	Key string `json:"key"`
+
+	// Actor is the user who made the request.
+	Actor string `json:"actor"`
+
+	// RequestHash is the hex-encoded SHA-256 hash of the method, URI and body
+	// of the request, which retries must match.
+	RequestHash string `json:"request_hash"`
+
+	// Time is the time of the request in microseconds since the Unix epoch,
+	// like the time of audit events.
+	Time int64 `json:"time"`
+
+	// Pending is set while the request is handled. The key is reserved in the
+	// request's transaction, so that concurrent requests with the same key
+	// are not handled too.
+	Pending bool `json:"pending,omitempty"`
+
+	// ResponseStatus, ResponseHeader and ResponseBody are the response to
+	// the request.
+	ResponseStatus int               `json:"response_status"`
+	ResponseHeader map[string]string `json:"response_header,omitempty"`
+	ResponseBody   []byte            `json:"response_body,omitempty"`
+
+	ResourceVersion
+}
+
+// GetTableName returns the name of the persistence table.
+func (*IdempotencyKey) GetTableName() string {
+	return "idempotency_keys"
+}
+
+// GetID gets the ID.
+func (k *IdempotencyKey) GetID() string {
+	return k.ID
+}
+
+// SetID sets the ID.
+func (k *IdempotencyKey) SetID(id string) {
+	k.ID = id
+}
+
+// GetTime gets the time of the request.
+func (k *IdempotencyKey) GetTime() time.Time {
+	return time.Unix(0, k.Time*int64(time.Microsecond)).UTC()
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*IdempotencyKey) FilterFields() []string {
+	return []string{
+		"key",
+		"actor",
+		"time",
+	}
+}
+
+// Validate validates the model.
+func (k *IdempotencyKey) Validate() error {
+	if !uuid.IsValid(k.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if k.Key == "" {
+		return errors.New("key cannot be empty")
+	}
+	if len(k.Key) > MaxIdempotencyKeyLength {
+		return fmt.Errorf("key cannot be longer than %d characters", MaxIdempotencyKeyLength)
+	}
+	if k.RequestHash == "" {
+		return errors.New("request_hash cannot be empty")
+	}
+	if http.StatusText(k.ResponseStatus) == "" {
+		return fmt.Errorf("response_status %d not a valid status code", k.ResponseStatus)
+	}
+
+	return nil
+}
+
+func (k *IdempotencyKey) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+IdempotencyKey[
+    ID: %s
+    Key: %s
+    Actor: %s
+    ResponseStatus: %d
+]`),
+		k.ID,
+		k.Key,
+		k.Actor,
+		k.ResponseStatus)
+}
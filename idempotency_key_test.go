@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: IdempotencyKey", func() {
+	var (
+		k *cce.IdempotencyKey
+	)
+
+	BeforeEach(func() {
+		k = &cce.IdempotencyKey{
+			ID:             "7f3e2d1c-0b9a-4c8d-8e7f-6a5b4c3d2e1f",
+			Key:            "create-app-42",
+			Actor:          "admin",
+			RequestHash:    "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			Time:           1577836800000000,
+			ResponseStatus: 201,
+			ResponseHeader: map[string]string{"Content-Type": "application/json"},
+			ResponseBody:   []byte(`{"id":"3c2b1a09-8f7e-4d6c-9b5a-4f3e2d1c0b9a"}`),
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "idempotency_keys"`, func() {
+			Expect(k.GetTableName()).To(Equal("idempotency_keys"))
+		})
+	})
+
+	Describe("GetID", func() {
+		It("Should return the ID", func() {
+			Expect(k.GetID()).To(Equal("7f3e2d1c-0b9a-4c8d-8e7f-6a5b4c3d2e1f"))
+		})
+	})
+
+	Describe("SetID", func() {
+		It("Should set and return the updated ID", func() {
+			By("Setting the ID")
+			k.SetID("456")
+
+			By("Getting the updated ID")
+			Expect(k.ID).To(Equal("456"))
+		})
+	})
+
+	Describe("GetTime", func() {
+		It("Should return the time", func() {
+			Expect(k.GetTime()).To(Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(k.FilterFields()).To(Equal([]string{
+				"key",
+				"actor",
+				"time",
+			}))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error for a valid key", func() {
+			Expect(k.Validate()).To(Succeed())
+		})
+
+		It("Should return an error for an invalid ID", func() {
+			k.ID = "123"
+			Expect(k.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if there is no key", func() {
+			k.Key = ""
+			Expect(k.Validate()).To(MatchError("key cannot be empty"))
+		})
+
+		It("Should return an error if the key is too long", func() {
+			k.Key = strings.Repeat("k", 256)
+			Expect(k.Validate()).To(MatchError("key cannot be longer than 255 characters"))
+		})
+
+		It("Should return an error if there is no request hash", func() {
+			k.RequestHash = ""
+			Expect(k.Validate()).To(MatchError("request_hash cannot be empty"))
+		})
+
+		It("Should return an error for an invalid response status", func() {
+			k.ResponseStatus = 0
+			Expect(k.Validate()).To(MatchError("response_status 0 not a valid status code"))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(k.String()).To(Equal(strings.TrimSpace(`
+IdempotencyKey[
+    ID: 7f3e2d1c-0b9a-4c8d-8e7f-6a5b4c3d2e1f
+    Key: create-app-42
+    Actor: admin
+    ResponseStatus: 201
+]`,
+			)))
+		})
+	})
+})
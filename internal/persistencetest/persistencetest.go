@@ -0,0 +1,595 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+// Package persistencetest is a conformance suite for implementations of
+// cce.PersistenceService. Every backend must pass it.
+package persistencetest
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/pkg/errors"
+)
+
+// DescribePersistenceService declares the conformance specs. newService is
+// called before each spec and must return a service on an empty database.
+func DescribePersistenceService(newService func() cce.PersistenceService) bool {
+	return Describe("PersistenceService", func() {
+		var (
+			ctx = context.Background()
+			ps  cce.PersistenceService
+
+			node *cce.Node
+			app  *cce.App
+		)
+
+		BeforeEach(func() {
+			ps = newService()
+
+			node = &cce.Node{
+				ID:     uuid.New(),
+				Name:   "node",
+				Serial: "ABC-123",
+			}
+			Expect(ps.Create(ctx, node)).To(Succeed())
+
+			app = &cce.App{
+				ID:   uuid.New(),
+				Type: "container",
+				Name: "app",
+			}
+			Expect(ps.Create(ctx, app)).To(Succeed())
+		})
+
+		Describe("Create and Read", func() {
+			It("Should read back a created entity", func() {
+				e, err := ps.Read(ctx, node.ID, &cce.Node{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(Equal(node))
+			})
+
+			It("Should return nil if the entity does not exist", func() {
+				e, err := ps.Read(ctx, uuid.New(), &cce.Node{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(BeNil())
+			})
+
+			It("Should fail on a duplicate id", func() {
+				err := ps.Create(ctx, &cce.Node{ID: node.ID})
+				Expect(errors.Cause(err)).To(Equal(cce.ErrDuplicateEntry))
+			})
+
+			It("Should fail on a duplicate unique key", func() {
+				Expect(ps.Create(ctx, &cce.NodeApp{
+					ID:     uuid.New(),
+					NodeID: node.ID,
+					AppID:  app.ID,
+				})).To(Succeed())
+				err := ps.Create(ctx, &cce.NodeApp{
+					ID:     uuid.New(),
+					NodeID: node.ID,
+					AppID:  app.ID,
+				})
+				Expect(errors.Cause(err)).To(Equal(cce.ErrDuplicateEntry))
+			})
+
+			It("Should fail on a missing foreign key", func() {
+				Expect(ps.Create(ctx, &cce.NodeApp{
+					ID:     uuid.New(),
+					NodeID: uuid.New(),
+					AppID:  app.ID,
+				})).ToNot(Succeed())
+			})
+		})
+
+		Describe("ReadAll", func() {
+			It("Should read all entities of a type", func() {
+				other := &cce.Node{ID: uuid.New(), Serial: "DEF-456"}
+				Expect(ps.Create(ctx, other)).To(Succeed())
+
+				es, err := ps.ReadAll(ctx, &cce.Node{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(es).To(ConsistOf(node, other))
+			})
+		})
+
+		Describe("Filter", func() {
+			var nodeApp *cce.NodeApp
+
+			BeforeEach(func() {
+				nodeApp = &cce.NodeApp{
+					ID:     uuid.New(),
+					NodeID: node.ID,
+					AppID:  app.ID,
+				}
+				Expect(ps.Create(ctx, nodeApp)).To(Succeed())
+			})
+
+			It("Should match all filters", func() {
+				es, err := ps.Filter(ctx, &cce.NodeApp{}, []cce.Filter{
+					{Field: "node_id", Value: node.ID},
+					{Field: "app_id", Value: app.ID},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(es).To(ConsistOf(nodeApp))
+
+				es, err = ps.Filter(ctx, &cce.NodeApp{}, []cce.Filter{
+					{Field: "node_id", Value: node.ID},
+					{Field: "app_id", Value: uuid.New()},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(es).To(BeEmpty())
+			})
+
+			It("Should return everything without filters", func() {
+				es, err := ps.Filter(ctx, &cce.NodeApp{}, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(es).To(ConsistOf(nodeApp))
+			})
+
+			It("Should fail on a field that is not a filter field", func() {
+				_, err := ps.Filter(ctx, &cce.NodeApp{}, []cce.Filter{
+					{Field: "entity", Value: nodeApp.ID},
+				})
+				Expect(err).To(MatchError(`disallowed filter field "entity"`))
+			})
+		})
+
+		Describe("Filter operators", func() {
+			var small, large *cce.App
+
+			BeforeEach(func() {
+				small = &cce.App{ID: uuid.New(), Type: "vm", Name: "vm-small", Vendor: "acme", Cores: 2}
+				Expect(ps.Create(ctx, small)).To(Succeed())
+				large = &cce.App{ID: uuid.New(), Type: "vm", Name: "vm_large", Vendor: "acme", Cores: 8}
+				Expect(ps.Create(ctx, large)).To(Succeed())
+			})
+
+			DescribeTable("Should match the filters",
+				func(fs func() []cce.Filter, expected func() []*cce.App) {
+					es, err := ps.Filter(ctx, &cce.App{}, fs())
+					Expect(err).ToNot(HaveOccurred())
+
+					var matchers []interface{}
+					for _, e := range expected() {
+						matchers = append(matchers, e)
+					}
+					if len(matchers) == 0 {
+						Expect(es).To(BeEmpty())
+					} else {
+						Expect(es).To(ConsistOf(matchers...))
+					}
+				},
+				Entry("IN",
+					func() []cce.Filter {
+						return []cce.Filter{{Field: "id", Op: cce.FilterOpIn, Values: []string{small.ID, large.ID}}}
+					},
+					func() []*cce.App { return []*cce.App{small, large} }),
+				Entry("IN without values",
+					func() []cce.Filter { return []cce.Filter{{Field: "id", Op: cce.FilterOpIn}} },
+					func() []*cce.App { return nil }),
+				Entry("NOT IN",
+					func() []cce.Filter {
+						return []cce.Filter{{Field: "id", Op: cce.FilterOpIn, Values: []string{small.ID}, Not: true}}
+					},
+					func() []*cce.App { return []*cce.App{app, large} }),
+				Entry("NOT equal",
+					func() []cce.Filter { return []cce.Filter{{Field: "type", Value: "vm", Not: true}} },
+					func() []*cce.App { return []*cce.App{app} }),
+				Entry("Prefix",
+					func() []cce.Filter { return []cce.Filter{{Field: "name", Op: cce.FilterOpPrefix, Value: "vm"}} },
+					func() []*cce.App { return []*cce.App{small, large} }),
+				Entry("Prefix with a LIKE wildcard",
+					func() []cce.Filter { return []cce.Filter{{Field: "name", Op: cce.FilterOpPrefix, Value: "vm_"}} },
+					func() []*cce.App { return []*cce.App{large} }),
+				Entry("LIKE",
+					func() []cce.Filter { return []cce.Filter{{Field: "name", Op: cce.FilterOpLike, Value: "vm_%"}} },
+					func() []*cce.App { return []*cce.App{small, large} }),
+				Entry("LIKE with an escaped wildcard",
+					func() []cce.Filter { return []cce.Filter{{Field: "name", Op: cce.FilterOpLike, Value: `vm\_l%e`}} },
+					func() []*cce.App { return []*cce.App{large} }),
+				Entry("Range",
+					func() []cce.Filter {
+						return []cce.Filter{
+							{Field: "cores", Op: cce.FilterOpGreater, Value: "2"},
+							{Field: "cores", Op: cce.FilterOpLessOrEqual, Value: "8"},
+						}
+					},
+					func() []*cce.App { return []*cce.App{large} }),
+				Entry("Range with inclusive bounds",
+					func() []cce.Filter {
+						return []cce.Filter{
+							{Field: "cores", Op: cce.FilterOpGreaterOrEqual, Value: "2"},
+							{Field: "cores", Op: cce.FilterOpLess, Value: "8.5"},
+						}
+					},
+					func() []*cce.App { return []*cce.App{small, large} }),
+				Entry("Range on a string",
+					func() []cce.Filter { return []cce.Filter{{Field: "name", Op: cce.FilterOpGreater, Value: "0"}} },
+					func() []*cce.App { return nil }),
+				Entry("IS NULL",
+					func() []cce.Filter { return []cce.Filter{{Field: "vendor", Op: cce.FilterOpNull}} },
+					func() []*cce.App { return nil }),
+				Entry("IS NOT NULL",
+					func() []cce.Filter { return []cce.Filter{{Field: "vendor", Op: cce.FilterOpNull, Not: true}} },
+					func() []*cce.App { return []*cce.App{app, small, large} }),
+				Entry("OR",
+					func() []cce.Filter {
+						return []cce.Filter{{Or: [][]cce.Filter{
+							{{Field: "type", Value: "container"}},
+							{{Field: "vendor", Value: "acme"}, {Field: "cores", Op: cce.FilterOpGreater, Value: "4"}},
+						}}}
+					},
+					func() []*cce.App { return []*cce.App{app, large} }),
+				Entry("NOT OR",
+					func() []cce.Filter {
+						return []cce.Filter{{Not: true, Or: [][]cce.Filter{
+							{{Field: "type", Value: "container"}},
+							{{Field: "name", Value: "vm_large"}},
+						}}}
+					},
+					func() []*cce.App { return []*cce.App{small} }),
+			)
+
+			It("Should fail on a range of a value that is not a number", func() {
+				_, err := ps.Filter(ctx, &cce.App{}, []cce.Filter{
+					{Field: "cores", Op: cce.FilterOpLess, Value: "many"},
+				})
+				Expect(err).To(MatchError(`filter value "many" of field "cores" is not a number`))
+			})
+
+			It("Should fail on a field of an OR group that is not a filter field", func() {
+				_, err := ps.Filter(ctx, &cce.App{}, []cce.Filter{
+					{Or: [][]cce.Filter{{{Field: "entity", Value: "x"}}}},
+				})
+				Expect(err).To(MatchError(`disallowed filter field "entity"`))
+			})
+		})
+
+		Describe("List", func() {
+			var nodes []*cce.Node
+
+			BeforeEach(func() {
+				nodes = []*cce.Node{node}
+				for i, location := range []string{"lab", "lab", "field", "lab"} {
+					n := &cce.Node{
+						ID:       uuid.New(),
+						Name:     fmt.Sprintf("edge-%d", i+1),
+						Location: location,
+						Serial:   fmt.Sprintf("EDGE-%d", i+1),
+					}
+					Expect(ps.Create(ctx, n)).To(Succeed())
+					nodes = append(nodes, n)
+				}
+			})
+
+			names := func(es []cce.Persistable) []string {
+				var ns []string
+				for _, e := range es {
+					ns = append(ns, e.(*cce.Node).Name)
+				}
+				return ns
+			}
+
+			It("Should match all filters", func() {
+				page, err := ps.List(ctx, &cce.Node{}, cce.ListOptions{
+					Filters: []cce.Filter{
+						{Field: "location", Value: "lab"},
+						{Field: "name", Op: cce.FilterOpContains, Value: "dge-"},
+					},
+					Sort: "name",
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(names(page.Entities)).To(Equal([]string{"edge-1", "edge-2", "edge-4"}))
+				Expect(page.Total).To(Equal(3))
+				Expect(page.Next).To(BeEmpty())
+			})
+
+			It("Should sort in ascending and descending order", func() {
+				page, err := ps.List(ctx, &cce.Node{}, cce.ListOptions{Sort: "name"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(names(page.Entities)).To(Equal([]string{"edge-1", "edge-2", "edge-3", "edge-4", "node"}))
+
+				page, err = ps.List(ctx, &cce.Node{}, cce.ListOptions{Sort: "-name"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(names(page.Entities)).To(Equal([]string{"node", "edge-4", "edge-3", "edge-2", "edge-1"}))
+			})
+
+			It("Should page through the entities with cursors", func() {
+				for _, sort := range []string{"location", "-location", "", "-id"} {
+					var (
+						listed []cce.Persistable
+						pages  int
+						opts   = cce.ListOptions{Sort: sort, Limit: 2}
+					)
+					for {
+						page, err := ps.List(ctx, &cce.Node{}, opts)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(page.Total).To(Equal(5))
+						Expect(len(page.Entities)).To(BeNumerically("<=", 2))
+
+						listed = append(listed, page.Entities...)
+						pages++
+						if page.Next == "" {
+							break
+						}
+						opts.Cursor = page.Next
+					}
+
+					Expect(pages).To(Equal(3), "sort %q", sort)
+					Expect(listed).To(HaveLen(5), "sort %q", sort)
+					for _, n := range nodes {
+						Expect(listed).To(ContainElement(n), "sort %q", sort)
+					}
+				}
+			})
+
+			It("Should sort numbers by value", func() {
+				for i, cores := range []int{4, 16, 2} {
+					Expect(ps.Create(ctx, &cce.App{
+						ID:    uuid.New(),
+						Type:  "container",
+						Name:  fmt.Sprintf("app-%d", i+1),
+						Cores: cores,
+					})).To(Succeed())
+				}
+				appNames := func(es []cce.Persistable) []string {
+					var ns []string
+					for _, e := range es {
+						ns = append(ns, e.(*cce.App).Name)
+					}
+					return ns
+				}
+
+				page, err := ps.List(ctx, &cce.App{}, cce.ListOptions{Sort: "cores"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(appNames(page.Entities)).To(Equal([]string{"app", "app-3", "app-1", "app-2"}))
+
+				page, err = ps.List(ctx, &cce.App{}, cce.ListOptions{Sort: "-cores"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(appNames(page.Entities)).To(Equal([]string{"app-2", "app-1", "app-3", "app"}))
+
+				By("Paging through the apps with cursors")
+				var listed []cce.Persistable
+				opts := cce.ListOptions{Sort: "cores", Limit: 1}
+				for {
+					page, err = ps.List(ctx, &cce.App{}, opts)
+					Expect(err).ToNot(HaveOccurred())
+					listed = append(listed, page.Entities...)
+					if page.Next == "" {
+						break
+					}
+					opts.Cursor = page.Next
+				}
+				Expect(appNames(listed)).To(Equal([]string{"app", "app-3", "app-1", "app-2"}))
+			})
+
+			It("Should fail on a field that is not a filter field", func() {
+				_, err := ps.List(ctx, &cce.Node{}, cce.ListOptions{Sort: "entity"})
+				Expect(err).To(MatchError(`disallowed sort field "entity"`))
+
+				_, err = ps.List(ctx, &cce.Node{}, cce.ListOptions{
+					Filters: []cce.Filter{{Field: "entity", Value: node.ID}},
+				})
+				Expect(err).To(MatchError(`disallowed filter field "entity"`))
+			})
+		})
+
+		Describe("BulkUpdate", func() {
+			It("Should update entities", func() {
+				node.Name = "updated"
+				app.Name = "updated"
+				Expect(ps.BulkUpdate(ctx, []cce.Persistable{node, app})).To(Succeed())
+
+				e, err := ps.Read(ctx, node.ID, &cce.Node{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(Equal(node))
+				e, err = ps.Read(ctx, app.ID, &cce.App{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(Equal(app))
+			})
+
+			It("Should fail on a duplicate unique key", func() {
+				other := &cce.Node{ID: uuid.New()}
+				Expect(ps.Create(ctx, other)).To(Succeed())
+				Expect(ps.Create(ctx, &cce.NodeGRPCTarget{
+					ID:         uuid.New(),
+					NodeID:     node.ID,
+					GRPCTarget: "127.0.0.1:42101",
+				})).To(Succeed())
+				target := &cce.NodeGRPCTarget{
+					ID:         uuid.New(),
+					NodeID:     other.ID,
+					GRPCTarget: "127.0.0.2:42101",
+				}
+				Expect(ps.Create(ctx, target)).To(Succeed())
+
+				target.GRPCTarget = "127.0.0.1:42101"
+				Expect(ps.BulkUpdate(ctx, []cce.Persistable{target})).ToNot(Succeed())
+			})
+		})
+
+		Describe("Delete", func() {
+			It("Should delete an entity", func() {
+				ok, err := ps.Delete(ctx, app.ID, &cce.App{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				e, err := ps.Read(ctx, app.ID, &cce.App{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(BeNil())
+			})
+
+			It("Should not delete an entity that does not exist", func() {
+				ok, err := ps.Delete(ctx, uuid.New(), &cce.App{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+
+			It("Should fail if the entity is referenced", func() {
+				Expect(ps.Create(ctx, &cce.NodeApp{
+					ID:     uuid.New(),
+					NodeID: node.ID,
+					AppID:  app.ID,
+				})).To(Succeed())
+
+				_, err := ps.Delete(ctx, app.ID, &cce.App{})
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("Should cascade to entities referencing it on delete cascade", func() {
+				target := &cce.NodeGRPCTarget{
+					ID:         uuid.New(),
+					NodeID:     node.ID,
+					GRPCTarget: "127.0.0.1:42101",
+				}
+				Expect(ps.Create(ctx, target)).To(Succeed())
+
+				ok, err := ps.Delete(ctx, node.ID, &cce.Node{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				e, err := ps.Read(ctx, target.ID, &cce.NodeGRPCTarget{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(BeNil())
+			})
+		})
+
+		Describe("Resource versions", func() {
+			It("Should start at version 1", func() {
+				Expect(node.GetResourceVersion()).To(Equal(int64(1)))
+
+				e, err := ps.Read(ctx, node.ID, &cce.Node{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e.(cce.Versioned).GetResourceVersion()).To(Equal(int64(1)))
+			})
+
+			It("Should increment the version on update", func() {
+				By("Updating without a version")
+				node.SetResourceVersion(0)
+				Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+				Expect(node.GetResourceVersion()).To(Equal(int64(2)))
+
+				By("Updating at the persisted version")
+				Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+				Expect(node.GetResourceVersion()).To(Equal(int64(3)))
+
+				es, err := ps.ReadAll(ctx, &cce.Node{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(es).To(ConsistOf(node))
+			})
+
+			It("Should fail to update at another version", func() {
+				stale := *node
+				Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+
+				stale.Name = "stale"
+				err := ps.BulkUpdate(ctx, []cce.Persistable{&stale})
+				Expect(errors.Cause(err)).To(Equal(cce.ErrVersionConflict))
+
+				e, err := ps.Read(ctx, node.ID, &cce.Node{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(Equal(node))
+			})
+
+			It("Should only delete at the persisted version", func() {
+				zv := &cce.App{}
+				zv.SetResourceVersion(2)
+				_, err := ps.Delete(ctx, app.ID, zv)
+				Expect(errors.Cause(err)).To(Equal(cce.ErrVersionConflict))
+
+				zv.SetResourceVersion(1)
+				ok, err := ps.Delete(ctx, app.ID, zv)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				ok, err = ps.Delete(ctx, app.ID, zv)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		Describe("WithTx", func() {
+			var nodeApp *cce.NodeApp
+
+			BeforeEach(func() {
+				nodeApp = &cce.NodeApp{
+					ID:     uuid.New(),
+					NodeID: node.ID,
+					AppID:  app.ID,
+				}
+			})
+
+			It("Should commit if the function succeeds", func() {
+				Expect(ps.WithTx(ctx, func(tx cce.PersistenceService) error {
+					if err := tx.Create(ctx, nodeApp); err != nil {
+						return err
+					}
+
+					By("Reading the uncommitted entity in the transaction")
+					e, err := tx.Read(ctx, nodeApp.ID, &cce.NodeApp{})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(e).To(Equal(nodeApp))
+
+					node.Name = "updated"
+					return tx.BulkUpdate(ctx, []cce.Persistable{node})
+				})).To(Succeed())
+
+				e, err := ps.Read(ctx, nodeApp.ID, &cce.NodeApp{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(Equal(nodeApp))
+				e, err = ps.Read(ctx, node.ID, &cce.Node{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(Equal(node))
+			})
+
+			It("Should roll back if the function fails", func() {
+				errFailed := errors.New("failed")
+
+				Expect(ps.WithTx(ctx, func(tx cce.PersistenceService) error {
+					if err := tx.Create(ctx, nodeApp); err != nil {
+						return err
+					}
+					if _, err := tx.Delete(ctx, app.ID, &cce.App{}); err == nil {
+						return errors.New("deleted a referenced app")
+					}
+					if _, err := tx.Delete(ctx, nodeApp.ID, &cce.NodeApp{}); err != nil {
+						return err
+					}
+					if _, err := tx.Delete(ctx, app.ID, &cce.App{}); err != nil {
+						return err
+					}
+					return errFailed
+				})).To(Equal(errFailed))
+
+				e, err := ps.Read(ctx, nodeApp.ID, &cce.NodeApp{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(BeNil())
+				e, err = ps.Read(ctx, app.ID, &cce.App{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(Equal(app))
+			})
+
+			It("Should run nested calls in the same transaction", func() {
+				errFailed := errors.New("failed")
+
+				Expect(ps.WithTx(ctx, func(tx cce.PersistenceService) error {
+					Expect(tx.WithTx(ctx, func(nested cce.PersistenceService) error {
+						return nested.Create(ctx, nodeApp)
+					})).To(Succeed())
+					return errFailed
+				})).To(Equal(errFailed))
+
+				e, err := ps.Read(ctx, nodeApp.ID, &cce.NodeApp{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(BeNil())
+			})
+		})
+	})
+}
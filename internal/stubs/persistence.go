@@ -6,6 +6,10 @@ package stubs
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+
 	cce "github.com/open-ness/edgecontroller"
 )
 
@@ -34,32 +38,141 @@ type PersistenceServiceStub struct {
 	FilterRet        []cce.Persistable
 	BulkUpdateErr    error
 	BulkUpdateValues [][]cce.Persistable
+
+	// Tables holds entities by table name. If it is not nil, created entities
+	// are added to it and the other methods are served from it rather than
+	// from FilterRet.
+	Tables map[string][]cce.Persistable
+
+	mu sync.Mutex
 }
 
 func (ps *PersistenceServiceStub) Create(c context.Context, p cce.Persistable) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
 	ps.CreateValues = append(ps.CreateValues, p)
+	if ps.Tables != nil && ps.CreateErr == nil {
+		ps.Tables[p.GetTableName()] = append(ps.Tables[p.GetTableName()], p)
+	}
 	return ps.CreateErr
 }
 
-func (ps *PersistenceServiceStub) Read(context.Context, string, cce.Persistable) (cce.Persistable, error) {
+func (ps *PersistenceServiceStub) Read(c context.Context, id string, zv cce.Persistable) (cce.Persistable, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for _, e := range ps.Tables[zv.GetTableName()] {
+		if e.GetID() == id {
+			return e, nil
+		}
+	}
 	return nil, nil
 }
 
 func (ps *PersistenceServiceStub) Filter(c context.Context, fb cce.Filterable, f []cce.Filter) ([]cce.Persistable,
 	error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
 	ps.FilterValues = append(ps.FilterValues, f)
-	return ps.FilterRet, ps.FilterErr
+	if ps.Tables == nil {
+		return ps.FilterRet, ps.FilterErr
+	}
+	if ps.FilterErr != nil {
+		return nil, ps.FilterErr
+	}
+
+	var ret []cce.Persistable
+	for _, e := range ps.Tables[fb.GetTableName()] {
+		ok, err := matches(e, f)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ret = append(ret, e)
+		}
+	}
+	return ret, nil
 }
 
-func (ps *PersistenceServiceStub) ReadAll(context.Context, cce.Persistable) ([]cce.Persistable, error) {
-	return nil, nil
+// matches returns whether an entity matches equality and "in" filters on its
+// JSON fields.
+func matches(e cce.Persistable, fs []cce.Filter) (bool, error) {
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+	fields := make(map[string]interface{})
+	if err = json.Unmarshal(bytes, &fields); err != nil {
+		return false, err
+	}
+
+	for _, f := range fs {
+		value := fmt.Sprint(fields[f.Field])
+		switch f.Op {
+		case cce.FilterOpIn:
+			in := false
+			for _, v := range f.Values {
+				in = in || value == v
+			}
+			if !in {
+				return false, nil
+			}
+		default:
+			if value != f.Value {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func (ps *PersistenceServiceStub) List(c context.Context, fb cce.Filterable, opts cce.ListOptions) (*cce.Page,
+	error) {
+	es, err := ps.Filter(c, fb, opts.Filters)
+	return &cce.Page{Entities: es, Total: len(es)}, err
+}
+
+func (ps *PersistenceServiceStub) ReadAll(c context.Context, zv cce.Persistable) ([]cce.Persistable, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	return ps.Tables[zv.GetTableName()], nil
 }
 
 func (ps *PersistenceServiceStub) BulkUpdate(c context.Context, p []cce.Persistable) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
 	ps.BulkUpdateValues = append(ps.BulkUpdateValues, p)
+	if ps.Tables != nil && ps.BulkUpdateErr == nil {
+		for _, e := range p {
+			table := ps.Tables[e.GetTableName()]
+			for i := range table {
+				if table[i].GetID() == e.GetID() {
+					table[i] = e
+				}
+			}
+		}
+	}
 	return ps.BulkUpdateErr
 }
 
-func (ps *PersistenceServiceStub) Delete(context.Context, string, cce.Persistable) (bool, error) {
+func (ps *PersistenceServiceStub) Delete(c context.Context, id string, zv cce.Persistable) (bool, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	table := ps.Tables[zv.GetTableName()]
+	for i := range table {
+		if table[i].GetID() == id {
+			ps.Tables[zv.GetTableName()] = append(table[:i:i], table[i+1:]...)
+			return true, nil
+		}
+	}
 	return false, nil
 }
+
+func (ps *PersistenceServiceStub) WithTx(c context.Context, f func(tx cce.PersistenceService) error) error {
+	return f(ps)
+}
@@ -4,60 +4,341 @@
 package jose
 
 import (
+	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"sort"
+	"sync"
 	"time"
 
+	logger "github.com/open-ness/common/log"
+	"github.com/open-ness/edgecontroller/uuid"
 	"github.com/pkg/errors"
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 )
 
-// JWSTokenIssuer issues and validates JSON web signature tokens.
+var log = logger.DefaultLogger.WithField("pkg", "jose")
+
+// Defaults of JWSTokenIssuer.
+const (
+	DefaultAccessTokenLifetime  = 15 * time.Minute
+	DefaultRefreshTokenLifetime = 24 * time.Hour
+	DefaultRotationInterval     = 30 * 24 * time.Hour
+)
+
+// Token uses.
+const (
+	UseAccess  = "access"
+	UseRefresh = "refresh"
+)
+
+// keyAlgorithm is the algorithm of the generated signing keys.
+const keyAlgorithm = jose.ES384
+
+// ErrRevoked is returned by Validate for a revoked token.
+var ErrRevoked = errors.New("token is revoked")
+
+// SigningKey is a key of the key set of a JWSTokenIssuer.
+type SigningKey struct {
+	// ID is the key ID (kid) in the header of the tokens signed with the key.
+	ID string
+
+	Key       crypto.Signer
+	Algorithm string
+
+	// Created is the time the key was generated. The newest key signs tokens
+	// and the others only validate them.
+	Created time.Time
+}
+
+// Store persists the key set and the revoked tokens of a JWSTokenIssuer, so
+// that they survive restarts.
+type Store interface {
+	// Keys returns the key set.
+	Keys(ctx context.Context) ([]*SigningKey, error)
+
+	// AddKey adds a key to the key set.
+	AddKey(ctx context.Context, k *SigningKey) error
+
+	// RemoveKey removes the key with an ID from the key set.
+	RemoveKey(ctx context.Context, id string) error
+
+	// Revoke revokes the token with an ID (jti) until it expires, and
+	// returns whether it was revoked by this call rather than before.
+	Revoke(ctx context.Context, id string, expiry time.Time) (bool, error)
+
+	// IsRevoked returns whether the token with an ID is revoked.
+	IsRevoked(ctx context.Context, id string) (bool, error)
+
+	// RemoveExpiredRevocations removes the revocations of the tokens that
+	// expired before a time.
+	RemoveExpiredRevocations(ctx context.Context, before time.Time) error
+}
+
+// JWSTokenIssuer issues and validates JSON web signature tokens. Short-lived
+// access tokens authenticate requests and long-lived refresh tokens are
+// exchanged for new access tokens. The signing key is rotated every
+// RotationInterval, and the previous keys are kept until the tokens they
+// signed have expired.
 type JWSTokenIssuer struct {
-	Key          crypto.PrivateKey
-	KeyAlgorithm string
+	Store Store
+
+	AccessTokenLifetime  time.Duration
+	RefreshTokenLifetime time.Duration
+	RotationInterval     time.Duration
+
+	mu   sync.RWMutex
+	keys []*SigningKey // newest first
 }
 
-// Issue issues a new JWT token signed with the authority key and valid for one
-// day. The signed JWT token is returned in the RFC 7519 compact serialization
-// format.
-func (s *JWSTokenIssuer) Issue() (string, error) {
+// Claims are the claims of the tokens issued by JWSTokenIssuer.
+type Claims struct {
+	jwt.Claims
+
+	// Use is UseAccess or UseRefresh.
+	Use string `json:"use"`
+
+	// Roles are the roles of the subject of an access token.
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Load loads the key set from the store. It generates a signing key if there
+// is none or the signing key is due for rotation.
+func (s *JWSTokenIssuer) Load(ctx context.Context) error {
+	keys, err := s.Store.Keys(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to load signing keys")
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Created.After(keys[j].Created) })
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	if len(keys) == 0 || time.Since(keys[0].Created) >= s.rotationInterval() {
+		return s.Rotate(ctx)
+	}
+	return nil
+}
+
+// Rotate generates a new signing key. The previous keys validate the tokens
+// they signed until they expire, after which they are removed.
+func (s *JWSTokenIssuer) Rotate(ctx context.Context) error {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return errors.Wrap(err, "unable to generate signing key")
+	}
+	thumbprint, err := (&jose.JSONWebKey{Key: key.Public()}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return errors.Wrap(err, "unable to identify signing key")
+	}
+	k := &SigningKey{
+		ID:        base64.RawURLEncoding.EncodeToString(thumbprint),
+		Key:       key,
+		Algorithm: string(keyAlgorithm),
+		Created:   time.Now(),
+	}
+	if err = s.Store.AddKey(ctx, k); err != nil {
+		return errors.Wrap(err, "unable to store signing key")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A key signs its last token when the next key is created
+	keys := []*SigningKey{k}
+	for i, old := range s.keys {
+		retired := k.Created
+		if i > 0 {
+			retired = s.keys[i-1].Created
+		}
+		if time.Since(retired) < s.refreshTokenLifetime() {
+			keys = append(keys, old)
+			continue
+		}
+		if err = s.Store.RemoveKey(ctx, old.ID); err != nil {
+			log.Errf("Error removing expired signing key %s: %v", old.ID, err)
+			keys = append(keys, old)
+		}
+	}
+	s.keys = keys
+	log.Infof("Rotated token signing key, signing with key %s", k.ID)
+
+	return nil
+}
+
+// Run rotates the signing key every RotationInterval and removes expired
+// revocations until the context is done. It reloads the key set first, in case
+// another controller sharing the store rotated it.
+func (s *JWSTokenIssuer) Run(ctx context.Context) error {
+	interval := time.Hour
+	if s.rotationInterval() < interval {
+		interval = s.rotationInterval()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := s.Load(ctx); err != nil {
+			log.Errf("Error rotating signing key: %v", err)
+		}
+		if err := s.Store.RemoveExpiredRevocations(ctx, time.Now()); err != nil {
+			log.Errf("Error removing expired revocations: %v", err)
+		}
+	}
+}
+
+// Issue issues a new access token for a subject with roles, valid for
+// AccessTokenLifetime. The signed JWT token is returned in the RFC 7519
+// compact serialization format.
+func (s *JWSTokenIssuer) Issue(subject string, roles []string) (string, error) {
+	return s.issue(Claims{
+		Claims: jwt.Claims{Subject: subject},
+		Use:    UseAccess,
+		Roles:  roles,
+	}, s.GetAccessTokenLifetime())
+}
+
+// IssueRefresh issues a new refresh token for a subject, valid for
+// RefreshTokenLifetime. The signed JWT token is returned in the RFC 7519
+// compact serialization format.
+func (s *JWSTokenIssuer) IssueRefresh(subject string) (string, error) {
+	return s.issue(Claims{
+		Claims: jwt.Claims{Subject: subject},
+		Use:    UseRefresh,
+	}, s.refreshTokenLifetime())
+}
+
+func (s *JWSTokenIssuer) issue(claims Claims, lifetime time.Duration) (string, error) {
+	s.mu.RLock()
+	if len(s.keys) == 0 {
+		s.mu.RUnlock()
+		return "", errors.New("no signing key")
+	}
+	key := s.keys[0]
+	s.mu.RUnlock()
+
 	signer, err := jose.NewSigner(
 		jose.SigningKey{
-			Key:       s.Key,
-			Algorithm: jose.SignatureAlgorithm(s.KeyAlgorithm),
+			Key:       jose.JSONWebKey{Key: key.Key, KeyID: key.ID},
+			Algorithm: jose.SignatureAlgorithm(key.Algorithm),
 		},
 		new(jose.SignerOptions).WithType("JWT"))
 	if err != nil {
 		return "", errors.Wrap(err, "unable to create token signer")
 	}
 
-	claims := jwt.Claims{
-		Expiry: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 1 day
-	}
+	now := time.Now()
+	claims.ID = uuid.New()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.Expiry = jwt.NewNumericDate(now.Add(lifetime))
 
 	return jwt.Signed(signer).Claims(claims).CompactSerialize()
 }
 
-// Validate validates the JWT token was signed with the authority key and has
-// not yet expired. The signed JWT token is expected to be in the RFC 7519
-// compact serialization format.
-func (s *JWSTokenIssuer) Validate(t string) error {
+// Validate validates that the access token was signed with a key of the key
+// set, has not yet expired and was not revoked, and returns its claims. The
+// signed JWT token is expected to be in the RFC 7519 compact serialization
+// format.
+func (s *JWSTokenIssuer) Validate(ctx context.Context, t string) (*Claims, error) {
+	return s.validate(ctx, t, UseAccess)
+}
+
+// ValidateRefresh validates a refresh token like Validate validates an access
+// token.
+func (s *JWSTokenIssuer) ValidateRefresh(ctx context.Context, t string) (*Claims, error) {
+	return s.validate(ctx, t, UseRefresh)
+}
+
+func (s *JWSTokenIssuer) validate(ctx context.Context, t, use string) (*Claims, error) {
 	token, err := jwt.ParseSigned(t)
 	if err != nil {
-		return errors.Wrap(err, "unable to parse token")
+		return nil, errors.Wrap(err, "unable to parse token")
+	}
+	if len(token.Headers) != 1 {
+		return nil, errors.New("token must have one signature")
+	}
+
+	key := s.key(token.Headers[0].KeyID)
+	if key == nil {
+		return nil, errors.Errorf("unknown signing key %q", token.Headers[0].KeyID)
+	}
+
+	var claims Claims
+	err = token.Claims(key.Key.Public(), &claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to deserialize token claims")
 	}
 
-	key, ok := s.Key.(crypto.Signer)
-	if !ok {
-		return errors.Wrap(err, "invalid signing key")
+	if err = claims.ValidateWithLeeway(jwt.Expected{Time: time.Now()}, 0); err != nil {
+		return nil, err
+	}
+	if claims.Use != use {
+		return nil, errors.Errorf("token use is not %q", use)
 	}
 
-	var claims jwt.Claims
-	err = token.Claims(key.Public(), &claims)
+	revoked, err := s.Store.IsRevoked(ctx, claims.ID)
 	if err != nil {
-		return errors.Wrap(err, "unable to deserialize token claims")
+		return nil, errors.Wrap(err, "unable to check token revocation")
+	}
+	if revoked {
+		return nil, ErrRevoked
+	}
+
+	return &claims, nil
+}
+
+// Revoke revokes a token, so that it no longer validates, and returns whether
+// it was revoked by this call. Of concurrent calls revoking a token, only one
+// returns true.
+func (s *JWSTokenIssuer) Revoke(ctx context.Context, claims *Claims) (bool, error) {
+	if claims.ID == "" || claims.Expiry == nil {
+		return false, errors.New("token has no ID or expiry")
+	}
+	return s.Store.Revoke(ctx, claims.ID, claims.Expiry.Time())
+}
+
+// key returns the key of the key set with an ID, or nil if there is none.
+func (s *JWSTokenIssuer) key(id string) *SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.ID == id {
+			return k
+		}
 	}
+	return nil
+}
 
-	return claims.Validate(jwt.Expected{Time: time.Now()})
+// GetAccessTokenLifetime gets the lifetime of access tokens.
+func (s *JWSTokenIssuer) GetAccessTokenLifetime() time.Duration {
+	if s.AccessTokenLifetime > 0 {
+		return s.AccessTokenLifetime
+	}
+	return DefaultAccessTokenLifetime
+}
+
+func (s *JWSTokenIssuer) refreshTokenLifetime() time.Duration {
+	if s.RefreshTokenLifetime > 0 {
+		return s.RefreshTokenLifetime
+	}
+	return DefaultRefreshTokenLifetime
+}
+
+func (s *JWSTokenIssuer) rotationInterval() time.Duration {
+	if s.RotationInterval > 0 {
+		return s.RotationInterval
+	}
+	return DefaultRotationInterval
 }
@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package jose_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestJOSE(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "JOSE Suite")
+}
@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package jose_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/jose"
+)
+
+// memStore is an in-memory jose.Store.
+type memStore struct {
+	mu      sync.Mutex
+	keys    map[string]*jose.SigningKey
+	revoked map[string]time.Time
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		keys:    make(map[string]*jose.SigningKey),
+		revoked: make(map[string]time.Time),
+	}
+}
+
+func (s *memStore) Keys(ctx context.Context) ([]*jose.SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []*jose.SigningKey
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (s *memStore) AddKey(ctx context.Context, k *jose.SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[k.ID] = k
+	return nil
+}
+
+func (s *memStore) RemoveKey(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, id)
+	return nil
+}
+
+func (s *memStore) Revoke(ctx context.Context, id string, expiry time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.revoked[id]; ok {
+		return false, nil
+	}
+	s.revoked[id] = expiry
+	return true, nil
+}
+
+func (s *memStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.revoked[id]
+	return ok, nil
+}
+
+func (s *memStore) RemoveExpiredRevocations(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, expiry := range s.revoked {
+		if expiry.Before(before) {
+			delete(s.revoked, id)
+		}
+	}
+	return nil
+}
+
+var _ = Describe("JWSTokenIssuer", func() {
+	var (
+		ctx    = context.Background()
+		store  *memStore
+		issuer *jose.JWSTokenIssuer
+	)
+
+	BeforeEach(func() {
+		store = newMemStore()
+		issuer = &jose.JWSTokenIssuer{Store: store}
+		Expect(issuer.Load(ctx)).To(Succeed())
+	})
+
+	Describe("Load", func() {
+		It("Should generate and store a key on first load", func() {
+			Expect(store.keys).To(HaveLen(1))
+		})
+
+		It("Should validate tokens issued before a restart", func() {
+			token, err := issuer.Issue("admin", []string{"admin"})
+			Expect(err).ToNot(HaveOccurred())
+
+			restarted := &jose.JWSTokenIssuer{Store: store}
+			Expect(restarted.Load(ctx)).To(Succeed())
+			Expect(store.keys).To(HaveLen(1))
+
+			claims, err := restarted.Validate(ctx, token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Subject).To(Equal("admin"))
+		})
+
+		It("Should rotate a key that is due for rotation", func() {
+			for _, k := range store.keys {
+				k.Created = time.Now().Add(-jose.DefaultRotationInterval)
+			}
+
+			restarted := &jose.JWSTokenIssuer{Store: store}
+			Expect(restarted.Load(ctx)).To(Succeed())
+			Expect(store.keys).To(HaveLen(2))
+		})
+	})
+
+	Describe("Issue and Validate", func() {
+		It("Should issue an access token with the subject and roles", func() {
+			token, err := issuer.Issue("jane", []string{"viewer", "operator"})
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := issuer.Validate(ctx, token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.ID).ToNot(BeEmpty())
+			Expect(claims.Subject).To(Equal("jane"))
+			Expect(claims.Roles).To(Equal([]string{"viewer", "operator"}))
+			Expect(claims.Use).To(Equal(jose.UseAccess))
+			Expect(claims.Expiry.Time()).To(BeTemporally("~", time.Now().Add(jose.DefaultAccessTokenLifetime), time.Second))
+		})
+
+		It("Should issue a refresh token that is not an access token", func() {
+			token, err := issuer.IssueRefresh("jane")
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := issuer.ValidateRefresh(ctx, token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Subject).To(Equal("jane"))
+			Expect(claims.Expiry.Time()).To(BeTemporally("~", time.Now().Add(jose.DefaultRefreshTokenLifetime), time.Second))
+
+			_, err = issuer.Validate(ctx, token)
+			Expect(err).To(MatchError(`token use is not "access"`))
+		})
+
+		It("Should not validate an access token as a refresh token", func() {
+			token, err := issuer.Issue("jane", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = issuer.ValidateRefresh(ctx, token)
+			Expect(err).To(MatchError(`token use is not "refresh"`))
+		})
+
+		It("Should not validate an expired token", func() {
+			issuer.AccessTokenLifetime = time.Millisecond
+			token, err := issuer.Issue("jane", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			// The expiry is in seconds
+			time.Sleep(1100 * time.Millisecond)
+
+			_, err = issuer.Validate(ctx, token)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should not validate a token signed with an unknown key", func() {
+			other := &jose.JWSTokenIssuer{Store: newMemStore()}
+			Expect(other.Load(ctx)).To(Succeed())
+			token, err := other.Issue("jane", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = issuer.Validate(ctx, token)
+			Expect(err).To(MatchError(ContainSubstring("unknown signing key")))
+		})
+
+		It("Should not validate a malformed token", func() {
+			_, err := issuer.Validate(ctx, "not a token")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Rotate", func() {
+		It("Should sign with the new key and validate with the old key", func() {
+			before, err := issuer.Issue("jane", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(issuer.Rotate(ctx)).To(Succeed())
+			Expect(store.keys).To(HaveLen(2))
+
+			after, err := issuer.Issue("jane", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = issuer.Validate(ctx, before)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = issuer.Validate(ctx, after)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should remove keys once the tokens they signed have expired", func() {
+			issuer.RefreshTokenLifetime = time.Millisecond
+			Expect(issuer.Rotate(ctx)).To(Succeed())
+			Expect(store.keys).To(HaveLen(2))
+
+			time.Sleep(10 * time.Millisecond)
+			Expect(issuer.Rotate(ctx)).To(Succeed())
+			Expect(store.keys).To(HaveLen(2))
+		})
+	})
+
+	Describe("Revoke", func() {
+		It("Should revoke a token", func() {
+			token, err := issuer.Issue("jane", nil)
+			Expect(err).ToNot(HaveOccurred())
+			other, err := issuer.Issue("jane", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := issuer.Validate(ctx, token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(issuer.Revoke(ctx, claims)).To(BeTrue())
+			Expect(issuer.Revoke(ctx, claims)).To(BeFalse())
+
+			_, err = issuer.Validate(ctx, token)
+			Expect(err).To(Equal(jose.ErrRevoked))
+			_, err = issuer.Validate(ctx, other)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/open-ness/edgecontroller/plan"
 	"github.com/open-ness/edgecontroller/uuid"
 	"github.com/pkg/errors"
 	appsV1 "k8s.io/api/apps/v1"
@@ -37,6 +38,11 @@ type PortProto struct {
 	Protocol string
 }
 
+// appRequest is the request recorded for a dry run of a call on an app.
+type appRequest struct {
+	AppID string `json:"app_id"`
+}
+
 const (
 	// Key for the label attached to a k8s pod or k8s node containing the Node ID
 	nodeIDLabelKey = "node-id"
@@ -114,6 +120,10 @@ func (ks *Client) init() {
 
 // Deploy creates a kubernetes deployment
 func (ks *Client) Deploy(ctx context.Context, nodeID string, app App) error {
+	if plan.Record(ctx, nodeID, plan.ServiceKubernetes, "Deploy", app) {
+		return nil
+	}
+
 	ks.connectOnce.Do(ks.init)
 	if ks.err != nil {
 		return ks.err
@@ -131,6 +141,10 @@ func (ks *Client) Deploy(ctx context.Context, nodeID string, app App) error {
 
 // Undeploy cascade deletes a kubernetes deployment
 func (ks *Client) Undeploy(ctx context.Context, nodeID, appID string) error {
+	if plan.Record(ctx, nodeID, plan.ServiceKubernetes, "Undeploy", appRequest{appID}) {
+		return nil
+	}
+
 	ks.connectOnce.Do(ks.init)
 	if ks.err != nil {
 		return ks.err
@@ -279,6 +293,10 @@ func int32Ptr(i int32) *int32 { return &i }
 
 // Start scales up the number of replicas of kubernetes deployment to 1.
 func (ks *Client) Start(ctx context.Context, nodeID, appID string) error {
+	if plan.Record(ctx, nodeID, plan.ServiceKubernetes, "Start", appRequest{appID}) {
+		return nil
+	}
+
 	deploymentName, err := ks.getDeploymentName(nodeID, appID)
 	if err != nil {
 		return errors.Wrap(err, "start: error getting deployment name by ID")
@@ -299,6 +317,10 @@ func (ks *Client) Start(ctx context.Context, nodeID, appID string) error {
 
 // Stop scales down the number of replicas of kubernetes deployment to 0.
 func (ks *Client) Stop(ctx context.Context, nodeID, appID string) error {
+	if plan.Record(ctx, nodeID, plan.ServiceKubernetes, "Stop", appRequest{appID}) {
+		return nil
+	}
+
 	deploymentName, err := ks.getDeploymentName(nodeID, appID)
 	if err != nil {
 		return errors.Wrap(err, "stop: error getting deployment name by ID")
@@ -319,6 +341,10 @@ func (ks *Client) Stop(ctx context.Context, nodeID, appID string) error {
 
 // Restart scales down the number of replicas of kubernetes deployment to 0 and then scale up to 1.
 func (ks *Client) Restart(ctx context.Context, nodeID, appID string) error {
+	if plan.Record(ctx, nodeID, plan.ServiceKubernetes, "Restart", appRequest{appID}) {
+		return nil
+	}
+
 	deploymentName, err := ks.getDeploymentName(nodeID, appID)
 	if err != nil {
 		return errors.Wrap(err, "restart: error getting deployment name by ID")
@@ -489,8 +515,6 @@ func (ks *Client) GetAppIDByIP(ctx context.Context, nodeID, ipAddr string) (stri
 func (ks *Client) ApplyNetworkPolicy(ctx context.Context,
 	nodeID, appID string, policy *networkingV1.NetworkPolicy) error {
 
-	networkingClient := ks.clientSet.NetworkingV1().RESTClient()
-
 	// Currently only 1 NetworkPolicy per app so we can just concatenate node and app
 	policy.ObjectMeta.Name = fmt.Sprintf("np-%s.%s", nodeID, appID)
 
@@ -501,6 +525,12 @@ func (ks *Client) ApplyNetworkPolicy(ctx context.Context,
 		},
 	}
 
+	if plan.Record(ctx, nodeID, plan.ServiceKubernetes, "ApplyNetworkPolicy", policy) {
+		return nil
+	}
+
+	networkingClient := ks.clientSet.NetworkingV1().RESTClient()
+
 	err := networkingClient.Post().
 		Context(ctx).
 		Namespace(apiV1.NamespaceDefault).
@@ -517,6 +547,10 @@ func (ks *Client) ApplyNetworkPolicy(ctx context.Context,
 
 // DeleteNetworkPolicy deletes network policy for app on specified node
 func (ks *Client) DeleteNetworkPolicy(ctx context.Context, nodeID, appID string) error {
+	if plan.Record(ctx, nodeID, plan.ServiceKubernetes, "DeleteNetworkPolicy", appRequest{appID}) {
+		return nil
+	}
+
 	networkingClient := ks.clientSet.NetworkingV1().RESTClient()
 
 	propagation := metaV1.DeletePropagationBackground
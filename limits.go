@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Limits are the limits on requests and applications, which can be changed
+// while the controller is running.
+type Limits struct {
+	// MaxCores is the maximum number of cores that an application can use.
+	MaxCores int `json:"max_cores"`
+
+	// MaxMemory is the maximum memory (in MB) that an application can use.
+	MaxMemory int `json:"max_memory"`
+
+	// MaxBodySize is the maximum size (in bytes) of an acceptable request
+	// body.
+	MaxBodySize int64 `json:"max_body_size"`
+}
+
+// DefaultLimits are the limits that are in effect until SetLimits is called.
+var DefaultLimits = Limits{
+	MaxCores:    DefaultMaxCores,
+	MaxMemory:   DefaultMaxMemory,
+	MaxBodySize: DefaultMaxBodySize,
+}
+
+var limits atomic.Value
+
+func init() {
+	limits.Store(DefaultLimits)
+}
+
+// CurrentLimits returns the limits in effect.
+func CurrentLimits() Limits {
+	return limits.Load().(Limits)
+}
+
+// SetLimits replaces the limits in effect. The limits should be validated
+// first.
+func SetLimits(l Limits) {
+	limits.Store(l)
+}
+
+// Validate validates the limits.
+func (l Limits) Validate() error {
+	if l.MaxCores < 1 {
+		return fmt.Errorf("max_cores must be at least 1, got %d", l.MaxCores)
+	}
+	if l.MaxMemory < 1 {
+		return fmt.Errorf("max_memory must be at least 1, got %d", l.MaxMemory)
+	}
+	if l.MaxBodySize < 1 {
+		return fmt.Errorf("max_body_size must be at least 1, got %d", l.MaxBodySize)
+	}
+	return nil
+}
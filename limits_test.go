@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Limits", func() {
+	var limits cce.Limits
+
+	BeforeEach(func() {
+		limits = cce.DefaultLimits
+	})
+
+	Describe("CurrentLimits", func() {
+		It("Should return the limits that were set", func() {
+			defer cce.SetLimits(cce.CurrentLimits())
+			Expect(cce.CurrentLimits()).To(Equal(cce.DefaultLimits))
+
+			limits.MaxCores = 4
+			cce.SetLimits(limits)
+			Expect(cce.CurrentLimits().MaxCores).To(Equal(4))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should accept the default limits", func() {
+			Expect(limits.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if MaxCores is < 1", func() {
+			limits.MaxCores = 0
+			Expect(limits.Validate()).To(MatchError("max_cores must be at least 1, got 0"))
+		})
+
+		It("Should return an error if MaxMemory is < 1", func() {
+			limits.MaxMemory = -1
+			Expect(limits.Validate()).To(MatchError("max_memory must be at least 1, got -1"))
+		})
+
+		It("Should return an error if MaxBodySize is < 1", func() {
+			limits.MaxBodySize = 0
+			Expect(limits.Validate()).To(MatchError("max_body_size must be at least 1, got 0"))
+		})
+	})
+})
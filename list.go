@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaxListLimit is the maximum number of entities in a page.
+const MaxListLimit = 1000
+
+// ListOptions are the options of PersistenceService.List.
+type ListOptions struct {
+	// Filters are the filters that the entities must all match.
+	Filters []Filter
+
+	// Sort is the filter field or "id" to sort by, prefixed with "-" for
+	// descending order. Values are compared as SortValues, and entities with
+	// the same value are sorted by ID. The default is to sort by ID.
+	Sort string
+
+	// Limit is the maximum number of entities in the page, or 0 for all.
+	Limit int
+
+	// Cursor is the Next cursor of the previous page, or "" for the first
+	// page. It is only valid with the same filters and sort.
+	Cursor string
+}
+
+// Page is a page of entities returned by PersistenceService.List.
+type Page struct {
+	Entities []Persistable
+
+	// Next is the cursor of the next page, or "" if this is the last page.
+	Next string
+
+	// Total is the number of entities matching the filters on all pages.
+	Total int
+}
+
+// Validate validates the options for listing entities like zv.
+func (o *ListOptions) Validate(zv Filterable) error {
+	if err := validateFilters(zv, o.Filters); err != nil {
+		return err
+	}
+
+	if field, _ := o.SortField(); field != "id" && !isFilterField(zv, field) {
+		return errors.Errorf("disallowed sort field %q", field)
+	}
+
+	if o.Limit < 0 || o.Limit > MaxListLimit {
+		return errors.Errorf("limit must be between 0 and %d", MaxListLimit)
+	}
+
+	if o.Cursor != "" {
+		if _, _, err := DecodeCursor(o.Cursor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SortField returns the field to sort by and whether the order is
+// descending.
+func (o *ListOptions) SortField() (field string, desc bool) {
+	switch {
+	case o.Sort == "":
+		return "id", false
+	case strings.HasPrefix(o.Sort, "-"):
+		return strings.TrimPrefix(o.Sort, "-"), true
+	default:
+		return o.Sort, false
+	}
+}
+
+// Ranks of SortValues.
+const (
+	SortRankNull = iota
+	SortRankNumber
+	SortRankString
+)
+
+// SortValue is the value of the field that an entity is sorted by. Missing
+// and null values sort first, then numbers by their value, then other values
+// by their string, as range filters compare numbers.
+type SortValue struct {
+	Rank   int
+	Number float64
+	String string
+}
+
+// NewSortValue returns the SortValue of the JSON value of a field, which is
+// empty if the field is missing.
+func NewSortValue(raw json.RawMessage) SortValue {
+	if len(raw) == 0 || string(raw) == "null" {
+		return SortValue{Rank: SortRankNull}
+	}
+
+	var n float64
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return SortValue{Rank: SortRankNumber, Number: n}
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return SortValue{Rank: SortRankString, String: s}
+	}
+	return SortValue{Rank: SortRankString, String: string(raw)}
+}
+
+// Less returns whether the value sorts before another.
+func (v SortValue) Less(o SortValue) bool {
+	switch {
+	case v.Rank != o.Rank:
+		return v.Rank < o.Rank
+	case v.Rank == SortRankNumber:
+		return v.Number < o.Number
+	default:
+		return v.String < o.String
+	}
+}
+
+// EncodeCursor encodes the sort field value and ID of the last entity of a
+// page as the cursor of the next page. The value is the JSON value of the
+// field, or "" if it is missing, except when sorting by ID.
+func EncodeCursor(value, id string) string {
+	bytes, _ := json.Marshal([]string{value, id}) // marshaling strings cannot fail
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// DecodeCursor decodes the sort field value and ID of the last entity of the
+// previous page from a cursor.
+func DecodeCursor(cursor string) (value, id string, err error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", errors.Wrap(err, "malformed cursor")
+	}
+
+	var fields []string
+	if err = json.Unmarshal(bytes, &fields); err != nil || len(fields) != 2 {
+		return "", "", errors.New("malformed cursor")
+	}
+
+	return fields[0], fields[1], nil
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("ListOptions", func() {
+	Describe("Validate", func() {
+		It("Should accept filter and sort fields", func() {
+			opts := cce.ListOptions{
+				Filters: []cce.Filter{
+					{Field: "serial", Value: "ABC-123"},
+					{Field: "name", Op: cce.FilterOpContains, Value: "edge"},
+				},
+				Sort:  "-location",
+				Limit: 10,
+			}
+			Expect(opts.Validate(&cce.Node{})).To(Succeed())
+		})
+
+		It("Should reject unknown fields, operators and bad limits", func() {
+			for _, opts := range []cce.ListOptions{
+				{Filters: []cce.Filter{{Field: "entity", Value: "x"}}},
+				{Filters: []cce.Filter{{Field: "name", Op: "?", Value: "x"}}},
+				{Sort: "-id-"},
+				{Limit: -1},
+				{Limit: cce.MaxListLimit + 1},
+				{Cursor: "not a cursor"},
+			} {
+				Expect(opts.Validate(&cce.Node{})).ToNot(Succeed(), "%+v", opts)
+			}
+		})
+	})
+
+	Describe("SortField", func() {
+		It("Should default to ascending ID", func() {
+			field, desc := (&cce.ListOptions{}).SortField()
+			Expect(field).To(Equal("id"))
+			Expect(desc).To(BeFalse())
+		})
+
+		It("Should parse descending order", func() {
+			field, desc := (&cce.ListOptions{Sort: "-name"}).SortField()
+			Expect(field).To(Equal("name"))
+			Expect(desc).To(BeTrue())
+		})
+	})
+
+	Describe("SortValue", func() {
+		It("Should sort missing values, then numbers, then strings", func() {
+			var values []cce.SortValue
+			for _, raw := range []string{"", "null", "2", "16", "1.5e2", `"16"`, `"2"`, "true"} {
+				values = append(values, cce.NewSortValue(json.RawMessage(raw)))
+			}
+
+			Expect(values[0]).To(Equal(values[1]))
+			Expect(values[1].Less(values[2])).To(BeTrue())
+			Expect(values[2].Less(values[3])).To(BeTrue())
+			Expect(values[3].Less(values[4])).To(BeTrue())
+			Expect(values[4].Less(values[5])).To(BeTrue())
+			Expect(values[5].Less(values[6])).To(BeTrue())
+			Expect(values[6].Less(values[7])).To(BeTrue())
+			Expect(values[7].Less(values[2])).To(BeFalse())
+		})
+	})
+
+	Describe("Cursors", func() {
+		It("Should decode an encoded cursor", func() {
+			value, id, err := cce.DecodeCursor(cce.EncodeCursor("edge", "123"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal("edge"))
+			Expect(id).To(Equal("123"))
+		})
+	})
+})
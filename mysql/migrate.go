@@ -0,0 +1,325 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	logger "github.com/open-ness/common/log"
+	"github.com/pkg/errors"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "mysql")
+
+// Migrations are the schema migrations of the controller, in order of
+// version. A migration must never be changed once released; schema changes
+// are made by adding a migration.
+var Migrations = []Migration{
+	migration0001,
+	migration0002,
+	migration0003,
+	migration0004,
+	migration0005,
+	migration0006,
+	migration0007,
+	migration0008,
+	migration0009,
+}
+
+var (
+	// ErrSchemaTooNew is returned when the schema is at a version newer than
+	// the latest migration known to the binary.
+	ErrSchemaTooNew = errors.New("schema is newer than the latest known migration")
+
+	// ErrSchemaOutdated is returned when the schema has pending migrations.
+	ErrSchemaOutdated = errors.New("schema has pending migrations")
+)
+
+// Migration is a numbered change to the schema. Up applies the change and
+// Down reverts it, each as statements that are executed in order.
+type Migration struct {
+	Version int
+	Name    string
+	Up      []Statement
+	Down    []Statement
+}
+
+// Statement is a statement of a migration. MySQL commits DDL statements
+// implicitly, so a migration that fails is left partly applied, and all its
+// statements run again when it is retried. A statement must therefore either
+// be idempotent, like CREATE TABLE IF NOT EXISTS, or have a Done query that
+// returns a positive number once it is applied, in which case it is skipped.
+type Statement struct {
+	SQL  string
+	Done string
+}
+
+// addColumn returns a statement adding a column to a table unless it exists.
+func addColumn(table, column, definition string) Statement {
+	return Statement{
+		SQL:  fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition),
+		Done: columnCount(table, column),
+	}
+}
+
+// dropColumn returns a statement dropping a column from a table unless it
+// doesn't exist.
+func dropColumn(table, column string) Statement {
+	return Statement{
+		SQL:  fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column),
+		Done: fmt.Sprintf("SELECT (%s) = 0", columnCount(table, column)),
+	}
+}
+
+func columnCount(table, column string) string {
+	return fmt.Sprintf(
+		`SELECT COUNT(*)
+         FROM information_schema.columns
+         WHERE table_schema = DATABASE() AND table_name = '%s' AND column_name = '%s'`,
+		table, column)
+}
+
+// Migrator migrates the schema of a MySQL DB. The version of the schema is
+// the highest version recorded in the migrations table.
+type Migrator struct {
+	DB CceDB
+
+	// Migrations are the migrations to apply, in order of version.
+	Migrations []Migration
+
+	// Table is the name of the migrations table.
+	Table string
+
+	// baseline returns the version of a schema that predates the migrations
+	// table, or 0 if the DB is empty.
+	baseline func(ctx context.Context, db CceDB) (int, error)
+}
+
+// NewMigrator returns a Migrator applying Migrations and recording them in
+// the schema_migrations table. A DB loaded with the schema.sql of earlier
+// releases is recognized as being at version 1.
+func NewMigrator(db CceDB) *Migrator {
+	return &Migrator{
+		DB:         db,
+		Migrations: Migrations,
+		Table:      "schema_migrations",
+		baseline:   schemaSQLBaseline,
+	}
+}
+
+// schemaSQLBaseline returns 1 if the tables of migration 0001 were created by
+// loading schema.sql, and 0 otherwise.
+func schemaSQLBaseline(ctx context.Context, db CceDB) (int, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT COUNT(*)
+         FROM information_schema.tables
+         WHERE table_schema = DATABASE() AND table_name = 'nodes'`)
+	if err != nil {
+		return 0, errors.Wrap(err, "error running query")
+	}
+	defer rows.Close()
+
+	var count int
+	if rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return 0, errors.Wrap(err, "error scanning row")
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// Latest returns the version of the latest migration.
+func (m *Migrator) Latest() int {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Version returns the version of the schema, creating the migrations table if
+// it doesn't exist yet.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	if _, err := m.DB.ExecContext(
+		ctx,
+		// gosec: Table name is not based on user input
+		fmt.Sprintf( //nolint:gosec
+			`CREATE TABLE IF NOT EXISTS %s (
+                 version INT NOT NULL PRIMARY KEY,
+                 name VARCHAR(255) NOT NULL,
+                 applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+             )`, m.Table),
+	); err != nil {
+		return 0, errors.Wrap(err, "error creating migrations table")
+	}
+
+	version, err := m.recordedVersion(ctx)
+	if err != nil || version != 0 || m.baseline == nil {
+		return version, err
+	}
+
+	// Record the version of a schema that predates the migrations table
+	if version, err = m.baseline(ctx, m.DB); err != nil || version == 0 {
+		return version, err
+	}
+	log.Infof("Recording existing schema as version %d", version)
+	for _, migration := range m.Migrations {
+		if migration.Version > version {
+			break
+		}
+		if err = m.record(ctx, migration); err != nil {
+			return 0, err
+		}
+	}
+
+	return version, nil
+}
+
+func (m *Migrator) recordedVersion(ctx context.Context) (int, error) {
+	rows, err := m.DB.QueryContext(
+		ctx,
+		// gosec: Table name is not based on user input
+		fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", m.Table)) //nolint:gosec
+	if err != nil {
+		return 0, errors.Wrap(err, "error running query")
+	}
+	defer rows.Close()
+
+	var version int
+	if rows.Next() {
+		if err = rows.Scan(&version); err != nil {
+			return 0, errors.Wrap(err, "error scanning row")
+		}
+	}
+
+	return version, nil
+}
+
+func (m *Migrator) record(ctx context.Context, migration Migration) error {
+	_, err := m.DB.ExecContext(
+		ctx,
+		// gosec: Table name is not based on user input
+		fmt.Sprintf("INSERT INTO %s (version, name) VALUES (?, ?)", m.Table), //nolint:gosec
+		migration.Version, migration.Name)
+	return errors.Wrapf(err, "error recording migration %04d", migration.Version)
+}
+
+func (m *Migrator) unrecord(ctx context.Context, migration Migration) error {
+	_, err := m.DB.ExecContext(
+		ctx,
+		// gosec: Table name is not based on user input
+		fmt.Sprintf("DELETE FROM %s WHERE version = ?", m.Table), //nolint:gosec
+		migration.Version)
+	return errors.Wrapf(err, "error unrecording migration %04d", migration.Version)
+}
+
+// Check returns ErrSchemaTooNew if the schema is newer than the latest
+// migration and ErrSchemaOutdated if it has pending migrations.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version > m.Latest():
+		return errors.Wrapf(ErrSchemaTooNew, "schema is at version %d, latest is %d", version, m.Latest())
+	case version < m.Latest():
+		return errors.Wrapf(ErrSchemaOutdated, "schema is at version %d, latest is %d", version, m.Latest())
+	}
+
+	return nil
+}
+
+// Migrate migrates the schema up or down to the target version. Each
+// migration is recorded as soon as it is applied, so a failed migration can
+// be fixed and retried without repeating the ones before it. The statements
+// of the failed migration that were applied are skipped or idempotent.
+func (m *Migrator) Migrate(ctx context.Context, target int) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version > m.Latest() {
+		return errors.Wrapf(ErrSchemaTooNew, "schema is at version %d, latest is %d", version, m.Latest())
+	}
+	if target < 0 || target > m.Latest() {
+		return errors.Errorf("unknown schema version %d, latest is %d", target, m.Latest())
+	}
+
+	// Apply the pending migrations up to the target in order
+	for _, migration := range m.Migrations {
+		if migration.Version <= version || migration.Version > target {
+			continue
+		}
+
+		log.Infof("Applying migration %04d %s", migration.Version, migration.Name)
+		if err := m.exec(ctx, migration.Up); err != nil {
+			return errors.Wrapf(err, "error applying migration %04d", migration.Version)
+		}
+		if err := m.record(ctx, migration); err != nil {
+			return err
+		}
+	}
+
+	// Revert the applied migrations down to the target in reverse order
+	for i := len(m.Migrations) - 1; i >= 0; i-- {
+		migration := m.Migrations[i]
+		if migration.Version > version || migration.Version <= target {
+			continue
+		}
+
+		log.Infof("Reverting migration %04d %s", migration.Version, migration.Name)
+		if err := m.exec(ctx, migration.Down); err != nil {
+			return errors.Wrapf(err, "error reverting migration %04d", migration.Version)
+		}
+		if err := m.unrecord(ctx, migration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) exec(ctx context.Context, stmts []Statement) error {
+	for _, stmt := range stmts {
+		if stmt.Done != "" {
+			done, err := m.done(ctx, stmt)
+			if err != nil {
+				return err
+			}
+			if done {
+				continue
+			}
+		}
+
+		if _, err := m.DB.ExecContext(ctx, stmt.SQL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// done returns whether the Done query of a statement returns a positive
+// number.
+func (m *Migrator) done(ctx context.Context, stmt Statement) (bool, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt.Done)
+	if err != nil {
+		return false, errors.Wrap(err, "error running query")
+	}
+	defer rows.Close()
+
+	var count int
+	if rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return false, errors.Wrap(err, "error scanning row")
+		}
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/mysql"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Migrator", func() {
+	var (
+		ctx      = context.Background()
+		migrator *mysql.Migrator
+	)
+
+	tableExists := func(name string) bool {
+		rows, err := db.Query(
+			`SELECT COUNT(*)
+             FROM information_schema.tables
+             WHERE table_schema = DATABASE() AND table_name = ?`, name)
+		Expect(err).ToNot(HaveOccurred())
+		defer rows.Close()
+
+		var count int
+		Expect(rows.Next()).To(BeTrue())
+		Expect(rows.Scan(&count)).To(Succeed())
+		return count == 1
+	}
+
+	BeforeEach(func() {
+		for _, stmt := range []string{
+			"DROP TABLE IF EXISTS migrate_test_b",
+			"DROP TABLE IF EXISTS migrate_test_a",
+			"DROP TABLE IF EXISTS migrate_test_migrations",
+		} {
+			_, err := db.Exec(stmt)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		migrator = &mysql.Migrator{
+			DB:    db,
+			Table: "migrate_test_migrations",
+			Migrations: []mysql.Migration{
+				{
+					Version: 1,
+					Name:    "create a",
+					Up:      []mysql.Statement{{SQL: "CREATE TABLE IF NOT EXISTS migrate_test_a (id INT)"}},
+					Down:    []mysql.Statement{{SQL: "DROP TABLE IF EXISTS migrate_test_a"}},
+				},
+				{
+					Version: 2,
+					Name:    "create b",
+					Up:      []mysql.Statement{{SQL: "CREATE TABLE IF NOT EXISTS migrate_test_b (id INT)"}},
+					Down:    []mysql.Statement{{SQL: "DROP TABLE IF EXISTS migrate_test_b"}},
+				},
+			},
+		}
+	})
+
+	It("Should start an empty DB at version 0", func() {
+		Expect(migrator.Version(ctx)).To(Equal(0))
+		Expect(errors.Cause(migrator.Check(ctx))).To(Equal(mysql.ErrSchemaOutdated))
+	})
+
+	It("Should migrate up to the latest version", func() {
+		Expect(migrator.Migrate(ctx, migrator.Latest())).To(Succeed())
+
+		Expect(migrator.Version(ctx)).To(Equal(2))
+		Expect(migrator.Check(ctx)).To(Succeed())
+		Expect(tableExists("migrate_test_a")).To(BeTrue())
+		Expect(tableExists("migrate_test_b")).To(BeTrue())
+	})
+
+	It("Should migrate up and down to a version", func() {
+		Expect(migrator.Migrate(ctx, 1)).To(Succeed())
+		Expect(migrator.Version(ctx)).To(Equal(1))
+		Expect(tableExists("migrate_test_a")).To(BeTrue())
+		Expect(tableExists("migrate_test_b")).To(BeFalse())
+
+		Expect(migrator.Migrate(ctx, 2)).To(Succeed())
+		Expect(migrator.Migrate(ctx, 0)).To(Succeed())
+		Expect(migrator.Version(ctx)).To(Equal(0))
+		Expect(tableExists("migrate_test_a")).To(BeFalse())
+		Expect(tableExists("migrate_test_b")).To(BeFalse())
+	})
+
+	It("Should resume a migration that failed partway", func() {
+		columnExists := func(name string) bool {
+			var count int
+			Expect(db.QueryRow(
+				`SELECT COUNT(*)
+                 FROM information_schema.columns
+                 WHERE table_schema = DATABASE() AND table_name = 'migrate_test_a' AND column_name = ?`,
+				name).Scan(&count)).To(Succeed())
+			return count == 1
+		}
+		columnDone := func(name string) string {
+			return fmt.Sprintf(
+				`SELECT COUNT(*)
+                 FROM information_schema.columns
+                 WHERE table_schema = DATABASE() AND table_name = 'migrate_test_a' AND column_name = '%s'`,
+				name)
+		}
+
+		migrator.Migrations[1].Up = []mysql.Statement{
+			{SQL: "ALTER TABLE migrate_test_a ADD COLUMN x INT", Done: columnDone("x")},
+			{SQL: "ALTER TABLE migrate_test_a ADD COLUMN y INT NOT NULL REFERENCES"},
+		}
+
+		By("Failing the second statement of a migration")
+		Expect(migrator.Migrate(ctx, 2)).ToNot(Succeed())
+		Expect(migrator.Version(ctx)).To(Equal(1))
+		Expect(columnExists("x")).To(BeTrue())
+
+		By("Retrying the fixed migration")
+		migrator.Migrations[1].Up[1] = mysql.Statement{
+			SQL:  "ALTER TABLE migrate_test_a ADD COLUMN y INT",
+			Done: columnDone("y"),
+		}
+		Expect(migrator.Migrate(ctx, 2)).To(Succeed())
+		Expect(migrator.Version(ctx)).To(Equal(2))
+		Expect(columnExists("x")).To(BeTrue())
+		Expect(columnExists("y")).To(BeTrue())
+	})
+
+	It("Should refuse a schema newer than the latest migration", func() {
+		Expect(migrator.Migrate(ctx, migrator.Latest())).To(Succeed())
+		_, err := db.Exec("INSERT INTO migrate_test_migrations (version, name) VALUES (3, 'unknown')")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(errors.Cause(migrator.Check(ctx))).To(Equal(mysql.ErrSchemaTooNew))
+		Expect(errors.Cause(migrator.Migrate(ctx, 2))).To(Equal(mysql.ErrSchemaTooNew))
+	})
+
+	It("Should reject an unknown target version", func() {
+		Expect(migrator.Migrate(ctx, 3)).ToNot(Succeed())
+	})
+
+	It("Should have migrated the controller schema to the latest version", func() {
+		controller := mysql.NewMigrator(db)
+		Expect(controller.Check(ctx)).To(Succeed())
+		Expect(tableExists("nodes")).To(BeTrue())
+		Expect(tableExists("audit_events")).To(BeTrue())
+		Expect(tableExists("users")).To(BeTrue())
+		Expect(tableExists("token_keys")).To(BeTrue())
+		Expect(tableExists("revoked_tokens")).To(BeTrue())
+		Expect(tableExists("api_keys")).To(BeTrue())
+		Expect(tableExists("webhooks")).To(BeTrue())
+		Expect(tableExists("webhook_deliveries")).To(BeTrue())
+		Expect(tableExists("operations")).To(BeTrue())
+		Expect(tableExists("idempotency_keys")).To(BeTrue())
+	})
+
+	It("Should migrate a DB loaded with the schema.sql of earlier releases", func() {
+		controller := mysql.NewMigrator(db)
+		Expect(controller.Migrate(ctx, 0)).To(Succeed())
+		Expect(tableExists("nodes")).To(BeFalse())
+
+		// Load the tables of testdata/schema.sql, which is the schema.sql of
+		// earlier releases, into the test DB
+		data, err := ioutil.ReadFile("testdata/schema.sql")
+		Expect(err).ToNot(HaveOccurred())
+		var stmts []string
+		for _, chunk := range strings.Split(string(data), ";\n") {
+			if i := strings.Index(chunk, "CREATE TABLE"); i >= 0 {
+				stmts = append(stmts, chunk[i:])
+			}
+		}
+		var up []string
+		for _, stmt := range mysql.Migrations[0].Up {
+			up = append(up, strings.Replace(stmt.SQL, "CREATE TABLE IF NOT EXISTS", "CREATE TABLE", 1))
+		}
+		Expect(stmts).To(Equal(up))
+		for _, stmt := range stmts {
+			_, err = db.Exec(stmt)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		_, err = db.Exec(`INSERT INTO nodes (entity) VALUES ('{"id": "node-1", "serial": "serial-1"}')`)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(controller.Version(ctx)).To(Equal(1))
+		Expect(controller.Migrate(ctx, controller.Latest())).To(Succeed())
+		Expect(controller.Check(ctx)).To(Succeed())
+
+		// The existing rows are kept at version 1
+		var version int
+		Expect(db.QueryRow("SELECT version FROM nodes WHERE id = 'node-1'").Scan(&version)).To(Succeed())
+		Expect(version).To(Equal(1))
+		_, err = db.Exec("DELETE FROM nodes")
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
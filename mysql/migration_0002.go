@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql
+
+// migration0002 adds the versions of the resources, which are incremented on
+// each update so that concurrent updates can be detected.
+var migration0002 = Migration{
+	Version: 2,
+	Name:    "add versions",
+	Up: []Statement{
+		addColumn("nodes", "version", "BIGINT NOT NULL DEFAULT 1 AFTER serial"),
+		addColumn("node_grpc_targets", "version", "BIGINT NOT NULL DEFAULT 1 AFTER grpc_target"),
+		addColumn("nodes_nfd_features", "version", "BIGINT NOT NULL DEFAULT 1 AFTER nfd_id"),
+		addColumn("apps", "version", "BIGINT NOT NULL DEFAULT 1 AFTER type"),
+		addColumn("traffic_policies", "version", "BIGINT NOT NULL DEFAULT 1 AFTER id"),
+		addColumn("dns_configs", "version", "BIGINT NOT NULL DEFAULT 1 AFTER id"),
+		addColumn("credentials", "version", "BIGINT NOT NULL DEFAULT 1 AFTER id"),
+		addColumn("dns_configs_app_aliases", "version", "BIGINT NOT NULL DEFAULT 1 AFTER app_id"),
+		addColumn("nodes_apps", "version", "BIGINT NOT NULL DEFAULT 1 AFTER app_id"),
+		addColumn("nodes_dns_configs", "version", "BIGINT NOT NULL DEFAULT 1 AFTER dns_config_id"),
+		addColumn("nodes_network_interfaces_traffic_policies", "version",
+			"BIGINT NOT NULL DEFAULT 1 AFTER traffic_policy_id"),
+		addColumn("nodes_apps_traffic_policies", "version", "BIGINT NOT NULL DEFAULT 1 AFTER traffic_policy_id"),
+	},
+	Down: []Statement{
+		dropColumn("nodes_apps_traffic_policies", "version"),
+		dropColumn("nodes_network_interfaces_traffic_policies", "version"),
+		dropColumn("nodes_dns_configs", "version"),
+		dropColumn("nodes_apps", "version"),
+		dropColumn("dns_configs_app_aliases", "version"),
+		dropColumn("credentials", "version"),
+		dropColumn("dns_configs", "version"),
+		dropColumn("traffic_policies", "version"),
+		dropColumn("apps", "version"),
+		dropColumn("nodes_nfd_features", "version"),
+		dropColumn("node_grpc_targets", "version"),
+		dropColumn("nodes", "version"),
+	},
+}
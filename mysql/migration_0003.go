@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql
+
+// migration0003 creates the audit log. The entity type and ID are indexed for
+// looking up the history of an entity; the log has no foreign keys so that it
+// outlives the entities.
+var migration0003 = Migration{
+	Version: 3,
+	Name:    "create audit_events",
+	Up: []Statement{
+		{SQL: `CREATE TABLE IF NOT EXISTS audit_events (
+		    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+		    entity_type VARCHAR(64) GENERATED ALWAYS AS (entity->>'$.entity_type') STORED,
+		    entity_id VARCHAR(64) GENERATED ALWAYS AS (entity->>'$.entity_id') STORED,
+		    version BIGINT NOT NULL DEFAULT 1,
+		    entity JSON,
+		    KEY (entity_type, entity_id)
+		)`},
+	},
+	Down: []Statement{
+		{SQL: `DROP TABLE IF EXISTS audit_events`},
+	},
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql
+
+// migration0004 creates the users of the REST API.
+var migration0004 = Migration{
+	Version: 4,
+	Name:    "create users",
+	Up: []Statement{
+		{SQL: `CREATE TABLE IF NOT EXISTS users (
+		    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+		    name VARCHAR(63) GENERATED ALWAYS AS (entity->>'$.name') STORED UNIQUE KEY,
+		    version BIGINT NOT NULL DEFAULT 1,
+		    entity JSON
+		)`},
+	},
+	Down: []Statement{
+		{SQL: `DROP TABLE IF EXISTS users`},
+	},
+}
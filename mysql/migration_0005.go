@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql
+
+// migration0005 creates the signing keys and revoked tokens of the REST API.
+// Key IDs are base64url-encoded SHA-256 thumbprints, longer than UUIDs.
+var migration0005 = Migration{
+	Version: 5,
+	Name:    "create token keys and revoked tokens",
+	Up: []Statement{
+		{SQL: `CREATE TABLE IF NOT EXISTS token_keys (
+		    id VARCHAR(64) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+		    version BIGINT NOT NULL DEFAULT 1,
+		    entity JSON
+		)`},
+		{SQL: `CREATE TABLE IF NOT EXISTS revoked_tokens (
+		    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+		    version BIGINT NOT NULL DEFAULT 1,
+		    entity JSON
+		)`},
+	},
+	Down: []Statement{
+		{SQL: `DROP TABLE IF EXISTS revoked_tokens`},
+		{SQL: `DROP TABLE IF EXISTS token_keys`},
+	},
+}
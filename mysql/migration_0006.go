@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql
+
+// migration0006 creates the API keys of users.
+var migration0006 = Migration{
+	Version: 6,
+	Name:    "create api keys",
+	Up: []Statement{
+		{SQL: `CREATE TABLE IF NOT EXISTS api_keys (
+		    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+		    user_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.user_id') STORED,
+		    name VARCHAR(255) GENERATED ALWAYS AS (entity->>'$.name') STORED,
+		    version BIGINT NOT NULL DEFAULT 1,
+		    entity JSON,
+		    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		    UNIQUE KEY (user_id, name)
+		)`},
+	},
+	Down: []Statement{
+		{SQL: `DROP TABLE IF EXISTS api_keys`},
+	},
+}
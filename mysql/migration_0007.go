@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql
+
+// migration0007 creates the webhooks and their delivery history, which is
+// deleted with the webhook.
+var migration0007 = Migration{
+	Version: 7,
+	Name:    "create webhooks",
+	Up: []Statement{
+		{SQL: `CREATE TABLE IF NOT EXISTS webhooks (
+		    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+		    name VARCHAR(255) GENERATED ALWAYS AS (entity->>'$.name') STORED UNIQUE KEY,
+		    version BIGINT NOT NULL DEFAULT 1,
+		    entity JSON
+		)`},
+		{SQL: `CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+		    webhook_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.webhook_id') STORED,
+		    status VARCHAR(16) GENERATED ALWAYS AS (entity->>'$.status') STORED,
+		    version BIGINT NOT NULL DEFAULT 1,
+		    entity JSON,
+		    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+		    KEY (status)
+		)`},
+	},
+	Down: []Statement{
+		{SQL: `DROP TABLE IF EXISTS webhook_deliveries`},
+		{SQL: `DROP TABLE IF EXISTS webhooks`},
+	},
+}
@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql
+
+// migration0008 creates the operations run in the background, which are
+// deleted with their node.
+var migration0008 = Migration{
+	Version: 8,
+	Name:    "create operations",
+	Up: []Statement{
+		{SQL: `CREATE TABLE IF NOT EXISTS operations (
+		    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+		    node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED,
+		    status VARCHAR(16) GENERATED ALWAYS AS (entity->>'$.status') STORED,
+		    version BIGINT NOT NULL DEFAULT 1,
+		    entity JSON,
+		    FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE,
+		    KEY (status)
+		)`},
+	},
+	Down: []Statement{
+		{SQL: `DROP TABLE IF EXISTS operations`},
+	},
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql
+
+// migration0009 creates the responses stored for Idempotency-Key headers,
+// which are unique per actor.
+var migration0009 = Migration{
+	Version: 9,
+	Name:    "create idempotency keys",
+	Up: []Statement{
+		{SQL: `CREATE TABLE IF NOT EXISTS idempotency_keys (
+		    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+		    actor VARCHAR(255) GENERATED ALWAYS AS (entity->>'$.actor') STORED,
+		    idempotency_key VARCHAR(255) GENERATED ALWAYS AS (entity->>'$.key') STORED,
+		    version BIGINT NOT NULL DEFAULT 1,
+		    entity JSON,
+		    UNIQUE KEY (actor, idempotency_key)
+		)`},
+	},
+	Down: []Statement{
+		{SQL: `DROP TABLE IF EXISTS idempotency_keys`},
+	},
+}
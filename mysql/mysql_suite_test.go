@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/internal/persistencetest"
+	"github.com/open-ness/edgecontroller/mysql"
+)
+
+// The suite runs against the database named by CCE_TEST_MYSQL_DSN, which is
+// migrated to the latest schema. All rows are deleted before each spec.
+var dsn = os.Getenv("CCE_TEST_MYSQL_DSN")
+
+// tables are ordered so that no table is referenced by a table before it.
+var tables = []string{
+	"nodes_apps_traffic_policies",
+	"nodes_network_interfaces_traffic_policies",
+	"nodes_dns_configs",
+	"nodes_apps",
+	"dns_configs_app_aliases",
+	"nodes_nfd_features",
+	"node_grpc_targets",
+	"credentials",
+	"dns_configs",
+	"traffic_policies",
+	"apps",
+	"nodes",
+}
+
+func TestMySQL(t *testing.T) {
+	if dsn == "" {
+		t.Skip("CCE_TEST_MYSQL_DSN is not set")
+	}
+
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MySQL Suite")
+}
+
+var db *sql.DB
+
+var _ = BeforeSuite(func() {
+	var err error
+	db, err = sql.Open("mysql", dsn)
+	Expect(err).ToNot(HaveOccurred())
+
+	migrator := mysql.NewMigrator(db)
+	Expect(migrator.Migrate(context.Background(), migrator.Latest())).To(Succeed())
+})
+
+var _ = AfterSuite(func() {
+	Expect(db.Close()).To(Succeed())
+})
+
+var _ = persistencetest.DescribePersistenceService(func() cce.PersistenceService {
+	for _, table := range tables {
+		_, err := db.Exec(fmt.Sprintf("DELETE FROM %s", table))
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	return &mysql.PersistenceService{DB: db}
+})
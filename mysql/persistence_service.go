@@ -9,25 +9,104 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
-	"sort"
+	"strconv"
 	"strings"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql" // provides the mysql driver
+	driver "github.com/go-sql-driver/mysql"
 	cce "github.com/open-ness/edgecontroller"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "cce_db_query_duration_seconds",
+	Help:    "Latency of DB queries by operation and table.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"operation", "table"})
+
+// observeQuery observes the latency of a DB query started at start.
+func observeQuery(operation, table string, start time.Time) {
+	queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+}
+
+// erDupEntry is the number of MySQL's error for a duplicate key.
+const erDupEntry = 1062
+
+// isDuplicateEntry returns whether an error is MySQL's for a duplicate key.
+func isDuplicateEntry(err error) bool {
+	mysqlErr, ok := errors.Cause(err).(*driver.MySQLError)
+	return ok && mysqlErr.Number == erDupEntry
+}
+
 type CceDB interface {
 	Ping() error
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 }
 
+// txBeginner is a CceDB that can begin transactions, such as *sql.DB.
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// txDB is a CceDB running its statements in a transaction.
+type txDB struct {
+	*sql.Tx
+}
+
+// Ping is a no-op, the transaction holds a connection.
+func (txDB) Ping() error {
+	return nil
+}
+
 // PersistenceService implements cce.PersistenceService.
 type PersistenceService struct {
 	DB CceDB
 }
 
+// WithTx calls f with a PersistenceService running in a transaction. The
+// transaction is committed if f returns nil and rolled back otherwise. If the
+// DB cannot begin a transaction, for example because it is already one, f is
+// called with s.
+func (s *PersistenceService) WithTx(
+	ctx context.Context,
+	f func(tx cce.PersistenceService) error,
+) (err error) {
+	db, ok := s.DB.(txBeginner)
+	if !ok {
+		return f(s)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error beginning transaction")
+	}
+
+	// Roll back if f returns an error or panics
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if rbErr := tx.Rollback(); rbErr != nil && err == nil {
+			err = errors.Wrap(rbErr, "error rolling back transaction")
+		}
+	}()
+
+	if err = f(&PersistenceService{DB: txDB{tx}}); err != nil {
+		return err
+	}
+
+	committed = true
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "error committing transaction")
+	}
+
+	return nil
+}
+
 // Create persists a resource.
 func (s *PersistenceService) Create(
 	ctx context.Context,
@@ -36,6 +115,7 @@ func (s *PersistenceService) Create(
 	// Create a timeout context for a DB operation
 	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
 	defer cancel()
+	defer observeQuery("create", e.GetTableName(), time.Now())
 
 	bytes, err := json.Marshal(e)
 	if err != nil {
@@ -49,9 +129,16 @@ func (s *PersistenceService) Create(
 			`INSERT INTO %s (entity) VALUES (?)`, e.GetTableName()),
 		bytes)
 	if err != nil {
+		if isDuplicateEntry(err) {
+			err = errors.Wrap(cce.ErrDuplicateEntry, err.Error())
+		}
 		return errors.Wrap(err, "error inserting record")
 	}
 
+	if v, ok := e.(cce.Versioned); ok {
+		v.SetResourceVersion(1)
+	}
+
 	return nil
 }
 
@@ -64,18 +151,20 @@ func (s *PersistenceService) Read(
 	// Create a timeout context for a DB operation
 	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
 	defer cancel()
+	defer observeQuery("read", zv.GetTableName(), time.Now())
 
 	rows, err := s.DB.QueryContext(
 		ctx,
 		// gosec: Table name is not based on user input
 		fmt.Sprintf( //nolint:gosec
-			`SELECT entity
+			`SELECT entity, version
              FROM %s
              WHERE id = ?`, zv.GetTableName()),
 		id)
 	if err != nil {
 		return nil, errors.Wrap(err, "error running query")
 	}
+	defer rows.Close()
 
 	if !rows.Next() {
 		return nil, nil
@@ -98,37 +187,20 @@ func (s *PersistenceService) Filter(
 	// Create a timeout context for a DB operation
 	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
 	defer cancel()
+	defer observeQuery("filter", zv.GetTableName(), time.Now())
 
-	// gosec: Table name is not based on user input
-	q := fmt.Sprintf("SELECT entity FROM %s", zv.GetTableName()) //nolint:gosec
+	// gosec: Only whitelisted filters are allowed to be injected into the SQL
+	// query
+	if err = (&cce.ListOptions{Filters: fs}).Validate(zv); err != nil {
+		return nil, err
+	}
 
-	ffs := zv.FilterFields()
-	sort.Strings(ffs)
+	// gosec: Table name is not based on user input
+	q := fmt.Sprintf("SELECT entity, version FROM %s", zv.GetTableName()) //nolint:gosec
 
-	var (
-		fields []string
-		params []interface{}
-	)
-	for _, f := range fs {
-		// gosec: Only whitelisted filters are allowed to be injected into
-		// the SQL query
-		allowed := false
-		for _, allowedField := range ffs {
-			if f.Field == allowedField {
-				allowed = true
-			}
-			if allowedField >= f.Field {
-				break
-			}
-		}
-		if !allowed {
-			return nil, errors.Errorf("disallowed filter field %q", f.Field)
-		}
-		fields = append(fields, fmt.Sprintf("%s = ?", f.Field))
-		params = append(params, f.Value)
-	}
-	if len(params) > 0 {
-		q += " WHERE " + strings.Join(fields, " AND ") //nolint:gosec
+	conds, params := filterConditions(zv.GetTableName(), fs)
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
 	}
 
 	rows, err := s.DB.QueryContext(
@@ -136,6 +208,7 @@ func (s *PersistenceService) Filter(
 	if err != nil {
 		return nil, errors.Wrap(err, "error running query")
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		e, err := s.scan(rows, zv)
@@ -149,6 +222,253 @@ func (s *PersistenceService) Filter(
 	return
 }
 
+// List retrieves a page of the resources of the given type matching the
+// filters of the options.
+func (s *PersistenceService) List(
+	ctx context.Context,
+	zv cce.Filterable,
+	opts cce.ListOptions,
+) (*cce.Page, error) {
+	// Create a timeout context for a DB operation
+	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
+	defer cancel()
+	defer observeQuery("list", zv.GetTableName(), time.Now())
+
+	// gosec: Only whitelisted filter and sort fields are allowed to be
+	// injected into the SQL query
+	if err := opts.Validate(zv); err != nil {
+		return nil, err
+	}
+
+	conds, params := filterConditions(zv.GetTableName(), opts.Filters)
+
+	// Count the resources on all pages
+	page := &cce.Page{}
+	if err := s.count(ctx, zv.GetTableName(), conds, params, &page.Total); err != nil {
+		return nil, err
+	}
+
+	// Seek past the last resource of the previous page
+	field, desc := opts.SortField()
+	var sortExprs []string
+	valueExpr := "id"
+	if field != "id" {
+		sortExprs, valueExpr = sortExpressions(field)
+	}
+	sortExprs = append(sortExprs, "id")
+	cmp, order := ">", "ASC"
+	if desc {
+		cmp, order = "<", "DESC"
+	}
+	if opts.Cursor != "" {
+		value, id, err := cce.DecodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, fmt.Sprintf("(%s) %s (?%s)",
+			strings.Join(sortExprs, ", "), cmp, strings.Repeat(", ?", len(sortExprs)-1)))
+		if field != "id" {
+			v := cce.NewSortValue(json.RawMessage(value))
+			params = append(params, v.Rank, v.Number, v.String)
+		}
+		params = append(params, id)
+	}
+
+	// gosec: Table name is not based on user input
+	q := fmt.Sprintf("SELECT entity, version, %s FROM %s", valueExpr, zv.GetTableName()) //nolint:gosec
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+	q += " ORDER BY " + strings.Join(sortExprs, " "+order+", ") + " " + order
+	if opts.Limit > 0 {
+		// Fetch one more to know if there is a next page
+		q += " LIMIT ?"
+		params = append(params, opts.Limit+1)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, q, params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error running query")
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		e, err := s.scan(rows, zv, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Entities = append(page.Entities, e)
+		values = append(values, value)
+	}
+
+	if opts.Limit > 0 && len(page.Entities) > opts.Limit {
+		page.Entities = page.Entities[:opts.Limit]
+		page.Next = cce.EncodeCursor(values[opts.Limit-1], page.Entities[opts.Limit-1].GetID())
+	}
+
+	return page, nil
+}
+
+// sortExpressions returns the expressions that order entities by a field like
+// cce.SortValue, by its rank, number and string, and the expression of the
+// field's JSON value for cursors.
+func sortExpressions(field string) (sortExprs []string, valueExpr string) {
+	doc := fmt.Sprintf("entity->'$.%s'", field)
+	isNull := fmt.Sprintf("COALESCE(JSON_TYPE(%s), 'NULL') = 'NULL'", doc)
+	isNumber := fmt.Sprintf("JSON_TYPE(%s) IN %s", doc, numberTypes)
+
+	return []string{
+		fmt.Sprintf("(CASE WHEN %s THEN %d WHEN %s THEN %d ELSE %d END)",
+			isNull, cce.SortRankNull, isNumber, cce.SortRankNumber, cce.SortRankString),
+		fmt.Sprintf("(CASE WHEN %s THEN entity->>'$.%s' + 0 ELSE 0 END)", isNumber, field),
+		fmt.Sprintf("(CASE WHEN %s OR %s THEN '' ELSE entity->>'$.%s' END)", isNull, isNumber, field),
+	}, fmt.Sprintf("COALESCE(CAST(%s AS CHAR), '')", doc)
+}
+
+func (s *PersistenceService) count(
+	ctx context.Context,
+	tableName string,
+	conds []string,
+	params []interface{},
+	total *int,
+) error {
+	// gosec: Table name is not based on user input
+	q := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName) //nolint:gosec
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	rows, err := s.DB.QueryContext(ctx, q, params...)
+	if err != nil {
+		return errors.Wrap(err, "error running query")
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err = rows.Scan(total); err != nil {
+			return errors.Wrap(err, "error scanning row")
+		}
+	}
+
+	return nil
+}
+
+// filterConditions returns the conditions of a WHERE clause matching the
+// filters, whose fields must have been validated, and their parameters.
+func filterConditions(table string, fs []cce.Filter) (conds []string, params []interface{}) {
+	for _, f := range fs {
+		cond, ps := filterCondition(table, f)
+		conds = append(conds, cond)
+		params = append(params, ps...)
+	}
+
+	return conds, params
+}
+
+// numberTypes are the JSON types of numbers compared by range filters.
+const numberTypes = "('INTEGER', 'UNSIGNED INTEGER', 'DOUBLE', 'DECIMAL')"
+
+// generatedColumns are the columns of each table generated from the fields of
+// its entities, by field. Filters on these fields compare the column, which
+// can be indexed, instead of the entity. They must match the migrations.
+var generatedColumns = map[string]map[string]string{
+	"nodes":                       {"serial": "serial"},
+	"node_grpc_targets":           {"node_id": "node_id", "grpc_target": "grpc_target"},
+	"nodes_nfd_features":          {"node_id": "node_id", "nfd_id": "nfd_id"},
+	"apps":                        {"type": "type"},
+	"dns_configs_app_aliases":     {"dns_config_id": "dns_config_id", "app_id": "app_id"},
+	"nodes_apps":                  {"node_id": "node_id", "app_id": "app_id"},
+	"nodes_dns_configs":           {"node_id": "node_id", "dns_config_id": "dns_config_id"},
+	"nodes_apps_traffic_policies": {"nodes_apps_id": "nodes_apps_id", "traffic_policy_id": "traffic_policy_id"},
+	"nodes_network_interfaces_traffic_policies": {
+		"node_id":              "node_id",
+		"network_interface_id": "network_interface_id",
+		"traffic_policy_id":    "traffic_policy_id",
+	},
+	"audit_events":       {"entity_type": "entity_type", "entity_id": "entity_id"},
+	"users":              {"name": "name"},
+	"api_keys":           {"user_id": "user_id", "name": "name"},
+	"webhooks":           {"name": "name"},
+	"webhook_deliveries": {"webhook_id": "webhook_id", "status": "status"},
+	"operations":         {"node_id": "node_id", "status": "status"},
+	"idempotency_keys":   {"actor": "actor", "key": "idempotency_key"},
+}
+
+func filterCondition(table string, f cce.Filter) (cond string, params []interface{}) {
+	if len(f.Or) > 0 {
+		var groups []string
+		for _, group := range f.Or {
+			conds, ps := filterConditions(table, group)
+			if len(conds) == 0 {
+				conds = []string{"TRUE"}
+			}
+			groups = append(groups, "("+strings.Join(conds, " AND ")+")")
+			params = append(params, ps...)
+		}
+		cond = "(" + strings.Join(groups, " OR ") + ")"
+		if f.Not {
+			cond = "NOT " + cond
+		}
+		return cond, params
+	}
+
+	// Compare strings with the generated column or the unquoted value and
+	// numbers with the JSON value
+	value := fmt.Sprintf("entity->>'$.%s'", f.Field)
+	if column, ok := generatedColumns[table][f.Field]; ok {
+		value = column
+	} else if f.Field == "id" {
+		value = "id"
+	}
+	doc := fmt.Sprintf("entity->'$.%s'", f.Field)
+
+	switch f.Op {
+	case cce.FilterOpContains:
+		cond = value + " LIKE ?"
+		params = append(params, "%"+likeReplacer.Replace(f.Value)+"%")
+	case cce.FilterOpPrefix:
+		cond = value + " LIKE ?"
+		params = append(params, likeReplacer.Replace(f.Value)+"%")
+	case cce.FilterOpLike:
+		cond = value + " LIKE ?"
+		params = append(params, f.Value)
+	case cce.FilterOpIn:
+		if len(f.Values) == 0 {
+			cond = "FALSE"
+			break
+		}
+		cond = value + " IN (?" + strings.Repeat(", ?", len(f.Values)-1) + ")"
+		for _, v := range f.Values {
+			params = append(params, v)
+		}
+	case cce.FilterOpNull:
+		cond = fmt.Sprintf("COALESCE(JSON_TYPE(%s), 'NULL') = 'NULL'", doc)
+	case cce.FilterOpLess, cce.FilterOpLessOrEqual, cce.FilterOpGreater, cce.FilterOpGreaterOrEqual:
+		cond = fmt.Sprintf("(JSON_TYPE(%[1]s) IN %[2]s AND %[1]s %[3]s ?)", doc, numberTypes, f.Op)
+		n, _ := strconv.ParseFloat(f.Value, 64) // validated
+		params = append(params, n)
+	default:
+		cond = value + " = ?"
+		params = append(params, f.Value)
+	}
+
+	switch {
+	case f.Not && f.Op == cce.FilterOpNull:
+		cond = "NOT " + cond
+	case f.Not:
+		// Negating a condition on a missing field must not match either
+		cond = fmt.Sprintf("(COALESCE(JSON_TYPE(%s), 'NULL') <> 'NULL' AND NOT (%s))", doc, cond)
+	}
+
+	return cond, params
+}
+
+// likeReplacer escapes the wildcards of a LIKE pattern.
+var likeReplacer = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // ReadAll retrieves all resources of the given type.
 func (s *PersistenceService) ReadAll(
 	ctx context.Context,
@@ -157,15 +477,17 @@ func (s *PersistenceService) ReadAll(
 	// Create a timeout context for a DB operation
 	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
 	defer cancel()
+	defer observeQuery("read_all", zv.GetTableName(), time.Now())
 
 	rows, err := s.DB.QueryContext(
 		ctx,
 		// gosec: Table name is not based on user input
 		fmt.Sprintf( //nolint:gosec
-			"SELECT entity FROM %s", zv.GetTableName()))
+			"SELECT entity, version FROM %s", zv.GetTableName()))
 	if err != nil {
 		return nil, errors.Wrap(err, "error running query")
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		e, err := s.scan(rows, zv)
@@ -182,9 +504,13 @@ func (s *PersistenceService) ReadAll(
 func (s *PersistenceService) scan(
 	rows *sql.Rows,
 	zv cce.Persistable,
+	dest ...interface{},
 ) (cce.Persistable, error) {
-	var bytes []byte
-	if err := rows.Scan(&bytes); err != nil {
+	var (
+		bytes   []byte
+		version int64
+	)
+	if err := rows.Scan(append([]interface{}{&bytes, &version}, dest...)...); err != nil {
 		return nil, errors.Wrap(err, "error scanning row")
 	}
 
@@ -193,10 +519,16 @@ func (s *PersistenceService) scan(
 		return nil, errors.Wrap(err, "error unmarshaling")
 	}
 
+	if v, ok := e.(cce.Versioned); ok {
+		v.SetResourceVersion(version)
+	}
+
 	return e, nil
 }
 
-// BulkUpdate updates multiple resources.
+// BulkUpdate updates multiple resources and increments their versions. A
+// resource with a non-zero version is only updated if its version matches,
+// otherwise cce.ErrVersionConflict is returned.
 func (s *PersistenceService) BulkUpdate(
 	ctx context.Context,
 	es []cce.Persistable,
@@ -211,24 +543,72 @@ func (s *PersistenceService) BulkUpdate(
 			return errors.Wrap(err, "error marshaling")
 		}
 
-		_, err = s.DB.ExecContext(
-			ctx,
-			// gosec: Table name is not based on user input
-			fmt.Sprintf( //nolint:gosec
-				`UPDATE %s
-                 SET entity = ?
-                 WHERE id = JSON_EXTRACT(?, "$.id")`,
-				e.GetTableName()),
-			bytes, bytes)
+		var version int64
+		if v, ok := e.(cce.Versioned); ok {
+			version = v.GetResourceVersion()
+		}
+
+		start := time.Now()
+		var result sql.Result
+		if version == 0 {
+			result, err = s.DB.ExecContext(
+				ctx,
+				// gosec: Table name is not based on user input
+				fmt.Sprintf( //nolint:gosec
+					`UPDATE %s
+                     SET entity = ?, version = version + 1
+                     WHERE id = JSON_EXTRACT(?, "$.id")`,
+					e.GetTableName()),
+				bytes, bytes)
+		} else {
+			result, err = s.DB.ExecContext(
+				ctx,
+				// gosec: Table name is not based on user input
+				fmt.Sprintf( //nolint:gosec
+					`UPDATE %s
+                     SET entity = ?, version = version + 1
+                     WHERE id = JSON_EXTRACT(?, "$.id") AND version = ?`,
+					e.GetTableName()),
+				bytes, bytes, version)
+		}
+		observeQuery("update", e.GetTableName(), start)
 		if err != nil {
 			return errors.Wrap(err, "error updating record")
 		}
+
+		v, ok := e.(cce.Versioned)
+		if !ok {
+			continue
+		}
+		if version != 0 {
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return errors.Wrap(err, "error getting rows affected")
+			}
+			if rows != 1 {
+				return errors.Wrapf(cce.ErrVersionConflict,
+					"%s %s is not at version %d", e.GetTableName(), e.GetID(), version)
+			}
+			v.SetResourceVersion(version + 1)
+			continue
+		}
+
+		// Read back the version of the blind update
+		persisted, err := s.Read(ctx, e.GetID(), e)
+		if err != nil {
+			return err
+		}
+		if persisted != nil {
+			v.SetResourceVersion(persisted.(cce.Versioned).GetResourceVersion())
+		}
 	}
 
 	return nil
 }
 
-// Delete deletes a resource of the given type.
+// Delete deletes a resource of the given type. If zv has a non-zero version
+// the resource is only deleted if its version matches, otherwise
+// cce.ErrVersionConflict is returned.
 func (s *PersistenceService) Delete(
 	ctx context.Context,
 	id string,
@@ -237,15 +617,33 @@ func (s *PersistenceService) Delete(
 	// Create a timeout context for a DB operation
 	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
 	defer cancel()
+	defer observeQuery("delete", zv.GetTableName(), time.Now())
 
-	result, err := s.DB.ExecContext(
-		ctx,
-		// gosec: Table name is not based on user input
-		fmt.Sprintf( //nolint:gosec
-			`DELETE
-             FROM %s
-             WHERE id = ?`, zv.GetTableName()),
-		id)
+	var version int64
+	if v, ok := zv.(cce.Versioned); ok {
+		version = v.GetResourceVersion()
+	}
+
+	var result sql.Result
+	if version == 0 {
+		result, err = s.DB.ExecContext(
+			ctx,
+			// gosec: Table name is not based on user input
+			fmt.Sprintf( //nolint:gosec
+				`DELETE
+                 FROM %s
+                 WHERE id = ?`, zv.GetTableName()),
+			id)
+	} else {
+		result, err = s.DB.ExecContext(
+			ctx,
+			// gosec: Table name is not based on user input
+			fmt.Sprintf( //nolint:gosec
+				`DELETE
+                 FROM %s
+                 WHERE id = ? AND version = ?`, zv.GetTableName()),
+			id, version)
+	}
 	if err != nil {
 		return false, errors.Wrap(err, "error deleting record")
 	}
@@ -256,7 +654,20 @@ func (s *PersistenceService) Delete(
 	}
 
 	if rows != 1 {
-		return false, nil
+		if version == 0 {
+			return false, nil
+		}
+
+		// Tell a missing resource from one at another version
+		persisted, err := s.Read(ctx, id, zv)
+		if err != nil {
+			return false, err
+		}
+		if persisted == nil {
+			return false, nil
+		}
+		return false, errors.Wrapf(cce.ErrVersionConflict,
+			"%s %s is not at version %d", zv.GetTableName(), id, version)
 	}
 
 	return true, nil
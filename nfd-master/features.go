@@ -6,6 +6,8 @@ package nfd
 import (
 	"fmt"
 	"strings"
+
+	cce "github.com/open-ness/edgecontroller"
 )
 
 // NodeFeatureNFD is a representation of NFD feature on the node
@@ -14,6 +16,8 @@ type NodeFeatureNFD struct {
 	NodeID   string `json:"node_id"`
 	NfdID    string `json:"nfd_id"`
 	NfdValue string `json:"nfd_value"`
+
+	cce.ResourceVersion
 }
 
 // GetTableName returns persistence table name for NodeFeatureNFD entities
@@ -17,6 +17,8 @@ type Node struct {
 	Name     string `json:"name"`
 	Location string `json:"location"`
 	Serial   string `json:"serial"`
+
+	ResourceVersion
 }
 
 // NodeReq is a Node request.
@@ -94,6 +96,8 @@ func (n *Node) Validate() error {
 func (*Node) FilterFields() []string {
 	return []string{
 		"serial",
+		"name",
+		"location",
 	}
 }
 
@@ -16,6 +16,13 @@ type NodeApp struct {
 	ID     string `json:"id"`
 	NodeID string `json:"node_id"`
 	AppID  string `json:"app_id"`
+
+	// Deploying is set while the app is being deployed to the node. The record
+	// is created before the deployment so that concurrent requests see it, and
+	// it is removed if the deployment fails.
+	Deploying bool `json:"deploying,omitempty"`
+
+	ResourceVersion
 }
 
 // NodeAppReq is a NodeApp request.
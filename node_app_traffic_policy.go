@@ -17,6 +17,8 @@ type NodeAppTrafficPolicy struct {
 	ID              string `json:"id"`
 	NodeAppID       string `json:"nodes_apps_id"`
 	TrafficPolicyID string `json:"traffic_policy_id"`
+
+	ResourceVersion
 }
 
 // GetTableName returns the name of the persistence table.
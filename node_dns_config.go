@@ -16,6 +16,8 @@ type NodeDNSConfig struct {
 	ID          string `json:"id"`
 	NodeID      string `json:"node_id"`
 	DNSConfigID string `json:"dns_config_id"`
+
+	ResourceVersion
 }
 
 // GetTableName returns the name of the persistence table.
@@ -13,6 +13,8 @@ type NodeGRPCTarget struct {
 	ID         string `json:"id"`
 	NodeID     string `json:"node_id"`
 	GRPCTarget string `json:"grpc_target"`
+
+	ResourceVersion
 }
 
 // GetTableName returns the name of the persistence table.
@@ -16,6 +16,8 @@ type NodeInterface struct {
 	ID          string `json:"id"`
 	NodeID      string `json:"node_id"`
 	InterfaceID string `json:"interface_id"`
+
+	ResourceVersion
 }
 
 // NodeInterfaceReq is a NodeInterface request.
@@ -27,6 +27,8 @@ type NodeInterfaceTrafficPolicy struct {
 	NodeID             string `json:"node_id"`
 	NetworkInterfaceID string `json:"network_interface_id"`
 	TrafficPolicyID    string `json:"traffic_policy_id"`
+
+	ResourceVersion
 }
 
 // GetTableName returns the name of the persistence table.
@@ -44,6 +46,11 @@ func (n_i_tp *NodeInterfaceTrafficPolicy) SetID(id string) {
 	n_i_tp.ID = id
 }
 
+// GetNodeID gets the node ID.
+func (n_i_tp *NodeInterfaceTrafficPolicy) GetNodeID() string {
+	return n_i_tp.NodeID
+}
+
 // Validate validates the model.
 func (n_i_tp *NodeInterfaceTrafficPolicy) Validate() error {
 	if !uuid.IsValid(n_i_tp.ID) {
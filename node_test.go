@@ -81,6 +81,8 @@ var _ = Describe("Entities: Node", func() {
 		It("Should return the filterable fields", func() {
 			Expect(node.FilterFields()).To(Equal([]string{
 				"serial",
+				"name",
+				"location",
 			}))
 		})
 	})
@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// Operation statuses.
+const (
+	OperationPending   = "pending"
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+)
+
+// Operation types.
+const (
+	OperationDeployApp    = "deploy_app"
+	OperationUpdateApp    = "update_app"
+	OperationUndeployApp  = "undeploy_app"
+	OperationUpdateDNS    = "update_dns"
+	OperationDeleteDNS    = "delete_dns"
+	OperationUpdatePolicy = "update_policy"
+	OperationDeletePolicy = "delete_policy"
+)
+
+// Operation is a long-running request that changes a node, which is accepted
+// by the HTTP API and run in the background.
+type Operation struct {
+	ID string `json:"id"`
+
+	// Type is one of the operation types.
+	Type string `json:"type"`
+
+	NodeID string `json:"node_id"`
+
+	// Actor is the user who made the request.
+	Actor string `json:"actor"`
+
+	// Request is the method and path of the request.
+	Request string `json:"request"`
+
+	// Time is the time the request was accepted in microseconds since the
+	// Unix epoch, like the time of audit events.
+	Time int64 `json:"time"`
+
+	// Status is one of the operation statuses.
+	Status string `json:"status"`
+
+	// Progress describes the step the operation is at.
+	Progress string `json:"progress,omitempty"`
+
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// ResponseStatus is the HTTP status code the request would have been
+	// answered with if it had not run in the background.
+	ResponseStatus int `json:"response_status,omitempty"`
+
+	// Result is the JSON response body of the request, if any.
+	Result json.RawMessage `json:"result,omitempty"`
+
+	// Error is why the operation failed.
+	Error string `json:"error,omitempty"`
+
+	ResourceVersion
+}
+
+// OperationFunc runs the request of an operation and returns the HTTP status
+// code and body of its response. The operation fails if the status code is an
+// error.
+type OperationFunc func(ctx context.Context) (status int, body []byte)
+
+// OperationRunner runs operations in the background.
+type OperationRunner interface {
+	// Submit runs a persisted pending operation.
+	Submit(op *Operation, run OperationFunc)
+}
+
+// GetTableName returns the name of the persistence table.
+func (*Operation) GetTableName() string {
+	return "operations"
+}
+
+// GetID gets the ID.
+func (o *Operation) GetID() string {
+	return o.ID
+}
+
+// SetID sets the ID.
+func (o *Operation) SetID(id string) {
+	o.ID = id
+}
+
+// GetNodeID gets the ID of the node.
+func (o *Operation) GetNodeID() string {
+	return o.NodeID
+}
+
+// GetTime gets the time the operation was accepted.
+func (o *Operation) GetTime() time.Time {
+	return time.Unix(0, o.Time*int64(time.Microsecond)).UTC()
+}
+
+// Done returns whether the operation succeeded or failed.
+func (o *Operation) Done() bool {
+	return o.Status == OperationSucceeded || o.Status == OperationFailed
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*Operation) FilterFields() []string {
+	return []string{
+		"type",
+		"node_id",
+		"actor",
+		"time",
+		"status",
+	}
+}
+
+// Validate validates the model.
+func (o *Operation) Validate() error {
+	if !uuid.IsValid(o.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if o.Type == "" {
+		return errors.New("type cannot be empty")
+	}
+	if !uuid.IsValid(o.NodeID) {
+		return errors.New("node_id not a valid uuid")
+	}
+	switch o.Status {
+	case OperationPending, OperationRunning, OperationSucceeded, OperationFailed:
+	default:
+		return fmt.Errorf("unknown status %q", o.Status)
+	}
+	if len(o.Result) != 0 && !json.Valid(o.Result) {
+		return errors.New("result not valid JSON")
+	}
+
+	return nil
+}
+
+func (o *Operation) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+Operation[
+    ID: %s
+    Type: %s
+    NodeID: %s
+    Actor: %s
+    Status: %s
+]`),
+		o.ID,
+		o.Type,
+		o.NodeID,
+		o.Actor,
+		o.Status)
+}
@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package operation
+
+import (
+	"context"
+	"fmt"
+)
+
+type progressKey struct{}
+
+// WithProgress returns a context that reports the progress of the operation
+// running with it to f.
+func WithProgress(ctx context.Context, f func(progress string)) context.Context {
+	return context.WithValue(ctx, progressKey{}, f)
+}
+
+// Progress reports the step that the operation running with a context is at.
+// It does nothing if the context doesn't run an operation, as when requests
+// are handled synchronously.
+func Progress(ctx context.Context, format string, args ...interface{}) {
+	if f, ok := ctx.Value(progressKey{}).(func(string)); ok {
+		f(fmt.Sprintf(format, args...))
+	}
+}
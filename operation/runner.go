@@ -0,0 +1,419 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package operation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/open-ness/common/log"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/pkg/errors"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "operation")
+
+// Defaults of the Runner.
+const (
+	DefaultWorkers   = 8
+	DefaultQueueSize = 1000
+	DefaultTimeout   = 30 * time.Minute
+	DefaultRetention = 7 * 24 * time.Hour
+)
+
+// pruneInterval is the interval between deleting the operations past their
+// retention.
+const pruneInterval = time.Hour
+
+// Runner runs operations on a pool of workers. Operations run independently
+// of the requests that submitted them, so they complete even if the client
+// disconnects. Their status, progress and result are persisted as they run.
+// The operations on a node run one at a time, in the order they were
+// submitted, so that e.g. an app is not undeployed while it is deployed.
+type Runner struct {
+	Controller *cce.Controller
+
+	// Workers is the number of operations run at once. If zero
+	// DefaultWorkers is used.
+	Workers int
+
+	// QueueSize is the number of operations that can wait for a worker,
+	// beyond which submitted operations fail. If zero DefaultQueueSize is
+	// used.
+	QueueSize int
+
+	// Timeout is the time an operation may run for. If zero DefaultTimeout
+	// is used.
+	Timeout time.Duration
+
+	// Retention is the time operations are kept for after they were
+	// submitted. If zero DefaultRetention is used.
+	Retention time.Duration
+
+	once sync.Once
+
+	mu sync.Mutex
+	// queued is the number of operations waiting for a worker
+	queued int
+	// nodes are the operations waiting for a worker by node ID. A node is
+	// in nodes from when an operation on it is submitted until none are
+	// left, including while a worker runs one.
+	nodes map[string][]job
+	// ready are the IDs of the nodes whose next operation can run
+	ready chan string
+}
+
+type job struct {
+	op  *cce.Operation
+	run cce.OperationFunc
+}
+
+func (r *Runner) init() {
+	r.once.Do(func() {
+		size := r.QueueSize
+		if size == 0 {
+			size = DefaultQueueSize
+		}
+		r.nodes = make(map[string][]job)
+		// A node is ready at most once and has a queued operation while it
+		// is, so the channel never blocks
+		r.ready = make(chan string, size)
+	})
+}
+
+// Submit queues an operation to run on a worker after the operations
+// submitted before it on its node. If the queue is full the operation fails.
+func (r *Runner) Submit(op *cce.Operation, run cce.OperationFunc) {
+	r.init()
+
+	if !r.enqueue(job{op: op, run: run}) {
+		log.Noticef("Operation queue is full, failing operation %s", op.ID)
+		t := newTracker(r.Controller.PersistenceService, op)
+		t.update(func(op *cce.Operation) {
+			op.Status = cce.OperationFailed
+			op.FinishedAt = time.Now().UTC()
+			op.Error = "too many operations are queued"
+		})
+		r.publishDone(t.close())
+	}
+}
+
+// Run runs the submitted operations until the context is done. Operations
+// that were pending or running when the Controller stopped are failed, as
+// their requests were lost. Operations still queued when the context is done
+// are failed the next time the Runner runs.
+func (r *Runner) Run(ctx context.Context) error {
+	r.init()
+
+	if err := r.failInterrupted(ctx, time.Now()); err != nil {
+		return err
+	}
+
+	workers := r.Workers
+	if workers == 0 {
+		workers = DefaultWorkers
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case <-ticker.C:
+			r.prune(ctx, time.Now())
+		}
+	}
+}
+
+// failInterrupted fails the operations submitted before a time that are not
+// done.
+func (r *Runner) failInterrupted(ctx context.Context, before time.Time) error {
+	ps := r.Controller.PersistenceService
+
+	interrupted, err := ps.Filter(
+		ctx,
+		&cce.Operation{},
+		[]cce.Filter{
+			{
+				Field:  "status",
+				Op:     cce.FilterOpIn,
+				Values: []string{cce.OperationPending, cce.OperationRunning},
+			},
+			{
+				Field: "time",
+				Op:    cce.FilterOpLess,
+				Value: strconv.FormatInt(cce.AuditTime(before), 10),
+			},
+		})
+	if err != nil {
+		return errors.Wrap(err, "could not fetch interrupted operations from DB")
+	}
+
+	for _, e := range interrupted {
+		op := e.(*cce.Operation)
+		op.Status = cce.OperationFailed
+		op.FinishedAt = time.Now().UTC()
+		op.Error = "interrupted by a restart of the controller"
+		op.SetResourceVersion(0)
+		if err = ps.BulkUpdate(ctx, []cce.Persistable{op}); err != nil {
+			return errors.Wrapf(err, "could not fail interrupted operation %s", op.ID)
+		}
+		log.Noticef("Failed operation %s interrupted by a restart", op.ID)
+	}
+
+	return nil
+}
+
+// enqueue queues an operation on its node, and reports whether the queue had
+// room for it.
+func (r *Runner) enqueue(j job) bool {
+	size := cap(r.ready)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.queued == size {
+		return false
+	}
+	r.queued++
+	jobs, busy := r.nodes[j.op.NodeID]
+	r.nodes[j.op.NodeID] = append(jobs, j)
+	if !busy {
+		r.ready <- j.op.NodeID
+	}
+	return true
+}
+
+// dequeue takes the next operation on a ready node.
+func (r *Runner) dequeue(nodeID string) job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobs := r.nodes[nodeID]
+	r.nodes[nodeID] = jobs[1:]
+	r.queued--
+	return jobs[0]
+}
+
+// release makes a node whose operation is done ready for its next operation,
+// if any.
+func (r *Runner) release(nodeID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.nodes[nodeID]) == 0 {
+		delete(r.nodes, nodeID)
+		return
+	}
+	r.ready <- nodeID
+}
+
+// work runs queued operations until the context is done.
+func (r *Runner) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case nodeID := <-r.ready:
+			r.execute(ctx, r.dequeue(nodeID))
+			r.release(nodeID)
+		}
+	}
+}
+
+// execute runs an operation and records its result.
+func (r *Runner) execute(ctx context.Context, j job) {
+	t := newTracker(r.Controller.PersistenceService, j.op)
+	t.update(func(op *cce.Operation) {
+		op.Status = cce.OperationRunning
+		op.StartedAt = time.Now().UTC()
+	})
+
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	status, body := j.run(WithProgress(runCtx, func(progress string) {
+		t.update(func(op *cce.Operation) { op.Progress = progress })
+	}))
+
+	t.update(func(op *cce.Operation) {
+		finish(op, status, body)
+		if op.Status == cce.OperationFailed && runCtx.Err() != nil {
+			op.Error = "operation canceled: " + runCtx.Err().Error()
+		}
+	})
+	op := t.close()
+
+	if op.Status == cce.OperationFailed {
+		log.Noticef("Operation %s failed: %s", op.ID, op.Error)
+	}
+	r.publishDone(op)
+}
+
+// finish sets the result of an operation from the status code and body of
+// the response to its request.
+func finish(op *cce.Operation, status int, body []byte) {
+	op.FinishedAt = time.Now().UTC()
+	op.ResponseStatus = status
+
+	body = bytes.TrimSpace(body)
+	if len(body) != 0 && json.Valid(body) {
+		op.Result = body
+	}
+
+	if status < http.StatusBadRequest {
+		op.Status = cce.OperationSucceeded
+		return
+	}
+	op.Status = cce.OperationFailed
+	if len(body) != 0 && op.Result == nil {
+		op.Error = strings.TrimSpace(string(body))
+	} else {
+		op.Error = http.StatusText(status)
+	}
+}
+
+// publishDone publishes the end of an operation to the event bus, if any.
+func (r *Runner) publishDone(op *cce.Operation) {
+	if r.Controller.Events == nil {
+		return
+	}
+
+	r.Controller.Events.Publish(&events.Event{
+		Type:       events.TypeOperationDone,
+		NodeID:     op.NodeID,
+		EntityType: op.GetTableName(),
+		EntityID:   op.ID,
+		Actor:      op.Actor,
+		Data: events.OperationResult{
+			Type:   op.Type,
+			Status: op.Status,
+			Error:  op.Error,
+		},
+	})
+}
+
+// prune deletes the operations that are done and were submitted before the
+// retention.
+func (r *Runner) prune(ctx context.Context, now time.Time) {
+	ps := r.Controller.PersistenceService
+
+	retention := r.Retention
+	if retention == 0 {
+		retention = DefaultRetention
+	}
+
+	expired, err := ps.Filter(
+		ctx,
+		&cce.Operation{},
+		[]cce.Filter{
+			{
+				Field:  "status",
+				Op:     cce.FilterOpIn,
+				Values: []string{cce.OperationSucceeded, cce.OperationFailed},
+			},
+			{
+				Field: "time",
+				Op:    cce.FilterOpLess,
+				Value: strconv.FormatInt(cce.AuditTime(now.Add(-retention)), 10),
+			},
+		})
+	if err != nil {
+		log.Errf("Error fetching expired operations: %v", err)
+		return
+	}
+
+	for _, e := range expired {
+		if _, err = ps.Delete(ctx, e.GetID(), &cce.Operation{}); err != nil {
+			log.Errf("Error deleting operation %s: %v", e.GetID(), err)
+		}
+	}
+}
+
+// tracker persists the changes of an operation in order. The changes are
+// saved by a goroutine, so that an operation can report its progress while it
+// holds a transaction of an embedded DB, which has a single writer.
+type tracker struct {
+	ps cce.PersistenceService
+
+	mu sync.Mutex
+	op cce.Operation
+
+	changed chan struct{}
+	done    chan struct{}
+}
+
+func newTracker(ps cce.PersistenceService, op *cce.Operation) *tracker {
+	t := &tracker{
+		ps:      ps,
+		op:      *op,
+		changed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go t.save()
+	return t
+}
+
+// update changes the operation and saves it.
+func (t *tracker) update(f func(op *cce.Operation)) {
+	t.mu.Lock()
+	f(&t.op)
+	t.mu.Unlock()
+
+	select {
+	case t.changed <- struct{}{}:
+	default:
+		// A save of the latest change is pending
+	}
+}
+
+// close waits for the last change to be saved and returns the operation.
+func (t *tracker) close() *cce.Operation {
+	close(t.changed)
+	<-t.done
+	return t.snapshot()
+}
+
+func (t *tracker) snapshot() *cce.Operation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	op := t.op
+	return &op
+}
+
+func (t *tracker) save() {
+	defer close(t.done)
+
+	for range t.changed {
+		op := t.snapshot()
+		op.SetResourceVersion(0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cce.MaxDBRequestTime)
+		if err := t.ps.BulkUpdate(ctx, []cce.Persistable{op}); err != nil {
+			log.Errf("Error saving operation %s: %v", op.ID, err)
+		}
+		cancel()
+	}
+}
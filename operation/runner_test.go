@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package operation_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/operation"
+	"github.com/open-ness/edgecontroller/uuid"
+	"go.etcd.io/bbolt"
+)
+
+var _ = Describe("Runner", func() {
+	const nodeID = "0b1c2d3e-4f5a-4b6c-9d7e-8f9a0b1c2d3e"
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		dir    string
+		db     *bbolt.DB
+		ps     cce.PersistenceService
+		bus    *events.Bus
+		runner *operation.Runner
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		var err error
+		dir, err = ioutil.TempDir("", "cce-operations")
+		Expect(err).ToNot(HaveOccurred())
+		db, err = bolt.Open(filepath.Join(dir, "cce.db"))
+		Expect(err).ToNot(HaveOccurred())
+		ps = &bolt.PersistenceService{DB: db}
+		Expect(ps.Create(ctx, &cce.Node{ID: nodeID, Name: "node"})).To(Succeed())
+
+		bus = events.NewBus(10)
+		runner = &operation.Runner{
+			Controller: &cce.Controller{
+				PersistenceService: ps,
+				Events:             bus,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+		Expect(db.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	// submit persists a pending operation and submits it.
+	submit := func(run cce.OperationFunc) string {
+		op := &cce.Operation{
+			ID:      uuid.New(),
+			Type:    cce.OperationDeployApp,
+			NodeID:  nodeID,
+			Actor:   "admin",
+			Request: "POST /nodes/" + nodeID + "/apps",
+			Time:    cce.AuditTime(time.Now()),
+			Status:  cce.OperationPending,
+		}
+		Expect(ps.Create(ctx, op)).To(Succeed())
+		runner.Submit(op, run)
+		return op.ID
+	}
+
+	read := func(id string) *cce.Operation {
+		op, err := ps.Read(ctx, id, &cce.Operation{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(op).ToNot(BeNil())
+		return op.(*cce.Operation)
+	}
+
+	status := func(id string) func() string {
+		return func() string { return read(id).Status }
+	}
+
+	It("Should run operations and record their progress and result", func() {
+		go func() { _ = runner.Run(ctx) }()
+
+		release := make(chan struct{})
+		id := submit(func(ctx context.Context) (int, []byte) {
+			operation.Progress(ctx, "Deploying app %s", "sample")
+			<-release
+			return http.StatusCreated, []byte(`{"id":"sample"}`)
+		})
+
+		Eventually(func() string { return read(id).Progress }).Should(Equal("Deploying app sample"))
+		op := read(id)
+		Expect(op.Status).To(Equal(cce.OperationRunning))
+		Expect(op.StartedAt).ToNot(BeZero())
+
+		close(release)
+		Eventually(status(id)).Should(Equal(cce.OperationSucceeded))
+		op = read(id)
+		Expect(op.ResponseStatus).To(Equal(http.StatusCreated))
+		Expect(string(op.Result)).To(Equal(`{"id":"sample"}`))
+		Expect(op.Error).To(BeEmpty())
+		Expect(op.FinishedAt).ToNot(BeZero())
+	})
+
+	It("Should fail operations with an error response", func() {
+		sub := bus.Subscribe(events.Filter{Types: []string{events.TypeOperationDone}})
+		defer sub.Close()
+		go func() { _ = runner.Run(ctx) }()
+
+		id := submit(func(ctx context.Context) (int, []byte) {
+			return http.StatusInternalServerError, []byte("node unreachable\n")
+		})
+
+		Eventually(status(id)).Should(Equal(cce.OperationFailed))
+		op := read(id)
+		Expect(op.ResponseStatus).To(Equal(http.StatusInternalServerError))
+		Expect(op.Error).To(Equal("node unreachable"))
+		Expect(op.Result).To(BeNil())
+
+		var e *events.Event
+		Eventually(sub.C).Should(Receive(&e))
+		Expect(e.EntityID).To(Equal(id))
+		Expect(e.NodeID).To(Equal(nodeID))
+		Expect(e.Data).To(Equal(events.OperationResult{
+			Type:   cce.OperationDeployApp,
+			Status: cce.OperationFailed,
+			Error:  "node unreachable",
+		}))
+	})
+
+	It("Should run the operations on a node one at a time in order", func() {
+		go func() { _ = runner.Run(ctx) }()
+
+		By("Waiting for the runner to start")
+		started := submit(func(ctx context.Context) (int, []byte) { return http.StatusOK, nil })
+		Eventually(status(started)).Should(Equal(cce.OperationSucceeded))
+
+		release := make(chan struct{})
+		var ran []int
+		first := submit(func(ctx context.Context) (int, []byte) {
+			<-release
+			ran = append(ran, 1)
+			return http.StatusOK, nil
+		})
+		second := submit(func(ctx context.Context) (int, []byte) {
+			ran = append(ran, 2)
+			return http.StatusOK, nil
+		})
+		third := submit(func(ctx context.Context) (int, []byte) {
+			ran = append(ran, 3)
+			return http.StatusOK, nil
+		})
+
+		Eventually(status(first)).Should(Equal(cce.OperationRunning))
+		Consistently(status(second), 100*time.Millisecond).Should(Equal(cce.OperationPending))
+		Expect(read(third).Status).To(Equal(cce.OperationPending))
+
+		close(release)
+		Eventually(status(third)).Should(Equal(cce.OperationSucceeded))
+		Expect(read(second).Status).To(Equal(cce.OperationSucceeded))
+		Expect(ran).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("Should fail operations when the queue is full", func() {
+		runner.QueueSize = 1
+
+		queued := submit(func(ctx context.Context) (int, []byte) { return http.StatusOK, nil })
+		rejected := submit(func(ctx context.Context) (int, []byte) { return http.StatusOK, nil })
+
+		Expect(read(rejected).Status).To(Equal(cce.OperationFailed))
+		Expect(read(rejected).Error).To(Equal("too many operations are queued"))
+
+		go func() { _ = runner.Run(ctx) }()
+		Eventually(status(queued)).Should(Equal(cce.OperationSucceeded))
+	})
+
+	It("Should fail operations interrupted by a restart", func() {
+		interrupted := &cce.Operation{
+			ID:     uuid.New(),
+			Type:   cce.OperationUndeployApp,
+			NodeID: nodeID,
+			Time:   cce.AuditTime(time.Now().Add(-time.Minute)),
+			Status: cce.OperationRunning,
+		}
+		Expect(ps.Create(ctx, interrupted)).To(Succeed())
+
+		go func() { _ = runner.Run(ctx) }()
+
+		Eventually(status(interrupted.ID)).Should(Equal(cce.OperationFailed))
+		Expect(read(interrupted.ID).Error).To(Equal("interrupted by a restart of the controller"))
+	})
+
+	It("Should fail operations that time out", func() {
+		runner.Timeout = 10 * time.Millisecond
+		go func() { _ = runner.Run(ctx) }()
+
+		id := submit(func(ctx context.Context) (int, []byte) {
+			<-ctx.Done()
+			return http.StatusInternalServerError, nil
+		})
+
+		Eventually(status(id)).Should(Equal(cce.OperationFailed))
+		Expect(read(id).Error).To(Equal("operation canceled: context deadline exceeded"))
+	})
+})
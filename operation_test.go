@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: Operation", func() {
+	var (
+		o *cce.Operation
+	)
+
+	BeforeEach(func() {
+		o = &cce.Operation{
+			ID:      "5d6c7b8a-9e0f-4a1b-8c2d-3e4f5a6b7c8d",
+			Type:    cce.OperationDeployApp,
+			NodeID:  "0b1c2d3e-4f5a-4b6c-9d7e-8f9a0b1c2d3e",
+			Actor:   "admin",
+			Request: "POST /nodes/0b1c2d3e-4f5a-4b6c-9d7e-8f9a0b1c2d3e/apps",
+			Time:    1577836800000000,
+			Status:  cce.OperationPending,
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "operations"`, func() {
+			Expect(o.GetTableName()).To(Equal("operations"))
+		})
+	})
+
+	Describe("GetID", func() {
+		It("Should return the ID", func() {
+			Expect(o.GetID()).To(Equal("5d6c7b8a-9e0f-4a1b-8c2d-3e4f5a6b7c8d"))
+		})
+	})
+
+	Describe("SetID", func() {
+		It("Should set and return the updated ID", func() {
+			By("Setting the ID")
+			o.SetID("456")
+
+			By("Getting the updated ID")
+			Expect(o.ID).To(Equal("456"))
+		})
+	})
+
+	Describe("GetNodeID", func() {
+		It("Should return the node ID", func() {
+			Expect(o.GetNodeID()).To(Equal("0b1c2d3e-4f5a-4b6c-9d7e-8f9a0b1c2d3e"))
+		})
+	})
+
+	Describe("GetTime", func() {
+		It("Should return the time", func() {
+			Expect(o.GetTime()).To(Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+		})
+	})
+
+	Describe("Done", func() {
+		It("Should return whether the operation is done", func() {
+			for status, done := range map[string]bool{
+				cce.OperationPending:   false,
+				cce.OperationRunning:   false,
+				cce.OperationSucceeded: true,
+				cce.OperationFailed:    true,
+			} {
+				o.Status = status
+				Expect(o.Done()).To(Equal(done), status)
+			}
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(o.FilterFields()).To(Equal([]string{
+				"type",
+				"node_id",
+				"actor",
+				"time",
+				"status",
+			}))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error for a valid operation", func() {
+			Expect(o.Validate()).To(Succeed())
+		})
+
+		It("Should return an error for an invalid ID", func() {
+			o.ID = "123"
+			Expect(o.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if there is no type", func() {
+			o.Type = ""
+			Expect(o.Validate()).To(MatchError("type cannot be empty"))
+		})
+
+		It("Should return an error for an invalid node ID", func() {
+			o.NodeID = "123"
+			Expect(o.Validate()).To(MatchError("node_id not a valid uuid"))
+		})
+
+		It("Should return an error for an unknown status", func() {
+			o.Status = "lost"
+			Expect(o.Validate()).To(MatchError(`unknown status "lost"`))
+		})
+
+		It("Should return an error for an invalid result", func() {
+			o.Result = []byte("{")
+			Expect(o.Validate()).To(MatchError("result not valid JSON"))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(o.String()).To(Equal(strings.TrimSpace(`
+Operation[
+    ID: 5d6c7b8a-9e0f-4a1b-8c2d-3e4f5a6b7c8d
+    Type: deploy_app
+    NodeID: 0b1c2d3e-4f5a-4b6c-9d7e-8f9a0b1c2d3e
+    Actor: admin
+    Status: pending
+]`,
+			)))
+		})
+	})
+})
@@ -9,10 +9,10 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"math/big"
-	rdm "math/rand"
 	"os"
 	"path/filepath"
 	"time"
@@ -99,7 +99,9 @@ func (ca *RootCA) CAChain() ([]*x509.Certificate, error) {
 	return []*x509.Certificate{ca.Cert}, nil
 }
 
-// SignCSR signs a ASN.1 DER encoded certificate signing request.
+// SignCSR signs a ASN.1 DER encoded certificate signing request. The subject,
+// key usages and expiry are taken from the template. The certificate is valid
+// until the CA expires if the template has no expiry.
 func (ca *RootCA) SignCSR(der []byte, template *x509.Certificate) (*x509.Certificate, error) {
 	csr, err := x509.ParseCertificateRequest(der)
 	if err != nil {
@@ -107,16 +109,23 @@ func (ca *RootCA) SignCSR(der []byte, template *x509.Certificate) (*x509.Certifi
 	}
 
 	// Pick random serial number
-	source := rdm.NewSource(time.Now().UnixNano())
-	serial := big.NewInt(int64(rdm.New(source).Uint64()))
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
 
 	// Sign certificate request
 	tmpl := &x509.Certificate{
 		SerialNumber: serial,
 		Subject:      template.Subject,
+		KeyUsage:     template.KeyUsage,
+		ExtKeyUsage:  template.ExtKeyUsage,
 		NotBefore:    time.Now(),
 		NotAfter:     ca.Cert.NotAfter, // Valid until CA expires
 	}
+	if !template.NotAfter.IsZero() && template.NotAfter.Before(ca.Cert.NotAfter) {
+		tmpl.NotAfter = template.NotAfter
+	}
 	certDER, err := x509.CreateCertificate(
 		rand.Reader,
 		tmpl,
@@ -133,17 +142,46 @@ func (ca *RootCA) SignCSR(der []byte, template *x509.Certificate) (*x509.Certifi
 
 // NewTLSClientCert creates a new TLS client certificate with a given SNI.
 func (ca *RootCA) NewTLSClientCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error) {
-	return ca.newTLSCert(key, sni, x509.ExtKeyUsageClientAuth)
+	return ca.newTLSCert(key, sni, time.Time{}, x509.ExtKeyUsageClientAuth)
 }
 
 // NewTLSServerCert creates a new TLS server certificate with a given SNI.
 func (ca *RootCA) NewTLSServerCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error) {
-	return ca.newTLSCert(key, sni, x509.ExtKeyUsageServerAuth)
+	return ca.newTLSCert(key, sni, time.Time{}, x509.ExtKeyUsageServerAuth)
+}
+
+// NewTLSServerKeyPair generates a key and a TLS server certificate for it with
+// a given SNI, chained to the CA. The certificate is valid until notAfter, or
+// until the CA expires if that is sooner or notAfter is zero.
+func (ca *RootCA) NewTLSServerKeyPair(sni string, notAfter time.Time) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate TLS key")
+	}
+	cert, err := ca.newTLSCert(key, sni, notAfter, x509.ExtKeyUsageServerAuth)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := ca.CAChain()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get CA chain")
+	}
+
+	keyPair := &tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  key,
+		Leaf:        cert,
+	}
+	for _, caCert := range chain {
+		keyPair.Certificate = append(keyPair.Certificate, caCert.Raw)
+	}
+	return keyPair, nil
 }
 
 func (ca *RootCA) newTLSCert(
 	key crypto.PrivateKey,
 	sni string,
+	notAfter time.Time,
 	extKeyUsage ...x509.ExtKeyUsage,
 ) (*x509.Certificate, error) {
 	pkey, ok := key.(crypto.Signer)
@@ -152,18 +190,24 @@ func (ca *RootCA) newTLSCert(
 	}
 
 	// Pick random serial number
-	source := rdm.NewSource(time.Now().UnixNano())
-	serial := big.NewInt(int64(rdm.New(source).Uint64()))
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
 
 	// Generate certificate
 	template := &x509.Certificate{
 		SerialNumber: serial,
 		Subject:      pkix.Name{CommonName: sni},
+		DNSNames:     []string{sni},
 		KeyUsage:     x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:  extKeyUsage,
 		NotBefore:    time.Now(),
 		NotAfter:     ca.Cert.NotAfter, // Valid until CA expires
 	}
+	if !notAfter.IsZero() && notAfter.Before(ca.Cert.NotAfter) {
+		template.NotAfter = notAfter
+	}
 	certDER, err := x509.CreateCertificate(
 		rand.Reader,
 		template,
@@ -184,7 +228,6 @@ func generateRootCA(key crypto.PrivateKey) (*x509.Certificate, error) {
 		err      error
 		k        crypto.Signer
 		ok       bool
-		source   rdm.Source
 		serial   *big.Int
 		template *x509.Certificate
 		der      []byte
@@ -194,9 +237,9 @@ func generateRootCA(key crypto.PrivateKey) (*x509.Certificate, error) {
 		return nil, errors.Wrap(err, "unable to parse key")
 	}
 
-	source = rdm.NewSource(time.Now().UnixNano())
-
-	serial = big.NewInt(int64(rdm.New(source).Uint64()))
+	if serial, err = newSerial(); err != nil {
+		return nil, err
+	}
 
 	template = &x509.Certificate{
 		SerialNumber: serial,
@@ -219,3 +262,12 @@ func generateRootCA(key crypto.PrivateKey) (*x509.Certificate, error) {
 
 	return x509.ParseCertificate(der)
 }
+
+// newSerial picks a random positive serial number of up to 128 bits.
+func newSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate serial number")
+	}
+	return serial.Add(serial, big.NewInt(1)), nil
+}
@@ -8,11 +8,13 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -131,4 +133,48 @@ var _ = Describe("Controller CA", func() {
 			})
 		})
 	})
+
+	Describe("SignCSR", func() {
+		var (
+			rootCA *pki.RootCA
+			csrDER []byte
+		)
+
+		BeforeEach(func() {
+			By("Initializing root CA")
+			rootCA, err = pki.InitRootCA(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Creating a CSR")
+			key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			csrDER, err = x509.CreateCertificateRequest(rand.Reader,
+				&x509.CertificateRequest{Subject: pkix.Name{CommonName: "requested"}}, key)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should sign the CSR with the subject of the template until the CA expires", func() {
+			cert, err := rootCA.SignCSR(csrDER, &x509.Certificate{
+				Subject: pkix.Name{CommonName: "node"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cert.Subject.CommonName).To(Equal("node"))
+			Expect(cert.NotAfter).To(Equal(rootCA.Cert.NotAfter))
+			Expect(cert.CheckSignatureFrom(rootCA.Cert)).To(Succeed())
+		})
+
+		It("Should sign the CSR with the key usages and expiry of the template", func() {
+			notAfter := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+			cert, err := rootCA.SignCSR(csrDER, &x509.Certificate{
+				Subject:     pkix.Name{CommonName: "jane"},
+				KeyUsage:    x509.KeyUsageDigitalSignature,
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+				NotAfter:    notAfter,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cert.KeyUsage).To(Equal(x509.KeyUsageDigitalSignature))
+			Expect(cert.ExtKeyUsage).To(Equal([]x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}))
+			Expect(cert.NotAfter).To(Equal(notAfter))
+		})
+	})
 })
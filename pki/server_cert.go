@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package pki
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultServerCertLifetime is the default lifetime of the certificates
+// issued by a ServerCert.
+const DefaultServerCertLifetime = 90 * 24 * time.Hour
+
+// ServerCert is the certificate of a TLS server, which is renewed before it
+// expires. It is either issued by a CA for a server name, or loaded from PEM
+// files that are reloaded when they change, for certificates renewed by
+// another tool.
+type ServerCert struct {
+	// CA issues certificates for ServerName valid for Lifetime, or
+	// DefaultServerCertLifetime if it is zero.
+	CA         *RootCA
+	ServerName string
+	Lifetime   time.Duration
+
+	// CertFile and KeyFile are the PEM files of a certificate chain and its
+	// key, which are loaded instead of issuing certificates if set.
+	CertFile string
+	KeyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// Load issues the certificate, or loads it from its files.
+func (c *ServerCert) Load() error {
+	if c.CertFile != "" {
+		return c.load()
+	}
+	return c.issue(time.Now())
+}
+
+func (c *ServerCert) issue(now time.Time) error {
+	lifetime := c.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultServerCertLifetime
+	}
+
+	cert, err := c.CA.NewTLSServerKeyPair(c.ServerName, now.Add(lifetime))
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = cert
+	log.Infof("Issued TLS server certificate for %s valid until %s",
+		c.ServerName, cert.Leaf.NotAfter.Format(time.RFC3339))
+
+	return nil
+}
+
+func (c *ServerCert) load() error {
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(filepath.Clean(c.CertFile), filepath.Clean(c.KeyFile))
+	if err != nil {
+		return errors.Wrap(err, "unable to load TLS key pair")
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return errors.Wrap(err, "unable to parse TLS certificate")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.modTime = modTime
+	log.Infof("Loaded TLS server certificate from %s valid until %s",
+		c.CertFile, cert.Leaf.NotAfter.Format(time.RFC3339))
+
+	return nil
+}
+
+// filesModTime returns the last time the certificate or key file changed.
+func (c *ServerCert) filesModTime() (time.Time, error) {
+	var modTime time.Time
+	for _, path := range []string{c.CertFile, c.KeyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "unable to stat TLS key pair")
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.
+func (c *ServerCert) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.cert == nil {
+		return nil, errors.New("TLS server certificate not loaded")
+	}
+	return c.cert, nil
+}
+
+// Renew renews the certificate if it is due at a time. Issued certificates are
+// renewed once two thirds of their lifetime have passed, and loaded ones are
+// reloaded if their files changed.
+func (c *ServerCert) Renew(now time.Time) error {
+	if c.CertFile != "" {
+		modTime, err := c.filesModTime()
+		if err != nil {
+			return err
+		}
+
+		c.mu.RLock()
+		changed := !modTime.Equal(c.modTime)
+		c.mu.RUnlock()
+		if !changed {
+			return nil
+		}
+		return c.load()
+	}
+
+	c.mu.RLock()
+	leaf := c.cert.Leaf
+	c.mu.RUnlock()
+	renewAt := leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) * 2 / 3)
+	if now.Before(renewAt) {
+		return nil
+	}
+	return c.issue(now)
+}
+
+// Run renews the certificate when it is due until the context is done. The
+// current certificate is kept if it cannot be renewed.
+func (c *ServerCert) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := c.Renew(time.Now()); err != nil {
+			log.Errf("Error renewing TLS server certificate: %v", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package pki_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/pki"
+)
+
+var _ = Describe("ServerCert", func() {
+	var (
+		tmpDir string
+		rootCA *pki.RootCA
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "server_cert_test")
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Initializing root CA")
+		rootCA, err = pki.InitRootCA(filepath.Join(tmpDir, "ca"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	getCertificate := func(sc *pki.ServerCert) *tls.Certificate {
+		cert, err := sc.GetCertificate(&tls.ClientHelloInfo{})
+		Expect(err).ToNot(HaveOccurred())
+		return cert
+	}
+
+	It("Should issue a certificate for the server name chained to the CA", func() {
+		sc := &pki.ServerCert{CA: rootCA, ServerName: "controller.example.com", Lifetime: time.Hour}
+		Expect(sc.Load()).To(Succeed())
+
+		cert := getCertificate(sc)
+		Expect(cert.Leaf.DNSNames).To(Equal([]string{"controller.example.com"}))
+		Expect(cert.Leaf.NotAfter).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+		Expect(cert.Leaf.CheckSignatureFrom(rootCA.Cert)).To(Succeed())
+		Expect(cert.Certificate).To(Equal([][]byte{cert.Leaf.Raw, rootCA.Cert.Raw}))
+	})
+
+	It("Should renew an issued certificate after two thirds of its lifetime", func() {
+		sc := &pki.ServerCert{CA: rootCA, ServerName: "controller.example.com", Lifetime: 3 * time.Hour}
+		Expect(sc.Load()).To(Succeed())
+		cert := getCertificate(sc)
+
+		Expect(sc.Renew(time.Now().Add(time.Hour))).To(Succeed())
+		Expect(getCertificate(sc)).To(BeIdenticalTo(cert))
+
+		Expect(sc.Renew(time.Now().Add(2 * time.Hour))).To(Succeed())
+		Expect(getCertificate(sc).Leaf.SerialNumber).ToNot(Equal(cert.Leaf.SerialNumber))
+	})
+
+	It("Should load a certificate from files and reload it when they change", func() {
+		certFile := filepath.Join(tmpDir, "cert.pem")
+		keyFile := filepath.Join(tmpDir, "key.pem")
+		writeKeyPair := func(modTime time.Time) *x509.Certificate {
+			keyPair, err := rootCA.NewTLSServerKeyPair("controller.example.com", time.Time{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pki.StoreKey(keyPair.PrivateKey, keyFile)).To(Succeed())
+			Expect(ioutil.WriteFile(certFile,
+				pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: keyPair.Leaf.Raw}), 0600)).To(Succeed())
+			Expect(os.Chtimes(certFile, modTime, modTime)).To(Succeed())
+			Expect(os.Chtimes(keyFile, modTime, modTime)).To(Succeed())
+			return keyPair.Leaf
+		}
+
+		first := writeKeyPair(time.Now().Add(-time.Hour))
+		sc := &pki.ServerCert{CertFile: certFile, KeyFile: keyFile}
+		Expect(sc.Load()).To(Succeed())
+		Expect(getCertificate(sc).Leaf.Raw).To(Equal(first.Raw))
+
+		By("Renewing without changes to the files")
+		cert := getCertificate(sc)
+		Expect(sc.Renew(time.Now())).To(Succeed())
+		Expect(getCertificate(sc)).To(BeIdenticalTo(cert))
+
+		By("Renewing after the files changed")
+		second := writeKeyPair(time.Now())
+		Expect(sc.Renew(time.Now())).To(Succeed())
+		Expect(getCertificate(sc).Leaf.Raw).To(Equal(second.Raw))
+	})
+
+	It("Should fail to load missing files", func() {
+		sc := &pki.ServerCert{CertFile: filepath.Join(tmpDir, "cert.pem"), KeyFile: filepath.Join(tmpDir, "key.pem")}
+		Expect(sc.Load()).ToNot(Succeed())
+		_, err := sc.GetCertificate(&tls.ClientHelloInfo{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package pki_test
+
+import (
+	"crypto/tls"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/pki"
+)
+
+var _ = Describe("TLS options", func() {
+	It("Should parse TLS versions", func() {
+		Expect(pki.ParseTLSVersion("1.2")).To(Equal(uint16(tls.VersionTLS12)))
+		Expect(pki.ParseTLSVersion("1.3")).To(Equal(uint16(tls.VersionTLS13)))
+
+		_, err := pki.ParseTLSVersion("TLS1.2")
+		Expect(err).To(MatchError(`bad TLS version "TLS1.2", not one of 1.0, 1.1, 1.2 or 1.3`))
+	})
+
+	It("Should parse cipher suites", func() {
+		Expect(pki.ParseCipherSuites(
+			"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256")).To(Equal(
+			[]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305}))
+		Expect(pki.ParseCipherSuites(strings.Join(pki.DefaultCipherSuites, ","))).To(
+			HaveLen(len(pki.DefaultCipherSuites)))
+
+		_, err := pki.ParseCipherSuites("TLS_RSA_WITH_RC4_128_SHA")
+		Expect(err).To(MatchError(HavePrefix(`bad cipher suite "TLS_RSA_WITH_RC4_128_SHA", not one of `)))
+	})
+})
@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+// Package plan records the calls that dry runs of requests would make to
+// nodes, instead of making them.
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	logger "github.com/open-ness/common/log"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "plan")
+
+// ServiceKubernetes is the service of the calls made to the Kubernetes master
+// for a node.
+const ServiceKubernetes = "kubernetes"
+
+// Call is a call that would have been made to a node.
+type Call struct {
+	NodeID string
+
+	// Service is the full name of the gRPC service of the node, or
+	// ServiceKubernetes.
+	Service string
+	Method  string
+
+	// Request is the request message in JSON.
+	Request json.RawMessage
+}
+
+// Recorder records the calls of a dry run.
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns a context for a dry run, whose calls to nodes are
+// recorded by r.
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// FromContext returns the recorder of a dry run's context, or nil if the
+// context isn't a dry run's.
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Record records a call to a node if the context is a dry run's, and returns
+// whether it did. A recorded call must not be made.
+func Record(ctx context.Context, nodeID, service, method string, req interface{}) bool {
+	r := FromContext(ctx)
+	if r == nil {
+		return false
+	}
+
+	r.record(nodeID, service, method, req)
+	return true
+}
+
+func (r *Recorder) record(nodeID, service, method string, req interface{}) {
+	call := Call{
+		NodeID:  nodeID,
+		Service: service,
+		Method:  method,
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	if msg, ok := req.(proto.Message); ok {
+		var s string
+		s, err = (&jsonpb.Marshaler{OrigName: true}).MarshalToString(msg)
+		b = []byte(s)
+	} else {
+		b, err = json.Marshal(req)
+	}
+	if err != nil {
+		log.Errf("Error marshaling request of %s/%s: %v", service, method, err)
+	} else {
+		call.Request = b
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+// Calls returns the recorded calls in the order they were made.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// UnaryClientInterceptor returns an interceptor that records the calls of a
+// client connection to a node instead of invoking them. The replies are left
+// empty.
+func (r *Recorder) UnaryClientInterceptor(nodeID string) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		// Full methods are "/package.Service/Method"
+		service := strings.TrimPrefix(method, "/")
+		if i := strings.LastIndex(service, "/"); i >= 0 {
+			service, method = service[:i], service[i+1:]
+		}
+
+		r.record(nodeID, service, method, req)
+		return nil
+	}
+}
+
+// Dialer is the dialer of the client connections of dry runs, which never
+// connect.
+func Dialer(ctx context.Context, addr string) (net.Conn, error) {
+	return nil, errors.Errorf("dry run, not connecting to %s", addr)
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package plan_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	elapb "github.com/open-ness/edgecontroller/pb/ela"
+	"github.com/open-ness/edgecontroller/plan"
+	"google.golang.org/grpc"
+)
+
+var _ = Describe("Plan", func() {
+	var (
+		rec *plan.Recorder
+		ctx context.Context
+	)
+
+	BeforeEach(func() {
+		rec = &plan.Recorder{}
+		ctx = plan.WithRecorder(context.Background(), rec)
+	})
+
+	Describe("FromContext", func() {
+		It("Should return the recorder of a dry run", func() {
+			Expect(plan.FromContext(ctx)).To(BeIdenticalTo(rec))
+		})
+
+		It("Should return nil if the context isn't a dry run's", func() {
+			Expect(plan.FromContext(context.Background())).To(BeNil())
+		})
+	})
+
+	Describe("Record", func() {
+		It("Should record a call of a dry run", func() {
+			type request struct {
+				AppID string `json:"app_id"`
+			}
+			Expect(plan.Record(ctx, "node1", plan.ServiceKubernetes, "Start", request{"app1"})).To(BeTrue())
+
+			Expect(rec.Calls()).To(Equal([]plan.Call{
+				{
+					NodeID:  "node1",
+					Service: "kubernetes",
+					Method:  "Start",
+					Request: json.RawMessage(`{"app_id":"app1"}`),
+				},
+			}))
+		})
+
+		It("Should not record a call if the context isn't a dry run's", func() {
+			Expect(plan.Record(context.Background(), "node1", plan.ServiceKubernetes, "Start", nil)).To(BeFalse())
+			Expect(rec.Calls()).To(BeEmpty())
+		})
+	})
+
+	Describe("UnaryClientInterceptor", func() {
+		It("Should record the calls of a client connection without connecting", func() {
+			conn, err := grpc.DialContext(
+				ctx,
+				"127.0.0.1:1",
+				grpc.WithInsecure(),
+				grpc.WithContextDialer(plan.Dialer),
+				grpc.WithUnaryInterceptor(rec.UnaryClientInterceptor("node1")))
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+
+			By("Calling the node")
+			_, err = elapb.NewDNSServiceClient(conn).SetA(ctx, &elapb.DNSARecordSet{
+				Name:   "app.openness",
+				Values: []string{"192.168.1.5"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Verifying the call was recorded")
+			Expect(rec.Calls()).To(HaveLen(1))
+			call := rec.Calls()[0]
+			Expect(call.NodeID).To(Equal("node1"))
+			Expect(call.Service).To(Equal("openness.ela.DNSService"))
+			Expect(call.Method).To(Equal("SetA"))
+			Expect(call.Request).To(MatchJSON(`{"name": "app.openness", "values": ["192.168.1.5"]}`))
+		})
+	})
+})
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// Limit is the limit of a token bucket. Buckets hold up to Burst tokens and
+// are refilled with Rate tokens per second. Each request takes a token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses a limit in the RATE:BURST format, as in "10:20" for 10
+// requests per second in bursts of up to 20. An empty string or "0" is no
+// limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return Limit{}, errors.Errorf("limit %q is not RATE:BURST", s)
+	}
+	rate, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+		return Limit{}, errors.Errorf("bad rate %q", parts[0])
+	}
+	burst, err := strconv.Atoi(parts[1])
+	if err != nil || burst < 1 {
+		return Limit{}, errors.Errorf("bad burst %q", parts[1])
+	}
+
+	return Limit{Rate: rate, Burst: burst}, nil
+}
+
+// IsZero returns whether the limit is no limit.
+func (l Limit) IsZero() bool {
+	return l.Rate == 0
+}
+
+func (l Limit) String() string {
+	if l.IsZero() {
+		return "0"
+	}
+	return fmt.Sprintf("%s:%d", strconv.FormatFloat(l.Rate, 'f', -1, 64), l.Burst)
+}
+
+// Set parses the limit of a flag.
+func (l *Limit) Set(s string) error {
+	limit, err := ParseLimit(s)
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// Limiter rate limits requests with a token bucket for each key, such as a
+// client IP address or a user.
+type Limiter struct {
+	Limit Limit
+
+	// Now returns the current time. If nil time.Now is used.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	pruned   time.Time
+}
+
+// Allow takes a token from the bucket of a key. If the bucket is empty the
+// request is not allowed, and the time until the bucket has a token is
+// returned.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.Limit.IsZero() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	limiter, ok := l.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(l.Limit.Rate), l.Limit.Burst)
+		l.limiters[key] = limiter
+	}
+
+	r := limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		// The request is rejected rather than delayed, so the token is put
+		// back
+		r.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// Len returns the number of keys with buckets that are not full.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(l.now())
+	return len(l.limiters)
+}
+
+// prune removes the buckets that have been refilled, which are the same as
+// new buckets, at most once a second.
+func (l *Limiter) prune(now time.Time) {
+	if l.limiters == nil {
+		l.limiters = make(map[string]*rate.Limiter)
+	}
+	if now.Sub(l.pruned) < time.Second {
+		return
+	}
+	l.pruned = now
+
+	for key, limiter := range l.limiters {
+		if isFull(limiter, now) {
+			delete(l.limiters, key)
+		}
+	}
+}
+
+// isFull returns whether the bucket of a limiter is full, which is when all
+// its tokens can be taken now.
+func isFull(limiter *rate.Limiter, now time.Time) bool {
+	r := limiter.ReserveN(now, limiter.Burst())
+	defer r.CancelAt(now)
+	return r.DelayFrom(now) == 0
+}
+
+func (l *Limiter) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
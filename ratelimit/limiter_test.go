@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package ratelimit_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/ratelimit"
+)
+
+var _ = Describe("Limiter", func() {
+	var (
+		now     time.Time
+		limiter *ratelimit.Limiter
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		limiter = &ratelimit.Limiter{
+			Limit: ratelimit.Limit{Rate: 2, Burst: 3},
+			Now:   func() time.Time { return now },
+		}
+	})
+
+	allow := func(key string) bool {
+		ok, _ := limiter.Allow(key)
+		return ok
+	}
+
+	It("Should allow bursts of requests", func() {
+		for i := 0; i < 3; i++ {
+			Expect(allow("jane")).To(BeTrue())
+		}
+
+		ok, retryAfter := limiter.Allow("jane")
+		Expect(ok).To(BeFalse())
+		Expect(retryAfter).To(Equal(500 * time.Millisecond))
+	})
+
+	It("Should refill the bucket at the rate", func() {
+		for i := 0; i < 3; i++ {
+			Expect(allow("jane")).To(BeTrue())
+		}
+
+		now = now.Add(250 * time.Millisecond)
+		ok, retryAfter := limiter.Allow("jane")
+		Expect(ok).To(BeFalse())
+		Expect(retryAfter).To(Equal(250 * time.Millisecond))
+
+		now = now.Add(250 * time.Millisecond)
+		Expect(allow("jane")).To(BeTrue())
+		Expect(allow("jane")).To(BeFalse())
+	})
+
+	It("Should limit each key separately", func() {
+		for i := 0; i < 3; i++ {
+			Expect(allow("jane")).To(BeTrue())
+		}
+		Expect(allow("jane")).To(BeFalse())
+		Expect(allow("john")).To(BeTrue())
+		Expect(limiter.Len()).To(Equal(2))
+	})
+
+	It("Should forget the keys with full buckets", func() {
+		Expect(allow("jane")).To(BeTrue())
+		Expect(limiter.Len()).To(Equal(1))
+
+		now = now.Add(time.Second)
+		Expect(limiter.Len()).To(Equal(0))
+	})
+
+	It("Should allow all requests without a limit", func() {
+		limiter.Limit = ratelimit.Limit{}
+		for i := 0; i < 100; i++ {
+			Expect(allow("jane")).To(BeTrue())
+		}
+		Expect(limiter.Len()).To(Equal(0))
+	})
+
+	DescribeTable("ParseLimit",
+		func(s string, expected ratelimit.Limit, expectedErr string) {
+			limit, err := ratelimit.ParseLimit(s)
+			if expectedErr != "" {
+				Expect(err).To(MatchError(expectedErr))
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limit).To(Equal(expected))
+			Expect(ratelimit.ParseLimit(limit.String())).To(Equal(expected))
+		},
+		Entry("no limit", "", ratelimit.Limit{}, ""),
+		Entry("zero", "0", ratelimit.Limit{}, ""),
+		Entry("rate and burst", "10:20", ratelimit.Limit{Rate: 10, Burst: 20}, ""),
+		Entry("fractional rate", "0.5:1", ratelimit.Limit{Rate: 0.5, Burst: 1}, ""),
+		Entry("no burst", "10", ratelimit.Limit{}, `limit "10" is not RATE:BURST`),
+		Entry("bad rate", "x:20", ratelimit.Limit{}, `bad rate "x"`),
+		Entry("negative rate", "-1:20", ratelimit.Limit{}, `bad rate "-1"`),
+		Entry("bad burst", "10:0", ratelimit.Limit{}, `bad burst "0"`),
+	)
+})
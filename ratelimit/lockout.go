@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults of Lockout.
+const (
+	DefaultLockoutThreshold   = 5
+	DefaultLockoutDuration    = time.Minute
+	DefaultMaxLockoutDuration = time.Hour
+)
+
+// Lockout locks out keys, such as a user logging in from a client IP address,
+// after repeated failures. A key is locked out for Duration after Threshold
+// consecutive failures, and the lockout doubles with each further failure up
+// to MaxDuration. A success resets the key.
+type Lockout struct {
+	Threshold   int
+	Duration    time.Duration
+	MaxDuration time.Duration
+
+	// Now returns the current time. If nil time.Now is used.
+	Now func() time.Time
+
+	mu     sync.Mutex
+	keys   map[string]*lockoutState
+	pruned time.Time
+}
+
+type lockoutState struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Check returns the time until a key is no longer locked out, or 0 if it is
+// not locked out.
+func (l *Lockout) Check(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s, ok := l.keys[key]
+	if !ok {
+		return 0
+	}
+	if wait := s.lockedUntil.Sub(l.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Fail records a failure of a key. It returns the duration the key is locked
+// out for, or 0 if it is not locked out.
+func (l *Lockout) Fail(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	s, ok := l.keys[key]
+	if !ok {
+		s = &lockoutState{}
+		l.keys[key] = s
+	}
+	s.failures++
+	s.lastFailure = now
+
+	if s.failures < l.threshold() {
+		return 0
+	}
+
+	// Double the lockout with each failure past the threshold
+	d := l.duration()
+	for i := l.threshold(); i < s.failures && d < l.maxDuration(); i++ {
+		d *= 2
+	}
+	if d > l.maxDuration() {
+		d = l.maxDuration()
+	}
+	s.lockedUntil = now.Add(d)
+
+	return d
+}
+
+// Succeed resets a key after a success.
+func (l *Lockout) Succeed(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.keys, key)
+}
+
+// Locked returns the number of keys that are locked out.
+func (l *Lockout) Locked() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	n := 0
+	for _, s := range l.keys {
+		if s.lockedUntil.After(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// prune forgets the failures of keys that have not failed for MaxDuration and
+// are no longer locked out, at most once a second.
+func (l *Lockout) prune(now time.Time) {
+	if l.keys == nil {
+		l.keys = make(map[string]*lockoutState)
+	}
+	if now.Sub(l.pruned) < time.Second {
+		return
+	}
+	l.pruned = now
+
+	for key, s := range l.keys {
+		if now.Sub(s.lastFailure) >= l.maxDuration() && !s.lockedUntil.After(now) {
+			delete(l.keys, key)
+		}
+	}
+}
+
+func (l *Lockout) threshold() int {
+	if l.Threshold > 0 {
+		return l.Threshold
+	}
+	return DefaultLockoutThreshold
+}
+
+func (l *Lockout) duration() time.Duration {
+	if l.Duration > 0 {
+		return l.Duration
+	}
+	return DefaultLockoutDuration
+}
+
+func (l *Lockout) maxDuration() time.Duration {
+	if l.MaxDuration > 0 {
+		return l.MaxDuration
+	}
+	return DefaultMaxLockoutDuration
+}
+
+func (l *Lockout) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package ratelimit_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/ratelimit"
+)
+
+var _ = Describe("Lockout", func() {
+	var (
+		now     time.Time
+		lockout *ratelimit.Lockout
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		lockout = &ratelimit.Lockout{
+			Threshold:   3,
+			Duration:    time.Minute,
+			MaxDuration: 10 * time.Minute,
+			Now:         func() time.Time { return now },
+		}
+	})
+
+	failUntilLocked := func(key string) {
+		Expect(lockout.Fail(key)).To(BeZero())
+		Expect(lockout.Fail(key)).To(BeZero())
+		Expect(lockout.Check(key)).To(BeZero())
+		Expect(lockout.Fail(key)).To(Equal(time.Minute))
+	}
+
+	It("Should lock out a key after the threshold of failures", func() {
+		failUntilLocked("jane")
+		Expect(lockout.Check("jane")).To(Equal(time.Minute))
+		Expect(lockout.Check("john")).To(BeZero())
+		Expect(lockout.Locked()).To(Equal(1))
+
+		now = now.Add(time.Minute)
+		Expect(lockout.Check("jane")).To(BeZero())
+		Expect(lockout.Locked()).To(Equal(0))
+	})
+
+	It("Should double the lockout with each further failure up to the maximum", func() {
+		failUntilLocked("jane")
+		Expect(lockout.Fail("jane")).To(Equal(2 * time.Minute))
+		Expect(lockout.Fail("jane")).To(Equal(4 * time.Minute))
+		Expect(lockout.Fail("jane")).To(Equal(8 * time.Minute))
+		Expect(lockout.Fail("jane")).To(Equal(10 * time.Minute))
+		Expect(lockout.Fail("jane")).To(Equal(10 * time.Minute))
+	})
+
+	It("Should reset a key after a success", func() {
+		failUntilLocked("jane")
+		lockout.Succeed("jane")
+		Expect(lockout.Check("jane")).To(BeZero())
+		failUntilLocked("jane")
+	})
+
+	It("Should forget the failures of a key after the maximum duration", func() {
+		Expect(lockout.Fail("jane")).To(BeZero())
+		Expect(lockout.Fail("jane")).To(BeZero())
+
+		now = now.Add(10 * time.Minute)
+		Expect(lockout.Fail("john")).To(BeZero())
+		failUntilLocked("jane")
+	})
+})
@@ -0,0 +1,537 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package reconcile
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	logger "github.com/open-ness/common/log"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "reconcile")
+
+const (
+	defaultELAPort = "42101"
+	defaultEVAPort = "42102"
+)
+
+// Defaults of the Reconciler.
+const (
+	DefaultNodeTimeout = time.Minute
+	DefaultConcurrency = 4
+)
+
+// Reconciler periodically compares the state persisted for each enrolled node
+// with the state the node reports and re-applies what the node is missing. It
+// keeps the drift found by the last pass for each node.
+type Reconciler struct {
+	Controller *cce.Controller
+
+	// Interval is the time between reconciliation passes.
+	Interval time.Duration
+
+	// NodeTimeout is the time reconciling a node may take, so that an
+	// unresponsive node does not hold up the pass. If zero
+	// DefaultNodeTimeout is used.
+	NodeTimeout time.Duration
+
+	// Concurrency is the number of nodes reconciled at once. If zero
+	// DefaultConcurrency is used.
+	Concurrency int
+
+	// Connect connects to a node on a port. If nil the node's gRPC target is
+	// looked up in persistence and dialed with the controller's edge node
+	// credentials.
+	Connect func(ctx context.Context, nodeID, port string) (*node.ClientConn, error)
+
+	mu    sync.RWMutex
+	drift map[string]*cce.NodeDrift
+}
+
+// Run reconciles all nodes every Interval until the context is done.
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := r.ReconcileAll(ctx); err != nil {
+			log.Errf("Error reconciling nodes: %v", err)
+		}
+	}
+}
+
+// ReconcileAll reconciles every enrolled node, Concurrency at a time and each
+// for at most NodeTimeout, and replaces the drift report with the results.
+func (r *Reconciler) ReconcileAll(ctx context.Context) error {
+	ps := r.Controller.PersistenceService
+
+	// Only nodes that enrolled have a gRPC target to reach them on
+	targets, err := ps.ReadAll(ctx, &cce.NodeGRPCTarget{})
+	if err != nil {
+		return errors.Wrap(err, "could not fetch gRPC targets from DB")
+	}
+
+	timeout := r.NodeTimeout
+	if timeout == 0 {
+		timeout = DefaultNodeTimeout
+	}
+	concurrency := r.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		drift   = make(map[string]*cce.NodeDrift)
+		workers = make(chan struct{}, concurrency)
+	)
+	for _, target := range targets {
+		nodeID := target.(*cce.NodeGRPCTarget).NodeID
+
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			nodeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			nodeDrift := r.ReconcileNode(nodeCtx, nodeID)
+			if !nodeDrift.InSync() {
+				log.Noticef("Node %s drifted from persistence: %+v", nodeID, nodeDrift)
+			}
+
+			mu.Lock()
+			drift[nodeID] = nodeDrift
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	r.drift = drift
+	r.mu.Unlock()
+
+	return nil
+}
+
+// ReconcileNode reconciles a single node and returns the drift found on it.
+func (r *Reconciler) ReconcileNode(ctx context.Context, nodeID string) *cce.NodeDrift {
+	drift := &cce.NodeDrift{
+		NodeID:    nodeID,
+		CheckedAt: time.Now(),
+	}
+
+	// The apps of a node with an app operation pending or running are left to
+	// it, so that e.g. an app being undeployed is not deployed again
+	inFlight, err := r.hasAppOperationsInFlight(ctx, nodeID)
+	if err != nil {
+		drift.Errors = append(drift.Errors, err.Error())
+	}
+	skipApps := inFlight || err != nil
+
+	if !skipApps {
+		if err := r.reconcileApps(ctx, drift); err != nil {
+			drift.Errors = append(drift.Errors, err.Error())
+		}
+	}
+	if err := r.reconcilePoliciesAndDNS(ctx, drift, skipApps); err != nil {
+		drift.Errors = append(drift.Errors, err.Error())
+	}
+
+	r.mu.Lock()
+	if r.drift == nil {
+		r.drift = make(map[string]*cce.NodeDrift)
+	}
+	r.drift[nodeID] = drift
+	r.mu.Unlock()
+
+	return drift
+}
+
+// DriftReport returns the drift found the last time each node was reconciled,
+// ordered by node ID.
+func (r *Reconciler) DriftReport() []*cce.NodeDrift {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := []*cce.NodeDrift{}
+	for _, drift := range r.drift {
+		report = append(report, drift)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].NodeID < report[j].NodeID
+	})
+
+	return report
+}
+
+// reconcileApps deploys the apps in nodes_apps that the node does not know
+// about, except those still being deployed.
+func (r *Reconciler) reconcileApps(ctx context.Context, drift *cce.NodeDrift) error {
+	ps := r.Controller.PersistenceService
+
+	nodeApps, err := ps.Filter(
+		ctx,
+		&cce.NodeApp{},
+		[]cce.Filter{
+			{
+				Field: "node_id",
+				Value: drift.NodeID,
+			},
+		})
+	if err != nil {
+		return errors.Wrap(err, "could not fetch nodes_apps from DB")
+	}
+	if len(nodeApps) == 0 {
+		return nil
+	}
+
+	nodePort := r.Controller.EVAPort
+	if nodePort == "" {
+		nodePort = defaultEVAPort
+	}
+	nodeCC, err := r.connect(ctx, drift.NodeID, nodePort)
+	if err != nil {
+		return err
+	}
+	defer nodeCC.Disconnect()
+
+	for _, nodeApp := range nodeApps {
+		if nodeApp.(*cce.NodeApp).Deploying {
+			continue
+		}
+		appID := nodeApp.(*cce.NodeApp).AppID
+
+		appStatus, err := nodeCC.AppLifeSvcCli.GetStatus(ctx, appID)
+		if err == nil {
+			r.publishAppStatus(nodeApp.(*cce.NodeApp), appStatus)
+			continue
+		}
+		if s, ok := status.FromError(errors.Cause(err)); !ok || s.Code() != codes.NotFound {
+			drift.Errors = append(drift.Errors, errors.Wrapf(err, "app %s", appID).Error())
+			continue
+		}
+
+		drift.MissingApps = append(drift.MissingApps, appID)
+		if err := r.deployApp(ctx, nodeCC, drift.NodeID, appID); err != nil {
+			drift.Errors = append(drift.Errors, errors.Wrapf(err, "app %s", appID).Error())
+		}
+	}
+
+	return nil
+}
+
+// hasAppOperationsInFlight returns whether an operation on the apps of a node
+// is pending or running.
+func (r *Reconciler) hasAppOperationsInFlight(ctx context.Context, nodeID string) (bool, error) {
+	ops, err := r.Controller.PersistenceService.Filter(
+		ctx,
+		&cce.Operation{},
+		[]cce.Filter{
+			{
+				Field: "node_id",
+				Value: nodeID,
+			},
+			{
+				Field:  "type",
+				Op:     cce.FilterOpIn,
+				Values: []string{cce.OperationDeployApp, cce.OperationUpdateApp, cce.OperationUndeployApp},
+			},
+			{
+				Field:  "status",
+				Op:     cce.FilterOpIn,
+				Values: []string{cce.OperationPending, cce.OperationRunning},
+			},
+		})
+	if err != nil {
+		return false, errors.Wrap(err, "could not fetch operations from DB")
+	}
+
+	return len(ops) != 0, nil
+}
+
+// publishAppStatus publishes the status of a node app reported by the node.
+// In Kubernetes modes the node only knows the status of apps that are not
+// deployed yet or failed, the rest being up to Kubernetes.
+func (r *Reconciler) publishAppStatus(nodeApp *cce.NodeApp, s cce.LifecycleStatus) {
+	if r.Controller.Events == nil {
+		return
+	}
+	if r.Controller.OrchestrationMode != cce.OrchestrationModeNative {
+		switch s {
+		case cce.Unknown, cce.Deploying, cce.Error:
+		default:
+			return
+		}
+	}
+
+	r.Controller.Events.PublishAppStatus(&events.Event{
+		NodeID:     nodeApp.NodeID,
+		EntityType: nodeApp.GetTableName(),
+		EntityID:   nodeApp.ID,
+	}, nodeApp.AppID, s.String())
+}
+
+func (r *Reconciler) deployApp(ctx context.Context, nodeCC *node.ClientConn, nodeID, appID string) error {
+	app, err := r.Controller.PersistenceService.Read(ctx, appID, &cce.App{})
+	if err != nil {
+		return err
+	}
+	if app == nil {
+		return errors.New("app not found in DB")
+	}
+
+	if err := nodeCC.AppDeploySvcCli.Deploy(ctx, app.(*cce.App)); err != nil {
+		return err
+	}
+
+	if r.Controller.OrchestrationMode == cce.OrchestrationModeKubernetes ||
+		r.Controller.OrchestrationMode == cce.OrchestrationModeKubernetesOVN {
+		return r.Controller.KubernetesClient.Deploy(ctx, nodeID, app.(*cce.App).K8SApp())
+	}
+
+	return nil
+}
+
+// reconcilePoliciesAndDNS re-applies the traffic policies and DNS records
+// persisted for the node. The node cannot report these, so they are always
+// set again; only the network interfaces they refer to are checked. The
+// policies of apps being deployed, or of all apps if skipApps is set, are
+// not re-applied.
+func (r *Reconciler) reconcilePoliciesAndDNS( //nolint:gocyclo
+	ctx context.Context,
+	drift *cce.NodeDrift,
+	skipApps bool,
+) error {
+	ps := r.Controller.PersistenceService
+	ovn := r.Controller.OrchestrationMode == cce.OrchestrationModeKubernetesOVN
+
+	nodeIfacePolicies, err := ps.Filter(
+		ctx,
+		&cce.NodeInterfaceTrafficPolicy{},
+		[]cce.Filter{
+			{
+				Field: "node_id",
+				Value: drift.NodeID,
+			},
+		})
+	if err != nil {
+		return errors.Wrap(err, "could not fetch nodes_network_interfaces_traffic_policies from DB")
+	}
+
+	// Kube-OVN app policies are Kubernetes network policies, not node state
+	var nodeAppPolicies []*cce.NodeAppTrafficPolicy
+	appIDs := make(map[string]string)
+	if !ovn && !skipApps {
+		nodeApps, err := ps.Filter(
+			ctx,
+			&cce.NodeApp{},
+			[]cce.Filter{
+				{
+					Field: "node_id",
+					Value: drift.NodeID,
+				},
+			})
+		if err != nil {
+			return errors.Wrap(err, "could not fetch nodes_apps from DB")
+		}
+		var nodeAppIDs []string
+		for _, nodeApp := range nodeApps {
+			if nodeApp.(*cce.NodeApp).Deploying {
+				continue
+			}
+			appIDs[nodeApp.GetID()] = nodeApp.(*cce.NodeApp).AppID
+			nodeAppIDs = append(nodeAppIDs, nodeApp.GetID())
+		}
+
+		policies, err := ps.Filter(
+			ctx,
+			&cce.NodeAppTrafficPolicy{},
+			[]cce.Filter{
+				{
+					Field:  "nodes_apps_id",
+					Op:     cce.FilterOpIn,
+					Values: nodeAppIDs,
+				},
+			})
+		if err != nil {
+			return errors.Wrap(err, "could not fetch nodes_apps_traffic_policies from DB")
+		}
+		for _, policy := range policies {
+			nodeAppPolicies = append(nodeAppPolicies, policy.(*cce.NodeAppTrafficPolicy))
+		}
+	}
+
+	dnsConfig, dnsAliases, err := r.readDNSConfig(ctx, drift.NodeID)
+	if err != nil {
+		return err
+	}
+
+	if len(nodeIfacePolicies) == 0 && len(nodeAppPolicies) == 0 && dnsConfig == nil {
+		return nil
+	}
+
+	nodePort := r.Controller.ELAPort
+	if nodePort == "" {
+		nodePort = defaultELAPort
+	}
+	nodeCC, err := r.connect(ctx, drift.NodeID, nodePort)
+	if err != nil {
+		return err
+	}
+	defer nodeCC.Disconnect()
+
+	if len(nodeIfacePolicies) != 0 {
+		ifaces, err := nodeCC.IfaceSvcCli.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		ifaceIDs := make(map[string]bool)
+		for _, iface := range ifaces {
+			ifaceIDs[iface.ID] = true
+		}
+
+		for _, nitp := range nodeIfacePolicies {
+			ifaceID := nitp.(*cce.NodeInterfaceTrafficPolicy).NetworkInterfaceID
+			if !ifaceIDs[ifaceID] {
+				drift.MissingInterfaces = append(drift.MissingInterfaces, ifaceID)
+				continue
+			}
+
+			tp, err := r.readTrafficPolicy(ctx, nitp.(*cce.NodeInterfaceTrafficPolicy).TrafficPolicyID)
+			if err == nil {
+				err = nodeCC.IfacePolicySvcCli.Set(ctx, ifaceID, tp)
+			}
+			if err != nil {
+				drift.Errors = append(drift.Errors, errors.Wrapf(err, "interface %s", ifaceID).Error())
+				continue
+			}
+			drift.ReappliedInterfacePolicies++
+		}
+	}
+
+	for _, natp := range nodeAppPolicies {
+		appID := appIDs[natp.NodeAppID]
+
+		tp, err := r.readTrafficPolicy(ctx, natp.TrafficPolicyID)
+		if err == nil {
+			err = nodeCC.AppPolicySvcCli.Set(ctx, appID, tp)
+		}
+		if err != nil {
+			drift.Errors = append(drift.Errors, errors.Wrapf(err, "app %s", appID).Error())
+			continue
+		}
+		drift.ReappliedAppPolicies++
+	}
+
+	if dnsConfig != nil {
+		records := append([]*cce.DNSARecord(nil), dnsConfig.ARecords...)
+		for _, alias := range dnsAliases {
+			records = append(records, alias.(*cce.DNSConfigAppAlias).ARecord())
+		}
+		for _, record := range records {
+			if err := nodeCC.DNSSvcCli.SetA(ctx, record); err != nil {
+				drift.Errors = append(drift.Errors, errors.Wrapf(err, "a record %s", record.Name).Error())
+				continue
+			}
+			drift.ReappliedDNSRecords++
+		}
+
+		if len(dnsConfig.Forwarders) != 0 {
+			if err := nodeCC.DNSSvcCli.SetForwarders(ctx, dnsConfig.Forwarders); err != nil {
+				drift.Errors = append(drift.Errors, errors.Wrap(err, "forwarders").Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// readDNSConfig returns the DNS config of a node and its app aliases, or nil
+// if the node has none.
+func (r *Reconciler) readDNSConfig(
+	ctx context.Context,
+	nodeID string,
+) (*cce.DNSConfig, []cce.Persistable, error) {
+	ps := r.Controller.PersistenceService
+
+	nodeDNSConfigs, err := ps.Filter(
+		ctx,
+		&cce.NodeDNSConfig{},
+		[]cce.Filter{
+			{
+				Field: "node_id",
+				Value: nodeID,
+			},
+		})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not fetch nodes_dns_configs from DB")
+	}
+	if len(nodeDNSConfigs) == 0 {
+		return nil, nil, nil
+	}
+
+	dnsConfigID := nodeDNSConfigs[0].(*cce.NodeDNSConfig).DNSConfigID
+	dnsConfig, err := ps.Read(ctx, dnsConfigID, &cce.DNSConfig{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not fetch dns_configs from DB")
+	}
+	if dnsConfig == nil {
+		return nil, nil, errors.Errorf("dns config %s not found in DB", dnsConfigID)
+	}
+
+	dnsAliases, err := ps.Filter(
+		ctx,
+		&cce.DNSConfigAppAlias{},
+		[]cce.Filter{
+			{
+				Field: "dns_config_id",
+				Value: dnsConfigID,
+			},
+		})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not fetch dns_configs_app_aliases from DB")
+	}
+
+	return dnsConfig.(*cce.DNSConfig), dnsAliases, nil
+}
+
+func (r *Reconciler) readTrafficPolicy(ctx context.Context, id string) (*cce.TrafficPolicy, error) {
+	tp, err := r.Controller.PersistenceService.Read(ctx, id, &cce.TrafficPolicy{})
+	if err != nil {
+		return nil, err
+	}
+	if tp == nil {
+		return nil, errors.Errorf("traffic policy %s not found in DB", id)
+	}
+
+	return tp.(*cce.TrafficPolicy), nil
+}
+
+func (r *Reconciler) connect(ctx context.Context, nodeID, port string) (*node.ClientConn, error) {
+	if r.Connect != nil {
+		return r.Connect(ctx, nodeID, port)
+	}
+
+	return node.Dial(ctx, r.Controller.PersistenceService, nodeID, port, r.Controller.EdgeNodeCreds)
+}
@@ -0,0 +1,272 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package reconcile_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
+	gclients "github.com/open-ness/edgecontroller/grpc/clients"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/open-ness/edgecontroller/internal/stubs"
+	ctrlgmock "github.com/open-ness/edgecontroller/mock/controller/grpc"
+	nodegmock "github.com/open-ness/edgecontroller/mock/node/grpc"
+	"github.com/open-ness/edgecontroller/reconcile"
+)
+
+var _ = Describe("Reconciler", func() {
+	var (
+		ctx        = context.Background()
+		mockNode   *nodegmock.MockNode
+		ps         *stubs.PersistenceServiceStub
+		reconciler *reconcile.Reconciler
+	)
+
+	const (
+		nodeID   = "1a6a3a24-5a29-4a47-a53c-60a9ea7f6e7a"
+		appID    = "5b10a2ba-6a0d-4c5a-a2a3-0c7a3b8f1e2d"
+		policyID = "9d5c3f4e-7a4b-4a3c-8f2e-1b6d0e9c8a7f"
+		configID = "3c8f9e0d-1a2b-4c3d-9e8f-7a6b5c4d3e2f"
+	)
+
+	BeforeEach(func() {
+		mockNode = nodegmock.NewMockNode()
+		ps = &stubs.PersistenceServiceStub{Tables: make(map[string][]cce.Persistable)}
+		reconciler = &reconcile.Reconciler{
+			Controller: &cce.Controller{PersistenceService: ps},
+			Connect: func(ctx context.Context, nodeID, port string) (*node.ClientConn, error) {
+				return &node.ClientConn{
+					AppDeploySvcCli: &gclients.ApplicationDeploymentServiceClient{
+						PBCli: &ctrlgmock.MockPBApplicationDeploymentServiceClient{MockNode: mockNode},
+					},
+					AppLifeSvcCli: &gclients.ApplicationLifecycleServiceClient{
+						PBCli: &ctrlgmock.MockPBApplicationLifecycleServiceClient{MockNode: mockNode},
+					},
+					AppPolicySvcCli: &gclients.ApplicationPolicyServiceClient{
+						PBCli: &ctrlgmock.MockPBApplicationPolicyServiceClient{MockNode: mockNode},
+					},
+					IfacePolicySvcCli: &gclients.InterfacePolicyServiceClient{
+						PBCli: &ctrlgmock.MockPBInterfacePolicyServiceClient{MockNode: mockNode},
+					},
+					IfaceSvcCli: &gclients.InterfaceServiceClient{
+						PBCli: &ctrlgmock.MockPBInterfaceServiceClient{MockNode: mockNode},
+					},
+					DNSSvcCli: &gclients.DNSServiceClient{
+						PBCli: &ctrlgmock.MockPBDNSServiceClient{MockNode: mockNode},
+					},
+				}, nil
+			},
+		}
+
+		Expect(ps.Create(ctx, &cce.NodeGRPCTarget{
+			ID:         "7e6d5c4b-3a29-4180-9f8e-7d6c5b4a3928",
+			NodeID:     nodeID,
+			GRPCTarget: "127.0.0.1:42101",
+		})).To(Succeed())
+	})
+
+	Describe("ReconcileNode", func() {
+		BeforeEach(func() {
+			Expect(ps.Create(ctx, &cce.App{
+				ID:     appID,
+				Type:   "container",
+				Name:   "test_app",
+				Cores:  4,
+				Memory: 1024,
+				Source: "http://www.test.com/my_file.zip",
+			})).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeApp{
+				ID:     "2f1e0d9c-8b7a-4659-8483-7261504f3e2d",
+				NodeID: nodeID,
+				AppID:  appID,
+			})).To(Succeed())
+			Expect(ps.Create(ctx, &cce.TrafficPolicy{
+				ID: policyID,
+			})).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeAppTrafficPolicy{
+				ID:              "8a7b6c5d-4e3f-4a1b-9c8d-7e6f5a4b3c2d",
+				NodeAppID:       "2f1e0d9c-8b7a-4659-8483-7261504f3e2d",
+				TrafficPolicyID: policyID,
+			})).To(Succeed())
+		})
+
+		It("Should redeploy missing apps and report them", func() {
+			By("Reconciling a node that lost its app")
+			drift := reconciler.ReconcileNode(ctx, nodeID)
+			Expect(drift.Errors).To(BeEmpty())
+			Expect(drift.MissingApps).To(ConsistOf(appID))
+			Expect(drift.ReappliedAppPolicies).To(Equal(1))
+			Expect(drift.InSync()).To(BeFalse())
+
+			By("Verifying the app was deployed to the node")
+			nodeCC, err := reconciler.Connect(ctx, nodeID, "42102")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = nodeCC.AppLifeSvcCli.GetStatus(ctx, appID)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Reconciling the node again")
+			drift = reconciler.ReconcileNode(ctx, nodeID)
+			Expect(drift.Errors).To(BeEmpty())
+			Expect(drift.MissingApps).To(BeEmpty())
+			Expect(drift.InSync()).To(BeTrue())
+		})
+
+		It("Should not redeploy apps with an operation in flight", func() {
+			Expect(ps.Create(ctx, &cce.Operation{
+				ID:     "5e4d3c2b-1a09-4f8e-8d7c-6b5a49382716",
+				Type:   cce.OperationUndeployApp,
+				NodeID: nodeID,
+				Status: cce.OperationRunning,
+			})).To(Succeed())
+
+			drift := reconciler.ReconcileNode(ctx, nodeID)
+			Expect(drift.Errors).To(BeEmpty())
+			Expect(drift.MissingApps).To(BeEmpty())
+
+			By("Verifying the app was not deployed to the node")
+			nodeCC, err := reconciler.Connect(ctx, nodeID, "42102")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = nodeCC.AppLifeSvcCli.GetStatus(ctx, appID)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should not redeploy apps being deployed", func() {
+			nodeApp, err := ps.Read(ctx, "2f1e0d9c-8b7a-4659-8483-7261504f3e2d", &cce.NodeApp{})
+			Expect(err).ToNot(HaveOccurred())
+			nodeApp.(*cce.NodeApp).Deploying = true
+
+			drift := reconciler.ReconcileNode(ctx, nodeID)
+			Expect(drift.Errors).To(BeEmpty())
+			Expect(drift.MissingApps).To(BeEmpty())
+		})
+
+		It("Should publish the status of the node's apps", func() {
+			reconciler.Controller.Events = events.NewBus(10)
+			sub := reconciler.Controller.Events.Subscribe(events.Filter{Types: []string{events.TypeAppStatus}})
+
+			By("Reconciling a node that lost its app")
+			reconciler.ReconcileNode(ctx, nodeID)
+			Expect(sub.C).ToNot(Receive())
+
+			By("Reconciling the node again")
+			reconciler.ReconcileNode(ctx, nodeID)
+			var e *events.Event
+			Expect(sub.C).To(Receive(&e))
+			Expect(e.NodeID).To(Equal(nodeID))
+			Expect(e.EntityID).To(Equal("2f1e0d9c-8b7a-4659-8483-7261504f3e2d"))
+			Expect(e.Data).To(Equal(events.AppStatus{AppID: appID, Status: "deployed"}))
+
+			By("Reconciling the node with the same status")
+			reconciler.ReconcileNode(ctx, nodeID)
+			Expect(sub.C).ToNot(Receive())
+		})
+
+		It("Should report interfaces missing on the node", func() {
+			Expect(ps.Create(ctx, &cce.NodeInterfaceTrafficPolicy{
+				ID:                 "4d3c2b1a-0f9e-4d8c-b7a6-5f4e3d2c1b0a",
+				NodeID:             nodeID,
+				NetworkInterfaceID: "if0",
+				TrafficPolicyID:    policyID,
+			})).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeInterfaceTrafficPolicy{
+				ID:                 "6f5e4d3c-2b1a-4098-8f7e-6d5c4b3a2918",
+				NodeID:             nodeID,
+				NetworkInterfaceID: "if9",
+				TrafficPolicyID:    policyID,
+			})).To(Succeed())
+
+			drift := reconciler.ReconcileNode(ctx, nodeID)
+			Expect(drift.Errors).To(BeEmpty())
+			Expect(drift.MissingInterfaces).To(ConsistOf("if9"))
+			Expect(drift.ReappliedInterfacePolicies).To(Equal(1))
+		})
+
+		It("Should reapply the node's DNS records", func() {
+			Expect(ps.Create(ctx, &cce.DNSConfig{
+				ID:   configID,
+				Name: "dns config",
+				ARecords: []*cce.DNSARecord{
+					{
+						Name: "a-record-1",
+						IPs:  []string{"172.16.55.43"},
+					},
+				},
+				Forwarders: []*cce.DNSForwarder{
+					{
+						Name: "forwarder-1",
+						IP:   "8.8.8.8",
+					},
+				},
+			})).To(Succeed())
+			Expect(ps.Create(ctx, &cce.DNSConfigAppAlias{
+				ID:          "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d",
+				DNSConfigID: configID,
+				Name:        "alias",
+				Description: "app alias",
+				AppID:       appID,
+			})).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeDNSConfig{
+				ID:          "1b2c3d4e-5f6a-4b7c-9d8e-0f1a2b3c4d5e",
+				NodeID:      nodeID,
+				DNSConfigID: configID,
+			})).To(Succeed())
+
+			drift := reconciler.ReconcileNode(ctx, nodeID)
+			Expect(drift.Errors).To(BeEmpty())
+			Expect(drift.ReappliedDNSRecords).To(Equal(2))
+		})
+	})
+
+	Describe("ReconcileAll", func() {
+		It("Should time out reconciling an unresponsive node", func() {
+			const hungNodeID = "6c5b4a39-2817-4f6e-9d5c-4b3a29180f7e"
+			Expect(ps.Create(ctx, &cce.NodeGRPCTarget{
+				ID:         "8e7d6c5b-4a39-4281-8f7e-6d5c4b3a2918",
+				NodeID:     hungNodeID,
+				GRPCTarget: "127.0.0.1:42201",
+			})).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeApp{
+				ID:     "9f8e7d6c-5b4a-4392-8a1b-0c9d8e7f6a5b",
+				NodeID: hungNodeID,
+				AppID:  appID,
+			})).To(Succeed())
+
+			connect := reconciler.Connect
+			reconciler.Connect = func(ctx context.Context, nodeID, port string) (*node.ClientConn, error) {
+				if nodeID == hungNodeID {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return connect(ctx, nodeID, port)
+			}
+			reconciler.NodeTimeout = 50 * time.Millisecond
+
+			Expect(reconciler.ReconcileAll(ctx)).To(Succeed())
+
+			report := reconciler.DriftReport()
+			Expect(report).To(HaveLen(2))
+			Expect(report[0].NodeID).To(Equal(nodeID))
+			Expect(report[0].InSync()).To(BeTrue())
+			Expect(report[1].NodeID).To(Equal(hungNodeID))
+			Expect(report[1].Errors).To(ConsistOf(ContainSubstring("deadline exceeded")))
+		})
+	})
+
+	Describe("DriftReport", func() {
+		It("Should report the last reconciliation of each enrolled node", func() {
+			Expect(reconciler.DriftReport()).To(BeEmpty())
+
+			Expect(reconciler.ReconcileAll(ctx)).To(Succeed())
+
+			report := reconciler.DriftReport()
+			Expect(report).To(HaveLen(1))
+			Expect(report[0].NodeID).To(Equal(nodeID))
+			Expect(report[0].InSync()).To(BeTrue())
+		})
+	})
+})
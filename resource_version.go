@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import "errors"
+
+// ErrVersionConflict is returned by PersistenceService.BulkUpdate and Delete
+// when the version of an entity does not match the persisted version.
+var ErrVersionConflict = errors.New("resource version conflict")
+
+// ErrDuplicateEntry is returned by PersistenceService.Create when an entity
+// with the same ID or unique key is persisted.
+var ErrDuplicateEntry = errors.New("duplicate entry")
+
+// ResourceVersion is embedded in persisted entities. The version is set by
+// the PersistenceService to 1 on Create and incremented on every update. It
+// is not part of the entity's JSON.
+//
+// An entity passed to BulkUpdate with a non-zero version is only updated if
+// the persisted version matches. A zero-value entity passed to Delete with a
+// non-zero version is only deleted if the persisted version matches.
+type ResourceVersion struct {
+	Value int64 `json:"-"`
+}
+
+// GetResourceVersion gets the resource version.
+func (v *ResourceVersion) GetResourceVersion() int64 {
+	return v.Value
+}
+
+// SetResourceVersion sets the resource version.
+func (v *ResourceVersion) SetResourceVersion(version int64) {
+	v.Value = version
+}
+
+// Versioned is an entity with a resource version.
+type Versioned interface {
+	GetResourceVersion() int64
+	SetResourceVersion(version int64)
+}
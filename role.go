@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+// Permission is a permission on the REST API.
+type Permission string
+
+const (
+	// PermissionRead allows reading resources.
+	PermissionRead Permission = "read"
+	// PermissionWrite allows creating, updating and deleting resources.
+	PermissionWrite Permission = "write"
+	// PermissionAdmin allows managing users and reading the audit log.
+	PermissionAdmin Permission = "admin"
+)
+
+// Roles of users.
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+// rolePermissions are the permissions of the roles.
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:    {PermissionRead, PermissionWrite, PermissionAdmin},
+	RoleOperator: {PermissionRead, PermissionWrite},
+	RoleViewer:   {PermissionRead},
+}
+
+// IsRole returns whether a role exists.
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission returns whether any of the roles has a permission.
+func HasPermission(roles []string, p Permission) bool {
+	for _, role := range roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == p {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CoversRoles returns whether the roles have all the permissions of the other
+// roles.
+func CoversRoles(roles, other []string) bool {
+	for _, role := range other {
+		for _, p := range rolePermissions[role] {
+			if !HasPermission(roles, p) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Roles", func() {
+	Describe("IsRole", func() {
+		It("Should only return true for the roles", func() {
+			Expect(cce.IsRole(cce.RoleAdmin)).To(BeTrue())
+			Expect(cce.IsRole(cce.RoleOperator)).To(BeTrue())
+			Expect(cce.IsRole(cce.RoleViewer)).To(BeTrue())
+			Expect(cce.IsRole("root")).To(BeFalse())
+		})
+	})
+
+	DescribeTable("HasPermission",
+		func(roles []string, read, write, admin bool) {
+			Expect(cce.HasPermission(roles, cce.PermissionRead)).To(Equal(read))
+			Expect(cce.HasPermission(roles, cce.PermissionWrite)).To(Equal(write))
+			Expect(cce.HasPermission(roles, cce.PermissionAdmin)).To(Equal(admin))
+		},
+		Entry("admin", []string{cce.RoleAdmin}, true, true, true),
+		Entry("operator", []string{cce.RoleOperator}, true, true, false),
+		Entry("viewer", []string{cce.RoleViewer}, true, false, false),
+		Entry("viewer and operator", []string{cce.RoleViewer, cce.RoleOperator}, true, true, false),
+		Entry("unknown role", []string{"root"}, false, false, false),
+		Entry("no roles", nil, false, false, false),
+	)
+
+	DescribeTable("CoversRoles",
+		func(roles, other []string, covers bool) {
+			Expect(cce.CoversRoles(roles, other)).To(Equal(covers))
+		},
+		Entry("same roles", []string{cce.RoleOperator}, []string{cce.RoleOperator}, true),
+		Entry("more roles", []string{cce.RoleAdmin}, []string{cce.RoleOperator, cce.RoleViewer}, true),
+		Entry("fewer roles", []string{cce.RoleViewer}, []string{cce.RoleAdmin}, false),
+		Entry("some roles", []string{cce.RoleOperator}, []string{cce.RoleViewer, cce.RoleAdmin}, false),
+		Entry("no other roles", nil, nil, true),
+	)
+})
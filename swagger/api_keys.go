@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package swagger
+
+import "time"
+
+// APIKeySummary is a summary representation of an API key.
+type APIKeySummary struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Roles      []string   `json:"roles"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// APIKeyDetail is a detailed representation of a created API key. The key is
+// only returned when the API key is created.
+type APIKeyDetail struct {
+	APIKeySummary
+	Key string `json:"key"`
+}
+
+// APIKeyCreate is the representation of an API key to create. The roles
+// default to the roles of the user and the expiry to 90 days.
+type APIKeyCreate struct {
+	Name      string     `json:"name"`
+	Roles     []string   `json:"roles,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyList is a list representation of API keys.
+type APIKeyList struct {
+	APIKeys []APIKeySummary `json:"api_keys"`
+	ListPage
+}
@@ -30,4 +30,5 @@ type AppDetail struct {
 // AppList is a list representation of apps.
 type AppList struct {
 	Apps []AppSummary `json:"apps"`
+	ListPage
 }
@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package swagger
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent is a representation of an audit event.
+type AuditEvent struct {
+	ID         string                 `json:"id"`
+	Time       time.Time              `json:"time"`
+	Actor      string                 `json:"actor"`
+	Action     string                 `json:"action"`
+	Request    string                 `json:"request"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	Diff       map[string]AuditChange `json:"diff,omitempty"`
+	SourceIP   string                 `json:"source_ip"`
+}
+
+// AuditChange is a representation of the change of a field in an audit event.
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditEventList is a list representation of audit events.
+type AuditEventList struct {
+	Events []AuditEvent `json:"events"`
+	ListPage
+}
@@ -7,3 +7,11 @@ package swagger
 type BaseResource struct {
 	ID string `json:"id"`
 }
+
+// ListPage is the page of a list representation.
+type ListPage struct {
+	// Next is the link to the next page, if there is one.
+	Next string `json:"next,omitempty"`
+	// Total is the number of resources on all pages.
+	Total int `json:"total"`
+}
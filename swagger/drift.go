@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package swagger
+
+import "time"
+
+// NodeDrift is how a node differed from persistence when it was last
+// reconciled.
+type NodeDrift struct {
+	ID                         string    `json:"id"`
+	CheckedAt                  time.Time `json:"checked_at"`
+	InSync                     bool      `json:"in_sync"`
+	MissingApps                []string  `json:"missing_apps"`
+	MissingInterfaces          []string  `json:"missing_interfaces"`
+	ReappliedAppPolicies       int       `json:"reapplied_app_policies"`
+	ReappliedInterfacePolicies int       `json:"reapplied_interface_policies"`
+	ReappliedDNSRecords        int       `json:"reapplied_dns_records"`
+	Errors                     []string  `json:"errors,omitempty"`
+}
+
+// DriftList is a list of node drift reports.
+type DriftList struct {
+	Nodes []NodeDrift `json:"nodes"`
+}
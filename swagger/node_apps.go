@@ -18,4 +18,5 @@ type NodeAppDetail struct {
 // NodeAppList is a list representation of node apps.
 type NodeAppList struct {
 	NodeApps []NodeAppSummary `json:"apps"`
+	ListPage
 }
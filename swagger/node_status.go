@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package swagger
+
+const (
+	// NodeStatusApplied means the change was applied on the node.
+	NodeStatusApplied = "applied"
+	// NodeStatusFailed means the change could not be applied on the node.
+	NodeStatusFailed = "failed"
+)
+
+// NodeStatus is the result of pushing a change to a single node.
+type NodeStatus struct {
+	ID     string   `json:"id"`
+	Status string   `json:"status"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// NodeStatusList is a list of per-node results of pushing a change to the
+// nodes it affects.
+type NodeStatusList struct {
+	Nodes []NodeStatus `json:"nodes"`
+}
@@ -19,4 +19,5 @@ type NodeDetail struct {
 // NodeList is a list representation of nodes.
 type NodeList struct {
 	Nodes []NodeSummary `json:"nodes"`
+	ListPage
 }
@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package swagger
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Operation is a representation of a request that changes a node, which runs
+// in the background. ResponseStatus and Result are the status code and body
+// of the response the request would have had if it had not.
+type Operation struct {
+	ID             string          `json:"id"`
+	Type           string          `json:"type"`
+	NodeID         string          `json:"node_id"`
+	Actor          string          `json:"actor"`
+	Request        string          `json:"request"`
+	Time           time.Time       `json:"time"`
+	Status         string          `json:"status"`
+	Progress       string          `json:"progress,omitempty"`
+	StartedAt      *time.Time      `json:"started_at,omitempty"`
+	FinishedAt     *time.Time      `json:"finished_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Result         json.RawMessage `json:"result,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// OperationList is a list representation of operations.
+type OperationList struct {
+	Operations []Operation `json:"operations"`
+	ListPage
+}
@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package swagger
+
+import "encoding/json"
+
+// Plan is a representation of what a request made with dry_run=true would
+// have done. Status and Response are the status code and body of the response
+// it would have had.
+type Plan struct {
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Writes   []PlannedWrite  `json:"writes"`
+	Calls    []PlannedCall   `json:"calls"`
+}
+
+// PlannedWrite is a representation of a row a dry run would have written,
+// with the changes of its fields as in audit events.
+type PlannedWrite struct {
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	NodeID     string                 `json:"node_id,omitempty"`
+	Diff       map[string]AuditChange `json:"diff,omitempty"`
+}
+
+// PlannedCall is a representation of a call a dry run would have made to a
+// node. Service is the full name of a gRPC service of the node, or
+// "kubernetes" for calls to the Kubernetes master.
+type PlannedCall struct {
+	NodeID  string          `json:"node_id"`
+	Service string          `json:"service"`
+	Method  string          `json:"method"`
+	Request json.RawMessage `json:"request,omitempty"`
+}
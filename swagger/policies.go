@@ -29,4 +29,5 @@ type PolicyKubeOVNDetail struct {
 // PolicyList is a list representation of traffic policies.
 type PolicyList struct {
 	Policies []PolicySummary `json:"policies"`
+	ListPage
 }
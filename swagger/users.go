@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package swagger
+
+import "time"
+
+// UserSummary is a summary representation of a user.
+type UserSummary struct {
+	Name           string   `json:"name"`
+	Roles          []string `json:"roles"`
+	ServiceAccount bool     `json:"service_account"`
+}
+
+// UserDetail is a detailed representation of a user.
+type UserDetail struct {
+	UserSummary
+}
+
+// UserCreate is the representation of a user to create. Service accounts have
+// no password.
+type UserCreate struct {
+	Name           string   `json:"name"`
+	Password       string   `json:"password,omitempty"`
+	Roles          []string `json:"roles"`
+	ServiceAccount bool     `json:"service_account,omitempty"`
+}
+
+// UserUpdate is the representation of an update of a user's roles.
+type UserUpdate struct {
+	Roles []string `json:"roles"`
+}
+
+// UserList is a list representation of users.
+type UserList struct {
+	Users []UserSummary `json:"users"`
+	ListPage
+}
+
+// UserPassword is the representation of a change of a user's password. The
+// current password is required when users change their own password.
+type UserPassword struct {
+	CurrentPassword string `json:"current_password,omitempty"`
+	Password        string `json:"password"`
+}
+
+// UserCertificateRequest is the representation of a request for a client
+// certificate of a user.
+type UserCertificateRequest struct {
+	// CSR is the PEM-encoded certificate signing request.
+	CSR string `json:"csr"`
+}
+
+// UserCertificate is the representation of a client certificate of a user.
+type UserCertificate struct {
+	// Certificate is the PEM-encoded certificate.
+	Certificate string `json:"certificate"`
+
+	// CAChain is the PEM-encoded chain of the issuing CA, ending with the root
+	// CA.
+	CAChain []string `json:"ca_chain"`
+
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package swagger
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookSummary is a summary representation of a webhook. The secret is
+// never returned.
+type WebhookSummary struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+// WebhookDetail is a detailed representation of a webhook. The secret is only
+// returned when it was generated for a created webhook.
+type WebhookDetail struct {
+	WebhookSummary
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookCreate is the representation of a webhook to create. A secret is
+// generated if none is given. No event types is all event types.
+type WebhookCreate struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types,omitempty"`
+	Secret     string   `json:"secret,omitempty"`
+}
+
+// WebhookUpdate is the representation of an update of a webhook. Only the
+// given fields are changed.
+type WebhookUpdate struct {
+	Name       *string   `json:"name,omitempty"`
+	URL        *string   `json:"url,omitempty"`
+	EventTypes *[]string `json:"event_types,omitempty"`
+	Secret     *string   `json:"secret,omitempty"`
+}
+
+// WebhookList is a list representation of webhooks.
+type WebhookList struct {
+	Webhooks []WebhookSummary `json:"webhooks"`
+	ListPage
+}
+
+// WebhookDelivery is a representation of the delivery of an event to a
+// webhook.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	Time           time.Time       `json:"time"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// WebhookDeliveryList is a list representation of the deliveries to a
+// webhook.
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	ListPage
+}
@@ -12,6 +12,8 @@ import (
 	"sync"
 
 	logger "github.com/open-ness/common/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 const (
@@ -30,6 +32,11 @@ const MaxUDPPacketSize = 64*1024 - 8 - 20
 
 var log = logger.DefaultLogger.WithField("pkg", "telemetry")
 
+var lines = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cce_telemetry_lines_total",
+	Help: "Telemetry lines received by stream, which is syslog or statsd.",
+}, []string{"stream"})
+
 // AcceptTCP wraps the Accept method on a TCP listener.
 func AcceptTCP(lis net.Listener) func() (io.ReadCloser, error) {
 	// TODO: consider removing any deadlines on the listener
@@ -108,6 +115,23 @@ func (nc *notifyCloser) Close() error {
 	return err
 }
 
+// CountLines returns a writer that writes to w and counts the lines of a
+// stream in the metrics. WriteToByLine writes one line at a time, so each
+// write is counted as a line.
+func CountLines(w io.Writer, stream string) io.Writer {
+	return lineCounter{Writer: w, stream: stream}
+}
+
+type lineCounter struct {
+	io.Writer
+	stream string
+}
+
+func (lc lineCounter) Write(p []byte) (int, error) {
+	lines.WithLabelValues(lc.stream).Inc()
+	return lc.Writer.Write(p)
+}
+
 // WriteToByLine accepts inbound connections and concurrently writes the lines
 // directly to disk. By using the AcceptTCP and AcceptUDP helper functions,
 // newlines are handled properly.
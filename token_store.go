@@ -0,0 +1,311 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/jose"
+	"github.com/pkg/errors"
+)
+
+// TokenKeyEncryptionKeySize is the size in bytes of the AES-256 keys that
+// encrypt the token signing keys.
+const TokenKeyEncryptionKeySize = 32
+
+// encryptedKeyPEMType is the PEM type of the private keys encrypted with
+// AES-256-GCM, whose blocks are the nonce followed by the sealed PKCS #8 key.
+const encryptedKeyPEMType = "CCE ENCRYPTED PRIVATE KEY"
+
+// TokenKey is a key that signs and validates the tokens of the REST API.
+type TokenKey struct {
+	// ID is the key ID (kid) in the header of the tokens signed with the key.
+	ID        string `json:"id"`
+	Algorithm string `json:"algorithm"`
+
+	// PrivateKey is the PEM-encoded PKCS #8 private key, encrypted if the
+	// TokenStore has an EncryptionKey.
+	PrivateKey string `json:"private_key"`
+
+	Created time.Time `json:"created"`
+
+	ResourceVersion
+}
+
+// GetTableName returns the name of the persistence table.
+func (*TokenKey) GetTableName() string {
+	return "token_keys"
+}
+
+// GetID gets the ID.
+func (k *TokenKey) GetID() string {
+	return k.ID
+}
+
+// SetID sets the ID.
+func (k *TokenKey) SetID(id string) {
+	k.ID = id
+}
+
+// SensitiveFields returns the fields that are redacted in the audit log.
+func (*TokenKey) SensitiveFields() []string {
+	return []string{
+		"private_key",
+	}
+}
+
+func (k *TokenKey) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+TokenKey[
+    ID: %s
+    Algorithm: %s
+    Created: %s
+]`),
+		k.ID,
+		k.Algorithm,
+		k.Created)
+}
+
+// RevokedToken is a token of the REST API that was revoked before it expired.
+type RevokedToken struct {
+	// ID is the token ID (jti).
+	ID string `json:"id"`
+
+	// Expiry is the expiry of the token in seconds since the Unix epoch.
+	Expiry int64 `json:"expiry"`
+
+	ResourceVersion
+}
+
+// GetTableName returns the name of the persistence table.
+func (*RevokedToken) GetTableName() string {
+	return "revoked_tokens"
+}
+
+// GetID gets the ID.
+func (t *RevokedToken) GetID() string {
+	return t.ID
+}
+
+// SetID sets the ID.
+func (t *RevokedToken) SetID(id string) {
+	t.ID = id
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*RevokedToken) FilterFields() []string {
+	return []string{
+		"expiry",
+	}
+}
+
+func (t *RevokedToken) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+RevokedToken[
+    ID: %s
+    Expiry: %s
+]`),
+		t.ID,
+		time.Unix(t.Expiry, 0).UTC())
+}
+
+// TokenStore is a jose.Store that persists the token signing keys and revoked
+// tokens.
+type TokenStore struct {
+	PersistenceService PersistenceService
+
+	// EncryptionKey is the AES-256 key that encrypts the private keys. If nil
+	// they are stored unencrypted, and anyone who can read the DB can sign
+	// tokens. Unencrypted keys are still read when it is set, so that
+	// encryption can be enabled, and are replaced as the keys rotate.
+	EncryptionKey []byte
+}
+
+// ReadTokenEncryptionKey reads a base64-encoded TokenStore EncryptionKey from
+// a file.
+func ReadTokenEncryptionKey(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read token encryption key file")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, errors.Wrap(err, "token encryption key not base64-encoded")
+	}
+	if len(key) != TokenKeyEncryptionKeySize {
+		return nil, errors.Errorf("token encryption key must be %d bytes", TokenKeyEncryptionKeySize)
+	}
+	return key, nil
+}
+
+// Keys returns the key set.
+func (s *TokenStore) Keys(ctx context.Context) ([]*jose.SigningKey, error) {
+	es, err := s.PersistenceService.ReadAll(ctx, &TokenKey{})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*jose.SigningKey
+	for _, e := range es {
+		k := e.(*TokenKey)
+
+		block, _ := pem.Decode([]byte(k.PrivateKey))
+		if block == nil {
+			return nil, errors.Errorf("private key of token key %s not PEM-encoded", k.ID)
+		}
+		der := block.Bytes
+		if block.Type == encryptedKeyPEMType {
+			if der, err = s.decrypt(k.ID, der); err != nil {
+				return nil, errors.Wrapf(err, "error decrypting private key of token key %s", k.ID)
+			}
+		}
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing private key of token key %s", k.ID)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.Errorf("private key of token key %s cannot sign", k.ID)
+		}
+
+		keys = append(keys, &jose.SigningKey{
+			ID:        k.ID,
+			Key:       signer,
+			Algorithm: k.Algorithm,
+			Created:   k.Created,
+		})
+	}
+
+	return keys, nil
+}
+
+// AddKey adds a key to the key set.
+func (s *TokenStore) AddKey(ctx context.Context, k *jose.SigningKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Key)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling private key")
+	}
+	block := &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	if s.EncryptionKey != nil {
+		block.Type = encryptedKeyPEMType
+		if block.Bytes, err = s.encrypt(k.ID, der); err != nil {
+			return errors.Wrap(err, "error encrypting private key")
+		}
+	}
+
+	return s.PersistenceService.Create(ctx, &TokenKey{
+		ID:         k.ID,
+		Algorithm:  k.Algorithm,
+		PrivateKey: string(pem.EncodeToMemory(block)),
+		Created:    k.Created.UTC(),
+	})
+}
+
+// encrypt seals a private key with the EncryptionKey. The key ID is
+// authenticated, so that a sealed key can't be stored as another.
+func (s *TokenStore) encrypt(id string, der []byte) ([]byte, error) {
+	aead, err := s.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, der, []byte(id)), nil
+}
+
+// decrypt opens a private key sealed by encrypt.
+func (s *TokenStore) decrypt(id string, sealed []byte) ([]byte, error) {
+	if s.EncryptionKey == nil {
+		return nil, errors.New("private key is encrypted and no encryption key is set")
+	}
+	aead, err := s.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted private key too short")
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, []byte(id))
+}
+
+func (s *TokenStore) aead() (cipher.AEAD, error) {
+	if len(s.EncryptionKey) != TokenKeyEncryptionKeySize {
+		return nil, errors.Errorf("encryption key must be %d bytes", TokenKeyEncryptionKeySize)
+	}
+	block, err := aes.NewCipher(s.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// RemoveKey removes the key with an ID from the key set.
+func (s *TokenStore) RemoveKey(ctx context.Context, id string) error {
+	_, err := s.PersistenceService.Delete(ctx, id, &TokenKey{})
+	return err
+}
+
+// Revoke revokes the token with an ID until it expires, and returns whether
+// it was revoked by this call. The unique ID of the revocation makes only one
+// of concurrent calls revoke the token.
+func (s *TokenStore) Revoke(ctx context.Context, id string, expiry time.Time) (bool, error) {
+	err := s.PersistenceService.Create(ctx, &RevokedToken{
+		ID:     id,
+		Expiry: expiry.Unix(),
+	})
+	switch {
+	case errors.Cause(err) == ErrDuplicateEntry:
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	return true, nil
+}
+
+// IsRevoked returns whether the token with an ID is revoked.
+func (s *TokenStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	e, err := s.PersistenceService.Read(ctx, id, &RevokedToken{})
+	return e != nil, err
+}
+
+// RemoveExpiredRevocations removes the revocations of the tokens that expired
+// before a time.
+func (s *TokenStore) RemoveExpiredRevocations(ctx context.Context, before time.Time) error {
+	es, err := s.PersistenceService.Filter(ctx, &RevokedToken{}, []Filter{
+		{
+			Field: "expiry",
+			Op:    FilterOpLess,
+			Value: strconv.FormatInt(before.Unix(), 10),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range es {
+		if _, err = s.PersistenceService.Delete(ctx, e.GetID(), &RevokedToken{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
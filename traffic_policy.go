@@ -18,6 +18,8 @@ type TrafficPolicy struct {
 	ID    string         `json:"id"`
 	Name  string         `json:"name"`
 	Rules []*TrafficRule `json:"traffic_rules"`
+
+	ResourceVersion
 }
 
 // GetTableName returns the name of the persistence table.
@@ -57,7 +59,9 @@ func (tp *TrafficPolicy) Validate() error {
 
 // FilterFields returns the filterable fields for this model.
 func (*TrafficPolicy) FilterFields() []string {
-	return []string{}
+	return []string{
+		"name",
+	}
 }
 
 func (tp *TrafficPolicy) String() string {
@@ -22,6 +22,8 @@ type TrafficPolicyKubeOVN struct {
 	Name    string         `json:"name"`
 	Ingress []*IngressRule `json:"ingress_rules"`
 	Egress  []*EgressRule  `json:"egress_rules"`
+
+	ResourceVersion
 }
 
 // GetTableName returns the name of the persistence table.
@@ -69,7 +71,9 @@ func (tp *TrafficPolicyKubeOVN) Validate() error {
 
 // FilterFields returns the filterable fields for this model.
 func (*TrafficPolicyKubeOVN) FilterFields() []string {
-	return []string{}
+	return []string{
+		"name",
+	}
 }
 
 func (tp *TrafficPolicyKubeOVN) String() string {
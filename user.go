@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AdminUsername is the name of the admin user that is created from the
+// -adminPass flag.
+const AdminUsername = "admin"
+
+// Password policy.
+const (
+	PasswordMinLength = 12
+
+	// PasswordMaxLength is the length in bytes beyond which bcrypt ignores
+	// the password.
+	PasswordMaxLength = 72
+
+	// PasswordMinClasses is the minimum number of character classes (lower
+	// case letters, upper case letters, digits and others) in a password.
+	PasswordMinClasses = 3
+)
+
+// userNameRegexp matches the valid names of users.
+var userNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._@-]{0,62}$`)
+
+// dummyPasswordHash is compared with the password of a login attempt for a
+// user that does not exist, so that it takes as long as for one that does.
+const dummyPasswordHash = "$2a$10$qTy0kmWbpRYJU0FjA39TPOZJT0B4zu5t2tFuinVHBQx6/0c4v1fei"
+
+// User is a user of the REST API.
+type User struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// PasswordHash is the bcrypt hash of the password.
+	PasswordHash string `json:"password_hash"`
+
+	Roles []string `json:"roles"`
+
+	// ServiceAccount is whether the user is an automation client, which has
+	// no password and authenticates with API keys.
+	ServiceAccount bool `json:"service_account,omitempty"`
+
+	ResourceVersion
+}
+
+// GetTableName returns the name of the persistence table.
+func (*User) GetTableName() string {
+	return "users"
+}
+
+// GetID gets the ID.
+func (u *User) GetID() string {
+	return u.ID
+}
+
+// SetID sets the ID.
+func (u *User) SetID(id string) {
+	u.ID = id
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*User) FilterFields() []string {
+	return []string{
+		"name",
+	}
+}
+
+// SensitiveFields returns the fields that are redacted in the audit log.
+func (*User) SensitiveFields() []string {
+	return []string{
+		"password_hash",
+	}
+}
+
+// SetPassword sets the password hash of the user.
+func (u *User) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// CheckPassword returns whether a password matches the password hash of the
+// user. The hashes are compared in constant time.
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// ValidatePassword validates a new password of the user against the password
+// policy.
+func (u *User) ValidatePassword(password string) error {
+	if len(password) < PasswordMinLength {
+		return fmt.Errorf("password must be at least %d characters", PasswordMinLength)
+	}
+	if len(password) > PasswordMaxLength {
+		return fmt.Errorf("password must be at most %d bytes", PasswordMaxLength)
+	}
+	if u.Name != "" && strings.Contains(strings.ToLower(password), strings.ToLower(u.Name)) {
+		return errors.New("password cannot contain the user name")
+	}
+
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	if lower+upper+digit+other < PasswordMinClasses {
+		return fmt.Errorf(
+			"password must contain %d of lower case letters, upper case letters, digits and other characters",
+			PasswordMinClasses)
+	}
+
+	return nil
+}
+
+// Validate validates the model.
+func (u *User) Validate() error {
+	if !userNameRegexp.MatchString(u.Name) {
+		return errors.New("name must be 1-63 letters, digits or ._@- and start with a letter or digit")
+	}
+	if u.ServiceAccount && u.PasswordHash != "" {
+		return errors.New("service accounts cannot have a password")
+	}
+	if !u.ServiceAccount && u.PasswordHash == "" {
+		return errors.New("password cannot be empty")
+	}
+	if len(u.Roles) == 0 {
+		return errors.New("roles cannot be empty")
+	}
+	for _, role := range u.Roles {
+		if !IsRole(role) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+	}
+
+	return nil
+}
+
+func (u *User) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+User[
+    ID: %s
+    Name: %s
+    Roles: %v
+    ServiceAccount: %t
+]`),
+		u.ID,
+		u.Name,
+		u.Roles,
+		u.ServiceAccount)
+}
+
+// ReadUser reads the user with a name, or returns nil if there is none.
+func ReadUser(ctx context.Context, ps PersistenceService, name string) (*User, error) {
+	es, err := ps.Filter(ctx, &User{}, []Filter{{Field: "name", Value: name}})
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(es) {
+	case 0:
+		return nil, nil
+	case 1:
+		return es[0].(*User), nil
+	default:
+		return nil, fmt.Errorf("found %d users named %q", len(es), name)
+	}
+}
+
+// AuthenticateUser returns the user with a name and password, or nil if there
+// is no such user, the user is a service account or the password does not
+// match. Every case takes as long, so that the response time does not reveal
+// which users exist.
+func AuthenticateUser(ctx context.Context, ps PersistenceService, name, password string) (*User, error) {
+	user, err := ReadUser(ctx, ps, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil || user.ServiceAccount {
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+		return nil, nil
+	}
+	if !user.CheckPassword(password) {
+		return nil, nil
+	}
+
+	return user, nil
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"time"
+)
+
+// UserCertificateOU is the organizational unit of the client certificates the
+// Controller CA issues to users. It tells them apart from the certificates of
+// nodes, which the Controller CA also issues.
+const UserCertificateOU = "users"
+
+// DefaultUserCertificateLifetime is the lifetime of the client certificates
+// issued to users.
+const DefaultUserCertificateLifetime = 90 * 24 * time.Hour
+
+// NewUserCertificateTemplate returns the template of a client certificate of
+// a user, for signing a CSR with AuthorityService.SignCSR.
+func NewUserCertificateTemplate(u *User, lifetime time.Duration) *x509.Certificate {
+	return &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:         u.Name,
+			OrganizationalUnit: []string{UserCertificateOU},
+		},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		NotAfter:    time.Now().Add(lifetime),
+	}
+}
+
+// AuthenticateCertificate returns the subject and roles of a verified client
+// certificate, or "" if it maps to no user or role.
+//
+// The common name of a certificate issued by the Controller CA is the name of
+// a user, and the certificate must be in UserCertificateOU. The common name of
+// a certificate issued by an operator CA is the name of a user or, if there is
+// no such user, the certificate is granted the roles named by its
+// organizational units and its subject is "cert:" followed by the common
+// name.
+func AuthenticateCertificate(
+	ctx context.Context,
+	ps PersistenceService,
+	cert *x509.Certificate,
+	controllerCA bool,
+) (string, []string, error) {
+	name := cert.Subject.CommonName
+	if name == "" {
+		return "", nil, nil
+	}
+
+	if controllerCA && !containsString(cert.Subject.OrganizationalUnit, UserCertificateOU) {
+		return "", nil, nil
+	}
+
+	user, err := ReadUser(ctx, ps, name)
+	if err != nil {
+		return "", nil, err
+	}
+	if user != nil {
+		return user.Name, user.Roles, nil
+	}
+	if controllerCA {
+		return "", nil, nil
+	}
+
+	var roles []string
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if IsRole(ou) {
+			roles = append(roles, ou)
+		}
+	}
+	if len(roles) == 0 {
+		return "", nil, nil
+	}
+
+	return "cert:" + name, roles, nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/internal/stubs"
+)
+
+var _ = Describe("User certificates", func() {
+	var (
+		ctx  = context.Background()
+		user *cce.User
+		ps   *stubs.PersistenceServiceStub
+	)
+
+	BeforeEach(func() {
+		user = &cce.User{
+			ID:    "ca0fa495-1020-405b-a78c-9a1884349078",
+			Name:  "jane.doe@example.com",
+			Roles: []string{cce.RoleOperator},
+		}
+		ps = &stubs.PersistenceServiceStub{FilterRet: []cce.Persistable{user}}
+	})
+
+	Describe("NewUserCertificateTemplate", func() {
+		It("Should return a client certificate template of the user", func() {
+			tmpl := cce.NewUserCertificateTemplate(user, time.Hour)
+			Expect(tmpl.Subject.CommonName).To(Equal(user.Name))
+			Expect(tmpl.Subject.OrganizationalUnit).To(Equal([]string{cce.UserCertificateOU}))
+			Expect(tmpl.ExtKeyUsage).To(Equal([]x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}))
+			Expect(tmpl.NotAfter).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+		})
+	})
+
+	Describe("AuthenticateCertificate", func() {
+		cert := func(cn string, ous ...string) *x509.Certificate {
+			return &x509.Certificate{Subject: pkix.Name{CommonName: cn, OrganizationalUnit: ous}}
+		}
+
+		It("Should map a certificate of the Controller CA to the user", func() {
+			subject, roles, err := cce.AuthenticateCertificate(ctx, ps, cert(user.Name, cce.UserCertificateOU), true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subject).To(Equal(user.Name))
+			Expect(roles).To(Equal([]string{cce.RoleOperator}))
+			Expect(ps.FilterValues).To(Equal([][]cce.Filter{{{Field: "name", Value: user.Name}}}))
+		})
+
+		It("Should not map a certificate of the Controller CA that is not a user's", func() {
+			subject, _, err := cce.AuthenticateCertificate(ctx, ps, cert(user.Name), true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subject).To(BeEmpty())
+
+			ps.FilterRet = nil
+			subject, _, err = cce.AuthenticateCertificate(
+				ctx, ps, cert(user.Name, cce.UserCertificateOU, cce.RoleAdmin), true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subject).To(BeEmpty())
+		})
+
+		It("Should map a certificate of an operator CA to the user", func() {
+			subject, roles, err := cce.AuthenticateCertificate(ctx, ps, cert(user.Name, cce.RoleAdmin), false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subject).To(Equal(user.Name))
+			Expect(roles).To(Equal([]string{cce.RoleOperator}))
+		})
+
+		It("Should map a certificate of an operator CA without a user to its roles", func() {
+			ps.FilterRet = nil
+			subject, roles, err := cce.AuthenticateCertificate(
+				ctx, ps, cert("ci", "pipelines", cce.RoleViewer), false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subject).To(Equal("cert:ci"))
+			Expect(roles).To(Equal([]string{cce.RoleViewer}))
+
+			subject, _, err = cce.AuthenticateCertificate(ctx, ps, cert("ci", "pipelines"), false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subject).To(BeEmpty())
+		})
+
+		It("Should not map a certificate without a common name", func() {
+			subject, _, err := cce.AuthenticateCertificate(ctx, ps, cert("", cce.RoleAdmin), false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subject).To(BeEmpty())
+			Expect(ps.FilterValues).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/internal/stubs"
+)
+
+var _ = Describe("Entities: User", func() {
+	var (
+		user *cce.User
+	)
+
+	BeforeEach(func() {
+		user = &cce.User{
+			ID:           "ca0fa495-1020-405b-a78c-9a1884349078",
+			Name:         "jane.doe@example.com",
+			PasswordHash: "$2a$10$Lp2ChOU09dlqLEyGlsK3MuRGwR6ZGZGbGdBvjGFQBvjoyOYnQj5Xy",
+			Roles:        []string{cce.RoleOperator},
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "users"`, func() {
+			Expect(user.GetTableName()).To(Equal("users"))
+		})
+	})
+
+	Describe("GetID", func() {
+		It("Should return the ID", func() {
+			Expect(user.GetID()).To(Equal(
+				"ca0fa495-1020-405b-a78c-9a1884349078"))
+		})
+	})
+
+	Describe("SetID", func() {
+		It("Should set and return the updated ID", func() {
+			By("Setting the ID")
+			user.SetID("456")
+
+			By("Getting the updated ID")
+			Expect(user.ID).To(Equal("456"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(user.FilterFields()).To(Equal([]string{
+				"name",
+			}))
+		})
+	})
+
+	Describe("SetPassword and CheckPassword", func() {
+		It("Should only match the password that was set", func() {
+			Expect(user.SetPassword("s3cret")).To(Succeed())
+			Expect(user.PasswordHash).ToNot(ContainSubstring("s3cret"))
+			Expect(user.CheckPassword("s3cret")).To(BeTrue())
+			Expect(user.CheckPassword("S3cret")).To(BeFalse())
+			Expect(user.CheckPassword("")).To(BeFalse())
+		})
+	})
+
+	Describe("ValidatePassword", func() {
+		It("Should accept a password that meets the policy", func() {
+			Expect(user.ValidatePassword("Correct-Horse-42")).To(Succeed())
+			Expect(user.ValidatePassword("correct horse 42")).To(Succeed())
+			Expect(user.ValidatePassword(strings.Repeat("Aa1", 24))).To(Succeed())
+		})
+
+		It("Should return an error for a short password", func() {
+			Expect(user.ValidatePassword("Horse-42")).To(MatchError("password must be at least 12 characters"))
+		})
+
+		It("Should return an error for a long password", func() {
+			Expect(user.ValidatePassword(strings.Repeat("Aa1", 24) + "x")).To(MatchError(
+				"password must be at most 72 bytes"))
+		})
+
+		It("Should return an error for a password containing the user name", func() {
+			Expect(user.ValidatePassword("1-Jane.Doe@Example.com")).To(MatchError(
+				"password cannot contain the user name"))
+		})
+
+		It("Should return an error for a password with too few character classes", func() {
+			for _, password := range []string{"correcthorsebattery", "correct-horse-battery", "CORRECTHORSE42"} {
+				Expect(user.ValidatePassword(password)).To(MatchError(
+					"password must contain 3 of lower case letters, upper case letters, digits and other characters"))
+			}
+		})
+	})
+
+	Describe("ReadUser and AuthenticateUser", func() {
+		var ps *stubs.PersistenceServiceStub
+
+		BeforeEach(func() {
+			Expect(user.SetPassword("Correct-Horse-42")).To(Succeed())
+			ps = &stubs.PersistenceServiceStub{FilterRet: []cce.Persistable{user}}
+		})
+
+		It("Should read the user by name", func() {
+			Expect(cce.ReadUser(context.Background(), ps, user.Name)).To(Equal(user))
+			Expect(ps.FilterValues).To(Equal([][]cce.Filter{{{Field: "name", Value: user.Name}}}))
+		})
+
+		It("Should return nil if there is no user", func() {
+			ps.FilterRet = nil
+			Expect(cce.ReadUser(context.Background(), ps, user.Name)).To(BeNil())
+			Expect(cce.AuthenticateUser(context.Background(), ps, user.Name, "Correct-Horse-42")).To(BeNil())
+		})
+
+		It("Should return the error of the persistence service", func() {
+			ps.FilterErr = errors.New("filter failed")
+			_, err := cce.AuthenticateUser(context.Background(), ps, user.Name, "Correct-Horse-42")
+			Expect(err).To(MatchError("filter failed"))
+		})
+
+		It("Should only authenticate the user with the password", func() {
+			Expect(cce.AuthenticateUser(context.Background(), ps, user.Name, "Correct-Horse-42")).To(Equal(user))
+			Expect(cce.AuthenticateUser(context.Background(), ps, user.Name, "Correct-Horse-43")).To(BeNil())
+		})
+
+		It("Should not authenticate a service account with a password", func() {
+			user.ServiceAccount = true
+			Expect(cce.AuthenticateUser(context.Background(), ps, user.Name, "Correct-Horse-42")).To(BeNil())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error for a valid user", func() {
+			Expect(user.Validate()).To(Succeed())
+		})
+
+		It("Should return an error for an invalid name", func() {
+			for _, name := range []string{"", ".jane", "jane doe", "jane/doe", strings.Repeat("a", 64)} {
+				user.Name = name
+				Expect(user.Validate()).To(MatchError(
+					"name must be 1-63 letters, digits or ._@- and start with a letter or digit"))
+			}
+		})
+
+		It("Should return an error if there is no password", func() {
+			user.PasswordHash = ""
+			Expect(user.Validate()).To(MatchError("password cannot be empty"))
+		})
+
+		It("Should not return an error for a service account without a password", func() {
+			user.PasswordHash = ""
+			user.ServiceAccount = true
+			Expect(user.Validate()).To(Succeed())
+		})
+
+		It("Should return an error for a service account with a password", func() {
+			user.ServiceAccount = true
+			Expect(user.Validate()).To(MatchError("service accounts cannot have a password"))
+		})
+
+		It("Should return an error if there are no roles", func() {
+			user.Roles = nil
+			Expect(user.Validate()).To(MatchError("roles cannot be empty"))
+		})
+
+		It("Should return an error for an unknown role", func() {
+			user.Roles = []string{cce.RoleViewer, "root"}
+			Expect(user.Validate()).To(MatchError(`unknown role "root"`))
+		})
+	})
+
+	Describe("NewAuditEvent", func() {
+		It("Should redact the password hash", func() {
+			updated := *user
+			updated.PasswordHash = "$2a$10$changed"
+
+			e, err := cce.NewAuditEvent(cce.AuditActionUpdate, user, &updated)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.Diff).To(Equal(map[string]cce.AuditChange{
+				"password_hash": {
+					Before: json.RawMessage(`"*****"`),
+					After:  json.RawMessage(`"*****"`),
+				},
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value without the password hash", func() {
+			Expect(user.String()).To(Equal(strings.TrimSpace(`
+User[
+    ID: ca0fa495-1020-405b-a78c-9a1884349078
+    Name: jane.doe@example.com
+    Roles: [operator]
+    ServiceAccount: false
+]`,
+			)))
+		})
+	})
+})
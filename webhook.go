@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// WebhookSecretMinLength is the minimum length of the secret of a webhook.
+const WebhookSecretMinLength = 16
+
+// webhookSecretSize is the size in bytes of the random secrets of webhooks
+// created without one.
+const webhookSecretSize = 32
+
+// Webhook is a subscription of an external URL to the events of the
+// Controller, which are POSTed to it signed with the secret.
+type Webhook struct {
+	ID string `json:"id"`
+
+	// Name describes the webhook. It is unique.
+	Name string `json:"name"`
+
+	// URL is the HTTP or HTTPS URL the events are delivered to. Its host
+	// is checked when the events are delivered, as it may resolve to a
+	// different address each time.
+	URL string `json:"url"`
+
+	// EventTypes are the types of the events delivered to the webhook. If
+	// empty all events are delivered.
+	EventTypes []string `json:"event_types"`
+
+	// Secret is the key of the HMAC-SHA256 signatures of the deliveries. It
+	// is stored as is, as it is needed to sign them.
+	Secret string `json:"secret"`
+
+	ResourceVersion
+}
+
+// NewWebhookSecret returns a random secret for a webhook.
+func NewWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// GetTableName returns the name of the persistence table.
+func (*Webhook) GetTableName() string {
+	return "webhooks"
+}
+
+// GetID gets the ID.
+func (w *Webhook) GetID() string {
+	return w.ID
+}
+
+// SetID sets the ID.
+func (w *Webhook) SetID(id string) {
+	w.ID = id
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*Webhook) FilterFields() []string {
+	return []string{
+		"name",
+		"url",
+	}
+}
+
+// SensitiveFields returns the fields that are redacted in the audit log.
+func (*Webhook) SensitiveFields() []string {
+	return []string{
+		"secret",
+	}
+}
+
+// Matches returns whether an event is delivered to the webhook.
+func (w *Webhook) Matches(e *events.Event) bool {
+	return events.Filter{Types: w.EventTypes}.Match(e)
+}
+
+// Validate validates the model.
+func (w *Webhook) Validate() error {
+	if !uuid.IsValid(w.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if w.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	for _, t := range w.EventTypes {
+		if !events.IsType(t) {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	if len(w.Secret) < WebhookSecretMinLength {
+		return fmt.Errorf("secret must be at least %d characters", WebhookSecretMinLength)
+	}
+
+	return nil
+}
+
+func (w *Webhook) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+Webhook[
+    ID: %s
+    Name: %s
+    URL: %s
+    EventTypes: %v
+]`),
+		w.ID,
+		w.Name,
+		w.URL,
+		w.EventTypes)
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package webhook
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// privateNetworks are the IPv4 private networks of RFC 1918 and the IPv6
+// unique local addresses of RFC 4193.
+var privateNetworks = mustParseNetworks("10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,fc00::/7")
+
+// ParseNetworks parses a comma-separated list of CIDR networks. An empty
+// string is no networks.
+func ParseNetworks(s string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(s, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Errorf("bad network %q, not a CIDR", cidr)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func mustParseNetworks(s string) []*net.IPNet {
+	networks, err := ParseNetworks(s)
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
+
+// destinations checks the addresses webhooks are delivered to. Loopback,
+// link-local and unspecified addresses are refused so that webhooks can't
+// reach the services of the Controller's host or cloud metadata services,
+// and private addresses are refused if denyPrivate is set. Allowed networks
+// are never refused.
+type destinations struct {
+	allowed     []*net.IPNet
+	denyPrivate bool
+}
+
+// check returns an error if an address is refused.
+func (ds *destinations) check(ip net.IP) error {
+	if contains(ds.allowed, ip) {
+		return nil
+	}
+
+	switch {
+	case ip.IsLoopback(), ip.IsUnspecified():
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast(), ip.IsInterfaceLocalMulticast():
+	case ds.denyPrivate && contains(privateNetworks, ip):
+	default:
+		return nil
+	}
+	return errors.Errorf("webhook destination %s is not allowed", ip)
+}
+
+// control checks the address of a connection before it is dialed, once the
+// host has been resolved.
+func (ds *destinations) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errors.Errorf("webhook destination %s is not an IP address", host)
+	}
+	return ds.check(ip)
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// newTransport returns a transport that checks the addresses it dials. It
+// doesn't use proxies, as the address of the proxy would be checked instead
+// of the webhook's.
+func newTransport(ds *destinations) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   ds.control,
+	}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
@@ -0,0 +1,460 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	logger "github.com/open-ness/common/log"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/pkg/errors"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "webhook")
+
+// Headers of the requests delivering events.
+const (
+	// HeaderEvent is the type of the event.
+	HeaderEvent = "X-CCE-Event"
+
+	// HeaderDelivery is the ID of the delivery, which is the same for each
+	// attempt.
+	HeaderDelivery = "X-CCE-Delivery"
+
+	// HeaderTimestamp is the time of the attempt in seconds since the Unix
+	// epoch.
+	HeaderTimestamp = "X-CCE-Timestamp"
+
+	// HeaderSignature is "sha256=" followed by the signature of the
+	// timestamp and body made by Sign.
+	HeaderSignature = "X-CCE-Signature"
+)
+
+// Defaults of the Dispatcher.
+const (
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = 30 * time.Second
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxHistory  = 100
+)
+
+// maxResponseSize is the size of the response bodies read to reuse the
+// connection.
+const maxResponseSize = 64 << 10
+
+// Dispatcher delivers the events published to the bus of the Controller to
+// the webhooks they match. Deliveries are persisted as the delivery history
+// of the webhooks and attempted until the webhook responds with a 2xx status
+// code, backing off exponentially between attempts. The deliveries to a
+// webhook are made in order by one goroutine, so a delivery waits for the
+// ones before it to succeed or fail.
+type Dispatcher struct {
+	Controller *cce.Controller
+
+	// Client posts the events. If nil a client with DefaultTimeout is used,
+	// which refuses to connect to loopback, link-local and unspecified
+	// addresses, and to private ones if DenyPrivate is set, unless they are
+	// in AllowedNetworks. The addresses are checked when dialing, after
+	// resolving the host of the webhook. Redirects are never followed.
+	Client *http.Client
+
+	// AllowedNetworks are the networks the default client connects to even
+	// if their addresses are refused.
+	AllowedNetworks []*net.IPNet
+
+	// DenyPrivate makes the default client refuse private addresses.
+	DenyPrivate bool
+
+	// MaxAttempts is the number of attempts before a delivery fails. If zero
+	// DefaultMaxAttempts is used.
+	MaxAttempts int
+
+	// Backoff is the time before the second attempt, which doubles for each
+	// attempt after. If zero DefaultBackoff is used.
+	Backoff time.Duration
+
+	// MaxHistory is the number of deliveries kept for each webhook. If zero
+	// DefaultMaxHistory is used.
+	MaxHistory int
+
+	wg sync.WaitGroup
+
+	mu sync.Mutex
+	// queues are the deliveries to make to each webhook with a running
+	// worker
+	queues map[string][]*cce.WebhookDelivery
+	// webhooks caches the webhooks, nil until they are read
+	webhooks []*cce.Webhook
+
+	transportOnce sync.Once
+	transport     *http.Transport
+}
+
+// Run delivers the events of the bus until the context is done. The
+// deliveries that were pending when the Controller stopped are resumed.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	pending, err := d.Controller.PersistenceService.Filter(
+		ctx,
+		&cce.WebhookDelivery{},
+		[]cce.Filter{{Field: "status", Value: cce.WebhookDeliveryPending}})
+	if err != nil {
+		return errors.Wrap(err, "could not fetch pending webhook deliveries from DB")
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].(*cce.WebhookDelivery).Time < pending[j].(*cce.WebhookDelivery).Time
+	})
+	for _, delivery := range pending {
+		d.start(ctx, delivery.(*cce.WebhookDelivery))
+	}
+
+	sub := d.Controller.Events.Subscribe(events.Filter{})
+	defer func() { sub.Close() }()
+
+	var lastID uint64
+	for {
+		select {
+		case <-ctx.Done():
+			d.wg.Wait()
+			return nil
+		case e, ok := <-sub.C:
+			if !ok {
+				// The subscription was dropped for falling behind
+				log.Noticef("Webhook dispatcher fell behind, resuming after event %d", lastID)
+				var missed []*events.Event
+				sub, missed = d.Controller.Events.SubscribeAfter(events.Filter{}, lastID)
+
+				// The webhooks may have changed in events that are no longer
+				// retained
+				d.mu.Lock()
+				d.webhooks = nil
+				d.mu.Unlock()
+				for _, e := range missed {
+					d.dispatch(ctx, e)
+					lastID = e.ID
+				}
+				continue
+			}
+			d.dispatch(ctx, e)
+			lastID = e.ID
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, e *events.Event) {
+	if err := d.Dispatch(ctx, e); err != nil {
+		log.Errf("Error dispatching event %d to webhooks: %v", e.ID, err)
+	}
+}
+
+// Dispatch creates a delivery of an event to each webhook it matches and
+// queues them for delivery. The webhooks are cached until an event of a
+// change of a webhook is dispatched.
+func (d *Dispatcher) Dispatch(ctx context.Context, e *events.Event) error {
+	ps := d.Controller.PersistenceService
+
+	webhooks, err := d.cachedWebhooks(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, w := range webhooks {
+		if !w.Matches(e) {
+			continue
+		}
+
+		if payload == nil {
+			if payload, err = json.Marshal(e); err != nil {
+				return errors.Wrap(err, "error marshaling event")
+			}
+		}
+
+		now := time.Now()
+		delivery := &cce.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     w.GetID(),
+			Time:          cce.AuditTime(now),
+			EventType:     e.Type,
+			Payload:       payload,
+			Status:        cce.WebhookDeliveryPending,
+			NextAttemptAt: now.UTC(),
+		}
+		if err = ps.Create(ctx, delivery); err != nil {
+			return errors.Wrapf(err, "could not create delivery to webhook %s", w.GetID())
+		}
+		d.start(ctx, delivery)
+	}
+
+	return nil
+}
+
+// cachedWebhooks returns the cached webhooks, which are read again if the
+// event is a change of a webhook.
+func (d *Dispatcher) cachedWebhooks(ctx context.Context, e *events.Event) ([]*cce.Webhook, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e.EntityType == (&cce.Webhook{}).GetTableName() {
+		d.webhooks = nil
+	}
+	if d.webhooks != nil {
+		return d.webhooks, nil
+	}
+
+	es, err := d.Controller.PersistenceService.ReadAll(ctx, &cce.Webhook{})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch webhooks from DB")
+	}
+	webhooks := make([]*cce.Webhook, 0, len(es))
+	for _, w := range es {
+		webhooks = append(webhooks, w.(*cce.Webhook))
+	}
+	d.webhooks = webhooks
+	return webhooks, nil
+}
+
+// start queues a delivery to its webhook, and starts the worker of the
+// webhook if it isn't running.
+func (d *Dispatcher) start(ctx context.Context, delivery *cce.WebhookDelivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.queues == nil {
+		d.queues = make(map[string][]*cce.WebhookDelivery)
+	}
+	queue, running := d.queues[delivery.WebhookID]
+	d.queues[delivery.WebhookID] = append(queue, delivery)
+	if running {
+		return
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.work(ctx, delivery.WebhookID)
+	}()
+}
+
+// work makes the queued deliveries to a webhook until there are none left or
+// the context is done. The deliveries left are pending, and resumed when the
+// Dispatcher runs again.
+func (d *Dispatcher) work(ctx context.Context, webhookID string) {
+	for {
+		d.mu.Lock()
+		queue := d.queues[webhookID]
+		if len(queue) == 0 || ctx.Err() != nil {
+			delete(d.queues, webhookID)
+			d.mu.Unlock()
+			return
+		}
+		delivery := queue[0]
+		d.queues[webhookID] = queue[1:]
+		d.mu.Unlock()
+
+		d.deliver(ctx, delivery)
+	}
+}
+
+// deliver attempts a delivery until it succeeds or fails, recording each
+// attempt. If the context is done the delivery is left pending.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *cce.WebhookDelivery) {
+	ps := d.Controller.PersistenceService
+
+	for delivery.Status == cce.WebhookDeliveryPending {
+		if wait := time.Until(delivery.NextAttemptAt); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		// The webhook may have been changed since, or deleted with its
+		// deliveries
+		w, err := ps.Read(ctx, delivery.WebhookID, &cce.Webhook{})
+		if err != nil {
+			log.Errf("Error reading webhook %s: %v", delivery.WebhookID, err)
+			return
+		}
+		if w == nil {
+			return
+		}
+
+		d.attempt(ctx, w.(*cce.Webhook), delivery)
+		if ctx.Err() != nil {
+			return
+		}
+
+		delivery.SetResourceVersion(0)
+		if err = ps.BulkUpdate(ctx, []cce.Persistable{delivery}); err != nil {
+			log.Errf("Error recording delivery %s to webhook %s: %v", delivery.ID, delivery.WebhookID, err)
+			return
+		}
+	}
+
+	if delivery.Status == cce.WebhookDeliveryFailed {
+		log.Noticef("Delivery %s to webhook %s failed after %d attempts: %s",
+			delivery.ID, delivery.WebhookID, delivery.Attempts, delivery.Error)
+	}
+	d.prune(ctx, delivery.WebhookID)
+}
+
+// attempt posts a delivery to a webhook and updates it with the result.
+func (d *Dispatcher) attempt(ctx context.Context, w *cce.Webhook, delivery *cce.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = now.UTC()
+
+	delivery.ResponseStatus, delivery.Error = 0, ""
+	var err error
+	delivery.ResponseStatus, err = d.post(ctx, w, delivery, now)
+
+	maxAttempts := d.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = cce.WebhookDeliverySucceeded
+	case delivery.Attempts >= maxAttempts:
+		delivery.Status = cce.WebhookDeliveryFailed
+		delivery.Error = err.Error()
+	default:
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts)).UTC()
+	}
+}
+
+// backoff returns the time to wait after a number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.Backoff
+	if backoff == 0 {
+		backoff = DefaultBackoff
+	}
+	return backoff << uint(attempts-1)
+}
+
+// post posts the payload of a delivery to a webhook and returns the status
+// code of the response, or 0 if there was none.
+func (d *Dispatcher) post(
+	ctx context.Context,
+	w *cce.Webhook,
+	delivery *cce.WebhookDelivery,
+	now time.Time,
+) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(w.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.Errorf("unexpected response status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) client() *http.Client {
+	client := http.Client{Timeout: DefaultTimeout}
+	if d.Client != nil {
+		client = *d.Client
+	} else {
+		d.transportOnce.Do(func() {
+			d.transport = newTransport(&destinations{
+				allowed:     d.AllowedNetworks,
+				denyPrivate: d.DenyPrivate,
+			})
+		})
+		client.Transport = d.transport
+	}
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &client
+}
+
+// prune deletes the completed deliveries of a webhook beyond the newest
+// MaxHistory.
+func (d *Dispatcher) prune(ctx context.Context, webhookID string) {
+	ps := d.Controller.PersistenceService
+
+	maxHistory := d.MaxHistory
+	if maxHistory == 0 {
+		maxHistory = DefaultMaxHistory
+	}
+
+	opts := cce.ListOptions{
+		Filters: []cce.Filter{{Field: "webhook_id", Value: webhookID}},
+		Sort:    "-time",
+		Limit:   maxHistory,
+	}
+	page, err := ps.List(ctx, &cce.WebhookDelivery{}, opts)
+	if err != nil || page.Next == "" {
+		return
+	}
+
+	// List the rest
+	opts.Limit, opts.Cursor = 0, page.Next
+	if page, err = ps.List(ctx, &cce.WebhookDelivery{}, opts); err != nil {
+		log.Errf("Error listing deliveries to webhook %s: %v", webhookID, err)
+		return
+	}
+	for _, e := range page.Entities {
+		if e.(*cce.WebhookDelivery).Status == cce.WebhookDeliveryPending {
+			continue
+		}
+		if _, err = ps.Delete(ctx, e.GetID(), &cce.WebhookDelivery{}); err != nil {
+			log.Errf("Error deleting delivery %s to webhook %s: %v", e.GetID(), webhookID, err)
+		}
+	}
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 signature with a webhook's secret
+// of the timestamp of a delivery attempt, a ".", and the payload. Receivers
+// compute it to verify that the payload came from the Controller, and check
+// the timestamp to reject replays.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns whether the signature header of a delivery attempt is the
+// signature of its timestamp and payload. The signatures are compared in
+// constant time.
+func Verify(secret, timestamp string, payload []byte, signature string) bool {
+	expected := "sha256=" + Sign(secret, timestamp, payload)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,317 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	"github.com/open-ness/edgecontroller/events"
+	"github.com/open-ness/edgecontroller/webhook"
+	"go.etcd.io/bbolt"
+)
+
+// receiver is a webhook receiver that responds with the next of its status
+// codes and records the requests it verified.
+type receiver struct {
+	secret string
+
+	mu       sync.Mutex
+	statuses []int
+	received []*events.Event
+	headers  []http.Header
+}
+
+func (rcv *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+
+	body, err := ioutil.ReadAll(r.Body)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(webhook.Verify(
+		rcv.secret,
+		r.Header.Get(webhook.HeaderTimestamp),
+		body,
+		r.Header.Get(webhook.HeaderSignature),
+	)).To(BeTrue())
+
+	var e events.Event
+	Expect(json.Unmarshal(body, &e)).To(Succeed())
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.received = append(rcv.received, &e)
+	rcv.headers = append(rcv.headers, r.Header)
+
+	status := http.StatusNoContent
+	if len(rcv.statuses) != 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rcv *receiver) Received() []*events.Event {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return append([]*events.Event(nil), rcv.received...)
+}
+
+var loopback, _ = webhook.ParseNetworks("127.0.0.0/8,::1/128")
+
+var _ = Describe("Dispatcher", func() {
+	const (
+		webhookID = "1f2e3d4c-5b6a-4789-9a8b-7c6d5e4f3a2b"
+		secret    = "0123456789abcdef"
+	)
+
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		dir        string
+		db         *bbolt.DB
+		ps         cce.PersistenceService
+		rcv        *receiver
+		server     *httptest.Server
+		dispatcher *webhook.Dispatcher
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		var err error
+		dir, err = ioutil.TempDir("", "cce-webhooks")
+		Expect(err).ToNot(HaveOccurred())
+		db, err = bolt.Open(filepath.Join(dir, "cce.db"))
+		Expect(err).ToNot(HaveOccurred())
+		ps = &bolt.PersistenceService{DB: db}
+
+		rcv = &receiver{secret: secret}
+		server = httptest.NewServer(rcv)
+
+		Expect(ps.Create(ctx, &cce.Webhook{
+			ID:         webhookID,
+			Name:       "noc",
+			URL:        server.URL,
+			EventTypes: []string{events.TypeNodeEnrolled},
+			Secret:     secret,
+		})).To(Succeed())
+
+		dispatcher = &webhook.Dispatcher{
+			Controller: &cce.Controller{
+				PersistenceService: ps,
+				Events:             events.NewBus(10),
+			},
+			Backoff:         10 * time.Millisecond,
+			AllowedNetworks: loopback,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+		server.Close()
+		Expect(db.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	deliveries := func() []*cce.WebhookDelivery {
+		es, err := ps.Filter(ctx, &cce.WebhookDelivery{}, []cce.Filter{{Field: "webhook_id", Value: webhookID}})
+		Expect(err).ToNot(HaveOccurred())
+		var ds []*cce.WebhookDelivery
+		for _, e := range es {
+			ds = append(ds, e.(*cce.WebhookDelivery))
+		}
+		return ds
+	}
+
+	status := func() string {
+		ds := deliveries()
+		if len(ds) != 1 {
+			return ""
+		}
+		return ds[0].Status
+	}
+
+	enrolled := &events.Event{ID: 7, Type: events.TypeNodeEnrolled, NodeID: "n1"}
+
+	setURL := func(url string) {
+		w, err := ps.Read(ctx, webhookID, &cce.Webhook{})
+		Expect(err).ToNot(HaveOccurred())
+		w.(*cce.Webhook).URL = url
+		Expect(ps.BulkUpdate(ctx, []cce.Persistable{w})).To(Succeed())
+	}
+
+	It("Should deliver signed events to the webhooks they match", func() {
+		Expect(dispatcher.Dispatch(ctx, &events.Event{ID: 6, Type: events.TypeEntityCreated})).To(Succeed())
+		Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+
+		Eventually(status).Should(Equal(cce.WebhookDeliverySucceeded))
+		Expect(rcv.Received()).To(HaveLen(1))
+		Expect(rcv.Received()[0].ID).To(Equal(uint64(7)))
+		Expect(rcv.headers[0].Get(webhook.HeaderEvent)).To(Equal(events.TypeNodeEnrolled))
+
+		d := deliveries()[0]
+		Expect(rcv.headers[0].Get(webhook.HeaderDelivery)).To(Equal(d.ID))
+		Expect(d.EventType).To(Equal(events.TypeNodeEnrolled))
+		Expect(d.Attempts).To(Equal(1))
+		Expect(d.ResponseStatus).To(Equal(http.StatusNoContent))
+		Expect(d.Error).To(BeEmpty())
+	})
+
+	It("Should retry failed deliveries", func() {
+		rcv.statuses = []int{http.StatusServiceUnavailable, http.StatusInternalServerError}
+		Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+
+		Eventually(status).Should(Equal(cce.WebhookDeliverySucceeded))
+		Expect(rcv.Received()).To(HaveLen(3))
+		Expect(deliveries()[0].Attempts).To(Equal(3))
+	})
+
+	It("Should fail deliveries after the maximum attempts", func() {
+		dispatcher.MaxAttempts = 2
+		rcv.statuses = []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK}
+		Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+
+		Eventually(status).Should(Equal(cce.WebhookDeliveryFailed))
+		d := deliveries()[0]
+		Expect(d.Attempts).To(Equal(2))
+		Expect(d.ResponseStatus).To(Equal(http.StatusBadGateway))
+		Expect(d.Error).To(Equal("unexpected response status 502 Bad Gateway"))
+		Consistently(rcv.Received, 50*time.Millisecond).Should(HaveLen(2))
+	})
+
+	It("Should not follow redirects", func() {
+		redirect := httptest.NewServer(http.RedirectHandler(server.URL, http.StatusTemporaryRedirect))
+		defer redirect.Close()
+		setURL(redirect.URL)
+
+		dispatcher.MaxAttempts = 1
+		Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+
+		Eventually(status).Should(Equal(cce.WebhookDeliveryFailed))
+		Expect(deliveries()[0].ResponseStatus).To(Equal(http.StatusTemporaryRedirect))
+		Expect(rcv.Received()).To(BeEmpty())
+	})
+
+	It("Should refuse loopback and link-local destinations", func() {
+		dispatcher.AllowedNetworks = nil
+		dispatcher.MaxAttempts = 1
+		Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+
+		Eventually(status).Should(Equal(cce.WebhookDeliveryFailed))
+		Expect(deliveries()[0].Error).To(ContainSubstring("webhook destination 127.0.0.1 is not allowed"))
+		Expect(rcv.Received()).To(BeEmpty())
+
+		Expect(ps.Delete(ctx, deliveries()[0].ID, &cce.WebhookDelivery{})).To(BeTrue())
+		setURL("http://169.254.169.254/latest/meta-data/")
+		Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+
+		Eventually(status).Should(Equal(cce.WebhookDeliveryFailed))
+		Expect(deliveries()[0].Error).To(ContainSubstring("webhook destination 169.254.169.254 is not allowed"))
+	})
+
+	It("Should deliver the events to a webhook in order", func() {
+		rcv.statuses = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+		for id := uint64(1); id <= 3; id++ {
+			Expect(dispatcher.Dispatch(ctx, &events.Event{ID: id, Type: events.TypeNodeEnrolled})).To(Succeed())
+		}
+
+		Eventually(func() int { return len(rcv.Received()) }).Should(Equal(5))
+		var ids []uint64
+		for _, e := range rcv.Received() {
+			ids = append(ids, e.ID)
+		}
+		Expect(ids).To(Equal([]uint64{1, 1, 1, 2, 3}))
+	})
+
+	It("Should cache the webhooks until they change", func() {
+		Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+		Eventually(status).Should(Equal(cce.WebhookDeliverySucceeded))
+
+		By("Deleting the webhook without an event")
+		Expect(ps.Delete(ctx, webhookID, &cce.Webhook{})).To(BeTrue())
+		Expect(ps.Create(ctx, &cce.Webhook{
+			ID:         webhookID,
+			Name:       "noc",
+			URL:        server.URL,
+			EventTypes: []string{events.TypeEntityUpdated},
+			Secret:     secret,
+		})).To(Succeed())
+		Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+		Eventually(rcv.Received).Should(HaveLen(2))
+
+		By("Dispatching the event of the change")
+		Expect(dispatcher.Dispatch(ctx, &events.Event{
+			ID:         8,
+			Type:       events.TypeEntityUpdated,
+			EntityType: "webhooks",
+			EntityID:   webhookID,
+		})).To(Succeed())
+		Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+		Eventually(rcv.Received).Should(HaveLen(3))
+		Consistently(rcv.Received, 50*time.Millisecond).Should(HaveLen(3))
+		Expect(rcv.Received()[2].ID).To(Equal(uint64(8)))
+	})
+
+	It("Should parse networks", func() {
+		networks, err := webhook.ParseNetworks(" 127.0.0.0/8, ::1/128,")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(networks).To(HaveLen(2))
+		Expect(networks[0].String()).To(Equal("127.0.0.0/8"))
+		Expect(networks[1].String()).To(Equal("::1/128"))
+
+		_, err = webhook.ParseNetworks("127.0.0.1")
+		Expect(err).To(MatchError(`bad network "127.0.0.1", not a CIDR`))
+	})
+
+	It("Should keep the newest deliveries", func() {
+		dispatcher.MaxHistory = 2
+		for i := 0; i < 3; i++ {
+			Expect(dispatcher.Dispatch(ctx, enrolled)).To(Succeed())
+			Eventually(func() int { return len(rcv.Received()) }).Should(Equal(i + 1))
+			time.Sleep(time.Millisecond)
+		}
+
+		Eventually(func() int { return len(deliveries()) }).Should(Equal(2))
+	})
+
+	It("Should deliver the events of the bus and resume pending deliveries", func() {
+		Expect(ps.Create(ctx, &cce.WebhookDelivery{
+			ID:        "2a3b4c5d-6e7f-4809-8a9b-0c1d2e3f4a5b",
+			WebhookID: webhookID,
+			Time:      cce.AuditTime(time.Now()),
+			EventType: events.TypeNodeEnrolled,
+			Payload:   json.RawMessage(`{"id":1,"type":"node.enrolled"}`),
+			Status:    cce.WebhookDeliveryPending,
+		})).To(Succeed())
+
+		done := make(chan error)
+		go func() { done <- dispatcher.Run(ctx) }()
+		Eventually(rcv.Received).Should(HaveLen(1))
+
+		Eventually(dispatcher.Controller.Events.Subscribers).Should(Equal(1))
+		dispatcher.Controller.Events.Publish(&events.Event{Type: events.TypeNodeEnrolled, NodeID: "n1"})
+		Eventually(rcv.Received).Should(HaveLen(2))
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("Should sign the timestamp and payload", func() {
+		signature := "sha256=" + webhook.Sign(secret, "1577880000", []byte(`{}`))
+		Expect(webhook.Verify(secret, "1577880000", []byte(`{}`), signature)).To(BeTrue())
+		Expect(webhook.Verify(secret, "1577880001", []byte(`{}`), signature)).To(BeFalse())
+		Expect(webhook.Verify(secret, "1577880000", []byte(`{ }`), signature)).To(BeFalse())
+		Expect(webhook.Verify("fedcba9876543210", "1577880000", []byte(`{}`), signature)).To(BeFalse())
+	})
+})
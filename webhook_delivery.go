@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is the delivery of an event to a webhook, which is kept as
+// its delivery history.
+type WebhookDelivery struct {
+	ID        string `json:"id"`
+	WebhookID string `json:"webhook_id"`
+
+	// Time is the time the delivery was created in microseconds since the
+	// Unix epoch, like the time of audit events.
+	Time int64 `json:"time"`
+
+	EventType string `json:"event_type"`
+
+	// Payload is the body POSTed to the webhook, which is the event.
+	Payload json.RawMessage `json:"payload"`
+
+	// Status is one of the webhook delivery statuses.
+	Status string `json:"status"`
+
+	// Attempts is the number of times the payload was POSTed.
+	Attempts int `json:"attempts"`
+
+	LastAttemptAt time.Time `json:"last_attempt_at"`
+
+	// NextAttemptAt is the time of the next attempt of a pending delivery.
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+
+	// ResponseStatus is the status code of the response to the last attempt,
+	// or 0 if there was none.
+	ResponseStatus int `json:"response_status"`
+
+	// Error is why the last attempt failed.
+	Error string `json:"error,omitempty"`
+
+	ResourceVersion
+}
+
+// GetTableName returns the name of the persistence table.
+func (*WebhookDelivery) GetTableName() string {
+	return "webhook_deliveries"
+}
+
+// GetID gets the ID.
+func (d *WebhookDelivery) GetID() string {
+	return d.ID
+}
+
+// SetID sets the ID.
+func (d *WebhookDelivery) SetID(id string) {
+	d.ID = id
+}
+
+// GetTime gets the time the delivery was created.
+func (d *WebhookDelivery) GetTime() time.Time {
+	return time.Unix(0, d.Time*int64(time.Microsecond)).UTC()
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*WebhookDelivery) FilterFields() []string {
+	return []string{
+		"webhook_id",
+		"time",
+		"event_type",
+		"status",
+	}
+}
+
+// Validate validates the model.
+func (d *WebhookDelivery) Validate() error {
+	if !uuid.IsValid(d.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(d.WebhookID) {
+		return errors.New("webhook_id not a valid uuid")
+	}
+	if !json.Valid(d.Payload) {
+		return errors.New("payload not valid JSON")
+	}
+	switch d.Status {
+	case WebhookDeliveryPending, WebhookDeliverySucceeded, WebhookDeliveryFailed:
+	default:
+		return fmt.Errorf("unknown status %q", d.Status)
+	}
+
+	return nil
+}
+
+func (d *WebhookDelivery) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+WebhookDelivery[
+    ID: %s
+    WebhookID: %s
+    EventType: %s
+    Status: %s
+    Attempts: %d
+]`),
+		d.ID,
+		d.WebhookID,
+		d.EventType,
+		d.Status,
+		d.Attempts)
+}
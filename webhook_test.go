@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/events"
+)
+
+var _ = Describe("Entities: Webhook", func() {
+	var (
+		w *cce.Webhook
+	)
+
+	BeforeEach(func() {
+		w = &cce.Webhook{
+			ID:         "1f2e3d4c-5b6a-4789-9a8b-7c6d5e4f3a2b",
+			Name:       "noc",
+			URL:        "https://noc.example.com/hooks/cce",
+			EventTypes: []string{events.TypeNodeEnrolled, events.TypePolicyApplied},
+			Secret:     "0123456789abcdef",
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "webhooks"`, func() {
+			Expect(w.GetTableName()).To(Equal("webhooks"))
+		})
+	})
+
+	Describe("GetID", func() {
+		It("Should return the ID", func() {
+			Expect(w.GetID()).To(Equal("1f2e3d4c-5b6a-4789-9a8b-7c6d5e4f3a2b"))
+		})
+	})
+
+	Describe("SetID", func() {
+		It("Should set and return the updated ID", func() {
+			By("Setting the ID")
+			w.SetID("456")
+
+			By("Getting the updated ID")
+			Expect(w.ID).To(Equal("456"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(w.FilterFields()).To(Equal([]string{
+				"name",
+				"url",
+			}))
+		})
+	})
+
+	Describe("NewWebhookSecret", func() {
+		It("Should return random secrets", func() {
+			s1, err := cce.NewWebhookSecret()
+			Expect(err).ToNot(HaveOccurred())
+			s2, err := cce.NewWebhookSecret()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(s1)).To(BeNumerically(">=", cce.WebhookSecretMinLength))
+			Expect(s1).ToNot(Equal(s2))
+		})
+	})
+
+	Describe("Matches", func() {
+		It("Should match the event types", func() {
+			Expect(w.Matches(&events.Event{Type: events.TypeNodeEnrolled})).To(BeTrue())
+			Expect(w.Matches(&events.Event{Type: events.TypeEntityCreated})).To(BeFalse())
+		})
+
+		It("Should match all events without event types", func() {
+			w.EventTypes = nil
+			Expect(w.Matches(&events.Event{Type: events.TypeEntityCreated})).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error for a valid webhook", func() {
+			Expect(w.Validate()).To(Succeed())
+		})
+
+		It("Should return an error for an invalid ID", func() {
+			w.ID = "123"
+			Expect(w.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if there is no name", func() {
+			w.Name = ""
+			Expect(w.Validate()).To(MatchError("name cannot be empty"))
+		})
+
+		It("Should return an error for an invalid URL", func() {
+			for _, url := range []string{"", "/hooks", "ftp://noc.example.com", "https://", "http://[::1"} {
+				w.URL = url
+				Expect(w.Validate()).To(MatchError("url must be an absolute http or https URL"), url)
+			}
+		})
+
+		It("Should return an error for an unknown event type", func() {
+			w.EventTypes = []string{"node.exploded"}
+			Expect(w.Validate()).To(MatchError(`unknown event type "node.exploded"`))
+		})
+
+		It("Should return an error for a short secret", func() {
+			w.Secret = "0123456789abcde"
+			Expect(w.Validate()).To(MatchError("secret must be at least 16 characters"))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value without the secret", func() {
+			Expect(w.String()).To(Equal(strings.TrimSpace(`
+Webhook[
+    ID: 1f2e3d4c-5b6a-4789-9a8b-7c6d5e4f3a2b
+    Name: noc
+    URL: https://noc.example.com/hooks/cce
+    EventTypes: [node.enrolled policy.applied]
+]`,
+			)))
+		})
+	})
+})